@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/client"
+	"github.com/pako-23/gtdd/internal/docker"
+	"github.com/pako-23/gtdd/internal/podman"
+	"github.com/pako-23/gtdd/internal/runner"
+	compose_runner "github.com/pako-23/gtdd/internal/runner/compose-runner"
+	k8s_runner "github.com/pako-23/gtdd/internal/runner/k8s-runner"
+	local_runner "github.com/pako-23/gtdd/internal/runner/local-runner"
+	podman_runner "github.com/pako-23/gtdd/internal/runner/podman-runner"
+	"github.com/pako-23/gtdd/internal/testsuite"
+	"github.com/spf13/viper"
+)
+
+// The backends on which the runners can be created.
+const (
+	backendCompose = "compose"
+	backendK8s     = "k8s"
+	backendPodman  = "podman"
+	backendLocal   = "local"
+)
+
+// composeDefinitions returns the definitions of the application and of the
+// drivers of the test suite at the provided path. The application is defined
+// by the Docker Compose files passed through the configuration or, if there
+// is none, by the docker-compose.yml file of the test suite if it exists. The
+// definitions have no files if there is no application or no driver. If
+// there is any error, it is returned.
+func composeDefinitions(path string) (docker.Definition, docker.Definition, error) {
+	var (
+		profiles = viper.GetStringSlice("profile")
+		envFiles = viper.GetStringSlice("env-file")
+		app      = docker.Definition{Files: viper.GetStringSlice("file"), Profiles: profiles, EnvFiles: envFiles}
+		driver   = docker.Definition{Profiles: profiles, EnvFiles: envFiles}
+	)
+
+	if len(app.Files) == 0 {
+		appComposePath := filepath.Join(path, "docker-compose.yml")
+		if _, err := os.Stat(appComposePath); err == nil {
+			app.Files = []string{appComposePath}
+		} else if !os.IsNotExist(err) {
+			return docker.Definition{}, docker.Definition{}, err
+		}
+	}
+
+	if driverDefinition := viper.GetString("driver"); driverDefinition != "" {
+		driver.Files = []string{driverDefinition}
+	}
+
+	return app, driver, nil
+}
+
+// newRunnerSet creates the set of runners for the test suite at the provided
+// path on the backend selected into the configuration. It also returns the
+// definitions of the application and of the drivers. If there is any error,
+// it is returned.
+func newRunnerSet(ctx context.Context, path string, suite *testsuite.TestSuite) (*runner.RunnerSet, []docker.Definition, error) {
+	appDefinition, driverDefinition, err := composeDefinitions(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	definitions := []docker.Definition{}
+	for _, definition := range []docker.Definition{appDefinition, driverDefinition} {
+		if len(definition.Files) != 0 {
+			definitions = append(definitions, definition)
+		}
+	}
+
+	var runners *runner.RunnerSet
+
+	switch viper.GetString("backend") {
+	case backendCompose:
+		options := []runner.RunnerOption[*compose_runner.ComposeRunner]{
+			compose_runner.WithEnv(viper.GetStringSlice("env")),
+			compose_runner.WithTestSuite(suite),
+		}
+		if len(appDefinition.Files) != 0 {
+			options = append(options, compose_runner.WithAppDefinition(appDefinition))
+		}
+		if len(driverDefinition.Files) != 0 {
+			options = append(options, compose_runner.WithDriverDefinition(driverDefinition))
+		}
+
+		runners, err = buildRunnerSet(ctx, compose_runner.ComposeRunnerBuilder, options...)
+	case backendK8s:
+		options := append(k8sImageOptions(suite), k8s_runner.WithEnv(viper.GetStringSlice("env")))
+		if len(appDefinition.Files) != 0 {
+			options = append(options, k8s_runner.WithAppDefinition(appDefinition))
+		}
+		if len(driverDefinition.Files) != 0 {
+			options = append(options, k8s_runner.WithDriverDefinition(driverDefinition))
+		}
+
+		runners, err = buildRunnerSet(ctx, k8s_runner.K8sRunnerBuilder, options...)
+	case backendPodman:
+		options := []runner.RunnerOption[*podman_runner.PodmanRunner]{
+			podman_runner.WithEnv(viper.GetStringSlice("env")),
+			podman_runner.WithTestSuite(suite),
+		}
+		if len(appDefinition.Files) != 0 {
+			options = append(options, podman_runner.WithAppDefinition(appDefinition))
+		}
+		if len(driverDefinition.Files) != 0 {
+			options = append(options, podman_runner.WithDriverDefinition(driverDefinition))
+		}
+
+		runners, err = buildRunnerSet(ctx, podman_runner.PodmanRunnerBuilder, options...)
+	case backendLocal:
+		runners, err = buildRunnerSet(ctx, local_runner.LocalRunnerBuilder,
+			local_runner.WithEnv(viper.GetStringSlice("env")),
+			local_runner.WithSuitePath(path),
+			local_runner.WithRunCommand(viper.GetString("run-command")),
+			local_runner.WithResetScript(viper.GetString("reset-script")))
+	default:
+		return nil, nil, errors.New("the runner backend does not exist")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return runners, definitions, nil
+}
+
+// buildRunnerSet creates a set of runners with the provided builder and
+// options. The set is elastic if a maximum number of runners is provided into
+// the configuration, and it has a fixed number of runners otherwise.
+func buildRunnerSet[T runner.Runner](ctx context.Context, builder runner.RunnerBuilder[T], options ...runner.RunnerOption[T]) (*runner.RunnerSet, error) {
+	if viper.GetInt("max-runners") == 0 {
+		return runner.NewRunnerSet(ctx, viper.GetInt("runners"), builder, options...)
+	}
+
+	return runner.NewElasticRunnerSet(ctx,
+		getScaling(viper.GetInt("runners"), viper.GetInt("max-runners")), builder, options...)
+}
+
+// dockerOptions returns the options of the Docker client building the images
+// for the backend selected into the configuration. The images are built
+// through the Docker-compatible API of Podman with the Podman backend, so
+// that they are available into the storage of Podman.
+func dockerOptions() []client.Opt {
+	if viper.GetString("backend") == backendPodman {
+		return []client.Opt{client.WithHost(podman.Host()), client.WithAPIVersionNegotiation()}
+	}
+
+	return nil
+}
+
+// k8sImageOptions returns the options of a Kubernetes runner running the
+// image of the test suite selected into the configuration.
+func k8sImageOptions(suite *testsuite.TestSuite) []runner.RunnerOption[*k8s_runner.K8sRunner] {
+	options := []runner.RunnerOption[*k8s_runner.K8sRunner]{k8s_runner.WithTestSuite(suite)}
+	if image := viper.GetString("image"); image != "" {
+		options = append(options, k8s_runner.WithImage(image))
+	}
+
+	return options
+}
+
+// listTests returns the tests of a test suite through the container engine of
+// the backend selected into the configuration, or through the list command if
+// the tests are run as local processes. With the k8s backend, the tests are
+// listed from the image run by the cluster. If there is any error, it is
+// returned.
+func listTests(ctx context.Context, suite *testsuite.TestSuite) ([]string, error) {
+	switch viper.GetString("backend") {
+	case backendK8s:
+		return k8s_runner.ListTests(ctx, k8sImageOptions(suite)...)
+	case backendPodman:
+		return podman_runner.ListTests(ctx, suite)
+	case backendLocal:
+		return local_runner.ListTests(ctx, suite.Path(), viper.GetString("list-command"),
+			viper.GetStringSlice("env"))
+	default:
+		return suite.ListTests(ctx)
+	}
+}
+
+// suiteDigest returns the digest of a test suite through the container engine
+// of the backend selected into the configuration, or of its files if the tests
+// are run as local processes. With the k8s backend, the digest is the ID of
+// the image run by the cluster. If there is any error, it is returned.
+func suiteDigest(ctx context.Context, suite *testsuite.TestSuite) (string, error) {
+	switch viper.GetString("backend") {
+	case backendK8s:
+		return k8s_runner.Digest(ctx, k8sImageOptions(suite)...)
+	case backendPodman:
+		return podman_runner.Digest(ctx, suite)
+	case backendLocal:
+		return local_runner.Digest(suite.Path(), viper.GetString("run-command"),
+			viper.GetString("reset-script"))
+	default:
+		return suite.Digest(ctx)
+	}
+}
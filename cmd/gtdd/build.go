@@ -2,8 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/pako-23/gtdd/internal/docker"
 	"github.com/pako-23/gtdd/internal/testsuite"
@@ -18,30 +16,31 @@ func newBuildCmd() *cobra.Command {
 		Use:   "build [flags] [path to testsuite]",
 		Short: "Builds the artifacts needed to run a test suite",
 		Args:  cobra.ExactArgs(1),
-		Long:  `Creates all the artifacts needed to run the test suite.`,
+		Long: `Creates all the artifacts needed to run the test suite. The images are
+built into the storage of the container engine of the selected backend.`,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			viper.BindPFlags(cmd.Flags())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			path := args[0]
 
-			var waitgroup errgroup.Group
+			waitgroup, ctx := errgroup.WithContext(cmd.Context())
 
 			waitgroup.Go(func() error {
-				composePath := filepath.Join(path, "docker-compose.yml")
-				if _, err := os.Stat(composePath); os.IsNotExist(err) {
-					return nil
-				} else if err != nil {
+				appDefinition, _, err := composeDefinitions(path)
+				if err != nil {
 					return err
+				} else if len(appDefinition.Files) == 0 {
+					return nil
 				}
 
-				client, err := docker.NewClient()
+				client, err := docker.NewClient(dockerOptions()...)
 				if err != nil {
 					return err
 				}
 				defer client.Close()
 
-				_, err = client.NewApp(composePath)
+				_, err = client.NewApp(ctx, appDefinition)
 				return err
 			})
 
@@ -51,7 +50,7 @@ func newBuildCmd() *cobra.Command {
 					return err
 				}
 
-				if err := suite.Build(); err != nil {
+				if err := suite.Build(ctx, dockerOptions()...); err != nil {
 					return fmt.Errorf("test suite artifacts build failed: %w", err)
 				}
 
@@ -67,5 +66,10 @@ func newBuildCmd() *cobra.Command {
 		},
 	}
 
+	buildCommand.Flags().String("backend", backendCompose, "the backend whose container engine stores the images: compose, k8s or podman")
+	buildCommand.Flags().StringArrayP("file", "f", []string{}, "a Docker Compose file defining the application; repeat it to apply overrides")
+	buildCommand.Flags().StringArray("profile", []string{}, "a Docker Compose profile to enable")
+	buildCommand.Flags().StringArray("env-file", []string{}, "a file with the variables to interpolate into the Docker Compose files")
+
 	return buildCommand
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/cache"
+	"github.com/pako-23/gtdd/internal/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newCacheCmd() *cobra.Command {
+	cacheCommand := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect or invalidate the cache of schedule results",
+		Long: `Manages the cache storing the results of the schedules run
+while detecting the dependencies between tests.`,
+	}
+
+	listCommand := &cobra.Command{
+		Use:   "list [flags]",
+		Short: "List the schedule results stored into the cache",
+		Args:  cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, unreadable, err := cache.List(viper.GetString("cache-dir"))
+			if err != nil {
+				return err
+			} else if len(unreadable) > 0 {
+				log.Warnf("%d unreadable cache entries, such as %s; run gtdd cache clear to remove them",
+					len(unreadable), unreadable[0])
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tCREATED\tDURATION\tTESTS\tRESULTS")
+			for _, entry := range entries {
+				results := make([]string, len(entry.Results))
+				for i, outcome := range entry.Results {
+					switch outcome.Status {
+					case runner.StatusPass:
+						results[i] = "1"
+					case runner.StatusFail:
+						results[i] = "0"
+					case runner.StatusError:
+						results[i] = "E"
+					case runner.StatusSkip:
+						results[i] = "S"
+					default:
+						results[i] = "-"
+					}
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%v\t%d\t%s\n",
+					entry.Key[:12],
+					entry.Created.Format(time.RFC3339),
+					entry.RunningTime,
+					len(entry.Schedule),
+					strings.Join(results, ""))
+			}
+
+			return w.Flush()
+		},
+	}
+
+	clearCommand := &cobra.Command{
+		Use:   "clear [flags]",
+		Short: "Remove all the schedule results stored into the cache",
+		Args:  cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			removed, err := cache.Clear(viper.GetString("cache-dir"))
+			if err != nil {
+				return err
+			}
+
+			log.Infof("removed %d entries from the cache", removed)
+
+			return nil
+		},
+	}
+
+	for _, command := range []*cobra.Command{listCommand, clearCommand} {
+		command.Flags().String("cache-dir", defaultCacheDir(), "The directory storing the results of the schedules already run")
+		cacheCommand.AddCommand(command)
+	}
+
+	return cacheCommand
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"os"
-	"path/filepath"
+	"sync/atomic"
+	"time"
 
+	"github.com/pako-23/gtdd/internal/algorithms"
 	"github.com/pako-23/gtdd/internal/runner"
-	"github.com/pako-23/gtdd/internal/runner/compose-runner"
 	"github.com/pako-23/gtdd/internal/testsuite"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -26,64 +29,120 @@ built.`,
 			viper.BindPFlags(cmd.Flags())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path := args[0]
+			ctx, path := cmd.Context(), args[0]
 
-			detector := getDetector(viper.GetString("strategy"))
+			strategy := viper.GetString("strategy")
+			detector := getDetector(strategy)
 			if detector == nil {
 				return errors.New("the dependency detection strategy does not exist")
 			}
 
-			suite, err := testsuite.NewTestSuite(path)
-			if err != nil {
+			if err := checkCheckpoint(viper.GetString("checkpoint")); err != nil {
 				return err
 			}
 
-			tests, err := suite.ListTests()
+			suite, err := testsuite.NewTestSuite(path)
 			if err != nil {
 				return err
 			}
 
-			options := []runner.RunnerOption[*compose_runner.ComposeRunner]{
-				compose_runner.WithEnv(viper.GetStringSlice("env")),
-				compose_runner.WithTestSuite(suite),
-			}
-
-			appComposePath := filepath.Join(path, "docker-compose.yml")
-			if _, err := os.Stat(appComposePath); err == nil {
-				options = append(options,
-					compose_runner.WithAppDefinition(appComposePath))
-			} else if !os.IsNotExist(err) {
+			tests, err := listTests(ctx, suite)
+			if err != nil {
 				return err
 			}
 
-			if viper.GetString("driver") != "" {
-				options = append(options,
-					compose_runner.WithDriverDefinition(viper.GetString("driver")))
-			}
-
-			runners, err := runner.NewRunnerSet(viper.GetInt("runners"),
-				compose_runner.ComposeRunnerBuilder, options...)
+			runners, definitions, err := newRunnerSet(ctx, path, suite)
 			if err != nil {
 				return err
 			}
 			defer func() {
-				if err := runners.Delete(); err != nil {
+				if err := runners.Delete(context.WithoutCancel(ctx)); err != nil {
 					log.Error(err)
 				}
 			}()
+			runners.SetTimeouts(getTimeouts())
+			runners.SetRecovery(getRecovery())
+
+			digest, err := suiteDigest(ctx, suite)
+			if err != nil {
+				return fmt.Errorf("failed to compute test suite digest: %w", err)
+			}
+
+			// The fingerprints are only needed to find the changed tests and
+			// to let the graph be the base of a later incremental detection.
+			var fingerprints map[string]string
+			if viper.GetString("base") != "" || !viper.GetBool("no-fingerprints") {
+				if fingerprints, err = suite.Fingerprints(tests); err != nil {
+					return err
+				}
+			}
+
+			if viper.GetString("base") != "" {
+				detector, err = incrementalDetector(viper.GetString("base"), tests, digest, fingerprints)
+				if err != nil {
+					return err
+				}
+				strategy = "incremental"
+			}
+
+			var (
+				oracle     runner.ScheduleRunner = runners
+				checkpoint *algorithms.Checkpoint
+			)
+
+			if !viper.GetBool("no-cache") {
+				resultsCache, err := newCache(digest, definitions, runners)
+				if err != nil {
+					return err
+				}
+				defer func() {
+					hits, misses := resultsCache.Stats()
+					log.Infof("reused %d cached schedules, run %d schedules", hits, misses)
+				}()
+
+				oracle = resultsCache
+			}
+
+			counter := &scheduleCounter{ScheduleRunner: oracle}
+			oracle = counter
+
+			if viper.GetString("checkpoint") != "" {
+				checkpoint, err = newCheckpoint(viper.GetString("checkpoint"), strategy, tests, oracle)
+				if err != nil {
+					return err
+				}
+
+				oracle = checkpoint
+			}
 
-			g, err := detector(tests, runners)
+			startedAt := time.Now()
+			g, err := detector(ctx, tests, oracle)
 			if err != nil {
+				if checkpoint != nil {
+					if saveErr := checkpoint.Save(); saveErr != nil {
+						log.Error(saveErr)
+					}
+				}
+
 				return err
 			}
 
-			file, err := os.Create(viper.GetString("output"))
+			err = writeGraph(viper.GetString("output"), g, algorithms.GraphMetadata{
+				Strategy:     strategy,
+				Digest:       digest,
+				Tests:        tests,
+				Fingerprints: fingerprints,
+				StartedAt:    startedAt,
+				FinishedAt:   time.Now(),
+				Schedules:    int(counter.count.Load()),
+			})
 			if err != nil {
-				log.Fatalf("failed to create output file %s: %v", viper.GetString("output"), err)
+				return err
 			}
-			defer file.Close()
 
-			g.ToJSON(file)
+			if checkpoint != nil {
+				return checkpoint.Remove()
+			}
 
 			return nil
 		},
@@ -91,9 +150,130 @@ built.`,
 
 	depsCommand.Flags().StringArrayP("env", "e", []string{}, "An environment variable to pass to the test suite container")
 	depsCommand.Flags().StringP("driver", "d", "", "The path to a Docker Compose file configuring the driver")
+	depsCommand.Flags().StringArrayP("file", "f", []string{}, "A Docker Compose file defining the application; repeat it to apply overrides")
+	depsCommand.Flags().StringArray("profile", []string{}, "A Docker Compose profile to enable")
+	depsCommand.Flags().StringArray("env-file", []string{}, "A file with the variables to interpolate into the Docker Compose files")
 	depsCommand.Flags().StringP("output", "o", "graph.json", "The file used to output the resulting dependency graph")
-	depsCommand.Flags().StringP("strategy", "s", "pfast", "The strategy to detect dependencies between tests")
+	depsCommand.Flags().StringP("strategy", "s", "pfast", "The strategy to detect dependencies between tests: pfast, pradet, mem-fast or random")
+	depsCommand.Flags().Uint("random-runs", algorithms.DefaultRandomRuns, "The number of random orders run by the random strategy")
+	depsCommand.Flags().Int64("seed", 0, "The seed generating the orders of the random strategy; 0 to use the current time, or the seed of the checkpoint when resuming")
+	depsCommand.Flags().String("classification", "classification.json", "The file used to output the order-dependent tests found by the random strategy")
+	depsCommand.Flags().String("base", "", "A dependency graph of a previous version of the test suite to reuse for the tests which did not change")
+	depsCommand.Flags().Int("confirmations", algorithms.DefaultConfirmations, "The number of schedules run to confirm the dependencies reused from the base graph")
 	depsCommand.Flags().UintP("runners", "r", runner.DefaultSetSize, "The number of concurrent runners")
+	depsCommand.Flags().Uint("max-runners", 0, "The maximum number of runners, growing from --runners while schedules wait for a runner; 0 to keep --runners runners")
+	depsCommand.Flags().Duration("idle-timeout", time.Minute, "The time the runners stay idle before removing one, down to --runners")
+	depsCommand.Flags().Float64("runner-cpus", 1, "The CPUs needed by each runner, bounding the number of runners by the CPUs in use on the host; ignored with the k8s backend")
+	depsCommand.Flags().Uint64("runner-memory", 1024, "The memory in MiB needed by each runner, bounding the number of runners by the host memory; ignored with the k8s backend")
+	depsCommand.Flags().String("backend", backendCompose, "The backend running the runners: compose, k8s, podman or local")
+	depsCommand.Flags().String("image", "", "The image running the test suite with the k8s backend, such as the test suite image pushed into a registry reachable from the cluster")
+	depsCommand.Flags().String("list-command", "", "The shell command listing the tests with the local backend")
+	depsCommand.Flags().String("run-command", "", "The shell command running the tests passed as arguments with the local backend")
+	depsCommand.Flags().String("reset-script", "", "The shell command resetting the application before each schedule with the local backend")
+	depsCommand.Flags().String("checkpoint", "checkpoint.json", "The file used to store the progress of the detection; empty to disable it")
+	depsCommand.Flags().Duration("checkpoint-interval", time.Minute, "The minimum time between two writes of the checkpoint file")
+	depsCommand.Flags().Bool("resume", false, "Resume the detection from the checkpoint file")
+	depsCommand.Flags().Bool("force", false, "Start a new detection overwriting the checkpoint file of an interrupted one")
+	depsCommand.Flags().String("cache-dir", defaultCacheDir(), "The directory storing the results of the schedules already run")
+	depsCommand.Flags().Bool("no-cache", false, "Run all the schedules without using the results cache")
+	depsCommand.Flags().Bool("no-fingerprints", false, "Do not record the fingerprints of the tests into the graph, which then finds all the tests changed when used as --base")
+	depsCommand.Flags().Duration("test-timeout", 0, "The maximum time allowed to each test without an outcome; 0 to disable it")
+	depsCommand.Flags().Duration("schedule-timeout", 0, "The maximum time allowed to each schedule; 0 to disable it")
+	depsCommand.Flags().Int("rebuilds", runner.DefaultRecovery.Rebuilds, "The maximum number of attempts to rebuild a failed runner before removing it")
+	depsCommand.Flags().Int("retries", runner.DefaultRecovery.Retries, "The maximum number of times a schedule is run again after an infrastructure failure")
 
 	return depsCommand
 }
+
+// scheduleCounter counts the schedules run on an oracle.
+type scheduleCounter struct {
+	runner.ScheduleRunner
+	count atomic.Int64
+}
+
+func (s *scheduleCounter) RunSchedule(ctx context.Context, schedule []string) (runner.RunResults, error) {
+	s.count.Add(1)
+
+	return s.ScheduleRunner.RunSchedule(ctx, schedule)
+}
+
+// checkCheckpoint checks that a new detection does not overwrite the
+// checkpoint file left by an interrupted one, unless it is resumed or the
+// overwrite is forced. If the checkpoint would be lost, an error is returned.
+func checkCheckpoint(path string) error {
+	if path == "" || viper.GetBool("resume") || viper.GetBool("force") {
+		return nil
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("the checkpoint file %s of an interrupted detection exists: use --resume to resume it or --force to start over", path)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to check checkpoint file: %w", err)
+	}
+
+	return nil
+}
+
+// newCheckpoint returns the checkpoint used to record the progress of a
+// dependency detection. If the detection should be resumed, the checkpoint
+// is loaded from the provided path.
+func newCheckpoint(path, strategy string, tests []string, runners runner.ScheduleRunner) (*algorithms.Checkpoint, error) {
+	interval := viper.GetDuration("checkpoint-interval")
+
+	if !viper.GetBool("resume") {
+		return algorithms.NewCheckpoint(path, strategy, tests, runners, interval), nil
+	}
+
+	checkpoint, err := algorithms.ResumeCheckpoint(path, strategy, tests, runners, interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resume detection: %w", err)
+	}
+
+	return checkpoint, nil
+}
+
+// writeGraph writes the dependency graph together with its metadata into
+// the provided file. If there is any error, it is returned.
+func writeGraph(fileName string, g algorithms.DependencyGraph, metadata algorithms.GraphMetadata) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to create output file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	g.ToJSON(file, metadata)
+
+	return nil
+}
+
+// incrementalDetector returns a DependencyDetector reusing the dependency
+// graph into the provided file for the tests which did not change since it
+// was produced. If there is any error, it is returned.
+func incrementalDetector(fileName string, tests []string, digest string, fingerprints map[string]string) (algorithms.DependencyDetector, error) {
+	base, metadata, err := algorithms.ReadDependencyGraph(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read base graph %s: %w", fileName, err)
+	} else if metadata == nil {
+		log.Warnf("the base graph %s has no metadata, all the tests are considered changed", fileName)
+	}
+
+	changed := algorithms.ChangedTests(base, metadata, tests, digest, fingerprints)
+	log.Infof("found %d new or modified tests", len(changed))
+
+	return algorithms.Incremental(base, changed, viper.GetInt("confirmations")), nil
+}
+
+// writeClassification writes the classification of the order-dependent tests
+// into the provided file. If there is any error, it is returned.
+func writeClassification(fileName string, classification *algorithms.Classification) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to create classification file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	classification.ToJSON(file)
+	log.Infof("written test classification to %s", fileName)
+
+	return nil
+}
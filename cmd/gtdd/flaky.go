@@ -1,13 +1,11 @@
 package main
 
 import (
+	"context"
 	"errors"
-	"fmt"
-	"os"
-	"path/filepath"
 	"runtime"
-	"slices"
 	"strings"
+	"time"
 
 	"github.com/pako-23/gtdd/internal/runner"
 	compose_runner "github.com/pako-23/gtdd/internal/runner/compose-runner"
@@ -28,13 +26,13 @@ order to detect flakiness.`,
 			viper.BindPFlags(cmd.Flags())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path := args[0]
+			ctx, path := cmd.Context(), args[0]
 
 			suite, err := testsuite.NewTestSuite(path)
 			if err != nil {
 				return err
 			}
-			tests, err := suite.ListTests()
+			tests, err := suite.ListTests(ctx)
 			if err != nil {
 				return err
 			}
@@ -44,55 +42,46 @@ order to detect flakiness.`,
 				compose_runner.WithTestSuite(suite),
 			}
 
-			appComposePath := filepath.Join(path, "docker-compose.yml")
-			if _, err := os.Stat(appComposePath); err == nil {
-				options = append(options,
-					compose_runner.WithAppDefinition(appComposePath))
-			} else if !os.IsNotExist(err) {
+			appDefinition, driverDefinition, err := composeDefinitions(path)
+			if err != nil {
 				return err
 			}
 
-			if viper.GetString("driver") != "" {
+			if len(appDefinition.Files) != 0 {
+				options = append(options,
+					compose_runner.WithAppDefinition(appDefinition))
+			}
+
+			if len(driverDefinition.Files) != 0 {
 				options = append(options,
-					compose_runner.WithDriverDefinition(viper.GetString("driver")))
+					compose_runner.WithDriverDefinition(driverDefinition))
 			}
 
-			runners, err := runner.NewRunnerSet(viper.GetInt("max-runners"),
-				compose_runner.ComposeRunnerBuilder, options...)
+			runners, err := buildRunnerSet(ctx, compose_runner.ComposeRunnerBuilder, options...)
 			if err != nil {
 				return err
 			}
 			defer func() {
-				if err := runners.Delete(); err != nil {
+				if err := runners.Delete(context.WithoutCancel(ctx)); err != nil {
 					log.Error(err)
 				}
 			}()
+			runners.SetTimeouts(getTimeouts())
+			runners.SetRecovery(getRecovery())
 
 			schedules, err := getSchedules(tests, "")
 			if err != nil {
 				return err
 			}
 
+			workerCtx, stopWorkers := context.WithCancel(ctx)
+			defer stopWorkers()
+
 			scheduleCh := make(chan []string, runners.Size())
 			errCh, resultsCh := make(chan error), make(chan runResults, runners.Size())
 
 			for i := 0; i < runners.Size(); i++ {
-				go func() {
-					for schedule := range scheduleCh {
-						out, err := runners.RunSchedule(schedule)
-						if err != nil {
-							errCh <- err
-
-							continue
-						}
-
-						resultsCh <- runResults{
-							schedule: schedule,
-							results:  out.Results,
-							time:     out.RunningTime}
-
-					}
-				}()
+				go runWorker(workerCtx, runners, scheduleCh, resultsCh, errCh)
 			}
 
 			errorMessages := []string{}
@@ -103,13 +92,12 @@ order to detect flakiness.`,
 
 				for j := 0; j < i; j++ {
 					select {
+					case <-ctx.Done():
+						return ctx.Err()
 					case err := <-errCh:
 						errorMessages = append(errorMessages, err.Error())
 					case result := <-resultsCh:
-						failed := slices.Index(result.results, false)
-						if failed != -1 {
-							msg := fmt.Sprintf("test %v failed in schedule %v",
-								result.schedule[failed], result.schedule)
+						if msg := result.failure(); msg != "" {
 							errorMessages = append(errorMessages, msg)
 						}
 					}
@@ -121,9 +109,6 @@ order to detect flakiness.`,
 
 				log.Infof("Testsuite is not flaky with parallelism %d", i)
 			}
-			close(scheduleCh)
-			close(resultsCh)
-
 			if len(errorMessages) > 0 {
 				return errors.New(strings.Join(errorMessages, "\n"))
 			}
@@ -134,7 +119,18 @@ order to detect flakiness.`,
 
 	flakyCommand.Flags().StringArrayP("env", "e", []string{}, "an environment variable to pass to the test suite container")
 	flakyCommand.Flags().StringP("driver", "d", "", "the path to a Docker Compose file configuring the driver")
-	flakyCommand.Flags().Uint("max-runners", uint(runtime.NumCPU()), "the maximum number of concurrent runners")
+	flakyCommand.Flags().StringArrayP("file", "f", []string{}, "a Docker Compose file defining the application; repeat it to apply overrides")
+	flakyCommand.Flags().StringArray("profile", []string{}, "a Docker Compose profile to enable")
+	flakyCommand.Flags().StringArray("env-file", []string{}, "a file with the variables to interpolate into the Docker Compose files")
+	flakyCommand.Flags().UintP("runners", "r", uint(runtime.NumCPU()), "the number of concurrent runners")
+	flakyCommand.Flags().Uint("max-runners", 0, "the maximum number of runners, growing from --runners while schedules wait for a runner; 0 to keep --runners runners")
+	flakyCommand.Flags().Duration("idle-timeout", time.Minute, "the time the runners stay idle before removing one, down to --runners")
+	flakyCommand.Flags().Float64("runner-cpus", 1, "the CPUs needed by each runner, bounding the number of runners by the CPUs in use on the host")
+	flakyCommand.Flags().Uint64("runner-memory", 1024, "the memory in MiB needed by each runner, bounding the number of runners by the host memory")
+	flakyCommand.Flags().Duration("test-timeout", 0, "the maximum time allowed to each test without an outcome; 0 to disable it")
+	flakyCommand.Flags().Duration("schedule-timeout", 0, "the maximum time allowed to each schedule; 0 to disable it")
+	flakyCommand.Flags().Int("rebuilds", runner.DefaultRecovery.Rebuilds, "the maximum number of attempts to rebuild a failed runner before removing it")
+	flakyCommand.Flags().Int("retries", runner.DefaultRecovery.Retries, "the maximum number of times a schedule is run again after an infrastructure failure")
 
 	return flakyCommand
 }
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/pako-23/gtdd/internal/algorithms"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func newGraphCmd() *cobra.Command {
@@ -29,5 +34,108 @@ read this format is GraphViz.`,
 		},
 	}
 
+	diffCommand := &cobra.Command{
+		Use:   "diff [flags] [old graph file] [new graph file]",
+		Short: "Report the tests and edges changed between two dependency graphs",
+		Args:  cobra.ExactArgs(2),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			old, err := algorithms.DependencyGraphFromJson(args[0])
+			if err != nil {
+				return err
+			}
+
+			updated, err := algorithms.DependencyGraphFromJson(args[1])
+			if err != nil {
+				return err
+			}
+
+			diff := algorithms.Diff(old, updated)
+
+			switch viper.GetString("format") {
+			case "text":
+				diff.ToText(os.Stdout)
+			case "json":
+				data, err := json.MarshalIndent(diff, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to create json from data: %w", err)
+				}
+
+				if _, err := os.Stdout.Write(append(data, '\n')); err != nil {
+					return err
+				}
+			default:
+				return errors.New("the diff format does not exist")
+			}
+
+			if viper.GetBool("exit-code") && !diff.Empty() {
+				return errors.New("the dependency graphs differ")
+			}
+
+			return nil
+		},
+	}
+
+	mergeCommand := &cobra.Command{
+		Use:   "merge [flags] [graph files]",
+		Short: "Merge dependency graphs produced by different strategies or runs",
+		Args:  cobra.MinimumNArgs(1),
+		Long: `Writes the union of the provided dependency graphs. The edges
+contradicted by another graph or by the evidence stored into the graphs
+are reported as conflicts. No graph is written if the union has a
+dependency cycle, as no order of the tests satisfies it.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				graphs   = make([]algorithms.DependencyGraph, 0, len(args))
+				metadata = make([]*algorithms.GraphMetadata, 0, len(args))
+			)
+
+			for _, fileName := range args {
+				g, m, err := algorithms.ReadDependencyGraph(fileName)
+				if err != nil {
+					return fmt.Errorf("failed to read graph %s: %w", fileName, err)
+				}
+
+				graphs = append(graphs, g)
+				metadata = append(metadata, m)
+			}
+
+			merged, conflicts := algorithms.Merge(graphs...)
+			for _, conflict := range conflicts {
+				log.Warnf("conflicting edge %v", conflict)
+			}
+
+			if merged.HasDependencyCycle() {
+				return errors.New("the merged dependency graph has a dependency cycle")
+			}
+
+			file, err := os.Create(viper.GetString("output"))
+			if err != nil {
+				return fmt.Errorf("failed to create output file %s: %w", viper.GetString("output"), err)
+			}
+			defer file.Close()
+
+			merged.ToJSON(file, algorithms.MergeMetadata(metadata...))
+
+			if viper.GetBool("strict") && len(conflicts) > 0 {
+				return fmt.Errorf("found %d conflicting edges", len(conflicts))
+			}
+
+			return nil
+		},
+	}
+
+	diffCommand.Flags().String("format", "text", "The format of the report: text or json")
+	diffCommand.Flags().Bool("exit-code", false, "Exit with an error if the graphs differ")
+	mergeCommand.Flags().StringP("output", "o", "graph.json", "The file used to output the merged dependency graph")
+	mergeCommand.Flags().Bool("strict", false, "Exit with an error if any edge is conflicting")
+
+	graphCommand.AddCommand(diffCommand, mergeCommand)
+
 	return graphCommand
 }
@@ -1,49 +1,155 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pako-23/gtdd/internal/algorithms"
+	"github.com/pako-23/gtdd/internal/cache"
+	"github.com/pako-23/gtdd/internal/docker"
+	"github.com/pako-23/gtdd/internal/history"
 	"github.com/pako-23/gtdd/internal/runner"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 	"golang.org/x/exp/slices"
 )
 
 type runResults struct {
-	results  []bool
+	results  []runner.TestOutcome
 	schedule []string
+	runner   string
 	time     time.Duration
 }
 
-func runSchedules(schedules [][]string, runners *runner.RunnerSet) (time.Duration, error) {
-	scheduleCh := make(chan []string, runners.Size())
-	errCh, resultsCh := make(chan error), make(chan runResults, runners.Size())
+// failure returns a message describing the first test which did not pass
+// into the schedule. If all the tests passed, it returns an empty string.
+func (r runResults) failure() string {
+	failed := runner.FirstNotPassed(r.results)
+	if failed == -1 {
+		return ""
+	}
 
-	for i := 0; i < runners.Size(); i++ {
-		go func() {
-			for schedule := range scheduleCh {
-				out, err := runners.RunSchedule(schedule)
-				if err != nil {
-					errCh <- err
+	var (
+		outcome = r.results[failed]
+		msg     string
+	)
 
-					continue
-				}
+	switch outcome.Status {
+	case runner.StatusError:
+		msg = fmt.Sprintf("test %v errored in schedule %v", r.schedule[failed], r.schedule)
+	case runner.StatusTimeout:
+		msg = fmt.Sprintf("test %v timed out in schedule %v", r.schedule[failed], r.schedule)
+	case runner.StatusNotRun:
+		msg = fmt.Sprintf("test %v was not run in schedule %v", r.schedule[failed], r.schedule)
+	default:
+		msg = fmt.Sprintf("test %v failed in schedule %v", r.schedule[failed], r.schedule)
+	}
 
-				resultsCh <- runResults{
-					schedule: schedule,
-					results:  out.Results,
-					time:     out.RunningTime}
+	if outcome.Message != "" {
+		msg += ": " + outcome.Message
+	}
 
-			}
-		}()
+	return msg
+}
+
+// without returns the results of the schedule without the outcomes of the
+// provided tests.
+func (r runResults) without(tests map[string]struct{}) runResults {
+	if len(tests) == 0 {
+		return r
+	}
+
+	filtered := runResults{
+		results:  make([]runner.TestOutcome, 0, len(r.results)),
+		schedule: make([]string, 0, len(r.schedule)),
+		runner:   r.runner,
+		time:     r.time,
+	}
+
+	for i, test := range r.schedule {
+		if _, ok := tests[test]; ok {
+			continue
+		}
+
+		filtered.schedule = append(filtered.schedule, test)
+		if i < len(r.results) {
+			filtered.results = append(filtered.results, r.results[i])
+		}
+	}
+
+	return filtered
+}
+
+// getTimeouts returns the timeouts applied to the schedules based on the
+// configuration.
+func getTimeouts() runner.Timeouts {
+	return runner.Timeouts{
+		Test:     viper.GetDuration("test-timeout"),
+		Schedule: viper.GetDuration("schedule-timeout"),
+	}
+}
+
+// getRecovery returns the policy used by the runners to recover from
+// infrastructure failures based on the configuration.
+func getRecovery() runner.Recovery {
+	recovery := runner.DefaultRecovery
+	recovery.Rebuilds = viper.GetInt("rebuilds")
+	recovery.Retries = viper.GetInt("retries")
+
+	return recovery
+}
+
+// getScaling returns the scaling of an elastic set of runners between the
+// provided minimum and maximum number of runners based on the configuration.
+// The growth of the set is bounded by the resources of the host needed by
+// each runner, unless the runners run on a cluster rather than on the host.
+func getScaling(min, max int) runner.Scaling {
+	scaling := runner.Scaling{
+		Min:         min,
+		Max:         max,
+		IdleTimeout: viper.GetDuration("idle-timeout"),
+	}
+
+	if viper.GetString("backend") != backendK8s {
+		scaling.Headroom = runner.HostHeadroom(viper.GetFloat64("runner-cpus"),
+			viper.GetUint64("runner-memory")*1024*1024)
+	}
+
+	return scaling
+}
+
+// runSchedules runs the schedules on a set of runners and returns the
+// running time of the longest schedule. The outcomes of the tests known to be
+// polluted into a schedule, indexed by schedule, are ignored. The results of
+// each schedule are passed to the provided recorders. If any test does not
+// pass, an error describing the failures is returned.
+func runSchedules(ctx context.Context, schedules [][]string, runners *runner.RunnerSet, polluted map[string]map[string]struct{}, recorders ...func([]string, runner.RunResults)) (time.Duration, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	scheduleCh := make(chan []string, runners.Size())
+	errCh, resultsCh := make(chan error), make(chan runResults, runners.Size())
+
+	for i := 0; i < runners.Size(); i++ {
+		go runWorker(ctx, runners, scheduleCh, resultsCh, errCh)
 	}
 
 	go func() {
 		for _, schedule := range schedules {
-			scheduleCh <- schedule
+			select {
+			case scheduleCh <- schedule:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 
@@ -54,24 +160,34 @@ func runSchedules(schedules [][]string, runners *runner.RunnerSet) (time.Duratio
 
 	for i := 0; i < len(schedules); i++ {
 		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
 		case err := <-errCh:
 			return 0, err
 		case result := <-resultsCh:
-			failed := slices.Index(result.results, false)
-			if failed != -1 {
-				msg := fmt.Sprintf("test %v failed in schedule %v",
-					result.schedule[failed], result.schedule)
+			if tests := polluted[scheduleKey(result.schedule)]; len(tests) > 0 {
+				log.Infof("ignoring the outcomes of %d tests polluted into schedule %v", len(tests), result.schedule)
+				result = result.without(tests)
+			}
+
+			if msg := result.failure(); msg != "" {
 				errorMessages = append(errorMessages, msg)
 			}
 
+			for _, record := range recorders {
+				record(result.schedule, runner.RunResults{
+					Results:     result.results,
+					RunningTime: result.time,
+					Runner:      result.runner,
+				})
+			}
+
 			log.Infof("run schedule in %v", result.time)
 			if duration < result.time {
 				duration = result.time
 			}
 		}
 	}
-	close(scheduleCh)
-	close(resultsCh)
 
 	if len(errorMessages) > 0 {
 		return 0, errors.New(strings.Join(errorMessages, "\n"))
@@ -80,6 +196,42 @@ func runSchedules(schedules [][]string, runners *runner.RunnerSet) (time.Duratio
 	return duration, nil
 }
 
+// runWorker runs the schedules received from a channel on a set of runners
+// until the context is cancelled. The results of the schedules are sent on
+// the results channel, while the errors are sent on the errors channel.
+func runWorker(ctx context.Context, runners *runner.RunnerSet, scheduleCh <-chan []string, resultsCh chan<- runResults, errCh chan<- error) {
+	for {
+		var schedule []string
+
+		select {
+		case <-ctx.Done():
+			return
+		case schedule = <-scheduleCh:
+		}
+
+		out, err := runners.RunSchedule(ctx, schedule)
+		if err != nil {
+			select {
+			case errCh <- err:
+			case <-ctx.Done():
+				return
+			}
+
+			continue
+		}
+
+		select {
+		case resultsCh <- runResults{
+			schedule: schedule,
+			results:  out.Results,
+			runner:   out.Runner,
+			time:     out.RunningTime}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 func getSchedules(tests []string, inputFileName string) ([][]string, error) {
 	if inputFileName == "" {
 		return [][]string{tests}, nil
@@ -92,6 +244,224 @@ func getSchedules(tests []string, inputFileName string) ([][]string, error) {
 	return graph.GetSchedules(tests), err
 }
 
+// computeSchedules returns the schedules covering the tests based on the
+// dependencies into the graph. If the optimization is enabled into the
+// configuration, the tests are packed into at most as many schedules as
+// runners. It is shared by the schedules and run commands, so that they
+// compute the same schedules from the same flags. If there is any error, it
+// is returned.
+func computeSchedules(path string, tests []string, graphFileName string) ([][]string, error) {
+	if viper.GetBool("optimize") && graphFileName != "" {
+		return getOptimizedSchedules(path, tests, graphFileName, viper.GetInt("runners"),
+			viper.GetString("durations"))
+	}
+
+	return getSchedules(tests, graphFileName)
+}
+
+// getOptimizedSchedules returns at most runners schedules covering the tests
+// based on the dependencies into the graph and on the durations of the tests
+// provided by the durations file if any, or by the history of the test suite
+// at the provided path otherwise. If there is any error, it is returned.
+func getOptimizedSchedules(path string, tests []string, graphFileName string, runners int, durationsFileName string) ([][]string, error) {
+	graph, err := algorithms.DependencyGraphFromJson(graphFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schedules from graph: %w", err)
+	}
+
+	var durations map[string]time.Duration
+	if durationsFileName != "" {
+		durations, err = readDurations(durationsFileName)
+	} else {
+		var hist *history.History
+		if hist, err = openHistory(path); err == nil {
+			durations = hist.Durations()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return graph.OptimizeSchedules(tests, runners, durations), nil
+}
+
+// readDurations reads the durations of the tests from a JSON file mapping
+// each test to its duration in seconds. If there is any error, it is
+// returned.
+func readDurations(fileName string) (map[string]time.Duration, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read durations file: %w", err)
+	}
+
+	seconds := map[string]float64{}
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return nil, fmt.Errorf("failed to decode durations data: %w", err)
+	}
+
+	durations := make(map[string]time.Duration, len(seconds))
+	for test, value := range seconds {
+		durations[test] = time.Duration(value * float64(time.Second))
+	}
+
+	return durations, nil
+}
+
+// getChangedTests returns the tests provided into the configuration as
+// changed, either directly or into a file listing one test per line. If
+// there is any error, it is returned.
+func getChangedTests() ([]string, error) {
+	changed := slices.Clone(viper.GetStringSlice("changed-tests"))
+
+	if fileName := viper.GetString("changed-tests-file"); fileName != "" {
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read changed tests file: %w", err)
+		}
+
+		for _, line := range strings.Split(string(data), "\n") {
+			if test := strings.TrimSpace(line); test != "" {
+				changed = append(changed, test)
+			}
+		}
+	}
+
+	return changed, nil
+}
+
+// selectTests returns the changed tests together with the tests which have
+// to run with them based on the dependencies into the graph, into their
+// original order. Without a graph, all the tests up to the last changed test
+// are returned. If there is any error, it is returned.
+func selectTests(tests, changed []string, graphFileName string) ([]string, error) {
+	for _, test := range changed {
+		if !slices.Contains(tests, test) {
+			log.Warnf("changed test %s is not into the test suite", test)
+		}
+	}
+
+	if graphFileName == "" {
+		last := -1
+		for i, test := range tests {
+			if slices.Contains(changed, test) {
+				last = i
+			}
+		}
+
+		return tests[:last+1], nil
+	}
+
+	graph, err := algorithms.DependencyGraphFromJson(graphFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select tests from graph: %w", err)
+	}
+
+	return graph.SelectTests(tests, changed), nil
+}
+
+// parseShard parses a shard of the schedules given as i/K, where K is the
+// number of shards and i is the shard, starting from 1. If there is any
+// error, it is returned.
+func parseShard(shard string) (int, int, error) {
+	index, total, ok := strings.Cut(shard, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid shard %s: expected i/K", shard)
+	}
+
+	i, err := strconv.Atoi(index)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid shard %s: %w", shard, err)
+	}
+
+	k, err := strconv.Atoi(total)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid shard %s: %w", shard, err)
+	}
+
+	if k < 1 || i < 1 || i > k {
+		return 0, 0, fmt.Errorf("invalid shard %s: expected 1 <= i <= K", shard)
+	}
+
+	return i, k, nil
+}
+
+// getShardDurations returns the durations of the tests used to balance the
+// shards, read from the durations file if any. The history is not used, as
+// each machine running a shard must compute the same shards.
+func getShardDurations(durationsFileName string) (map[string]time.Duration, error) {
+	if durationsFileName == "" {
+		return nil, nil
+	}
+
+	return readDurations(durationsFileName)
+}
+
+// shardSchedules splits the schedules into the provided number of shards
+// balancing their running time by the durations file into the
+// configuration. If there is any error, it is returned.
+func shardSchedules(schedules [][]string, total int) ([][][]string, error) {
+	durations, err := getShardDurations(viper.GetString("durations"))
+	if err != nil {
+		return nil, err
+	}
+
+	return algorithms.Shard(schedules, total, durations), nil
+}
+
+// readShards reads the shards of the schedules from a file written by gtdd
+// schedules with the provided number of shards. If there is any error, it is
+// returned.
+func readShards(fileName string, total int) ([][][]string, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules file: %w", err)
+	}
+
+	var shards [][][]string
+	if err := json.Unmarshal(data, &shards); err != nil {
+		return nil, fmt.Errorf("failed to parse schedules file %s, expected the output of gtdd schedules --shards: %w",
+			fileName, err)
+	}
+
+	if len(shards) != total {
+		return nil, fmt.Errorf("the schedules file %s contains %d shards, while %d shards were requested",
+			fileName, len(shards), total)
+	}
+
+	return shards, nil
+}
+
+// scheduleKey returns the key identifying a schedule.
+func scheduleKey(schedule []string) string {
+	return strings.Join(schedule, "\x00")
+}
+
+// pollutedRuns returns the tests known to be polluted into each of the
+// provided schedules, indexed by schedule, based on the graph into the
+// provided file. The tests are only returned if they also run into a
+// schedule where they are not polluted, so that their outcome is taken from
+// that schedule. If no graph is provided, no test is returned. If there is
+// any error, it is returned.
+func pollutedRuns(schedules [][]string, graphFileName string) (map[string]map[string]struct{}, error) {
+	polluted := map[string]map[string]struct{}{}
+	if graphFileName == "" {
+		return polluted, nil
+	}
+
+	graph, err := algorithms.DependencyGraphFromJson(graphFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get polluted tests from graph: %w", err)
+	}
+
+	for i, tests := range graph.PollutedRuns(schedules) {
+		if len(tests) > 0 {
+			polluted[scheduleKey(schedules[i])] = tests
+		}
+	}
+
+	return polluted, nil
+}
+
 func getDetector(strategy string) algorithms.DependencyDetector {
 	switch strategy {
 	case "pfast":
@@ -100,7 +470,84 @@ func getDetector(strategy string) algorithms.DependencyDetector {
 		return algorithms.PraDet
 	case "mem-fast":
 		return algorithms.MEMFAST
+	case "random":
+		return func(ctx context.Context, tests []string, oracle runner.ScheduleRunner) (algorithms.DependencyGraph, error) {
+			var classification algorithms.Classification
+
+			g, err := algorithms.RandomOrder(viper.GetInt("random-runs"), viper.GetInt64("seed"),
+				&classification)(ctx, tests, oracle)
+			if err != nil {
+				return nil, err
+			}
+
+			return g, writeClassification(viper.GetString("classification"), &classification)
+		}
 	default:
 		return nil
 	}
 }
+
+// defaultCacheDir returns the default directory storing the results of the
+// schedules already run.
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ".gtdd-cache"
+	}
+
+	return filepath.Join(dir, "gtdd")
+}
+
+// defaultHistoryDir returns the default directory storing the durations of
+// the tests and schedules already run.
+func defaultHistoryDir() string {
+	return filepath.Join(defaultCacheDir(), "history")
+}
+
+// openHistory returns the history of the test suite at the provided path.
+// The history is named after the directory of the test suite and a hash of
+// its absolute path, so that test suites into directories with the same
+// name do not share their durations.
+func openHistory(path string) (*history.History, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256([]byte(absPath))
+
+	return history.Open(viper.GetString("history-dir"),
+		filepath.Base(absPath)+"-"+hex.EncodeToString(hash[:8]))
+}
+
+// newCache returns a cache of the results of the schedules run on the test
+// suite with the provided digest against the application and driver defined
+// by the provided definitions. The definitions are digested once resolved,
+// as the variables interpolated into them change the App which is run. The
+// results are only shared between runs on the same backend, as the commands
+// of the local backend run the tests outside of the test suite image.
+func newCache(digest string, definitions []docker.Definition, oracle runner.ScheduleRunner) (*cache.Cache, error) {
+	digests := make([]string, 0, len(definitions))
+	for _, definition := range definitions {
+		definitionDigest, err := definition.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute definitions digest: %w", err)
+		}
+		digests = append(digests, definitionDigest)
+	}
+	definitionsDigest := strings.Join(digests, ",")
+
+	env := cache.Environment{
+		TestSuite:   digest,
+		Definitions: definitionsDigest,
+		Profiles:    viper.GetStringSlice("profile"),
+		Env:         viper.GetStringSlice("env"),
+		Backend:     viper.GetString("backend"),
+	}
+	if env.Backend == backendLocal {
+		env.RunCommand = viper.GetString("run-command")
+		env.ResetScript = viper.GetString("reset-script")
+	}
+
+	return cache.New(viper.GetString("cache-dir"), env, oracle)
+}
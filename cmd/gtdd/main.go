@@ -7,8 +7,11 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -54,11 +57,16 @@ func newRootCmd() *cobra.Command {
 
 	rootCommand.AddCommand(
 		newBuildCmd(),
+		newCacheCmd(),
 		newDepsCmd(),
 		newFlakyCmd(),
 		newGraphCmd(),
+		newReportCmd(),
 		newRunCmd(),
 		newSchedulesCmd(),
+		newServeCmd(),
+		newStatsCmd(),
+		newWorkerCmd(),
 	)
 
 	return rootCommand
@@ -98,7 +106,17 @@ func toLogLevel(level string) log.Level {
 }
 
 func main() {
-	if err := newRootCmd().Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		// Restore the default behaviour, so that a second signal kills the
+		// process without waiting for the cleanup.
+		stop()
+	}()
+
+	if err := newRootCmd().ExecuteContext(ctx); err != nil {
 		log.Error(err)
 		os.Exit(1)
 	}
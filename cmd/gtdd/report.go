@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pako-23/gtdd/internal/report"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newReportCmd() *cobra.Command {
+	reportCommand := &cobra.Command{
+		Use:   "report",
+		Short: "Combine the results of the schedules run on a test suite",
+		Long: `Manages the results of the schedules written by gtdd run, such
+as the results of the shards of a test suite run on different
+machines.`,
+	}
+
+	mergeCommand := &cobra.Command{
+		Use:   "merge [flags] [results files]",
+		Short: "Merge the results of the shards of a test suite into a single report",
+		Args:  cobra.MinimumNArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			results := report.NewResults()
+
+			for _, fileName := range args {
+				shard, err := readResults(fileName)
+				if err != nil {
+					return err
+				}
+
+				results.Merge(shard)
+			}
+
+			if viper.GetString("output") != "" {
+				if err := writeResults(viper.GetString("output"), results); err != nil {
+					return err
+				}
+			}
+
+			if viper.GetString("report") != "" {
+				if err := writeReport(viper.GetString("report"), results.JUnit(viper.GetString("name"))); err != nil {
+					return err
+				}
+			}
+
+			summary := results.Summary()
+			fmt.Println(summary)
+
+			if summary.Failed > 0 || summary.Errored > 0 {
+				return fmt.Errorf("%d tests failed and %d tests errored", summary.Failed, summary.Errored)
+			}
+
+			return nil
+		},
+	}
+
+	mergeCommand.Flags().StringP("output", "o", "", "The file where to write the merged results; empty to disable it")
+	mergeCommand.Flags().String("report", "report.xml", "The file where to write a JUnit XML report of the merged results; empty to disable it")
+	mergeCommand.Flags().String("name", "gtdd", "The name of the JUnit XML report")
+
+	reportCommand.AddCommand(mergeCommand)
+
+	return reportCommand
+}
+
+// readResults reads the results of the schedules from the provided file. If
+// there is any error, it is returned.
+func readResults(fileName string) (*report.Results, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	results, err := report.ReadResults(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results file %s: %w", fileName, err)
+	}
+	log.Debugf("read schedule results from %s", fileName)
+
+	return results, nil
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/pako-23/gtdd/internal/report"
 	"github.com/pako-23/gtdd/internal/runner"
-	compose_runner "github.com/pako-23/gtdd/internal/runner/compose-runner"
 	"github.com/pako-23/gtdd/internal/testsuite"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -20,57 +24,156 @@ func newRunCmd() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		Long: `Runs a given test suite in parallel. The parallel schedules are
 computed based on a given graph. If no graph is provided, it
-will run the tests in the original order.`,
+will run the tests in the original order.
+
+If some tests are provided as changed, only they are run together
+with the tests they depend on into the graph, and the run fails if
+none of them is into the test suite. If a shard is provided,
+only the schedules of that shard are run, so that the shards can run
+on different machines. The shard is taken from the schedules file
+written by gtdd schedules --shards if any, or computed as gtdd
+schedules does from the same flags and durations file otherwise. The
+history is not used with a shard, as it differs between machines.
+
+A test polluted into a schedule according to the graph runs again
+into a schedule where it is not polluted, and only the outcome of
+that schedule is reported.`,
 		PreRun: func(cmd *cobra.Command, args []string) {
 			viper.BindPFlags(cmd.Flags())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path := args[0]
+			ctx, path := cmd.Context(), args[0]
 
 			suite, err := testsuite.NewTestSuite(path)
 			if err != nil {
 				return err
 			}
-			tests, err := suite.ListTests()
-			if err != nil {
-				return err
+
+			var index, total int
+			if viper.GetString("shard") != "" {
+				if index, total, err = parseShard(viper.GetString("shard")); err != nil {
+					return err
+				}
 			}
 
-			options := []runner.RunnerOption[*compose_runner.ComposeRunner]{
-				compose_runner.WithEnv(viper.GetStringSlice("env")),
-				compose_runner.WithTestSuite(suite),
+			if total != 0 && viper.GetString("schedules") == "" && viper.GetString("durations") == "" {
+				return errors.New("a shard can only be run from a schedules file or a durations file, as the history differs between machines")
 			}
 
-			appComposePath := filepath.Join(path, "docker-compose.yml")
-			if _, err := os.Stat(appComposePath); err == nil {
-				options = append(options,
-					compose_runner.WithAppDefinition(appComposePath))
-			} else if !os.IsNotExist(err) {
-				return err
+			// All the schedules, including the ones of the other shards,
+			// tell which tests run into a schedule where they are not
+			// polluted.
+			var schedules, all [][]string
+			if viper.GetString("schedules") != "" {
+				if total == 0 {
+					return errors.New("the schedules file can only be used to run a shard")
+				} else if len(viper.GetStringSlice("changed-tests")) > 0 || viper.GetString("changed-tests-file") != "" {
+					return errors.New("the changed tests cannot be selected from a schedules file")
+				}
+
+				shards, err := readShards(viper.GetString("schedules"), total)
+				if err != nil {
+					return err
+				}
+
+				schedules = shards[index-1]
+				for _, shard := range shards {
+					all = append(all, shard...)
+				}
+			} else {
+				tests, err := listTests(ctx, suite)
+				if err != nil {
+					return err
+				}
+
+				changed, err := getChangedTests()
+				if err != nil {
+					return err
+				}
+
+				if len(changed) > 0 {
+					tests, err = selectTests(tests, changed, viper.GetString("graph"))
+					if err != nil {
+						return err
+					}
+					log.Infof("selected %d tests to run for %d changed tests", len(tests), len(changed))
+
+					if len(tests) == 0 {
+						return errors.New("none of the changed tests is into the test suite")
+					}
+				}
+
+				if schedules, err = computeSchedules(path, tests, viper.GetString("graph")); err != nil {
+					return err
+				}
+				all = schedules
+
+				if total != 0 {
+					shards, err := shardSchedules(schedules, total)
+					if err != nil {
+						return err
+					}
+					schedules = shards[index-1]
+				}
+			}
+			if total != 0 {
+				log.Infof("running %d schedules of shard %d/%d", len(schedules), index, total)
 			}
 
-			if viper.GetString("driver") != "" {
-				options = append(options,
-					compose_runner.WithDriverDefinition(viper.GetString("driver")))
+			polluted, err := pollutedRuns(all, viper.GetString("graph"))
+			if err != nil {
+				return err
 			}
 
-			runners, err := runner.NewRunnerSet(viper.GetInt("runners"),
-				compose_runner.ComposeRunnerBuilder, options...)
+			runners, _, err := newRunnerSet(ctx, path, suite)
 			if err != nil {
 				return err
 			}
 			defer func() {
-				if err := runners.Delete(); err != nil {
+				if err := runners.Delete(context.WithoutCancel(ctx)); err != nil {
 					log.Error(err)
 				}
 			}()
+			runners.SetTimeouts(getTimeouts())
+			runners.SetRecovery(getRecovery())
 
-			schedules, err := getSchedules(tests, viper.GetString("graph"))
+			hist, err := openHistory(path)
 			if err != nil {
 				return err
 			}
+			if total == 0 {
+				hist.SortLongestFirst(schedules)
+			}
+			defer func() {
+				if err := hist.Save(); err != nil {
+					log.Error(err)
+				}
+			}()
+			recorders := []func([]string, runner.RunResults){hist.Record}
+
+			var junit *report.JUnit
+			if viper.GetString("report") != "" {
+				junit = report.NewJUnit(filepath.Base(path))
+				recorders = append(recorders, junit.Add)
+			}
 
-			duration, err := runSchedules(schedules, runners)
+			var results *report.Results
+			if viper.GetString("results") != "" {
+				results = report.NewResults()
+				recorders = append(recorders, results.Add)
+			}
+
+			duration, err := runSchedules(ctx, schedules, runners, polluted, recorders...)
+			if junit != nil {
+				if reportErr := writeReport(viper.GetString("report"), junit); reportErr != nil {
+					log.Error(reportErr)
+				}
+			}
+			if results != nil {
+				if resultsErr := writeResults(viper.GetString("results"), results); resultsErr != nil {
+					log.Error(resultsErr)
+				}
+			}
 			if err != nil {
 				return err
 			}
@@ -82,8 +185,67 @@ will run the tests in the original order.`,
 
 	runCommand.Flags().StringArrayP("env", "e", []string{}, "an environment variable to pass to the test suite container")
 	runCommand.Flags().StringP("driver", "d", "", "the path to a Docker Compose file configuring the driver")
+	runCommand.Flags().StringArrayP("file", "f", []string{}, "a Docker Compose file defining the application; repeat it to apply overrides")
+	runCommand.Flags().StringArray("profile", []string{}, "a Docker Compose profile to enable")
+	runCommand.Flags().StringArray("env-file", []string{}, "a file with the variables to interpolate into the Docker Compose files")
 	runCommand.Flags().StringP("graph", "g", "", "the file containing the graph of dependencies")
+	runCommand.Flags().StringSlice("changed-tests", []string{}, "the changed tests to run together with their dependencies")
+	runCommand.Flags().String("changed-tests-file", "", "a file listing one changed test per line to run together with their dependencies")
 	runCommand.Flags().UintP("runners", "r", runner.DefaultSetSize, "the number of concurrent runners")
+	runCommand.Flags().Uint("max-runners", 0, "the maximum number of runners, growing from --runners while schedules wait for a runner; 0 to keep --runners runners")
+	runCommand.Flags().Duration("idle-timeout", time.Minute, "the time the runners stay idle before removing one, down to --runners")
+	runCommand.Flags().Float64("runner-cpus", 1, "the CPUs needed by each runner, bounding the number of runners by the CPUs in use on the host; ignored with the k8s backend")
+	runCommand.Flags().Uint64("runner-memory", 1024, "the memory in MiB needed by each runner, bounding the number of runners by the host memory; ignored with the k8s backend")
+	runCommand.Flags().String("backend", backendCompose, "the backend running the runners: compose, k8s, podman or local")
+	runCommand.Flags().String("image", "", "the image running the test suite with the k8s backend, such as the test suite image pushed into a registry reachable from the cluster")
+	runCommand.Flags().String("list-command", "", "the shell command listing the tests with the local backend")
+	runCommand.Flags().String("run-command", "", "the shell command running the tests passed as arguments with the local backend")
+	runCommand.Flags().String("reset-script", "", "the shell command resetting the application before each schedule with the local backend")
+	runCommand.Flags().Duration("test-timeout", 0, "the maximum time allowed to each test without an outcome; 0 to disable it")
+	runCommand.Flags().Duration("schedule-timeout", 0, "the maximum time allowed to each schedule; 0 to disable it")
+	runCommand.Flags().Int("rebuilds", runner.DefaultRecovery.Rebuilds, "the maximum number of attempts to rebuild a failed runner before removing it")
+	runCommand.Flags().Int("retries", runner.DefaultRecovery.Retries, "the maximum number of times a schedule is run again after an infrastructure failure")
+	runCommand.Flags().Bool("optimize", false, "pack the tests into at most as many schedules as --runners minimizing the running time")
+	runCommand.Flags().String("shard", "", "the shard of the schedules to run, as i/K to run the i-th of K shards")
+	runCommand.Flags().String("schedules", "", "the schedules file written by gtdd schedules --shards from which to take the shard")
+	runCommand.Flags().String("durations", "", "the path to a JSON file mapping each test to its duration in seconds, used to balance the shards and to optimize the schedules; required by --shard without --schedules")
+	runCommand.Flags().String("report", "", "the file where to write a JUnit XML report of the run")
+	runCommand.Flags().String("results", "", "the file where to write the results of the schedules, to be merged with gtdd report merge")
+	runCommand.Flags().String("history-dir", defaultHistoryDir(), "the directory storing the durations of the tests already run")
 
 	return runCommand
 }
+
+// writeReport writes a JUnit XML report into the provided file. If there is
+// any error, it is returned.
+func writeReport(fileName string, junit *report.JUnit) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to create report file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	if err := junit.Write(file); err != nil {
+		return err
+	}
+	log.Infof("written JUnit report to %s", fileName)
+
+	return nil
+}
+
+// writeResults writes the results of the schedules run into the provided
+// file. If there is any error, it is returned.
+func writeResults(fileName string, results *report.Results) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to create results file %s: %w", fileName, err)
+	}
+	defer file.Close()
+
+	if err := results.Write(file); err != nil {
+		return err
+	}
+	log.Infof("written schedule results to %s", fileName)
+
+	return nil
+}
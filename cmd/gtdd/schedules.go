@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/pako-23/gtdd/internal/runner"
 	"github.com/pako-23/gtdd/internal/testsuite"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -21,23 +22,30 @@ func newSchedulesCmd() *cobra.Command {
 			viper.BindPFlags(cmd.Flags())
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path := args[0]
+			ctx, path := cmd.Context(), args[0]
 
 			suite, err := testsuite.NewTestSuite(path)
 			if err != nil {
 				return err
 			}
-			tests, err := suite.ListTests()
+			tests, err := listTests(ctx, suite)
 			if err != nil {
 				return err
 			}
 
-			schedules, err := getSchedules(tests, viper.GetString("input"))
+			schedules, err := computeSchedules(path, tests, viper.GetString("input"))
 			if err != nil {
 				return err
 			}
 
-			data, err := json.MarshalIndent(schedules, "", "  ")
+			var output any = schedules
+			if shards := viper.GetInt("shards"); shards > 0 {
+				if output, err = shardSchedules(schedules, shards); err != nil {
+					return err
+				}
+			}
+
+			data, err := json.MarshalIndent(output, "", "  ")
 			if err != nil {
 				return fmt.Errorf("failed to create json from data: %w", err)
 			}
@@ -62,6 +70,14 @@ func newSchedulesCmd() *cobra.Command {
 
 	schedulesCommand.Flags().StringP("input", "i", "graph.json", "The path to the file containing the graph representing the dependencies between tests")
 	schedulesCommand.Flags().StringP("output", "o", "schedules.json", "The path where to write the resulting schedules")
+	schedulesCommand.Flags().UintP("runners", "r", runner.DefaultSetSize, "The number of runners available to run the schedules")
+	schedulesCommand.Flags().Bool("optimize", false, "Pack the tests into at most as many schedules as runners minimizing the running time")
+	schedulesCommand.Flags().String("durations", "", "The path to a JSON file mapping each test to its duration in seconds; by default, the optimized schedules use the durations from the history")
+	schedulesCommand.Flags().Uint("shards", 0, "Split the schedules into the provided number of shards balancing their running time; 0 to disable it")
+	schedulesCommand.Flags().String("backend", backendCompose, "The backend listing the tests: compose, k8s, podman or local")
+	schedulesCommand.Flags().String("image", "", "The image listing the tests with the k8s backend, such as the test suite image pushed into a registry reachable from the cluster")
+	schedulesCommand.Flags().String("list-command", "", "The shell command listing the tests with the local backend")
+	schedulesCommand.Flags().String("history-dir", defaultHistoryDir(), "The directory storing the durations of the tests already run")
 
 	return schedulesCommand
 }
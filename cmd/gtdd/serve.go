@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/algorithms"
+	"github.com/pako-23/gtdd/internal/remote"
+	"github.com/pako-23/gtdd/internal/testsuite"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newServeCmd() *cobra.Command {
+	serveCommand := &cobra.Command{
+		Use:   "serve [flags] [path to testsuite]",
+		Short: "Coordinate a dependency detection run by remote workers",
+		Args:  cobra.ExactArgs(1),
+		Long: `Finds all the dependencies between tests into a provided test
+suite, running the schedules on the remote workers started with
+gtdd worker. The detection starts once enough workers are
+connected. If a worker disconnects, the schedules it was running
+are handed to another worker. The workers running another build of
+the test suite are rejected: with the local backend, the build
+includes the run command and the reset script, which must be the
+same for the coordinator and the workers.
+
+The connections of the workers are neither encrypted nor
+authenticated: the coordinator listens on localhost by default,
+and it should only listen on other addresses into trusted
+networks.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, path := cmd.Context(), args[0]
+
+			strategy := viper.GetString("strategy")
+			detector := getDetector(strategy)
+			if detector == nil {
+				return errors.New("the dependency detection strategy does not exist")
+			}
+
+			suite, err := testsuite.NewTestSuite(path)
+			if err != nil {
+				return err
+			}
+
+			tests, err := listTests(ctx, suite)
+			if err != nil {
+				return err
+			}
+
+			digest, err := suiteDigest(ctx, suite)
+			if err != nil {
+				return fmt.Errorf("failed to compute test suite digest: %w", err)
+			}
+
+			var fingerprints map[string]string
+			if !viper.GetBool("no-fingerprints") {
+				if fingerprints, err = suite.Fingerprints(tests); err != nil {
+					return err
+				}
+			}
+
+			listener, err := net.Listen("tcp", viper.GetString("listen"))
+			if err != nil {
+				return fmt.Errorf("failed to listen on %s: %w", viper.GetString("listen"), err)
+			}
+
+			var (
+				coordinator = remote.NewCoordinator(digest)
+				server      = remote.NewServer(coordinator)
+			)
+
+			go func() {
+				if err := server.Serve(listener); err != nil {
+					log.Error(err)
+				}
+			}()
+			defer server.GracefulStop()
+			defer coordinator.Close()
+			coordinator.SetMaxCapacity(viper.GetInt("max-capacity"))
+
+			log.Infof("waiting for %d workers on %s", viper.GetInt("workers"), listener.Addr())
+			if err := coordinator.WaitForWorkers(ctx, viper.GetInt("workers")); err != nil {
+				return err
+			}
+
+			counter := &scheduleCounter{ScheduleRunner: coordinator}
+
+			startedAt := time.Now()
+			g, err := detector(ctx, tests, counter)
+			if err != nil {
+				return err
+			}
+
+			return writeGraph(viper.GetString("output"), g, algorithms.GraphMetadata{
+				Strategy:     strategy,
+				Digest:       digest,
+				Tests:        tests,
+				Fingerprints: fingerprints,
+				StartedAt:    startedAt,
+				FinishedAt:   time.Now(),
+				Schedules:    int(counter.count.Load()),
+			})
+		},
+	}
+
+	serveCommand.Flags().String("listen", "localhost:7070", "The address on which the workers connect to the coordinator, without encryption nor authentication")
+	serveCommand.Flags().Uint("workers", 1, "The number of workers to wait for before starting the detection")
+	serveCommand.Flags().Uint("max-capacity", 0, "The number of schedules run concurrently by all the workers, including the ones connecting after the start of the detection; 0 to use the runners of the workers connected at the start")
+	serveCommand.Flags().StringP("output", "o", "graph.json", "The file used to output the resulting dependency graph")
+	serveCommand.Flags().Bool("no-fingerprints", false, "Do not record the fingerprints of the tests into the graph, which then finds all the tests changed when used as --base")
+	serveCommand.Flags().StringP("strategy", "s", "pfast", "The strategy to detect dependencies between tests: pfast, pradet, mem-fast or random")
+	serveCommand.Flags().Uint("random-runs", algorithms.DefaultRandomRuns, "The number of random orders run by the random strategy")
+	serveCommand.Flags().Int64("seed", 0, "The seed generating the orders of the random strategy; 0 to use the current time")
+	serveCommand.Flags().String("classification", "classification.json", "The file used to output the order-dependent tests found by the random strategy")
+	serveCommand.Flags().String("backend", backendCompose, "The backend listing the tests: compose, k8s, podman or local")
+	serveCommand.Flags().String("image", "", "The image listing the tests with the k8s backend, such as the test suite image pushed into a registry reachable from the cluster")
+	serveCommand.Flags().String("list-command", "", "The shell command listing the tests with the local backend")
+	serveCommand.Flags().String("run-command", "", "The shell command running the tests passed as arguments with the local backend, which must match the one of the workers")
+	serveCommand.Flags().String("reset-script", "", "The shell command resetting the application before each schedule with the local backend, which must match the one of the workers")
+
+	return serveCommand
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/history"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newStatsCmd() *cobra.Command {
+	statsCommand := &cobra.Command{
+		Use:   "stats [flags] [path to testsuite]",
+		Short: "Show the durations recorded for the tests of a test suite",
+		Args:  cobra.ExactArgs(1),
+		Long: `Shows the durations of the tests recorded while running a test
+suite, from the longest test on average. With the --schedules flag, it
+shows the durations of the schedules instead.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hist, err := openHistory(args[0])
+			if err != nil {
+				return err
+			}
+
+			stats := hist.Tests()
+			header := "TEST"
+			if viper.GetBool("schedules") {
+				stats = hist.Schedules()
+				header = "SCHEDULE"
+			}
+
+			return printStats(header, stats)
+		},
+	}
+
+	statsCommand.Flags().String("history-dir", defaultHistoryDir(), "The directory storing the durations of the tests already run")
+	statsCommand.Flags().Bool("schedules", false, "Show the durations of the schedules instead of the tests")
+
+	return statsCommand
+}
+
+// printStats prints the provided statistics as a table sorted from the
+// longest average duration.
+func printStats(header string, stats map[string]history.Stats) error {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		si, sj := stats[names[i]], stats[names[j]]
+		if si.Mean() != sj.Mean() {
+			return si.Mean() > sj.Mean()
+		}
+
+		return names[i] < names[j]
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tRUNS\tMEAN\tMIN\tMAX\tLAST\tUPDATED\n", header)
+	for _, name := range names {
+		s := stats[name]
+
+		fmt.Fprintf(w, "%s\t%d\t%v\t%v\t%v\t%v\t%s\n",
+			name,
+			s.Runs,
+			s.Mean().Round(time.Millisecond),
+			s.Min.Round(time.Millisecond),
+			s.Max.Round(time.Millisecond),
+			s.Last.Round(time.Millisecond),
+			s.Updated.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
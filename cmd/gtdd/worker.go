@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/remote"
+	"github.com/pako-23/gtdd/internal/runner"
+	"github.com/pako-23/gtdd/internal/testsuite"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newWorkerCmd() *cobra.Command {
+	workerCommand := &cobra.Command{
+		Use:   "worker [flags] [path to testsuite]",
+		Short: "Run the schedules handed by a coordinator started with gtdd serve",
+		Args:  cobra.ExactArgs(1),
+		Long: `Connects to a coordinator started with gtdd serve and runs the
+schedules it hands on a local set of runners, until the detection
+is completed. The artifacts to run the test suite should already
+be built, and match the ones of the coordinator. The connection to
+the coordinator is neither encrypted nor authenticated.`,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, path := cmd.Context(), args[0]
+
+			suite, err := testsuite.NewTestSuite(path)
+			if err != nil {
+				return err
+			}
+
+			runners, _, err := newRunnerSet(ctx, path, suite)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := runners.Delete(context.WithoutCancel(ctx)); err != nil {
+					log.Error(err)
+				}
+			}()
+			runners.SetTimeouts(getTimeouts())
+			runners.SetRecovery(getRecovery())
+
+			id := viper.GetString("id")
+			if id == "" {
+				if id, err = os.Hostname(); err != nil {
+					return err
+				}
+			}
+
+			digest, err := suiteDigest(ctx, suite)
+			if err != nil {
+				return fmt.Errorf("failed to compute test suite digest: %w", err)
+			}
+
+			return remote.NewWorker(id, digest, runners).Run(ctx, viper.GetString("coordinator"),
+				viper.GetDuration("retry-interval"))
+		},
+	}
+
+	workerCommand.Flags().String("coordinator", "localhost:7070", "The address of the coordinator")
+	workerCommand.Flags().String("id", "", "The identifier of the worker; by default, the hostname")
+	workerCommand.Flags().Duration("retry-interval", 5*time.Second, "The time to wait before connecting again to the coordinator")
+	workerCommand.Flags().StringArrayP("env", "e", []string{}, "An environment variable to pass to the test suite container")
+	workerCommand.Flags().StringP("driver", "d", "", "The path to a Docker Compose file configuring the driver")
+	workerCommand.Flags().StringArrayP("file", "f", []string{}, "A Docker Compose file defining the application; repeat it to apply overrides")
+	workerCommand.Flags().StringArray("profile", []string{}, "A Docker Compose profile to enable")
+	workerCommand.Flags().StringArray("env-file", []string{}, "A file with the variables to interpolate into the Docker Compose files")
+	workerCommand.Flags().UintP("runners", "r", runner.DefaultSetSize, "The number of concurrent runners")
+	workerCommand.Flags().String("backend", backendCompose, "The backend running the runners: compose, k8s, podman or local")
+	workerCommand.Flags().String("image", "", "The image running the test suite with the k8s backend, such as the test suite image pushed into a registry reachable from the cluster")
+	workerCommand.Flags().String("run-command", "", "The shell command running the tests passed as arguments with the local backend")
+	workerCommand.Flags().String("reset-script", "", "The shell command resetting the application before each schedule with the local backend")
+	workerCommand.Flags().Duration("test-timeout", 0, "The maximum time allowed to each test without an outcome; 0 to disable it")
+	workerCommand.Flags().Duration("schedule-timeout", 0, "The maximum time allowed to each schedule; 0 to disable it")
+	workerCommand.Flags().Int("rebuilds", runner.DefaultRecovery.Rebuilds, "The maximum number of attempts to rebuild a failed runner before removing it")
+	workerCommand.Flags().Int("retries", runner.DefaultRecovery.Retries, "The maximum number of times a schedule is run again after an infrastructure failure")
+
+	return workerCommand
+}
@@ -0,0 +1,365 @@
+package algorithms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/atomicfile"
+	"github.com/pako-23/gtdd/internal/runner"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/exp/slices"
+)
+
+// ErrCheckpointMismatch is returned when a checkpoint is resumed with a
+// strategy or a list of tests different from the ones used to create it, or
+// when it was written into an unsupported format.
+var ErrCheckpointMismatch = errors.New("the checkpoint does not match the current detection")
+
+// The version of the format of the checkpoint files. It changes every time
+// the checkpoints written by a previous version cannot be resumed anymore.
+const checkpointVersion = 1
+
+// checkpointRun is a schedule run during a dependency detection together with
+// its results.
+type checkpointRun struct {
+	Schedule    []string             `json:"schedule"`
+	Results     []runner.TestOutcome `json:"results"`
+	RunningTime time.Duration        `json:"running_time"`
+}
+
+// memfastCheckpoint is the state of the MEMFAST algorithm before working on
+// a given rank.
+type memfastCheckpoint struct {
+	Rank   int                  `json:"rank"`
+	Table  [][][]string         `json:"table"`
+	Failed []string             `json:"failed"`
+	Runned []string             `json:"runned"`
+	Max    int                  `json:"max"`
+	Graph  map[string]graphNode `json:"graph"`
+}
+
+// checkpointData is the content of a checkpoint file.
+type checkpointData struct {
+	Version  int                  `json:"version"`
+	Strategy string               `json:"strategy"`
+	Tests    []string             `json:"tests"`
+	Runs     []checkpointRun      `json:"runs"`
+	Graph    map[string]graphNode `json:"graph,omitempty"`
+	MEMFAST  *memfastCheckpoint   `json:"memfast,omitempty"`
+	Seed     int64                `json:"seed,omitempty"`
+}
+
+// Checkpoint records the progress of a dependency detection algorithm into a
+// file, so that an interrupted detection can be resumed without running again
+// the schedules that were already executed. A Checkpoint wraps the oracle
+// used by the detection algorithm: the results of the schedules already run
+// are replayed, while the other schedules are run on the wrapped oracle.
+type Checkpoint struct {
+	// The oracle running the schedules which are not into the checkpoint.
+	oracle runner.ScheduleRunner
+	// The path of the checkpoint file.
+	path string
+	// The minimum time between two writes of the checkpoint file.
+	interval time.Duration
+	// The last time the checkpoint file was written.
+	lastSave time.Time
+	// The results of the schedules to replay indexed by schedule.
+	replay map[string][]checkpointRun
+	data   checkpointData
+	mu     sync.Mutex
+}
+
+// NewCheckpoint creates a new empty checkpoint for a dependency detection
+// with the given strategy on the given tests. The checkpoint is written to
+// the provided path at most once every interval.
+func NewCheckpoint(path, strategy string, tests []string, oracle runner.ScheduleRunner, interval time.Duration) *Checkpoint {
+	return &Checkpoint{
+		oracle:   oracle,
+		path:     path,
+		interval: interval,
+		lastSave: time.Now(),
+		replay:   map[string][]checkpointRun{},
+		data: checkpointData{
+			Version:  checkpointVersion,
+			Strategy: strategy,
+			Tests:    tests,
+			Runs:     []checkpointRun{},
+		},
+	}
+}
+
+// ResumeCheckpoint loads the checkpoint stored at the provided path. The
+// checkpoint must have been created for the same strategy and tests. If there
+// is any error, it is returned.
+func ResumeCheckpoint(path, strategy string, tests []string, oracle runner.ScheduleRunner, interval time.Duration) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+
+	var version struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint data: %w", err)
+	} else if version.Version != checkpointVersion {
+		return nil, fmt.Errorf("%w: unsupported checkpoint format version %d", ErrCheckpointMismatch, version.Version)
+	}
+
+	var stored checkpointData
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint data: %w", err)
+	}
+
+	if stored.Strategy != strategy {
+		return nil, fmt.Errorf("%w: checkpoint strategy is %s", ErrCheckpointMismatch, stored.Strategy)
+	} else if !slices.Equal(stored.Tests, tests) {
+		return nil, fmt.Errorf("%w: the tests are different", ErrCheckpointMismatch)
+	}
+
+	c := NewCheckpoint(path, strategy, tests, oracle, interval)
+	c.data = stored
+
+	for _, run := range c.data.Runs {
+		key := strings.Join(run.Schedule, ",")
+		c.replay[key] = append(c.replay[key], run)
+	}
+	log.Infof("resuming detection with %d schedules already run", len(c.data.Runs))
+
+	return c, nil
+}
+
+// checkpointOf returns the Checkpoint used as oracle by a dependency
+// detection algorithm if any.
+func checkpointOf(oracle runner.ScheduleRunner) *Checkpoint {
+	if c, ok := oracle.(*Checkpoint); ok {
+		return c
+	}
+
+	return nil
+}
+
+// RunSchedule returns the results of a schedule. If the schedule was already
+// run before the checkpoint was resumed, the stored results are returned;
+// otherwise, the schedule is run on the wrapped oracle and its results are
+// recorded into the checkpoint.
+func (c *Checkpoint) RunSchedule(ctx context.Context, schedule []string) (runner.RunResults, error) {
+	key := strings.Join(schedule, ",")
+
+	c.mu.Lock()
+	if runs := c.replay[key]; len(runs) > 0 {
+		c.replay[key] = runs[1:]
+		c.mu.Unlock()
+
+		return runner.RunResults{
+			Results:     runs[0].Results,
+			RunningTime: runs[0].RunningTime,
+		}, nil
+	}
+	c.mu.Unlock()
+
+	results, err := c.oracle.RunSchedule(ctx, schedule)
+	if err != nil {
+		return results, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data.Runs = append(c.data.Runs, checkpointRun{
+		Schedule:    slices.Clone(schedule),
+		Results:     results.Results,
+		RunningTime: results.RunningTime,
+	})
+	c.saveIfDue()
+
+	return results, nil
+}
+
+// Size returns the number of schedules that can be run concurrently.
+func (c *Checkpoint) Size() int {
+	return c.oracle.Size()
+}
+
+// Save writes the checkpoint to its file. If there is any error, it is
+// returned.
+func (c *Checkpoint) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.save()
+}
+
+// Remove deletes the checkpoint file. If there is any error, it is returned.
+func (c *Checkpoint) Remove() error {
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove checkpoint file: %w", err)
+	}
+
+	return nil
+}
+
+// recordGraph records the dependency graph built so far by a detection
+// algorithm.
+func (c *Checkpoint) recordGraph(g DependencyGraph) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data.Graph = g.toMap()
+	c.saveIfDue()
+}
+
+// restoreSeed returns the seed of the random orders stored into the checkpoint
+// if any, and the provided seed otherwise. If the provided seed is not 0 and
+// differs from the stored one, an error is returned, as the random orders
+// already run would not be replayed.
+func (c *Checkpoint) restoreSeed(seed int64) (int64, error) {
+	if c == nil {
+		return seed, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.data.Seed == 0 {
+		return seed, nil
+	} else if seed != 0 && seed != c.data.Seed {
+		return 0, fmt.Errorf("%w: checkpoint seed is %d", ErrCheckpointMismatch, c.data.Seed)
+	}
+
+	return c.data.Seed, nil
+}
+
+// recordSeed records the seed used to generate the random orders, so that a
+// resumed detection generates the same orders.
+func (c *Checkpoint) recordSeed(seed int64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data.Seed = seed
+}
+
+// recordMEMFAST records the state of the MEMFAST algorithm before working on
+// the given rank.
+func (c *Checkpoint) recordMEMFAST(rank int, s *state) {
+	if c == nil {
+		return
+	}
+
+	checkpoint := &memfastCheckpoint{
+		Rank:   rank,
+		Table:  make([][][]string, len(s.table)),
+		Failed: make([]string, 0, len(s.failed)),
+		Runned: make([]string, 0, len(s.runned)),
+		Max:    s.max,
+		Graph:  s.graph.toMap(),
+	}
+
+	for i, set := range s.table {
+		checkpoint.Table[i] = make([][]string, 0, len(set))
+		for _, sched := range set {
+			checkpoint.Table[i] = append(checkpoint.Table[i], sched)
+		}
+	}
+
+	for test := range s.failed {
+		checkpoint.Failed = append(checkpoint.Failed, test)
+	}
+
+	for sched := range s.runned {
+		checkpoint.Runned = append(checkpoint.Runned, sched)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.data.MEMFAST = checkpoint
+	c.data.Graph = checkpoint.Graph
+	c.saveIfDue()
+}
+
+// restoreMEMFAST returns the state of the MEMFAST algorithm stored into the
+// checkpoint and the rank from which the algorithm should restart. If there
+// is no stored state, nil is returned.
+func (c *Checkpoint) restoreMEMFAST(tests []string) (*state, int) {
+	if c == nil || c.data.MEMFAST == nil {
+		return nil, 0
+	}
+
+	checkpoint := c.data.MEMFAST
+	s := &state{
+		table:    make([]scheduleSet, len(tests)),
+		revIndex: buildReverseIndex(tests),
+		failed:   make(map[string]struct{}, len(checkpoint.Failed)),
+		graph:    dependencyGraphFromMap(checkpoint.Graph),
+		max:      checkpoint.Max,
+		runned:   make(map[string]struct{}, len(checkpoint.Runned)),
+	}
+
+	for i := range s.table {
+		s.table[i] = make(scheduleSet)
+		if i >= len(checkpoint.Table) {
+			continue
+		}
+
+		for _, sched := range checkpoint.Table[i] {
+			s.table[i].Insert(sched)
+		}
+	}
+
+	for _, test := range checkpoint.Failed {
+		s.failed[test] = struct{}{}
+	}
+
+	for _, sched := range checkpoint.Runned {
+		s.runned[sched] = struct{}{}
+	}
+	log.Infof("resuming MEMFAST from rank %d", checkpoint.Rank)
+
+	return s, checkpoint.Rank
+}
+
+// saveIfDue writes the checkpoint file if enough time has passed since the
+// last write. It must be called holding the checkpoint lock.
+func (c *Checkpoint) saveIfDue() {
+	if time.Since(c.lastSave) < c.interval {
+		return
+	}
+
+	if err := c.save(); err != nil {
+		log.Errorf("failed to save checkpoint: %v", err)
+	}
+}
+
+// save atomically writes the checkpoint file, so that a crash during the
+// write does not corrupt the previous checkpoint. It must be called holding
+// the checkpoint lock.
+func (c *Checkpoint) save() error {
+	data, err := json.Marshal(&c.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint data: %w", err)
+	}
+
+	if err := atomicfile.WriteFile(c.path, data); err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+
+	c.lastSave = time.Now()
+	log.Debugf("saved checkpoint with %d schedules to %s", len(c.data.Runs), c.path)
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package algorithms_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/algorithms"
+	"github.com/pako-23/gtdd/internal/runner"
+	"gotest.tools/v3/assert"
+)
+
+var errOracleStopped = errors.New("oracle stopped")
+
+type countingOracle struct {
+	oracle runner.ScheduleRunner
+	runs   atomic.Int32
+	limit  int32
+}
+
+func (c *countingOracle) RunSchedule(ctx context.Context, schedule []string) (runner.RunResults, error) {
+	if c.runs.Add(1) > c.limit {
+		return runner.RunResults{}, errOracleStopped
+	}
+
+	return c.oracle.RunSchedule(ctx, schedule)
+}
+
+func (c *countingOracle) Size() int {
+	return c.oracle.Size()
+}
+
+var checkpointTestSuite = []string{"test1", "test2", "test3", "test4", "test5"}
+
+var checkpointDependencies = map[string][][]string{
+	"test3": {{"test1", "test2"}},
+	"test5": {{"test1", "test2", "test3"}},
+}
+
+func TestCheckpointResume(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		strategy string
+		detector algorithms.DependencyDetector
+	}{
+		{"pfast", algorithms.PFAST},
+		{"mem-fast", algorithms.MEMFAST},
+		{"pradet", algorithms.PraDet},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "checkpoint.json")
+		set, err := runner.NewRunnerSet[*mockRunner](context.Background(), 3,
+			newMockRunnerBuilder,
+			withDependencyMap(checkpointDependencies))
+		assert.NilError(t, err)
+
+		checkpoint := algorithms.NewCheckpoint(path, test.strategy,
+			checkpointTestSuite, set, time.Hour)
+		expected, err := test.detector(context.Background(), checkpointTestSuite, checkpoint)
+		assert.NilError(t, err)
+		assert.NilError(t, checkpoint.Save())
+
+		oracle := &countingOracle{oracle: set, limit: 0}
+		checkpoint, err = algorithms.ResumeCheckpoint(path, test.strategy,
+			checkpointTestSuite, oracle, time.Hour)
+		assert.NilError(t, err)
+
+		got, err := test.detector(context.Background(), checkpointTestSuite, checkpoint)
+		assert.NilError(t, err)
+		assert.Check(t, got.Equal(expected),
+			fmt.Sprintf("expected graph %v, but got %v", expected, got))
+		assert.Equal(t, oracle.runs.Load(), int32(0))
+	}
+}
+
+func TestCheckpointInterruptedDetection(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	set, err := runner.NewRunnerSet[*mockRunner](context.Background(), 1,
+		newMockRunnerBuilder,
+		withDependencyMap(checkpointDependencies))
+	assert.NilError(t, err)
+
+	full := &countingOracle{oracle: set, limit: 1 << 30}
+	expected, err := algorithms.PraDet(context.Background(), checkpointTestSuite, full)
+	assert.NilError(t, err)
+
+	limit := full.runs.Load() / 2
+	interrupted := &countingOracle{oracle: set, limit: limit}
+	checkpoint := algorithms.NewCheckpoint(path, "pradet",
+		checkpointTestSuite, interrupted, time.Hour)
+	_, err = algorithms.PraDet(context.Background(), checkpointTestSuite, checkpoint)
+	assert.ErrorIs(t, err, errOracleStopped)
+	assert.NilError(t, checkpoint.Save())
+
+	resumed := &countingOracle{oracle: set, limit: 1 << 30}
+	checkpoint, err = algorithms.ResumeCheckpoint(path, "pradet",
+		checkpointTestSuite, resumed, time.Hour)
+	assert.NilError(t, err)
+
+	got, err := algorithms.PraDet(context.Background(), checkpointTestSuite, checkpoint)
+	assert.NilError(t, err)
+	assert.Check(t, got.Equal(expected),
+		fmt.Sprintf("expected graph %v, but got %v", expected, got))
+	assert.Equal(t, resumed.runs.Load(), full.runs.Load()-limit)
+
+	assert.NilError(t, checkpoint.Remove())
+	_, err = algorithms.ResumeCheckpoint(path, "pradet",
+		checkpointTestSuite, resumed, time.Hour)
+	assert.ErrorContains(t, err, "failed to read checkpoint file")
+}
+
+func TestCheckpointResumeRandomSeed(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	set, err := runner.NewRunnerSet[*mockRunner](context.Background(), 3,
+		newMockRunnerBuilder,
+		withDependencyMap(checkpointDependencies))
+	assert.NilError(t, err)
+
+	var classification algorithms.Classification
+	checkpoint := algorithms.NewCheckpoint(path, "random",
+		checkpointTestSuite, set, time.Hour)
+	expected, err := algorithms.RandomOrder(algorithms.DefaultRandomRuns, 0, &classification)(
+		context.Background(), checkpointTestSuite, checkpoint)
+	assert.NilError(t, err)
+	assert.NilError(t, checkpoint.Save())
+
+	oracle := &countingOracle{oracle: set, limit: 0}
+	checkpoint, err = algorithms.ResumeCheckpoint(path, "random",
+		checkpointTestSuite, oracle, time.Hour)
+	assert.NilError(t, err)
+
+	var resumed algorithms.Classification
+	got, err := algorithms.RandomOrder(algorithms.DefaultRandomRuns, 0, &resumed)(
+		context.Background(), checkpointTestSuite, checkpoint)
+	assert.NilError(t, err)
+	assert.Check(t, got.Equal(expected),
+		fmt.Sprintf("expected graph %v, but got %v", expected, got))
+	assert.Equal(t, resumed.Seed, classification.Seed)
+	assert.Equal(t, oracle.runs.Load(), int32(0))
+
+	_, err = algorithms.RandomOrder(algorithms.DefaultRandomRuns, classification.Seed+1, nil)(
+		context.Background(), checkpointTestSuite, checkpoint)
+	assert.ErrorIs(t, err, algorithms.ErrCheckpointMismatch)
+}
+
+func TestCheckpointMismatch(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	set, err := runner.NewRunnerSet[*mockRunner](context.Background(), 1, newMockRunnerBuilder)
+	assert.NilError(t, err)
+
+	checkpoint := algorithms.NewCheckpoint(path, "pfast",
+		checkpointTestSuite, set, time.Hour)
+	assert.NilError(t, checkpoint.Save())
+
+	_, err = algorithms.ResumeCheckpoint(path, "pradet",
+		checkpointTestSuite, set, time.Hour)
+	assert.ErrorIs(t, err, algorithms.ErrCheckpointMismatch)
+
+	_, err = algorithms.ResumeCheckpoint(path, "pfast",
+		checkpointTestSuite[1:], set, time.Hour)
+	assert.ErrorIs(t, err, algorithms.ErrCheckpointMismatch)
+
+	old := `{"strategy":"pfast","tests":["test1"],"runs":[{"schedule":["test1"],"results":[true]}]}`
+	assert.NilError(t, os.WriteFile(path, []byte(old), 0o644))
+
+	_, err = algorithms.ResumeCheckpoint(path, "pfast",
+		[]string{"test1"}, set, time.Hour)
+	assert.ErrorIs(t, err, algorithms.ErrCheckpointMismatch)
+}
@@ -0,0 +1,119 @@
+package algorithms
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// DiffEdge represents an edge of a dependency graph into a GraphDiff.
+type DiffEdge struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Kind     EdgeKind `json:"kind"`
+	Cleaners []string `json:"cleaners,omitempty"`
+}
+
+func (e DiffEdge) String() string {
+	if len(e.Cleaners) == 0 {
+		return fmt.Sprintf("%s -> %s (%v)", e.From, e.To, e.Kind)
+	}
+
+	return fmt.Sprintf("%s -> %s (%v, cleaners: %s)", e.From, e.To, e.Kind, strings.Join(e.Cleaners, ", "))
+}
+
+// GraphDiff represents the changes between two dependency graphs. An edge
+// whose kind or cleaners changed is both removed and added.
+type GraphDiff struct {
+	AddedTests   []string   `json:"added_tests"`
+	RemovedTests []string   `json:"removed_tests"`
+	AddedEdges   []DiffEdge `json:"added_edges"`
+	RemovedEdges []DiffEdge `json:"removed_edges"`
+}
+
+// Diff returns the changes needed to turn the old dependency graph into the
+// new one. The tests and edges are sorted by name.
+func Diff(old, updated DependencyGraph) GraphDiff {
+	diff := GraphDiff{
+		AddedTests:   []string{},
+		RemovedTests: []string{},
+		AddedEdges:   []DiffEdge{},
+		RemovedEdges: []DiffEdge{},
+	}
+
+	for test := range updated {
+		if _, ok := old[test]; !ok {
+			diff.AddedTests = append(diff.AddedTests, test)
+		}
+	}
+
+	for test := range old {
+		if _, ok := updated[test]; !ok {
+			diff.RemovedTests = append(diff.RemovedTests, test)
+		}
+	}
+
+	diff.AddedEdges = missingEdges(updated, old)
+	diff.RemovedEdges = missingEdges(old, updated)
+
+	sort.Strings(diff.AddedTests)
+	sort.Strings(diff.RemovedTests)
+
+	return diff
+}
+
+// missingEdges returns the edges of a graph which are not into another graph
+// with the same kind and cleaners, sorted by tests.
+func missingEdges(g, other DependencyGraph) []DiffEdge {
+	missing := []DiffEdge{}
+
+	for from, edges := range g {
+		for to, edge := range edges {
+			otherEdge, ok := other[from][to]
+			if ok && otherEdge.Kind == edge.Kind && slices.Equal(otherEdge.Cleaners, edge.Cleaners) {
+				continue
+			}
+
+			missing = append(missing, DiffEdge{From: from, To: to, Kind: edge.Kind, Cleaners: edge.Cleaners})
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].From != missing[j].From {
+			return missing[i].From < missing[j].From
+		}
+
+		return missing[i].To < missing[j].To
+	})
+
+	return missing
+}
+
+// Empty reports whether the graphs compared by the diff are the same.
+func (d GraphDiff) Empty() bool {
+	return len(d.AddedTests) == 0 && len(d.RemovedTests) == 0 &&
+		len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0
+}
+
+// ToText writes a textual representation of the diff, with a line for each
+// added or removed test and edge.
+func (d GraphDiff) ToText(w io.Writer) {
+	for _, test := range d.RemovedTests {
+		fmt.Fprintf(w, "- test %s\n", test)
+	}
+
+	for _, test := range d.AddedTests {
+		fmt.Fprintf(w, "+ test %s\n", test)
+	}
+
+	for _, edge := range d.RemovedEdges {
+		fmt.Fprintf(w, "- %v\n", edge)
+	}
+
+	for _, edge := range d.AddedEdges {
+		fmt.Fprintf(w, "+ %v\n", edge)
+	}
+}
@@ -0,0 +1,63 @@
+package algorithms_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pako-23/gtdd/internal/algorithms"
+	"gotest.tools/v3/assert"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	old := algorithms.NewDependencyGraph([]string{"test1", "test2", "test3", "test4"})
+	old.AddDependency("test2", "test1")
+	old.AddDependency("test3", "test2")
+	old.AddPollution("test4", "test1", "test2")
+
+	updated := algorithms.NewDependencyGraph([]string{"test1", "test2", "test4", "test5"})
+	updated.AddDependency("test2", "test1")
+	updated.AddDependency("test5", "test4")
+	updated.AddPollution("test4", "test1", "test5")
+
+	diff := algorithms.Diff(old, updated)
+	assert.DeepEqual(t, diff, algorithms.GraphDiff{
+		AddedTests:   []string{"test5"},
+		RemovedTests: []string{"test3"},
+		AddedEdges: []algorithms.DiffEdge{
+			{From: "test4", To: "test1", Kind: algorithms.Pollution, Cleaners: []string{"test5"}},
+			{From: "test5", To: "test4", Kind: algorithms.Dependency},
+		},
+		RemovedEdges: []algorithms.DiffEdge{
+			{From: "test3", To: "test2", Kind: algorithms.Dependency},
+			{From: "test4", To: "test1", Kind: algorithms.Pollution, Cleaners: []string{"test2"}},
+		},
+	})
+	assert.Assert(t, !diff.Empty())
+
+	var buf bytes.Buffer
+	diff.ToText(&buf)
+	assert.Equal(t, buf.String(), `- test test3
++ test test5
+- test3 -> test2 (dependency)
+- test4 -> test1 (pollution, cleaners: test2)
++ test4 -> test1 (pollution, cleaners: test5)
++ test5 -> test4 (dependency)
+`)
+}
+
+func TestDiffSameGraph(t *testing.T) {
+	t.Parallel()
+
+	g := algorithms.NewDependencyGraph([]string{"test1", "test2", "test3"})
+	g.AddDependency("test2", "test1")
+	g.AddPollution("test3", "test1")
+
+	diff := algorithms.Diff(g, g)
+	assert.Assert(t, diff.Empty())
+
+	var buf bytes.Buffer
+	diff.ToText(&buf)
+	assert.Equal(t, buf.String(), "")
+}
@@ -0,0 +1,218 @@
+package algorithms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// GraphFormatVersion is the version of the format of the dependency graph
+// files written by ToJSON.
+const GraphFormatVersion = 1
+
+// ErrUnsupportedGraphVersion is returned when a dependency graph file was
+// written with a newer version of the format.
+var ErrUnsupportedGraphVersion = errors.New("unsupported dependency graph format version")
+
+// GraphMetadata describes how a dependency graph was produced.
+type GraphMetadata struct {
+	// The strategy which detected the dependencies.
+	Strategy string `json:"strategy,omitempty"`
+	// The digest of the test suite image.
+	Digest string `json:"digest,omitempty"`
+	// The tests into the order used by the detection.
+	Tests []string `json:"tests,omitempty"`
+	// The fingerprints of the sources of each test, used to find the tests
+	// modified by a new version of the test suite.
+	Fingerprints map[string]string `json:"fingerprints,omitempty"`
+	// The time the detection started.
+	StartedAt time.Time `json:"started_at"`
+	// The time the detection finished.
+	FinishedAt time.Time `json:"finished_at"`
+	// The number of schedules run by the detection.
+	Schedules int `json:"schedules"`
+}
+
+// graphFile is the content of a dependency graph file.
+type graphFile struct {
+	Version  int                  `json:"version"`
+	Metadata GraphMetadata        `json:"metadata"`
+	Graph    map[string]graphNode `json:"graph"`
+}
+
+// graphDependency is the JSON representation of a dependency edge. Into the
+// legacy format, a dependency is represented by the name of the test only.
+type graphDependency struct {
+	Test     string    `json:"test"`
+	Evidence *Evidence `json:"evidence,omitempty"`
+}
+
+func (g *graphDependency) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &g.Test); err == nil {
+		return nil
+	}
+
+	type dependency graphDependency
+
+	return json.Unmarshal(data, (*dependency)(g))
+}
+
+// graphPolluter is the JSON representation of a pollution edge.
+type graphPolluter struct {
+	Test     string    `json:"test"`
+	Cleaners []string  `json:"cleaners,omitempty"`
+	Evidence *Evidence `json:"evidence,omitempty"`
+}
+
+// graphNode is the JSON representation of the edges of a test. Into the
+// legacy format, a test which is not polluted by any other test is
+// represented by the list of its dependencies.
+type graphNode struct {
+	Dependencies []graphDependency `json:"dependencies"`
+	Polluters    []graphPolluter   `json:"polluters,omitempty"`
+}
+
+func (n *graphNode) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &n.Dependencies); err == nil {
+		return nil
+	}
+
+	type node graphNode
+
+	return json.Unmarshal(data, (*node)(n))
+}
+
+// DependencyGraphFromJson returns a DependencyGraph form a JSON file. If
+// there is any error it is returned.
+func DependencyGraphFromJson(fileName string) (DependencyGraph, error) {
+	g, _, err := ReadDependencyGraph(fileName)
+
+	return g, err
+}
+
+// ReadDependencyGraph returns a DependencyGraph and its metadata from a JSON
+// file. The files written before the format was versioned, which contain the
+// graph only, have no metadata. If there is any error it is returned.
+func ReadDependencyGraph(fileName string) (DependencyGraph, *GraphMetadata, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open JSON file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read JSON file: %w", err)
+	}
+
+	// A legacy file maps each test to its edges, so a version number
+	// identifies the versioned format even if a test is named version.
+	var header struct {
+		Version json.RawMessage `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode graph JSON data: %w", err)
+	}
+
+	var version int
+	if json.Unmarshal(header.Version, &version) != nil {
+		graph := map[string]graphNode{}
+		if err := json.Unmarshal(data, &graph); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode graph JSON data: %w", err)
+		}
+
+		return dependencyGraphFromMap(graph), nil, nil
+	} else if version > GraphFormatVersion {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnsupportedGraphVersion, version)
+	}
+
+	var content graphFile
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode graph JSON data: %w", err)
+	}
+
+	return dependencyGraphFromMap(content.Graph), &content.Metadata, nil
+}
+
+// dependencyGraphFromMap returns a DependencyGraph from its adjacency list
+// representation.
+func dependencyGraphFromMap(graph map[string]graphNode) DependencyGraph {
+	tests := make([]string, 0, len(graph))
+	for test := range graph {
+		tests = append(tests, test)
+	}
+
+	g := NewDependencyGraph(tests)
+	for test, node := range graph {
+		for _, dependency := range node.Dependencies {
+			g.AddDependency(test, dependency.Test)
+			if dependency.Evidence != nil {
+				g.SetEvidence(test, dependency.Test, dependency.Evidence.Schedule, dependency.Evidence.Results)
+			}
+		}
+
+		for _, polluter := range node.Polluters {
+			g.AddPollution(test, polluter.Test, polluter.Cleaners...)
+			if polluter.Evidence != nil {
+				g.SetEvidence(test, polluter.Test, polluter.Evidence.Schedule, polluter.Evidence.Results)
+			}
+		}
+	}
+
+	return g
+}
+
+// toMap returns the adjacency list representation of a dependency graph.
+func (d DependencyGraph) toMap() map[string]graphNode {
+	graph := make(map[string]graphNode, len(d))
+
+	for test, edges := range d {
+		node := graphNode{Dependencies: []graphDependency{}}
+		for to, edge := range edges {
+			if edge.Kind == Pollution {
+				node.Polluters = append(node.Polluters, graphPolluter{
+					Test:     to,
+					Cleaners: edge.Cleaners,
+					Evidence: edge.Evidence,
+				})
+			} else {
+				node.Dependencies = append(node.Dependencies, graphDependency{Test: to, Evidence: edge.Evidence})
+			}
+		}
+
+		sort.Slice(node.Dependencies, func(i, j int) bool {
+			return node.Dependencies[i].Test < node.Dependencies[j].Test
+		})
+		sort.Slice(node.Polluters, func(i, j int) bool {
+			return node.Polluters[i].Test < node.Polluters[j].Test
+		})
+
+		graph[test] = node
+	}
+
+	return graph
+}
+
+// ToJSON writes a JSON representation of the dependencies relationship
+// between tests of a test suite, together with the metadata describing how
+// the dependencies were detected.
+func (d DependencyGraph) ToJSON(w io.Writer, metadata GraphMetadata) {
+	d.TransitiveReduction()
+
+	data, err := json.MarshalIndent(graphFile{
+		Version:  GraphFormatVersion,
+		Metadata: metadata,
+		Graph:    d.toMap(),
+	}, "", "  ")
+	if err != nil {
+		log.Errorf("failed to create json from data: %v", err)
+	}
+
+	w.Write(data)
+}
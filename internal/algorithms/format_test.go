@@ -0,0 +1,128 @@
+package algorithms_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/algorithms"
+	"github.com/pako-23/gtdd/internal/runner"
+	"gotest.tools/v3/assert"
+)
+
+func writeGraphFile(t *testing.T, data []byte) string {
+	path := filepath.Join(t.TempDir(), "graph.json")
+	assert.NilError(t, os.WriteFile(path, data, 0o644))
+
+	return path
+}
+
+func TestDependencyGraphJSON(t *testing.T) {
+	t.Parallel()
+
+	var (
+		evidence = []runner.TestOutcome{{Status: runner.StatusPass}, {Status: runner.StatusFail, Message: "missing user"}}
+		metadata = algorithms.GraphMetadata{
+			Strategy:   "pfast",
+			Digest:     "sha256:1234",
+			Tests:      []string{"node1", "node2", "node3", "node4"},
+			StartedAt:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			FinishedAt: time.Date(2024, 1, 2, 4, 4, 5, 0, time.UTC),
+			Schedules:  12,
+		}
+		g = algorithms.NewDependencyGraph(metadata.Tests)
+	)
+
+	g.AddDependency("node2", "node1")
+	g.SetEvidence("node2", "node1", []string{"node3", "node2"}, evidence)
+	g.AddDependency("node3", "node1")
+	g.AddDependency("node3", "node2")
+	g.AddPollution("node4", "node1", "node3", "node2")
+	g.SetEvidence("node4", "node1", []string{"node1", "node4"}, evidence)
+
+	var buf bytes.Buffer
+	g.ToJSON(&buf, metadata)
+
+	var data map[string]any
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &data))
+	assert.Equal(t, data["version"], float64(algorithms.GraphFormatVersion))
+	assert.DeepEqual(t, data["graph"], map[string]any{
+		"node1": map[string]any{"dependencies": []any{}},
+		"node2": map[string]any{
+			"dependencies": []any{map[string]any{
+				"test": "node1",
+				"evidence": map[string]any{
+					"schedule": []any{"node3", "node2"},
+					"results": []any{
+						map[string]any{"status": "pass"},
+						map[string]any{"status": "fail", "message": "missing user"},
+					},
+				},
+			}},
+		},
+		"node3": map[string]any{"dependencies": []any{map[string]any{"test": "node2"}}},
+		"node4": map[string]any{
+			"dependencies": []any{},
+			"polluters": []any{map[string]any{
+				"test":     "node1",
+				"cleaners": []any{"node2", "node3"},
+				"evidence": map[string]any{
+					"schedule": []any{"node1", "node4"},
+					"results": []any{
+						map[string]any{"status": "pass"},
+						map[string]any{"status": "fail", "message": "missing user"},
+					},
+				},
+			}},
+		},
+	})
+
+	got, gotMetadata, err := algorithms.ReadDependencyGraph(writeGraphFile(t, buf.Bytes()))
+	assert.NilError(t, err)
+	assert.Check(t, got.Equal(g), fmt.Sprintf("expected graph %v, but got %v", g, got))
+	assert.DeepEqual(t, *gotMetadata, metadata)
+	assert.DeepEqual(t, got["node2"]["node1"].Evidence, &algorithms.Evidence{
+		Schedule: []string{"node3", "node2"},
+		Results:  evidence,
+	})
+}
+
+func TestDependencyGraphFromJsonLegacy(t *testing.T) {
+	t.Parallel()
+
+	path := writeGraphFile(t, []byte(`{
+		"version": ["node1"],
+		"node1": [],
+		"node2": ["node1", "version"],
+		"node3": {"dependencies": ["node2"], "polluters": [{"test": "node1", "cleaners": ["node2"]}]}
+	}`))
+
+	expected := algorithms.NewDependencyGraph([]string{"version", "node1", "node2", "node3"})
+	expected.AddDependency("version", "node1")
+	expected.AddDependency("node2", "node1")
+	expected.AddDependency("node2", "version")
+	expected.AddDependency("node3", "node2")
+	expected.AddPollution("node3", "node1", "node2")
+
+	got, metadata, err := algorithms.ReadDependencyGraph(path)
+	assert.NilError(t, err)
+	assert.Check(t, metadata == nil)
+	assert.Check(t, got.Equal(expected), fmt.Sprintf("expected graph %v, but got %v", expected, got))
+
+	got, err = algorithms.DependencyGraphFromJson(path)
+	assert.NilError(t, err)
+	assert.Check(t, got.Equal(expected), fmt.Sprintf("expected graph %v, but got %v", expected, got))
+}
+
+func TestDependencyGraphFromJsonUnsupportedVersion(t *testing.T) {
+	t.Parallel()
+
+	path := writeGraphFile(t, []byte(`{"version": 99, "metadata": {}, "graph": {}}`))
+
+	_, err := algorithms.DependencyGraphFromJson(path)
+	assert.ErrorIs(t, err, algorithms.ErrUnsupportedGraphVersion)
+}
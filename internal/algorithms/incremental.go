@@ -0,0 +1,216 @@
+package algorithms
+
+import (
+	"context"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultConfirmations is the default number of schedules run to confirm the
+// edges retained by the incremental dependency detection.
+const DefaultConfirmations = 4
+
+// ChangedTests returns the tests which are new or modified with respect to a
+// base dependency graph, into their original order. A test is modified if
+// the digest of the test suite changed and its fingerprint differs from the
+// one recorded into the metadata of the base graph. If the base graph has no
+// metadata, all the tests are considered modified.
+func ChangedTests(base DependencyGraph, metadata *GraphMetadata, tests []string, digest string, fingerprints map[string]string) []string {
+	changed := []string{}
+
+	for _, test := range tests {
+		if _, ok := base[test]; !ok || metadata == nil {
+			changed = append(changed, test)
+		} else if metadata.Digest == digest {
+			continue
+		} else if fingerprint, ok := metadata.Fingerprints[test]; !ok || fingerprint != fingerprints[test] {
+			changed = append(changed, test)
+		}
+	}
+
+	return changed
+}
+
+// Incremental returns a DependencyDetector reusing the edges of a base
+// dependency graph. The edges of the tests which are not changed, and which
+// do not depend on a changed or a removed test, are retained. The
+// dependencies of the other tests are searched again, as the ones of the
+// brittle tests by RandomOrder. The retained edges are then confirmed by
+// running the provided number of schedules packing each test together with
+// its dependencies: the dependencies of the tests failing into them are
+// searched again as well.
+//
+// The pollution edges are only retained: the ones involving changed tests
+// are dropped.
+func Incremental(base DependencyGraph, changed []string, confirmations int) DependencyDetector {
+	return func(ctx context.Context, tests []string, r runner.ScheduleRunner) (DependencyGraph, error) {
+		g, affected := retainedGraph(base, tests, changed)
+		log.Infof("reusing the dependencies of %d tests, detecting the dependencies of %v",
+			len(tests)-len(affected), affected)
+
+		if err := detectStateSetters(ctx, r, tests, affected, g); err != nil {
+			return nil, err
+		}
+		checkpointOf(r).recordGraph(g)
+
+		notPassing, err := detectFailingTests(ctx, r, g.OptimizeSchedules(tests, confirmations, nil))
+		if err != nil {
+			return nil, err
+		}
+
+		detected := make(map[string]struct{}, len(affected))
+		for _, test := range affected {
+			detected[test] = struct{}{}
+		}
+
+		unconfirmed := []string{}
+		for _, test := range tests {
+			if _, ok := notPassing[test]; !ok {
+				continue
+			} else if _, ok := detected[test]; ok {
+				log.Warnf("test %s does not pass with the detected dependencies", test)
+				continue
+			}
+
+			for to, edge := range g[test] {
+				if edge.Kind == Dependency {
+					g.RemoveDependency(test, to)
+				}
+			}
+			unconfirmed = append(unconfirmed, test)
+		}
+		log.Infof("the dependencies of %v were not confirmed", unconfirmed)
+
+		if err := detectStateSetters(ctx, r, tests, unconfirmed, g); err != nil {
+			return nil, err
+		}
+
+		g.TransitiveReduction()
+		checkpointOf(r).recordGraph(g)
+
+		return g, nil
+	}
+}
+
+// retainedGraph returns the graph of the provided tests with the edges of the
+// base graph which can be retained, together with the tests whose
+// dependencies have to be searched again into their original order. These
+// are the new and the changed tests, and the tests depending on a changed or
+// a removed test.
+func retainedGraph(base DependencyGraph, tests, changed []string) (DependencyGraph, []string) {
+	var (
+		g        = NewDependencyGraph(tests)
+		stale    = map[string]struct{}{}
+		affected = []string{}
+	)
+
+	for _, test := range changed {
+		stale[test] = struct{}{}
+	}
+
+	for test := range base {
+		if _, ok := g[test]; !ok {
+			stale[test] = struct{}{}
+		}
+	}
+
+	for _, test := range tests {
+		if _, ok := base[test]; !ok {
+			stale[test] = struct{}{}
+		}
+	}
+
+	for _, test := range tests {
+		if _, ok := stale[test]; ok {
+			affected = append(affected, test)
+			continue
+		}
+
+		edges := map[string]Edge{}
+		for to, edge := range base[test] {
+			if _, ok := stale[to]; !ok {
+				edges[to] = edge
+			} else if edge.Kind == Dependency {
+				edges = nil
+				break
+			}
+		}
+
+		if edges == nil {
+			affected = append(affected, test)
+			continue
+		}
+
+		for to, edge := range edges {
+			if edge.Kind == Pollution {
+				edge.Cleaners = retainedCleaners(edge.Cleaners, g, stale)
+			}
+			g[test][to] = edge
+		}
+	}
+
+	return g, affected
+}
+
+// retainedCleaners returns the cleaners which are still into the graph and
+// which were not changed.
+func retainedCleaners(cleaners []string, g DependencyGraph, stale map[string]struct{}) []string {
+	retained := []string{}
+
+	for _, cleaner := range cleaners {
+		if _, ok := g[cleaner]; !ok {
+			continue
+		} else if _, ok := stale[cleaner]; !ok {
+			retained = append(retained, cleaner)
+		}
+	}
+
+	return retained
+}
+
+// detectStateSetters adds to the graph the dependencies of the provided tests.
+// The tests are first run in isolation: the ones failing are brittle, and
+// depend on the tests found by findStateSetters. The tests not passing into
+// the original order are skipped. If there is any error, it is returned.
+func detectStateSetters(ctx context.Context, r runner.ScheduleRunner, tests, candidates []string, g DependencyGraph) error {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	schedules := make([][]string, 0, len(candidates)+1)
+	schedules = append(schedules, tests)
+	for _, test := range candidates {
+		schedules = append(schedules, []string{test})
+	}
+
+	notPassing, err := detectFailingTests(ctx, r, schedules)
+	if err != nil {
+		return err
+	}
+
+	brittle := []string{}
+	for _, test := range candidates {
+		if _, ok := notPassing[test][0]; ok {
+			log.Warnf("test %s does not pass into the original order", test)
+		} else if _, ok := notPassing[test]; ok {
+			brittle = append(brittle, test)
+		}
+	}
+
+	stateSetters, err := forEachTest(ctx, brittle, func(ctx context.Context, test string) (map[string]Edge, error) {
+		return findStateSetters(ctx, r, tests, test)
+	})
+	if err != nil {
+		return err
+	}
+
+	for test, setters := range stateSetters {
+		for setter, edge := range setters {
+			g.AddDependency(test, setter)
+			g.SetEvidence(test, setter, edge.Evidence.Schedule, edge.Evidence.Results)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package algorithms_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pako-23/gtdd/internal/algorithms"
+	"github.com/pako-23/gtdd/internal/runner"
+	"gotest.tools/v3/assert"
+)
+
+func TestChangedTests(t *testing.T) {
+	t.Parallel()
+
+	var (
+		base     = algorithms.NewDependencyGraph([]string{"test1", "test2", "test3"})
+		tests    = []string{"test1", "test2", "test4", "test3"}
+		metadata = &algorithms.GraphMetadata{
+			Digest:       "sha256:1234",
+			Fingerprints: map[string]string{"test1": "a", "test2": "b", "test3": "c"},
+		}
+	)
+
+	assert.DeepEqual(t, algorithms.ChangedTests(base, metadata, tests, "sha256:1234", nil),
+		[]string{"test4"})
+	assert.DeepEqual(t, algorithms.ChangedTests(base, metadata, tests, "sha256:5678",
+		map[string]string{"test1": "a", "test2": "d", "test3": "c", "test4": "e"}),
+		[]string{"test2", "test4"})
+	assert.DeepEqual(t, algorithms.ChangedTests(base, metadata, tests, "sha256:5678",
+		map[string]string{"test1": "a", "test2": "b"}),
+		[]string{"test4", "test3"})
+	assert.DeepEqual(t, algorithms.ChangedTests(base, nil, tests, "sha256:1234", nil), tests)
+}
+
+func TestIncremental(t *testing.T) {
+	t.Parallel()
+
+	var (
+		testsuite    = []string{"test1", "test2", "test3", "test5", "test4", "test6"}
+		dependencies = map[string][][]string{
+			"test2": {{"test1"}},
+			"test3": {{"test1"}},
+			"test4": {{"test3"}},
+			"test5": {{"test2"}},
+		}
+		expected = algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
+			"test1": {},
+			"test2": {"test1": {}},
+			"test3": {"test1": {}},
+			"test4": {"test3": {}},
+			"test5": {"test2": {}},
+			"test6": {"test1": {Kind: algorithms.Pollution, Cleaners: []string{"test2"}}},
+		})
+	)
+
+	base := algorithms.NewDependencyGraph([]string{"test0", "test1", "test2", "test3", "test4", "test6"})
+	base.AddDependency("test2", "test1")
+	base.AddDependency("test3", "test0")
+	base.AddDependency("test4", "test3")
+	base.AddPollution("test6", "test1", "test0", "test2")
+
+	set, err := runner.NewRunnerSet[*mockRunner](context.Background(), 5,
+		newMockRunnerBuilder,
+		withDependencyMap(dependencies))
+	assert.NilError(t, err)
+
+	got, err := algorithms.Incremental(base, []string{"test5"}, algorithms.DefaultConfirmations)(context.Background(), testsuite, set)
+	assert.NilError(t, err)
+	assert.Check(t, got.Equal(expected),
+		fmt.Sprintf("expected graph %v, but got %v", expected, got))
+}
+
+func TestIncrementalUnconfirmedDependencies(t *testing.T) {
+	t.Parallel()
+
+	var (
+		testsuite    = []string{"test1", "test2", "test3", "test4"}
+		dependencies = map[string][][]string{
+			"test3": {{"test1"}},
+			"test4": {{"test2"}},
+		}
+		expected = algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
+			"test1": {},
+			"test2": {},
+			"test3": {"test1": {}},
+			"test4": {"test2": {}},
+		})
+	)
+
+	base := algorithms.NewDependencyGraph(testsuite)
+	base.AddDependency("test3", "test2")
+	base.AddDependency("test4", "test2")
+
+	set, err := runner.NewRunnerSet[*mockRunner](context.Background(), 5,
+		newMockRunnerBuilder,
+		withDependencyMap(dependencies))
+	assert.NilError(t, err)
+
+	got, err := algorithms.Incremental(base, []string{}, len(testsuite))(context.Background(), testsuite, set)
+	assert.NilError(t, err)
+	assert.Check(t, got.Equal(expected),
+		fmt.Sprintf("expected graph %v, but got %v", expected, got))
+}
+
+func TestIncrementalNoDependencies(t *testing.T) {
+	testNoDependencies(t, algorithms.Incremental(algorithms.DependencyGraph{}, nil, algorithms.DefaultConfirmations))
+}
+
+func TestIncrementalExistingDependencies(t *testing.T) {
+	testExistingDependencies(t, algorithms.Incremental(algorithms.DependencyGraph{}, nil, algorithms.DefaultConfirmations))
+}
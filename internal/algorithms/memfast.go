@@ -1,11 +1,11 @@
 package algorithms
 
 import (
+	"context"
 	"strings"
 
 	"github.com/pako-23/gtdd/internal/runner"
 	log "github.com/sirupsen/logrus"
-	"golang.org/x/exp/slices"
 )
 
 type schedule []string
@@ -14,6 +14,7 @@ type scheduleSet map[string]schedule
 
 type result struct {
 	schedule    schedule
+	results     []runner.TestOutcome
 	failedIndex int
 	err         error
 }
@@ -31,7 +32,7 @@ func (s scheduleSet) Insert(sched schedule) {
 	s[strings.Join(sched, ",")] = sched
 }
 
-func newState(tests []string, jobCh chan<- schedule, resultCh <-chan result) (*state, error) {
+func newState(ctx context.Context, tests []string, jobCh chan<- schedule, resultCh <-chan result) (*state, error) {
 	var (
 		t = &state{
 			failed:   map[string]struct{}{},
@@ -49,13 +50,17 @@ func newState(tests []string, jobCh chan<- schedule, resultCh <-chan result) (*s
 
 	go func() {
 		for _, test := range tests {
-			jobCh <- schedule{test}
+			if !send(ctx, jobCh, schedule{test}) {
+				return
+			}
 		}
 	}()
 
 	for range tests {
-		res := <-resultCh
-		if res.err != nil {
+		res, err := receive(ctx, resultCh)
+		if err != nil {
+			return nil, err
+		} else if res.err != nil {
 			return nil, res.err
 		}
 
@@ -122,20 +127,25 @@ func mergeSchedules(s1 schedule, s2 schedule, revIndex map[string]int) schedule
 	return result
 }
 
-func workerMEMFAST(r *runner.RunnerSet, jobCh <-chan schedule, resultCh chan<- result) {
-	for job := range jobCh {
+func workerMEMFAST(ctx context.Context, r runner.ScheduleRunner, jobCh <-chan schedule, resultCh chan<- result) {
+	for {
+		job, err := receive(ctx, jobCh)
+		if err != nil {
+			return
+		}
+
 		tries := 0
 		for {
-			out, err := r.RunSchedule(job)
+			out, err := runSchedule(ctx, r, job)
 			if err != nil {
-				resultCh <- result{nil, 0, err}
+				send(ctx, resultCh, result{err: err})
 				return
 			}
 			log.Debugf("run tests %v -> %v", job, out.Results)
 			tries++
-			firstFailed := slices.Index(out.Results, false)
+			firstFailed := runner.FirstNotPassed(out.Results)
 			if firstFailed == -1 || firstFailed == len(out.Results)-1 || tries >= 3 {
-				resultCh <- result{schedule: job, failedIndex: firstFailed, err: nil}
+				send(ctx, resultCh, result{schedule: job, results: out.Results, failedIndex: firstFailed, err: nil})
 				break
 			}
 
@@ -143,16 +153,20 @@ func workerMEMFAST(r *runner.RunnerSet, jobCh <-chan schedule, resultCh chan<- r
 	}
 }
 
-func appendMEMFAST(s *state, schedules []schedule, jobCh chan<- schedule, resultCh <-chan result) error {
+func appendMEMFAST(ctx context.Context, s *state, schedules []schedule, jobCh chan<- schedule, resultCh <-chan result) error {
 	go func() {
 		for _, schedule := range schedules {
-			jobCh <- schedule
+			if !send(ctx, jobCh, schedule) {
+				return
+			}
 		}
 	}()
 
 	for range schedules {
-		res := <-resultCh
-		if res.err != nil {
+		res, err := receive(ctx, resultCh)
+		if err != nil {
+			return err
+		} else if res.err != nil {
 			return res.err
 		}
 
@@ -164,6 +178,7 @@ func appendMEMFAST(s *state, schedules []schedule, jobCh chan<- schedule, result
 		if _, ok := s.failed[passedTest]; ok {
 			delete(s.failed, passedTest)
 			s.graph.AddDependency(passedTest, res.schedule[len(res.schedule)-2])
+			s.graph.SetEvidence(passedTest, res.schedule[len(res.schedule)-2], res.schedule, res.results)
 			log.Infof("done with test: %s, schedule: %v", passedTest, res.schedule)
 		}
 	}
@@ -171,7 +186,7 @@ func appendMEMFAST(s *state, schedules []schedule, jobCh chan<- schedule, result
 	return nil
 }
 
-func extensiveSearchMEMFAST(s *state, prefixLen, rank int, jobCh chan<- schedule, resultCh <-chan result) error {
+func extensiveSearchMEMFAST(ctx context.Context, s *state, prefixLen, rank int, jobCh chan<- schedule, resultCh <-chan result) error {
 	schedules := make(scheduleSet, prefixLen*prefixLen)
 
 	for base := 1; base < prefixLen; base++ {
@@ -204,15 +219,19 @@ func extensiveSearchMEMFAST(s *state, prefixLen, rank int, jobCh chan<- schedule
 
 	go func() {
 		for _, schedule := range schedules {
-			jobCh <- schedule
+			if !send(ctx, jobCh, schedule) {
+				return
+			}
 		}
 	}()
 
 	passing := make(scheduleSet, len(schedules))
 
 	for range schedules {
-		res := <-resultCh
-		if res.err != nil {
+		res, err := receive(ctx, resultCh)
+		if err != nil {
+			return err
+		} else if res.err != nil {
 			return res.err
 		}
 
@@ -230,6 +249,7 @@ func extensiveSearchMEMFAST(s *state, prefixLen, rank int, jobCh chan<- schedule
 
 				for i := 0; i < len(res.schedule)-1; i++ {
 					s.graph.AddDependency(passedTest, res.schedule[i])
+					s.graph.SetEvidence(passedTest, res.schedule[i], res.schedule, res.results)
 				}
 			}
 
@@ -260,15 +280,19 @@ func extensiveSearchMEMFAST(s *state, prefixLen, rank int, jobCh chan<- schedule
 
 		go func() {
 			for _, schedule := range schedules {
-				jobCh <- schedule
+				if !send(ctx, jobCh, schedule) {
+					return
+				}
 			}
 		}()
 
 		updatedPassing := make(scheduleSet, len(schedules))
 
 		for range schedules {
-			res := <-resultCh
-			if res.err != nil {
+			res, err := receive(ctx, resultCh)
+			if err != nil {
+				return err
+			} else if res.err != nil {
 				return res.err
 			}
 
@@ -284,6 +308,7 @@ func extensiveSearchMEMFAST(s *state, prefixLen, rank int, jobCh chan<- schedule
 			delete(s.failed, res.schedule[last])
 
 			s.graph.AddDependency(passedTest, res.schedule[len(res.schedule)-2])
+			s.graph.SetEvidence(passedTest, res.schedule[len(res.schedule)-2], res.schedule, res.results)
 			if len(res.schedule) <= rank {
 				updatedPassing.Insert(res.schedule)
 			}
@@ -295,23 +320,32 @@ func extensiveSearchMEMFAST(s *state, prefixLen, rank int, jobCh chan<- schedule
 	return nil
 }
 
-func MEMFAST(tests []string, r *runner.RunnerSet) (DependencyGraph, error) {
+func MEMFAST(ctx context.Context, tests []string, r runner.ScheduleRunner) (DependencyGraph, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	resultCh := make(chan result)
 	jobCh := make(chan schedule, r.Size())
 
 	for i := 0; i < r.Size(); i++ {
-		go workerMEMFAST(r, jobCh, resultCh)
+		go workerMEMFAST(ctx, r, jobCh, resultCh)
 	}
 
 	log.Info("starting dependency detection algorithm")
-	s, err := newState(tests, jobCh, resultCh)
-	if err != nil {
-		close(jobCh)
-		close(resultCh)
-		return nil, err
+	s, start := checkpointOf(r).restoreMEMFAST(tests)
+	if s == nil {
+		var err error
+
+		start = 1
+		s, err = newState(ctx, tests, jobCh, resultCh)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	for rank := 1; rank < len(tests); rank++ {
+	for rank := start; rank < len(tests); rank++ {
+		checkpointOf(r).recordMEMFAST(rank, s)
+
 		schedules := make([]schedule, 0, len(s.failed)*len(s.table[rank-1]))
 		for test := range s.failed {
 			for _, seq := range s.table[rank-1] {
@@ -329,9 +363,7 @@ func MEMFAST(tests []string, r *runner.RunnerSet) (DependencyGraph, error) {
 			}
 		}
 
-		if err := appendMEMFAST(s, schedules, jobCh, resultCh); err != nil {
-			close(jobCh)
-			close(resultCh)
+		if err := appendMEMFAST(ctx, s, schedules, jobCh, resultCh); err != nil {
 			return nil, err
 		}
 
@@ -344,9 +376,7 @@ func MEMFAST(tests []string, r *runner.RunnerSet) (DependencyGraph, error) {
 
 		log.Debugf("bruteforce test: %s started", tests[rank])
 		for prefixLen := 2; prefixLen <= rank; prefixLen++ {
-			if err := extensiveSearchMEMFAST(s, prefixLen, rank, jobCh, resultCh); err != nil {
-				close(jobCh)
-				close(resultCh)
+			if err := extensiveSearchMEMFAST(ctx, s, prefixLen, rank, jobCh, resultCh); err != nil {
 				return nil, err
 			}
 
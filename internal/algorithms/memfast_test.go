@@ -22,6 +22,10 @@ func TestMEMFASTOrDependencies(t *testing.T) {
 	testOrDependencies(t, algorithms.MEMFAST)
 }
 
+func TestMEMFASTInfrastructureErrors(t *testing.T) {
+	testInfrastructureErrors(t, algorithms.MEMFAST)
+}
+
 func TestMEMFASTOrDependenciesMultipleLen(t *testing.T) {
 	testMinLenOrDependencies(t, algorithms.MEMFAST)
 }
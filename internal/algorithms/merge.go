@@ -0,0 +1,217 @@
+package algorithms
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// MergeConflict represents an edge of a merged dependency graph which is
+// contradicted by another of the merged graphs or by the evidence they
+// contain.
+type MergeConflict struct {
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Reason   string    `json:"reason"`
+	Evidence *Evidence `json:"evidence,omitempty"`
+}
+
+func (c MergeConflict) String() string {
+	if c.Evidence == nil {
+		return fmt.Sprintf("%s -> %s: %s", c.From, c.To, c.Reason)
+	}
+
+	return fmt.Sprintf("%s -> %s: %s in schedule %v", c.From, c.To, c.Reason, c.Evidence.Schedule)
+}
+
+// Merge returns the union of the provided dependency graphs together with
+// the conflicts between them. If the graphs disagree on the kind of an edge,
+// the dependency is kept. The cleaners of the pollution edges are merged. An
+// edge is also conflicting if it is part of a dependency cycle, or if the
+// evidence of any of the graphs shows a test passing without one of its
+// dependencies running before it, or passing after one of its polluters
+// without any cleaner in between.
+func Merge(graphs ...DependencyGraph) (DependencyGraph, []MergeConflict) {
+	var (
+		merged    = DependencyGraph{}
+		conflicts = []MergeConflict{}
+		evidence  = []*Evidence{}
+	)
+
+	for _, g := range graphs {
+		for test := range g {
+			if _, ok := merged[test]; !ok {
+				merged[test] = map[string]Edge{}
+			}
+		}
+	}
+
+	for _, g := range graphs {
+		for from, edges := range g {
+			for to, edge := range edges {
+				if edge.Evidence != nil {
+					evidence = append(evidence, edge.Evidence)
+				}
+
+				existing, ok := merged[from][to]
+				if !ok {
+					edge.Cleaners = slices.Clone(edge.Cleaners)
+					merged[from][to] = edge
+					continue
+				}
+
+				if existing.Kind != edge.Kind {
+					conflicts = append(conflicts, MergeConflict{
+						From:   from,
+						To:     to,
+						Reason: fmt.Sprintf("the edge is both a %v and a %v", existing.Kind, edge.Kind),
+					})
+
+					if edge.Kind == Dependency {
+						merged[from][to] = edge
+					}
+
+					continue
+				}
+
+				for _, cleaner := range edge.Cleaners {
+					if !slices.Contains(existing.Cleaners, cleaner) {
+						existing.Cleaners = append(existing.Cleaners, cleaner)
+					}
+				}
+				sort.Strings(existing.Cleaners)
+
+				if existing.Evidence == nil {
+					existing.Evidence = edge.Evidence
+				}
+				merged[from][to] = existing
+			}
+		}
+	}
+
+	for from, edges := range merged {
+		for to, edge := range edges {
+			if _, cycle := merged.GetDependencies(to)[from]; edge.Kind == Dependency && cycle {
+				conflicts = append(conflicts, MergeConflict{From: from, To: to, Reason: "the edge is part of a dependency cycle"})
+			}
+		}
+	}
+
+	conflicts = append(conflicts, merged.contradictions(evidence)...)
+
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if conflicts[i].From != conflicts[j].From {
+			return conflicts[i].From < conflicts[j].From
+		}
+
+		return conflicts[i].To < conflicts[j].To
+	})
+
+	return merged, conflicts
+}
+
+// contradictions returns the edges of the graph contradicted by the runs of
+// the provided schedules. Each edge is reported at most once for each reason.
+func (d DependencyGraph) contradictions(evidence []*Evidence) []MergeConflict {
+	type key struct {
+		from, to string
+		kind     EdgeKind
+	}
+
+	var (
+		conflicts = []MergeConflict{}
+		reported  = map[key]struct{}{}
+	)
+
+	for _, run := range evidence {
+		for i := 0; i < len(run.Schedule) && i < len(run.Results); i++ {
+			if !run.Results[i].Passed() {
+				continue
+			}
+
+			test := run.Schedule[i]
+			for to, edge := range d[test] {
+				if _, ok := reported[key{test, to, edge.Kind}]; ok {
+					continue
+				}
+
+				index := slices.Index(run.Schedule[:i], to)
+
+				var reason string
+				switch {
+				case edge.Kind == Dependency && index == -1:
+					reason = "the test passed without its dependency"
+				case edge.Kind == Pollution && index != -1 && !slices.ContainsFunc(run.Schedule[index+1:i],
+					func(test string) bool { return slices.Contains(edge.Cleaners, test) }):
+					reason = "the test passed after its polluter"
+				default:
+					continue
+				}
+
+				reported[key{test, to, edge.Kind}] = struct{}{}
+				conflicts = append(conflicts, MergeConflict{From: test, To: to, Reason: reason, Evidence: run})
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// MergeMetadata returns the metadata of a graph merging the graphs with the
+// provided metadata. The graphs without metadata are ignored. The strategies
+// are joined, the digest is kept only if it is the same for all the graphs,
+// and the tests are ordered as into the first graph followed by the tests
+// only into the later ones. The fingerprints are kept only together with the
+// digest.
+func MergeMetadata(metadata ...*GraphMetadata) GraphMetadata {
+	var (
+		merged     GraphMetadata
+		strategies = []string{}
+		digests    = map[string]struct{}{}
+	)
+
+	for _, m := range metadata {
+		if m == nil {
+			continue
+		}
+
+		if m.Strategy != "" && !slices.Contains(strategies, m.Strategy) {
+			strategies = append(strategies, m.Strategy)
+		}
+		digests[m.Digest] = struct{}{}
+
+		for _, test := range m.Tests {
+			if !slices.Contains(merged.Tests, test) {
+				merged.Tests = append(merged.Tests, test)
+			}
+		}
+
+		if merged.StartedAt.IsZero() || (!m.StartedAt.IsZero() && m.StartedAt.Before(merged.StartedAt)) {
+			merged.StartedAt = m.StartedAt
+		}
+		if m.FinishedAt.After(merged.FinishedAt) {
+			merged.FinishedAt = m.FinishedAt
+		}
+		merged.Schedules += m.Schedules
+
+		for test, fingerprint := range m.Fingerprints {
+			if merged.Fingerprints == nil {
+				merged.Fingerprints = map[string]string{}
+			}
+			merged.Fingerprints[test] = fingerprint
+		}
+	}
+
+	merged.Strategy = strings.Join(strategies, "+")
+	if len(digests) == 1 {
+		for digest := range digests {
+			merged.Digest = digest
+		}
+	} else {
+		merged.Fingerprints = nil
+	}
+
+	return merged
+}
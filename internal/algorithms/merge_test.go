@@ -0,0 +1,157 @@
+package algorithms_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/algorithms"
+	"github.com/pako-23/gtdd/internal/runner"
+	"gotest.tools/v3/assert"
+)
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	first := algorithms.NewDependencyGraph([]string{"test1", "test2", "test3", "test4"})
+	first.AddDependency("test2", "test1")
+	first.AddPollution("test4", "test1", "test2")
+
+	second := algorithms.NewDependencyGraph([]string{"test1", "test3", "test4", "test5"})
+	second.AddDependency("test3", "test1")
+	second.AddPollution("test4", "test1", "test3")
+
+	merged, conflicts := algorithms.Merge(first, second)
+	assert.DeepEqual(t, conflicts, []algorithms.MergeConflict{})
+
+	expected := algorithms.NewDependencyGraph([]string{"test1", "test2", "test3", "test4", "test5"})
+	expected.AddDependency("test2", "test1")
+	expected.AddDependency("test3", "test1")
+	expected.AddPollution("test4", "test1", "test2", "test3")
+	assert.Assert(t, merged.Equal(expected))
+}
+
+func TestMergeKindConflict(t *testing.T) {
+	t.Parallel()
+
+	first := algorithms.NewDependencyGraph([]string{"test1", "test2"})
+	first.AddPollution("test2", "test1")
+
+	second := algorithms.NewDependencyGraph([]string{"test1", "test2"})
+	second.AddDependency("test2", "test1")
+
+	merged, conflicts := algorithms.Merge(first, second)
+	assert.DeepEqual(t, conflicts, []algorithms.MergeConflict{
+		{From: "test2", To: "test1", Reason: "the edge is both a pollution and a dependency"},
+	})
+	assert.Assert(t, merged.Equal(second))
+}
+
+func TestMergeDependencyCycle(t *testing.T) {
+	t.Parallel()
+
+	first := algorithms.NewDependencyGraph([]string{"test1", "test2"})
+	first.AddDependency("test2", "test1")
+
+	second := algorithms.NewDependencyGraph([]string{"test1", "test2"})
+	second.AddDependency("test1", "test2")
+
+	merged, conflicts := algorithms.Merge(first, second)
+	assert.DeepEqual(t, conflicts, []algorithms.MergeConflict{
+		{From: "test1", To: "test2", Reason: "the edge is part of a dependency cycle"},
+		{From: "test2", To: "test1", Reason: "the edge is part of a dependency cycle"},
+	})
+	assert.Assert(t, merged.HasDependencyCycle())
+	assert.Assert(t, !first.HasDependencyCycle())
+}
+
+func TestMergeContradictingEvidence(t *testing.T) {
+	t.Parallel()
+
+	var (
+		pass = runner.TestOutcome{Status: runner.StatusPass}
+		fail = runner.TestOutcome{Status: runner.StatusFail}
+	)
+
+	first := algorithms.NewDependencyGraph([]string{"test1", "test2", "test3"})
+	first.AddDependency("test3", "test2")
+	first.SetEvidence("test3", "test2", []string{"test1", "test3"}, []runner.TestOutcome{pass, fail})
+
+	second := algorithms.NewDependencyGraph([]string{"test1", "test2", "test3"})
+	second.AddDependency("test2", "test1")
+	second.SetEvidence("test2", "test1", []string{"test2"}, []runner.TestOutcome{fail})
+	second.AddPollution("test1", "test3")
+	second.SetEvidence("test1", "test3", []string{"test3", "test2", "test1"}, []runner.TestOutcome{pass, pass, pass})
+
+	_, conflicts := algorithms.Merge(first, second)
+	assert.DeepEqual(t, conflicts, []algorithms.MergeConflict{
+		{
+			From:   "test1",
+			To:     "test3",
+			Reason: "the test passed after its polluter",
+			Evidence: &algorithms.Evidence{
+				Schedule: []string{"test3", "test2", "test1"},
+				Results:  []runner.TestOutcome{pass, pass, pass},
+			},
+		},
+		{
+			From:   "test2",
+			To:     "test1",
+			Reason: "the test passed without its dependency",
+			Evidence: &algorithms.Evidence{
+				Schedule: []string{"test3", "test2", "test1"},
+				Results:  []runner.TestOutcome{pass, pass, pass},
+			},
+		},
+		{
+			From:   "test3",
+			To:     "test2",
+			Reason: "the test passed without its dependency",
+			Evidence: &algorithms.Evidence{
+				Schedule: []string{"test3", "test2", "test1"},
+				Results:  []runner.TestOutcome{pass, pass, pass},
+			},
+		},
+	})
+}
+
+func TestMergeMetadata(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	merged := algorithms.MergeMetadata(
+		&algorithms.GraphMetadata{
+			Strategy:   "pfast",
+			Digest:     "sha256:1234",
+			Tests:      []string{"test1", "test2"},
+			StartedAt:  start.Add(time.Hour),
+			FinishedAt: start.Add(2 * time.Hour),
+			Schedules:  4,
+		},
+		nil,
+		&algorithms.GraphMetadata{
+			Strategy:   "random",
+			Digest:     "sha256:1234",
+			Tests:      []string{"test2", "test3"},
+			StartedAt:  start,
+			FinishedAt: start.Add(time.Hour),
+			Schedules:  6,
+		},
+	)
+
+	assert.DeepEqual(t, merged, algorithms.GraphMetadata{
+		Strategy:   "pfast+random",
+		Digest:     "sha256:1234",
+		Tests:      []string{"test1", "test2", "test3"},
+		StartedAt:  start,
+		FinishedAt: start.Add(2 * time.Hour),
+		Schedules:  10,
+	})
+
+	merged = algorithms.MergeMetadata(
+		&algorithms.GraphMetadata{Strategy: "pfast", Digest: "sha256:1234"},
+		&algorithms.GraphMetadata{Strategy: "pfast", Digest: "sha256:5678"},
+	)
+	assert.Equal(t, merged.Strategy, "pfast")
+	assert.Equal(t, merged.Digest, "")
+}
@@ -1,6 +1,7 @@
 package algorithms
 
 import (
+	"context"
 	"sort"
 	"time"
 
@@ -10,39 +11,43 @@ import (
 	"github.com/pako-23/gtdd/internal/runner"
 )
 
-func detectFailingTests(runners *runner.RunnerSet, schedules [][]string) (map[string]map[int]struct{}, error) {
+func detectFailingTests(ctx context.Context, runners runner.ScheduleRunner, schedules [][]string) (map[string]map[int]struct{}, error) {
 	type results struct {
-		results  []bool
+		results  []runner.TestOutcome
 		schedule int
 		err      error
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	ch := make(chan results)
 	notPassing := map[string]map[int]struct{}{}
 
 	for i := range schedules {
 		go func(index int) {
-			out, err := runners.RunSchedule(schedules[index])
+			out, err := runSchedule(ctx, runners, schedules[index])
 			if err != nil {
-				ch <- results{err: err}
+				send(ctx, ch, results{err: err})
 
 				return
 			}
 			log.Debugf("run tests %v -> %v", schedules[index], out.Results)
 
-			ch <- results{schedule: index, results: out.Results, err: nil}
+			send(ctx, ch, results{schedule: index, results: out.Results, err: nil})
 		}(i)
 	}
 
 	for i := 0; i < len(schedules); i++ {
-		results := <-ch
-		if results.err != nil {
-			close(ch)
+		results, err := receive(ctx, ch)
+		if err != nil {
+			return nil, err
+		} else if results.err != nil {
 			return nil, results.err
 		}
 
 		for j, test := range schedules[results.schedule] {
-			if results.results[j] {
+			if results.results[j].Passed() {
 				continue
 			}
 
@@ -93,9 +98,10 @@ func findTargets(tests []string, g *DependencyGraph) []dependency {
 	return targets
 }
 
-func solveNode(tests []string, runners *runner.RunnerSet, i int, test string, g *DependencyGraph) error {
+func solveNode(ctx context.Context, tests []string, runners runner.ScheduleRunner, i int, test string, g *DependencyGraph) error {
 	targets := findTargets(tests[:i], g)
 	end := 0
+	var lastFailure *Evidence
 	for i, target := range targets {
 		log.Infof("recovery add edge %s -> %s", test, target.test)
 		g.AddDependency(test, target.test)
@@ -108,16 +114,20 @@ func solveNode(tests []string, runners *runner.RunnerSet, i int, test string, g
 			}
 		}
 		schedule = append(schedule, test)
-		results, err := runners.RunSchedule(schedule)
+		results, err := runSchedule(ctx, runners, schedule)
 		if err != nil {
 			return err
 		}
 		log.Debugf("run tests %v -> %v", schedule, results.Results)
 
-		if firstFailed := slices.Index(results.Results, false); firstFailed == -1 {
+		if firstFailed := runner.FirstNotPassed(results.Results); firstFailed == -1 {
+			if lastFailure != nil {
+				g.SetEvidence(test, target.test, lastFailure.Schedule, lastFailure.Results)
+			}
 			end = i
 			break
 		}
+		lastFailure = &Evidence{Schedule: schedule, Results: results.Results}
 	}
 
 	for i, target := range targets {
@@ -134,23 +144,24 @@ func solveNode(tests []string, runners *runner.RunnerSet, i int, test string, g
 			}
 		}
 		schedule = append(schedule, test)
-		results, err := runners.RunSchedule(schedule)
+		results, err := runSchedule(ctx, runners, schedule)
 		if err != nil {
 			return err
 		}
 		log.Debugf("run tests %v -> %v", schedule, results.Results)
 
-		if firstFailed := slices.Index(results.Results, false); firstFailed != -1 {
+		if firstFailed := runner.FirstNotPassed(results.Results); firstFailed != -1 {
 			g.AddDependency(test, target.test)
+			g.SetEvidence(test, target.test, schedule, results.Results)
 		}
 	}
 
 	return nil
 }
 
-func recoveryPFAST(tests []string, runners *runner.RunnerSet, g *DependencyGraph) error {
+func recoveryPFAST(ctx context.Context, tests []string, runners runner.ScheduleRunner, g *DependencyGraph) error {
 	schedules := g.GetSchedules(tests)
-	notPassingTests, err := detectFailingTests(runners, schedules)
+	notPassingTests, err := detectFailingTests(ctx, runners, schedules)
 	if err != nil {
 		return err
 	}
@@ -164,9 +175,10 @@ func recoveryPFAST(tests []string, runners *runner.RunnerSet, g *DependencyGraph
 			continue
 		}
 
-		if err := solveNode(tests, runners, i, test, g); err != nil {
+		if err := solveNode(ctx, tests, runners, i, test, g); err != nil {
 			return err
 		}
+		checkpointOf(runners).recordGraph(*g)
 
 		deps := g.GetDependencies(test)
 		prefix := []string{}
@@ -184,13 +196,13 @@ func recoveryPFAST(tests []string, runners *runner.RunnerSet, g *DependencyGraph
 			index := slices.Index(schedules[s], test)
 			schedule := prefix
 			schedule = append(schedule, schedules[s][index:]...)
-			results, err := runners.RunSchedule(schedule)
+			results, err := runSchedule(ctx, runners, schedule)
 			if err != nil {
 				return err
 			}
 			log.Debugf("run tests %v -> %v", schedule, results.Results)
 
-			if firstFailed := slices.Index(results.Results, false); firstFailed == -1 {
+			if firstFailed := runner.FirstNotPassed(results.Results); firstFailed == -1 {
 				passedSchedules[s] = struct{}{}
 			}
 		}
@@ -199,10 +211,11 @@ func recoveryPFAST(tests []string, runners *runner.RunnerSet, g *DependencyGraph
 	return nil
 }
 
-func PFAST(tests []string, r *runner.RunnerSet) (DependencyGraph, error) {
+func PFAST(ctx context.Context, tests []string, r runner.ScheduleRunner) (DependencyGraph, error) {
 	type result struct {
 		edge
-		err error
+		evidence Evidence
+		err      error
 	}
 
 	type job struct {
@@ -211,6 +224,9 @@ func PFAST(tests []string, r *runner.RunnerSet) (DependencyGraph, error) {
 		excluded int
 	}
 
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+
 	results := make(chan result, r.Size())
 	jobs := make(chan job, r.Size())
 	done := make(chan struct{})
@@ -220,40 +236,50 @@ func PFAST(tests []string, r *runner.RunnerSet) (DependencyGraph, error) {
 	// start workers
 	for i := 0; i < r.Size()+1; i++ {
 		go func() {
-			for job := range jobs {
+			for {
+				job, err := receive(workerCtx, jobs)
+				if err != nil {
+					return
+				}
+
 				var sleepTime time.Duration = 1
 
 				job.schedule = remove(job.schedule, job.toRemove)
 				for {
-					out, err := r.RunSchedule(job.schedule)
+					out, err := runSchedule(workerCtx, r, job.schedule)
 					if err != nil {
-						results <- result{edge: edge{from: "", to: ""}, err: err}
+						send(workerCtx, results, result{edge: edge{from: "", to: ""}, err: err})
 
 						return
 					}
 					log.Debugf("run tests %v -> %v", job.schedule, out.Results)
 
-					if firstFailed := slices.Index(out.Results, false); firstFailed == -1 {
-						done <- struct{}{}
+					if firstFailed := runner.FirstNotPassed(out.Results); firstFailed == -1 {
+						send(workerCtx, done, struct{}{})
 						break
 					} else if firstFailed < job.excluded {
-						time.Sleep(sleepTime * time.Second)
+						select {
+						case <-time.After(sleepTime * time.Second):
+						case <-workerCtx.Done():
+							return
+						}
 						sleepTime *= 2
 						continue
 					} else if firstFailed != -1 {
-						results <- result{
+						send(workerCtx, results, result{
 							edge: edge{
 								from: job.schedule[firstFailed],
 								to:   tests[job.excluded],
 							},
-							err: nil,
-						}
+							evidence: Evidence{Schedule: job.schedule, Results: out.Results},
+							err:      nil,
+						})
 
 						if len(job.schedule) != 1 {
 							job.toRemove = firstFailed
-							jobs <- job
+							send(workerCtx, jobs, job)
 						} else {
-							done <- struct{}{}
+							send(workerCtx, done, struct{}{})
 						}
 						break
 					}
@@ -265,7 +291,9 @@ func PFAST(tests []string, r *runner.RunnerSet) (DependencyGraph, error) {
 	log.Debug("starting dependency detection algorithm")
 	go func() {
 		for i := 0; i < len(tests)-1; i++ {
-			jobs <- job{schedule: tests, toRemove: i, excluded: i}
+			if !send(workerCtx, jobs, job{schedule: tests, toRemove: i, excluded: i}) {
+				return
+			}
 		}
 	}()
 
@@ -279,14 +307,30 @@ func PFAST(tests []string, r *runner.RunnerSet) (DependencyGraph, error) {
 			}
 
 			g.AddDependency(res.from, res.to)
+			g.SetEvidence(res.from, res.to, res.evidence.Schedule, res.evidence.Results)
+			checkpointOf(r).recordGraph(g)
 		case <-done:
 			jobsNum--
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 	}
-	close(jobs)
+
+	// A worker sends the edges it found before signalling that its job is
+	// done, so some edges could still be buffered.
+	for len(results) > 0 {
+		res := <-results
+		if res.err != nil {
+			return nil, res.err
+		}
+
+		g.AddDependency(res.from, res.to)
+		g.SetEvidence(res.from, res.to, res.evidence.Schedule, res.evidence.Results)
+	}
+	stopWorkers()
 
 	g.TransitiveReduction()
-	if err := recoveryPFAST(tests, r, &g); err != nil {
+	if err := recoveryPFAST(ctx, tests, r, &g); err != nil {
 		return nil, err
 	}
 
@@ -22,6 +22,10 @@ func TestPFASTOrDependencies(t *testing.T) {
 	testOrDependencies(t, algorithms.PFAST)
 }
 
+func TestPFASTInfrastructureErrors(t *testing.T) {
+	testInfrastructureErrors(t, algorithms.PFAST)
+}
+
 func TestPFASTOrDependenciesMultipleLen(t *testing.T) {
 	testMinLenOrDependencies(t, algorithms.PFAST)
 }
@@ -1,6 +1,7 @@
 package algorithms
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/pako-23/gtdd/internal/runner"
@@ -33,7 +34,7 @@ func edgeSelectPraDet(g DependencyGraph, edges []edge) (int, map[string]struct{}
 	return it, deps
 }
 
-func PraDet(tests []string, oracle *runner.RunnerSet) (DependencyGraph, error) {
+func PraDet(ctx context.Context, tests []string, oracle runner.ScheduleRunner) (DependencyGraph, error) {
 	g := NewDependencyGraph(tests)
 	edges := []edge{}
 
@@ -58,7 +59,7 @@ func PraDet(tests []string, oracle *runner.RunnerSet) (DependencyGraph, error) {
 			}
 		}
 		schedule = append(schedule, edges[it].to)
-		results, err := oracle.RunSchedule(schedule)
+		results, err := runSchedule(ctx, oracle, schedule)
 		if err != nil {
 			return nil, fmt.Errorf("pradet could not run schedule: %w", err)
 		}
@@ -68,17 +69,21 @@ func PraDet(tests []string, oracle *runner.RunnerSet) (DependencyGraph, error) {
 
 		for i, test := range schedule {
 			if test == edges[it].from {
-				if !results.Results[i] {
+				if !results.Results[i].Passed() {
 					g.AddDependency(edges[it].from, edges[it].to)
+					g.SetEvidence(edges[it].from, edges[it].to, schedule, results.Results)
 				}
 				edges = append(edges[:it], edges[it+1:]...)
 				break
-			} else if !results.Results[i] {
+			} else if !results.Results[i].Passed() {
 				g.AddDependency(edges[it].from, edges[it].to)
+				g.SetEvidence(edges[it].from, edges[it].to, schedule, results.Results)
 				break
 			}
 		}
 
+		checkpointOf(oracle).recordGraph(g)
+
 		if len(edges) == 0 {
 			break
 		}
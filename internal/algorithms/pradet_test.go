@@ -1,6 +1,7 @@
 package algorithms_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -25,6 +26,10 @@ func TestPraDetErdosRenyiGenerated(t *testing.T) {
 	testErdosRenyiGenerated(t, algorithms.PraDet)
 }
 
+func TestPraDetInfrastructureErrors(t *testing.T) {
+	testInfrastructureErrors(t, algorithms.PraDet)
+}
+
 func TestPraDetOrDependenciesMultipleLen(t *testing.T) {
 	t.Parallel()
 
@@ -44,14 +49,14 @@ func TestPraDetOrDependenciesMultipleLen(t *testing.T) {
 				},
 			},
 			expected: []algorithms.DependencyGraph{
-				algorithms.DependencyGraph(map[string]map[string]struct{}{
+				algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 					"test1": {},
 					"test2": {"test1": {}},
 					"test3": {"test2": {}},
 					"test4": {},
 					"test5": {"test4": {}},
 				}),
-				algorithms.DependencyGraph(map[string]map[string]struct{}{
+				algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 					"test1": {},
 					"test2": {"test1": {}},
 					"test3": {"test2": {}},
@@ -75,7 +80,7 @@ func TestPraDetOrDependenciesMultipleLen(t *testing.T) {
 				},
 			},
 			expected: []algorithms.DependencyGraph{
-				algorithms.DependencyGraph(map[string]map[string]struct{}{
+				algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 					"test1": {},
 					"test2": {"test1": {}},
 					"test3": {"test2": {}},
@@ -83,7 +88,7 @@ func TestPraDetOrDependenciesMultipleLen(t *testing.T) {
 					"test5": {"test4": {}},
 					"test6": {"test5": {}},
 				}),
-				algorithms.DependencyGraph(map[string]map[string]struct{}{
+				algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 					"test1": {},
 					"test2": {"test1": {}},
 					"test3": {"test2": {}},
@@ -96,10 +101,10 @@ func TestPraDetOrDependenciesMultipleLen(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		runner, _ := runner.NewRunnerSet[*mockRunner](5,
+		runner, _ := runner.NewRunnerSet[*mockRunner](context.Background(), 5,
 			newMockRunnerBuilder,
 			withDependencyMap(test.dependencies))
-		graph, err := algorithms.PraDet(test.testsuite, runner)
+		graph, err := algorithms.PraDet(context.Background(), test.testsuite, runner)
 
 		assert.NilError(t, err)
 		found := false
@@ -0,0 +1,387 @@
+package algorithms
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/exp/slices"
+)
+
+// DefaultRandomRuns is the default number of random orders run by the
+// randomized dependency detection.
+const DefaultRandomRuns = 10
+
+// Classification represents the order-dependent tests found by running a
+// test suite into randomized orders.
+type Classification struct {
+	// The seed used to generate the random orders.
+	Seed int64 `json:"seed"`
+	// The number of random orders which were run.
+	Runs int `json:"runs"`
+	// The tests which pass in isolation, but fail when run after some other
+	// tests, together with the tests making them fail.
+	Victims map[string][]string `json:"victims"`
+	// The tests making at least a victim fail.
+	Polluters []string `json:"polluters"`
+	// The tests restoring the state broken by each polluter for at least
+	// one of its victims.
+	Cleaners map[string][]string `json:"cleaners"`
+	// The tests which fail in isolation, together with the tests setting
+	// up the state they need to pass.
+	Brittle map[string][]string `json:"brittle"`
+	// The tests which do not pass into the original order, and which are
+	// therefore not classified.
+	Failing []string `json:"failing"`
+}
+
+// ToJSON writes a JSON representation of the classification.
+func (c *Classification) ToJSON(w io.Writer) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		log.Errorf("failed to create json from data: %v", err)
+	}
+
+	w.Write(data)
+}
+
+// RandomOrder returns a DependencyDetector which runs the tests into their
+// original order, into the reversed order and into the provided number of
+// random orders generated from the seed. If the seed is 0, a seed based on
+// the current time is used, unless the detection is resumed from a
+// checkpoint which recorded the seed of the interrupted detection. The tests
+// which fail in some orders are run in isolation: the ones failing are
+// brittle and depend on the tests setting up their state, while the ones
+// passing are victims of the tests polluting their state. The victims are
+// linked to their polluters by pollution edges, together with the tests
+// cleaning the polluted state. If classification is not nil, it is filled
+// with the classification of the tests.
+//
+// Unlike the other detectors, the dependencies are only searched for the
+// tests failing into some of the orders which were run, so the resulting
+// graph is a candidate which can miss dependencies.
+func RandomOrder(runs int, seed int64, classification *Classification) DependencyDetector {
+	return func(ctx context.Context, tests []string, r runner.ScheduleRunner) (DependencyGraph, error) {
+		checkpoint := checkpointOf(r)
+		seed, err := checkpoint.restoreSeed(seed)
+		if err != nil {
+			return nil, err
+		} else if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		checkpoint.recordSeed(seed)
+		log.Infof("running %d random orders with seed %d", runs, seed)
+
+		c := Classification{
+			Seed:      seed,
+			Runs:      runs,
+			Victims:   map[string][]string{},
+			Polluters: []string{},
+			Cleaners:  map[string][]string{},
+			Brittle:   map[string][]string{},
+			Failing:   []string{},
+		}
+		g := NewDependencyGraph(tests)
+
+		schedules := randomSchedules(tests, runs, seed)
+		notPassing, err := detectFailingTests(ctx, r, schedules)
+		if err != nil {
+			return nil, err
+		}
+
+		orderDependent := []string{}
+		for _, test := range tests {
+			if _, ok := notPassing[test][0]; ok {
+				c.Failing = append(c.Failing, test)
+			} else if _, ok := notPassing[test]; ok {
+				orderDependent = append(orderDependent, test)
+			}
+		}
+		log.Infof("order-dependent tests %v", orderDependent)
+
+		isolated := make([][]string, 0, len(orderDependent))
+		for _, test := range orderDependent {
+			isolated = append(isolated, []string{test})
+		}
+
+		failingAlone, err := detectFailingTests(ctx, r, isolated)
+		if err != nil {
+			return nil, err
+		}
+
+		brittle, victims := []string{}, []string{}
+		for _, test := range orderDependent {
+			if _, ok := failingAlone[test]; ok {
+				brittle = append(brittle, test)
+			} else {
+				victims = append(victims, test)
+			}
+		}
+
+		stateSetters, err := forEachTest(ctx, brittle, func(ctx context.Context, test string) (map[string]Edge, error) {
+			return findStateSetters(ctx, r, tests, test)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for test, setters := range stateSetters {
+			c.Brittle[test] = []string{}
+			for _, setter := range tests {
+				if edge, ok := setters[setter]; ok {
+					g.AddDependency(test, setter)
+					g.SetEvidence(test, setter, edge.Evidence.Schedule, edge.Evidence.Results)
+					c.Brittle[test] = append(c.Brittle[test], setter)
+				}
+			}
+		}
+		checkpointOf(r).recordGraph(g)
+
+		pollutions, err := forEachTest(ctx, victims, func(ctx context.Context, test string) (map[string]Edge, error) {
+			return findPolluters(ctx, r, tests, schedules, notPassing[test], test, g)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		polluters, cleaners := map[string]struct{}{}, map[string]map[string]struct{}{}
+		for victim, victimPolluters := range pollutions {
+			for _, polluter := range tests {
+				edge, ok := victimPolluters[polluter]
+				if !ok {
+					continue
+				}
+
+				g.AddPollution(victim, polluter, edge.Cleaners...)
+				g.SetEvidence(victim, polluter, edge.Evidence.Schedule, edge.Evidence.Results)
+				c.Victims[victim] = append(c.Victims[victim], polluter)
+
+				polluters[polluter] = struct{}{}
+				if _, ok := cleaners[polluter]; !ok {
+					cleaners[polluter] = map[string]struct{}{}
+				}
+				for _, cleaner := range edge.Cleaners {
+					cleaners[polluter][cleaner] = struct{}{}
+				}
+			}
+		}
+
+		for _, test := range tests {
+			if _, ok := polluters[test]; ok {
+				c.Polluters = append(c.Polluters, test)
+			}
+
+			for polluter, polluterCleaners := range cleaners {
+				if _, ok := polluterCleaners[test]; ok {
+					c.Cleaners[polluter] = append(c.Cleaners[polluter], test)
+				}
+			}
+		}
+
+		if classification != nil {
+			*classification = c
+		}
+
+		g.TransitiveReduction()
+		checkpointOf(r).recordGraph(g)
+
+		return g, nil
+	}
+}
+
+// randomSchedules returns the original order of the tests, followed by the
+// reversed order and by the provided number of random orders generated from
+// the seed.
+func randomSchedules(tests []string, runs int, seed int64) [][]string {
+	var (
+		rng       = rand.New(rand.NewSource(seed))
+		reversed  = slices.Clone(tests)
+		schedules = make([][]string, 0, runs+2)
+	)
+
+	slices.Reverse(reversed)
+	schedules = append(schedules, tests, reversed)
+
+	for i := 0; i < runs; i++ {
+		schedule := make([]string, 0, len(tests))
+		for _, index := range rng.Perm(len(tests)) {
+			schedule = append(schedule, tests[index])
+		}
+
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules
+}
+
+// forEachTest concurrently runs a search on each of the provided tests and
+// returns what was found for each of them. If there is any error, it is
+// returned.
+func forEachTest[T any](ctx context.Context, tests []string, search func(context.Context, string) (T, error)) (map[string]T, error) {
+	type result struct {
+		test  string
+		found T
+		err   error
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan result)
+	for _, test := range tests {
+		go func(test string) {
+			found, err := search(ctx, test)
+			send(ctx, ch, result{test: test, found: found, err: err})
+		}(test)
+	}
+
+	found := map[string]T{}
+	for range tests {
+		res, err := receive(ctx, ch)
+		if err != nil {
+			return nil, err
+		} else if res.err != nil {
+			return nil, res.err
+		}
+
+		found[res.test] = res.found
+	}
+
+	return found, nil
+}
+
+// findStateSetters returns a minimal set of the tests preceding a brittle
+// test into the original order which make it pass, together with the
+// dependency edges on them. Starting from all the preceding tests, each test
+// is dropped, from the last to the first, if the brittle test still passes
+// without it. The tests which fail once a test is dropped are dropped as
+// well, as they cannot set up any state. If there is any error, it is
+// returned.
+func findStateSetters(ctx context.Context, r runner.ScheduleRunner, tests []string, test string) (map[string]Edge, error) {
+	var (
+		setters = slices.Clone(tests[:slices.Index(tests, test)])
+		edges   = map[string]Edge{}
+	)
+
+	for i := len(setters) - 1; i >= 0; i-- {
+		candidate := remove(setters, i)
+
+		for {
+			schedule := append(slices.Clone(candidate), test)
+
+			results, err := runSchedule(ctx, r, schedule)
+			if err != nil {
+				return nil, err
+			}
+			log.Debugf("run tests %v -> %v", schedule, results.Results)
+
+			firstFailed := runner.FirstNotPassed(results.Results)
+			if firstFailed == -1 {
+				setters = candidate
+				break
+			} else if firstFailed == len(candidate) {
+				edges[setters[i]] = Edge{
+					Kind:     Dependency,
+					Evidence: &Evidence{Schedule: schedule, Results: results.Results},
+				}
+				break
+			}
+
+			candidate = remove(candidate, firstFailed)
+		}
+	}
+	log.Infof("brittle test %s depends on %v", test, setters)
+
+	return edges, nil
+}
+
+// findPolluters returns the tests which make a victim fail when run right
+// before it, together with the pollution edges listing the tests cleaning the
+// state polluted by each of them. The candidate polluters are the tests
+// preceding the victim into the schedules where it failed. If there is any
+// error, it is returned.
+func findPolluters(ctx context.Context, r runner.ScheduleRunner, tests []string, schedules [][]string, failed map[int]struct{}, test string, g DependencyGraph) (map[string]Edge, error) {
+	candidates := map[string]struct{}{}
+	for s := range failed {
+		for _, candidate := range schedules[s][:slices.Index(schedules[s], test)] {
+			candidates[candidate] = struct{}{}
+		}
+	}
+
+	polluters := map[string]Edge{}
+	for _, candidate := range tests {
+		if _, ok := candidates[candidate]; !ok {
+			continue
+		}
+
+		schedule := append(withDependencies(g, tests, candidate), test)
+
+		results, err := runSchedule(ctx, r, schedule)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("run tests %v -> %v", schedule, results.Results)
+
+		if runner.FirstNotPassed(results.Results) != len(schedule)-1 {
+			continue
+		}
+
+		cleaners, err := findCleaners(ctx, r, tests, candidate, test, g)
+		if err != nil {
+			return nil, err
+		}
+		polluters[candidate] = Edge{
+			Kind:     Pollution,
+			Cleaners: cleaners,
+			Evidence: &Evidence{Schedule: schedule, Results: results.Results},
+		}
+	}
+	log.Infof("victim test %s is polluted by %d tests", test, len(polluters))
+
+	return polluters, nil
+}
+
+// findCleaners returns the tests which make a victim pass when run between
+// a polluter and the victim. If there is any error, it is returned.
+func findCleaners(ctx context.Context, r runner.ScheduleRunner, tests []string, polluter, victim string, g DependencyGraph) ([]string, error) {
+	cleaners := []string{}
+
+	for _, candidate := range tests {
+		if candidate == polluter || candidate == victim {
+			continue
+		}
+
+		schedule := append(withDependencies(g, tests, polluter), candidate, victim)
+
+		results, err := runSchedule(ctx, r, schedule)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("run tests %v -> %v", schedule, results.Results)
+
+		if runner.FirstNotPassed(results.Results) == -1 {
+			cleaners = append(cleaners, candidate)
+		}
+	}
+
+	return cleaners, nil
+}
+
+// withDependencies returns a schedule running a test after all the tests it
+// depends on into the dependency graph, in their original order.
+func withDependencies(g DependencyGraph, tests []string, test string) []string {
+	deps := g.GetDependencies(test)
+	schedule := []string{}
+
+	for _, dep := range tests {
+		if _, ok := deps[dep]; ok {
+			schedule = append(schedule, dep)
+		}
+	}
+
+	return append(schedule, test)
+}
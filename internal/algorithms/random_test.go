@@ -0,0 +1,112 @@
+package algorithms_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pako-23/gtdd/internal/algorithms"
+	"github.com/pako-23/gtdd/internal/runner"
+	"golang.org/x/exp/slices"
+	"gotest.tools/v3/assert"
+)
+
+func randomOrder(ctx context.Context, tests []string, r runner.ScheduleRunner) (algorithms.DependencyGraph, error) {
+	return algorithms.RandomOrder(algorithms.DefaultRandomRuns, 42, nil)(ctx, tests, r)
+}
+
+func TestRandomOrderNoDependencies(t *testing.T) {
+	testNoDependencies(t, randomOrder)
+}
+
+func TestRandomOrderExistingDependencies(t *testing.T) {
+	testExistingDependencies(t, randomOrder)
+}
+
+func TestRandomOrderOrDependencies(t *testing.T) {
+	testOrDependencies(t, randomOrder)
+}
+
+func TestRandomOrderErdosRenyiGenerated(t *testing.T) {
+	testErdosRenyiGenerated(t, randomOrder)
+}
+
+func TestRandomOrderInfrastructureErrors(t *testing.T) {
+	testInfrastructureErrors(t, randomOrder)
+}
+
+// pollutingOracle runs schedules where each victim fails if any of its
+// polluters runs before it without one of its cleaners in between, and each
+// test into the failing set always fails.
+type pollutingOracle struct {
+	polluters map[string][]string
+	cleaners  map[string][]string
+	failing   map[string]struct{}
+}
+
+func (p *pollutingOracle) RunSchedule(ctx context.Context, schedule []string) (runner.RunResults, error) {
+	results := make([]runner.TestOutcome, len(schedule))
+
+	for i, test := range schedule {
+		results[i].Status = runner.StatusPass
+		if _, ok := p.failing[test]; ok {
+			results[i].Status = runner.StatusFail
+		}
+
+		for j := i - 1; j >= 0; j-- {
+			if slices.Contains(p.cleaners[test], schedule[j]) {
+				break
+			} else if slices.Contains(p.polluters[test], schedule[j]) {
+				results[i].Status = runner.StatusFail
+			}
+		}
+	}
+
+	return runner.RunResults{Results: results}, nil
+}
+
+func (p *pollutingOracle) Size() int {
+	return 5
+}
+
+func TestRandomOrderClassification(t *testing.T) {
+	t.Parallel()
+
+	var (
+		testsuite = []string{"test1", "test2", "test3", "test4", "test5", "test6", "test7"}
+		oracle    = &pollutingOracle{
+			polluters: map[string][]string{
+				"test1": {"test4"},
+				"test2": {"test3"},
+				"test6": {"test1"},
+			},
+			cleaners: map[string][]string{"test6": {"test5"}},
+			failing:  map[string]struct{}{"test7": {}},
+		}
+		classification algorithms.Classification
+	)
+
+	got, err := algorithms.RandomOrder(20, 7, &classification)(context.Background(), testsuite, oracle)
+	assert.NilError(t, err)
+
+	expected := algorithms.NewDependencyGraph(testsuite)
+	expected.AddPollution("test1", "test4")
+	expected.AddPollution("test2", "test3")
+	expected.AddPollution("test6", "test1", "test5")
+	assert.Check(t, got.Equal(expected),
+		fmt.Sprintf("expected graph %v, but got %v", expected, got))
+
+	assert.DeepEqual(t, classification, algorithms.Classification{
+		Seed: 7,
+		Runs: 20,
+		Victims: map[string][]string{
+			"test1": {"test4"},
+			"test2": {"test3"},
+			"test6": {"test1"},
+		},
+		Polluters: []string{"test1", "test3", "test4"},
+		Cleaners:  map[string][]string{"test1": {"test5"}},
+		Brittle:   map[string][]string{},
+		Failing:   []string{"test7"},
+	})
+}
@@ -0,0 +1,240 @@
+package algorithms
+
+import (
+	"sort"
+	"time"
+)
+
+// testWeights returns the expected running time of each test. The tests
+// without a known duration are expected to last as the average of the known
+// ones; if no duration is known, all the tests are expected to last the same.
+func testWeights(tests []string, durations map[string]time.Duration) map[string]time.Duration {
+	var (
+		weights = make(map[string]time.Duration, len(tests))
+		total   time.Duration
+		known   int
+	)
+
+	for _, test := range tests {
+		if duration, ok := durations[test]; ok {
+			total += duration
+			known++
+		}
+	}
+
+	fallback := time.Second
+	if known > 0 {
+		fallback = total / time.Duration(known)
+	}
+
+	for _, test := range tests {
+		duration, ok := durations[test]
+		if !ok {
+			duration = fallback
+		}
+
+		// Every test takes some time, so that a schedule strictly
+		// containing another one always costs more.
+		weights[test] = max(duration, 1)
+	}
+
+	return weights
+}
+
+// OptimizeSchedules returns at most runners schedules covering all the
+// provided tests while respecting the dependencies into the graph. Each
+// schedule contains the dependencies of all its tests, so that the schedules
+// can run in parallel. The tests are packed to minimize the expected running
+// time of the longest schedule given the expected duration of each test.
+//
+// The tests are considered from the one with the most expensive dependency
+// closure, which bounds the running time of any packing. Each test which is
+// not covered yet is added, together with its dependencies, to the schedule
+// whose running time grows the least, so that shared dependencies are not run
+// more than needed. A test is never added to a schedule where one of its
+// polluters would run before it, or where it would run before a test it
+// pollutes, without a cleaner in between: if no schedule can take it, a new
+// schedule is created even if there are already runners schedules. The
+// schedules are returned from the longest one.
+func (d DependencyGraph) OptimizeSchedules(tests []string, runners int, durations map[string]time.Duration) [][]string {
+	type schedule struct {
+		tests map[string]struct{}
+		cost  time.Duration
+	}
+
+	var (
+		weights   = testWeights(tests, durations)
+		closures  = make(map[string]map[string]struct{}, len(tests))
+		costs     = make(map[string]time.Duration, len(tests))
+		revIndex  = buildReverseIndex(tests)
+		schedules = []*schedule{}
+	)
+
+	if runners < 1 {
+		runners = 1
+	}
+
+	for _, test := range tests {
+		closure := d.scheduleClosure(test, revIndex)
+		closures[test] = closure
+
+		for dependency := range closure {
+			costs[test] += weights[dependency]
+		}
+	}
+
+	candidates := make([]string, len(tests))
+	copy(candidates, tests)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if costs[candidates[i]] != costs[candidates[j]] {
+			return costs[candidates[i]] > costs[candidates[j]]
+		}
+
+		return revIndex[candidates[i]] > revIndex[candidates[j]]
+	})
+
+	for _, test := range candidates {
+		covered := false
+		for _, s := range schedules {
+			if _, ok := s.tests[test]; ok {
+				_, polluted := d.pollutedTests(s.tests, revIndex)[test]
+				covered = !polluted
+				break
+			}
+		}
+
+		if covered {
+			continue
+		}
+
+		var (
+			best      *schedule
+			bestAdded time.Duration
+		)
+
+		for _, s := range schedules {
+			if !d.canMerge(s.tests, closures[test], revIndex) {
+				continue
+			}
+
+			var added time.Duration
+			for dependency := range closures[test] {
+				if _, ok := s.tests[dependency]; !ok {
+					added += weights[dependency]
+				}
+			}
+
+			if best == nil || s.cost+added < best.cost+bestAdded ||
+				(s.cost+added == best.cost+bestAdded && added < bestAdded) {
+				best, bestAdded = s, added
+			}
+		}
+
+		if best == nil || (len(schedules) < runners && costs[test] < best.cost+bestAdded) {
+			best, bestAdded = &schedule{tests: map[string]struct{}{}}, costs[test]
+			schedules = append(schedules, best)
+		}
+
+		for dependency := range closures[test] {
+			best.tests[dependency] = struct{}{}
+		}
+		best.cost += bestAdded
+	}
+
+	sort.SliceStable(schedules, func(i, j int) bool {
+		return schedules[i].cost > schedules[j].cost
+	})
+
+	result := make([][]string, 0, len(schedules))
+	for _, s := range schedules {
+		ordered := make([]string, 0, len(s.tests))
+		for _, test := range tests {
+			if _, ok := s.tests[test]; ok {
+				ordered = append(ordered, test)
+			}
+		}
+
+		result = append(result, ordered)
+	}
+
+	return result
+}
+
+// canMerge reports whether the tests of a closure can be added to a schedule
+// without any of the tests being polluted by the tests coming from the other
+// set. The order of the tests is given by the reverse index.
+func (d DependencyGraph) canMerge(schedule, closure map[string]struct{}, revIndex map[string]int) bool {
+	merged := make(map[string]struct{}, len(schedule)+len(closure))
+	for test := range schedule {
+		merged[test] = struct{}{}
+	}
+	for test := range closure {
+		merged[test] = struct{}{}
+	}
+
+	var (
+		pollutedSchedule = d.pollutedTests(schedule, revIndex)
+		pollutedClosure  = d.pollutedTests(closure, revIndex)
+	)
+
+	for test := range d.pollutedTests(merged, revIndex) {
+		_, inSchedule := pollutedSchedule[test]
+		_, inClosure := pollutedClosure[test]
+
+		if !inSchedule && !inClosure {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Shard splits the schedules into the provided number of shards balancing
+// their expected running time given the expected duration of each test. As
+// each schedule contains the dependencies of all its tests, the shards can
+// run independently on different machines. The schedules are assigned from
+// the longest one to the shard with the shortest running time, so that the
+// same schedules and durations always produce the same shards.
+func Shard(schedules [][]string, shards int, durations map[string]time.Duration) [][][]string {
+	tests := []string{}
+	for _, schedule := range schedules {
+		tests = append(tests, schedule...)
+	}
+
+	var (
+		weights = testWeights(tests, durations)
+		costs   = make([]time.Duration, len(schedules))
+		order   = make([]int, len(schedules))
+		loads   = make([]time.Duration, shards)
+		result  = make([][][]string, shards)
+	)
+
+	for i, schedule := range schedules {
+		for _, test := range schedule {
+			costs[i] += weights[test]
+		}
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return costs[order[i]] > costs[order[j]]
+	})
+
+	for i := range result {
+		result[i] = [][]string{}
+	}
+
+	for _, index := range order {
+		shard := 0
+		for i := range loads {
+			if loads[i] < loads[shard] {
+				shard = i
+			}
+		}
+
+		result[shard] = append(result[shard], schedules[index])
+		loads[shard] += costs[index]
+	}
+
+	return result
+}
@@ -0,0 +1,173 @@
+package algorithms_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/algorithms"
+	"gotest.tools/v3/assert"
+)
+
+func TestOptimizeSchedules(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		graph     algorithms.DependencyGraph
+		tests     []string
+		runners   int
+		durations map[string]time.Duration
+		expected  [][]string
+	}{
+		{
+			graph:    algorithms.NewDependencyGraph([]string{"node1", "node2", "node3", "node4"}),
+			tests:    []string{"node1", "node2", "node3", "node4"},
+			runners:  2,
+			expected: [][]string{{"node2", "node4"}, {"node1", "node3"}},
+		},
+		{
+			graph:   algorithms.NewDependencyGraph([]string{"node1", "node2", "node3", "node4"}),
+			tests:   []string{"node1", "node2", "node3", "node4"},
+			runners: 2,
+			durations: map[string]time.Duration{
+				"node1": 3 * time.Second,
+				"node2": time.Second,
+				"node3": time.Second,
+				"node4": time.Second,
+			},
+			expected: [][]string{{"node1"}, {"node2", "node3", "node4"}},
+		},
+		{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
+				"node1": {},
+				"node2": {"node1": {}},
+				"node3": {"node1": {}},
+				"node4": {"node1": {}, "node3": {}},
+				"node5": {"node1": {}, "node2": {}},
+			}),
+			tests:    []string{"node1", "node2", "node3", "node4", "node5"},
+			runners:  1,
+			expected: [][]string{{"node1", "node2", "node3", "node4", "node5"}},
+		},
+		{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
+				"node1": {},
+				"node2": {"node1": {}},
+				"node3": {"node1": {}},
+				"node4": {},
+			}),
+			tests:   []string{"node1", "node2", "node3", "node4"},
+			runners: 3,
+			durations: map[string]time.Duration{
+				"node1": 10 * time.Second,
+				"node2": time.Second,
+				"node3": time.Second,
+				"node4": time.Second,
+			},
+			expected: [][]string{{"node1", "node3"}, {"node1", "node2"}, {"node4"}},
+		},
+	}
+
+	for _, test := range tests {
+		schedules := test.graph.OptimizeSchedules(test.tests, test.runners, test.durations)
+
+		assert.DeepEqual(t, schedules, test.expected)
+	}
+}
+
+func TestOptimizeSchedulesRespectDependencies(t *testing.T) {
+	t.Parallel()
+
+	tests := make([]string, 30)
+	for i := range tests {
+		tests[i] = fmt.Sprintf("test%d", i)
+	}
+
+	for runners := 1; runners <= 8; runners++ {
+		graph := erdosRenyiGenerate(tests)
+		schedules := graph.OptimizeSchedules(tests, runners, nil)
+		assert.Check(t, len(schedules) <= runners)
+
+		covered := map[string]struct{}{}
+		for _, schedule := range schedules {
+			position := map[string]int{}
+			for i, test := range schedule {
+				position[test] = i
+				covered[test] = struct{}{}
+			}
+
+			for i, test := range schedule {
+				for dependency := range graph.GetDependencies(test) {
+					j, ok := position[dependency]
+					assert.Check(t, ok && j < i,
+						fmt.Sprintf("dependency %s of %s is missing from schedule %v", dependency, test, schedule))
+				}
+			}
+		}
+
+		assert.Equal(t, len(covered), len(tests))
+	}
+}
+
+func TestOptimizeSchedulesPollution(t *testing.T) {
+	t.Parallel()
+
+	graph := algorithms.NewDependencyGraph([]string{"node1", "node2", "node3"})
+	graph.AddPollution("node3", "node1")
+
+	schedules := graph.OptimizeSchedules([]string{"node1", "node2", "node3"}, 1, nil)
+	assert.DeepEqual(t, schedules, [][]string{{"node2", "node3"}, {"node1"}})
+
+	graph.AddPollution("node3", "node1", "node2")
+
+	schedules = graph.OptimizeSchedules([]string{"node1", "node2", "node3"}, 1, nil)
+	assert.DeepEqual(t, schedules, [][]string{{"node1", "node2", "node3"}})
+}
+
+func TestShard(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		schedules [][]string
+		shards    int
+		durations map[string]time.Duration
+		expected  [][][]string
+	}{
+		{
+			schedules: [][]string{{"test1"}, {"test2"}, {"test3"}},
+			shards:    1,
+			expected:  [][][]string{{{"test1"}, {"test2"}, {"test3"}}},
+		},
+		{
+			schedules: [][]string{{"test1", "test2"}, {"test1", "test3", "test4"}, {"test5"}},
+			shards:    2,
+			expected: [][][]string{
+				{{"test1", "test3", "test4"}},
+				{{"test1", "test2"}, {"test5"}},
+			},
+		},
+		{
+			schedules: [][]string{{"test1", "test2"}, {"test3"}, {"test4"}},
+			shards:    2,
+			durations: map[string]time.Duration{
+				"test1": time.Second,
+				"test2": time.Second,
+				"test3": 3 * time.Second,
+				"test4": time.Second,
+			},
+			expected: [][][]string{
+				{{"test3"}},
+				{{"test1", "test2"}, {"test4"}},
+			},
+		},
+		{
+			schedules: [][]string{{"test1"}},
+			shards:    3,
+			expected:  [][][]string{{{"test1"}}, {}, {}},
+		},
+	}
+
+	for _, test := range tests {
+		assert.DeepEqual(t, algorithms.Shard(test.schedules, test.shards, test.durations), test.expected)
+	}
+}
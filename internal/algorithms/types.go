@@ -1,15 +1,15 @@
 package algorithms
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"sort"
 
 	"github.com/pako-23/gtdd/internal/runner"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/exp/slices"
 )
 
 var ErrDependencyDetectorNotExisting = errors.New("the dependency detection strategy does not exist")
@@ -20,12 +20,79 @@ type edge struct {
 	to   string
 }
 
+// EdgeKind represents the kind of relationship between two tests.
+type EdgeKind int
+
+const (
+	// Dependency means that a test passes only if the other test runs
+	// before it.
+	Dependency EdgeKind = iota
+	// Pollution means that a test fails if the other test runs before it,
+	// unless one of the cleaners of the edge runs in between.
+	Pollution
+)
+
+var edgeKindNames = []string{
+	Dependency: "dependency",
+	Pollution:  "pollution",
+}
+
+func (k EdgeKind) String() string {
+	if k < 0 || int(k) >= len(edgeKindNames) {
+		return fmt.Sprintf("EdgeKind(%d)", int(k))
+	}
+
+	return edgeKindNames[k]
+}
+
+// MarshalText encodes the edge kind as its name.
+func (k EdgeKind) MarshalText() ([]byte, error) {
+	if k < 0 || int(k) >= len(edgeKindNames) {
+		return nil, fmt.Errorf("unknown edge kind %d", int(k))
+	}
+
+	return []byte(edgeKindNames[k]), nil
+}
+
+// UnmarshalText decodes an edge kind from its name.
+func (k *EdgeKind) UnmarshalText(text []byte) error {
+	for kind, name := range edgeKindNames {
+		if name == string(text) {
+			*k = EdgeKind(kind)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown edge kind %q", text)
+}
+
+// Edge represents the relationship between a test and another test into the
+// DependencyGraph.
+type Edge struct {
+	Kind EdgeKind
+	// The tests restoring the state polluted by the other test. Only the
+	// pollution edges have cleaners.
+	Cleaners []string
+	// The run of a schedule which justified the edge, if any.
+	Evidence *Evidence
+}
+
+// Evidence represents the run of a schedule which justified an edge of the
+// DependencyGraph.
+type Evidence struct {
+	Schedule []string             `json:"schedule"`
+	Results  []runner.TestOutcome `json:"results"`
+}
+
 // DependencyGraph represents the graph encoding the dependencies between the
 // tests of a test suite. In the graph, each node is a test of the test suite
-// and each edge represents the dependency relationship between the tests.
-type DependencyGraph map[string]map[string]struct{}
+// and each edge represents either the dependency of a test on another test,
+// or the pollution of the state of a test by another test.
+type DependencyGraph map[string]map[string]Edge
 
-type DependencyDetector func([]string, *runner.RunnerSet) (DependencyGraph, error)
+// DependencyDetector finds the dependencies between the provided tests by
+// running schedules on the provided oracle.
+type DependencyDetector func(context.Context, []string, runner.ScheduleRunner) (DependencyGraph, error)
 
 // NewDependencyGraph returns a DependencyGraph without any edges from a
 // list of tests.
@@ -33,50 +100,53 @@ func NewDependencyGraph(nodes []string) DependencyGraph {
 	graph := DependencyGraph{}
 
 	for _, node := range nodes {
-		graph[node] = map[string]struct{}{}
+		graph[node] = map[string]Edge{}
 	}
 
 	return graph
 }
 
-// DependencyGraphFromJson returns a DependencyGraph form a JSON file. If
-// there is any error it is returned.
-func DependencyGraphFromJson(fileName string) (DependencyGraph, error) {
-	file, err := os.Open(fileName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open JSON file: %w", err)
-	}
-	defer file.Close()
+// AddDependency adds a dependency relationship between two tests of a
+// test suite.
+func (d DependencyGraph) AddDependency(from, to string) {
+	d[from][to] = Edge{Kind: Dependency}
+}
 
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read JSON file: %w", err)
-	}
+// AddPollution adds a pollution relationship between two tests of a test
+// suite: the victim fails if the polluter runs before it, unless one of the
+// cleaners runs in between. Any dependency between the two tests is
+// replaced.
+func (d DependencyGraph) AddPollution(victim, polluter string, cleaners ...string) {
+	cleaners = slices.Clone(cleaners)
+	sort.Strings(cleaners)
 
-	graph := map[string][]string{}
-	if err := json.Unmarshal(data, &graph); err != nil {
-		return nil, fmt.Errorf("failed to decode graph JSON data: %w", err)
-	}
+	d[victim][polluter] = Edge{Kind: Pollution, Cleaners: cleaners}
+}
 
-	tests := make([]string, 0, len(graph))
-	for test := range graph {
-		tests = append(tests, test)
+// SetEvidence records the run of a schedule which justified the edge between
+// two tests. If there is no edge between the tests, nothing is done.
+func (d DependencyGraph) SetEvidence(from, to string, schedule []string, results []runner.TestOutcome) {
+	edge, ok := d[from][to]
+	if !ok {
+		return
 	}
 
-	g := NewDependencyGraph(tests)
-	for test, dependencies := range graph {
-		for _, dependency := range dependencies {
-			g.AddDependency(test, dependency)
+	edge.Evidence = &Evidence{Schedule: slices.Clone(schedule), Results: slices.Clone(results)}
+	d[from][to] = edge
+}
+
+// GetPolluters returns the tests polluting the state of a given test,
+// together with the pollution edges.
+func (d DependencyGraph) GetPolluters(test string) map[string]Edge {
+	polluters := map[string]Edge{}
+
+	for to, edge := range d[test] {
+		if edge.Kind == Pollution {
+			polluters[to] = edge
 		}
 	}
 
-	return g, nil
-}
-
-// AddDependency adds a dependency relationship between two tests of a
-// test suite.
-func (d DependencyGraph) AddDependency(from, to string) {
-	d[from][to] = struct{}{}
+	return polluters
 }
 
 // RemoveDependency removes a dependency relationship between two tests of a
@@ -103,8 +173,9 @@ func (d DependencyGraph) Equal(other DependencyGraph) bool {
 			return false
 		}
 
-		for to := range otherEdges {
-			if _, ok := edges[to]; !ok {
+		for to, otherEdge := range otherEdges {
+			edge, ok := edges[to]
+			if !ok || edge.Kind != otherEdge.Kind || !slices.Equal(edge.Cleaners, otherEdge.Cleaners) {
 				return false
 			}
 		}
@@ -114,7 +185,8 @@ func (d DependencyGraph) Equal(other DependencyGraph) bool {
 
 }
 
-// GetDependencies returns all the dependencies of a given test.
+// GetDependencies returns all the dependencies of a given test. The pollution
+// edges are not dependencies.
 func (d DependencyGraph) GetDependencies(test string) map[string]struct{} {
 	var (
 		dependencies = map[string]struct{}{}
@@ -128,8 +200,10 @@ func (d DependencyGraph) GetDependencies(test string) map[string]struct{} {
 		v := stack[len(stack)-1]
 		stack = stack[:len(stack)-1]
 
-		for u := range d[v] {
-			if _, seen := visited[u]; !seen {
+		for u, edge := range d[v] {
+			if edge.Kind != Dependency {
+				continue
+			} else if _, seen := visited[u]; !seen {
 				dependencies[u] = struct{}{}
 				stack = append(stack, u)
 			} else if u == test {
@@ -142,23 +216,40 @@ func (d DependencyGraph) GetDependencies(test string) map[string]struct{} {
 	return dependencies
 }
 
+// HasDependencyCycle reports whether any test of the graph transitively
+// depends on itself. The pollution edges are not dependencies.
+func (d DependencyGraph) HasDependencyCycle() bool {
+	for test := range d {
+		if _, cycle := d.GetDependencies(test)[test]; cycle {
+			return true
+		}
+	}
+
+	return false
+}
+
 // TransitiveReduction computes the transitive reduction of a dependency graph.
+// The pollution edges are kept as they are.
 func (d DependencyGraph) TransitiveReduction() {
 	for node, edges := range d {
-		minEdges := make(map[string]struct{})
+		minEdges := make(map[string]Edge)
 
-		for edge := range edges {
-			minEdges[edge] = struct{}{}
+		for to, edge := range edges {
+			minEdges[to] = edge
 		}
 
-		for v := range edges {
+		for v, edge := range edges {
+			if edge.Kind != Dependency {
+				continue
+			}
+
 			dependencies := d.GetDependencies(v)
 
-			for u := range edges {
+			for u, edge := range edges {
 				_, isDependency := dependencies[u]
 				_, isMinimalEdge := minEdges[u]
 
-				if isDependency && isMinimalEdge {
+				if isDependency && isMinimalEdge && edge.Kind == Dependency {
 					delete(minEdges, u)
 				}
 			}
@@ -168,31 +259,8 @@ func (d DependencyGraph) TransitiveReduction() {
 	}
 }
 
-// ToJSON returns a JSON representation of the dependencies relationship
-// between tests of a test suite.
-func (d DependencyGraph) ToJSON(w io.Writer) {
-	d.TransitiveReduction()
-	graph := map[string][]string{}
-
-	for test, dependencies := range d {
-		tests := []string{}
-		for dependency := range dependencies {
-			tests = append(tests, dependency)
-		}
-
-		graph[test] = tests
-	}
-
-	data, err := json.MarshalIndent(graph, "", "  ")
-	if err != nil {
-		log.Errorf("failed to create json from data: %v", err)
-	}
-
-	w.Write(data)
-}
-
 // ToDOT returns a DOT representation of the dependencies relationship
-// between tests of a test suite.
+// between tests of a test suite. The pollution edges are dashed.
 func (d DependencyGraph) ToDOT(w io.Writer) {
 	w.Write([]byte("digraph {\n"))
 	w.Write([]byte("    compound = \"true\"\n"))
@@ -218,7 +286,11 @@ func (d DependencyGraph) ToDOT(w io.Writer) {
 		sort.Strings(dependencies)
 
 		for _, dependency := range dependencies {
-			fmt.Fprintf(w, "        \"%s\" -> \"%s\"\n", test, dependency)
+			if d[test][dependency].Kind == Pollution {
+				fmt.Fprintf(w, "        \"%s\" -> \"%s\" [style = \"dashed\"]\n", test, dependency)
+			} else {
+				fmt.Fprintf(w, "        \"%s\" -> \"%s\"\n", test, dependency)
+			}
 		}
 	}
 
@@ -227,11 +299,14 @@ func (d DependencyGraph) ToDOT(w io.Writer) {
 }
 
 // GetSchedules returns the schedules needed to cover all the provided tests
-// based on the dependencies into the dependency graph.
+// based on the dependencies into the dependency graph. A test polluted by
+// another test of its schedule is not considered covered by the schedule,
+// so that it is run into a schedule of its own.
 func (d DependencyGraph) GetSchedules(tests []string) [][]string {
 	var (
 		schedules = [][]string{}
 		visited   = map[string]struct{}{}
+		revIndex  = buildReverseIndex(tests)
 	)
 
 	for i := len(tests) - 1; i >= 0; i-- {
@@ -239,12 +314,18 @@ func (d DependencyGraph) GetSchedules(tests []string) [][]string {
 			continue
 		}
 
-		deps := d.GetDependencies(tests[i])
-		schedule := []string{}
+		closure := d.scheduleClosure(tests[i], revIndex)
+		polluted := d.pollutedTests(closure, revIndex)
+		if _, ok := polluted[tests[i]]; ok {
+			log.Warnf("test %s is polluted by the tests it depends on", tests[i])
+		}
 
+		schedule := []string{}
 		for _, item := range tests {
-			if _, ok := deps[item]; ok {
-				visited[item] = struct{}{}
+			if _, ok := closure[item]; ok && item != tests[i] {
+				if _, ok := polluted[item]; !ok {
+					visited[item] = struct{}{}
+				}
 				schedule = append(schedule, item)
 			}
 		}
@@ -254,3 +335,149 @@ func (d DependencyGraph) GetSchedules(tests []string) [][]string {
 
 	return schedules
 }
+
+// PollutedRuns returns, for each of the provided schedules, the tests whose
+// outcome into the schedule is not meaningful: the tests preceded by one of
+// their polluters without any of the cleaners in between, which run into
+// another schedule where they are not polluted. The tests polluted into all
+// the schedules where they run are not returned, as no other outcome is
+// available for them.
+func (d DependencyGraph) PollutedRuns(schedules [][]string) []map[string]struct{} {
+	var (
+		polluted = make([]map[string]struct{}, len(schedules))
+		clean    = map[string]struct{}{}
+	)
+
+	for i, schedule := range schedules {
+		tests := make(map[string]struct{}, len(schedule))
+		for _, test := range schedule {
+			tests[test] = struct{}{}
+		}
+
+		polluted[i] = d.pollutedTests(tests, buildReverseIndex(schedule))
+		for _, test := range schedule {
+			if _, ok := polluted[i][test]; !ok {
+				clean[test] = struct{}{}
+			}
+		}
+	}
+
+	for _, tests := range polluted {
+		for test := range tests {
+			if _, ok := clean[test]; !ok {
+				delete(tests, test)
+			}
+		}
+	}
+
+	return polluted
+}
+
+// SelectTests returns the selected tests together with the tests which have
+// to run with them: their transitive dependencies and the cleaners restoring
+// the state polluted for them. The tests are returned into their original
+// order. The selected tests which are not into the tests are ignored.
+func (d DependencyGraph) SelectTests(tests, selected []string) []string {
+	var (
+		closure  = map[string]struct{}{}
+		revIndex = buildReverseIndex(tests)
+	)
+
+	for _, test := range selected {
+		if _, ok := revIndex[test]; !ok {
+			continue
+		}
+
+		for item := range d.scheduleClosure(test, revIndex) {
+			closure[item] = struct{}{}
+		}
+	}
+
+	selection := []string{}
+	for _, test := range tests {
+		if _, ok := closure[test]; ok {
+			selection = append(selection, test)
+		}
+	}
+
+	return selection
+}
+
+// scheduleClosure returns the tests which have to run to run a given test:
+// the test itself, its dependencies and the cleaners restoring the state
+// polluted by a test of the closure for a later test of the closure. The
+// order of the tests is given by the reverse index.
+func (d DependencyGraph) scheduleClosure(test string, revIndex map[string]int) map[string]struct{} {
+	closure := d.GetDependencies(test)
+	closure[test] = struct{}{}
+
+	for {
+		added := false
+
+		for victim := range d.pollutedTests(closure, revIndex) {
+			for polluter, edge := range d.GetPolluters(victim) {
+				if _, ok := closure[polluter]; !ok || revIndex[polluter] >= revIndex[victim] {
+					continue
+				}
+
+				for _, cleaner := range edge.Cleaners {
+					index, ok := revIndex[cleaner]
+					if !ok || index <= revIndex[polluter] || index >= revIndex[victim] {
+						continue
+					}
+
+					if _, ok := closure[cleaner]; !ok {
+						closure[cleaner] = struct{}{}
+						added = true
+					}
+
+					for dependency := range d.GetDependencies(cleaner) {
+						if _, ok := closure[dependency]; !ok {
+							closure[dependency] = struct{}{}
+							added = true
+						}
+					}
+
+					break
+				}
+			}
+		}
+
+		if !added {
+			return closure
+		}
+	}
+}
+
+// pollutedTests returns the tests of a set which are preceded by one of their
+// polluters into the set without any of the cleaners of the pollution in
+// between. The order of the tests is given by the reverse index.
+func (d DependencyGraph) pollutedTests(tests map[string]struct{}, revIndex map[string]int) map[string]struct{} {
+	polluted := map[string]struct{}{}
+
+	for victim := range tests {
+		victimIndex, ok := revIndex[victim]
+		if !ok {
+			continue
+		}
+
+		for polluter, edge := range d.GetPolluters(victim) {
+			polluterIndex, ok := revIndex[polluter]
+			if _, in := tests[polluter]; !in || !ok || polluterIndex >= victimIndex {
+				continue
+			}
+
+			cleaned := slices.ContainsFunc(edge.Cleaners, func(cleaner string) bool {
+				_, in := tests[cleaner]
+				index, ok := revIndex[cleaner]
+
+				return in && ok && polluterIndex < index && index < victimIndex
+			})
+			if !cleaned {
+				polluted[victim] = struct{}{}
+			}
+		}
+	}
+
+	return polluted
+}
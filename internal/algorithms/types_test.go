@@ -43,12 +43,12 @@ func TestEqualDependencyGraph(t *testing.T) {
 		expected bool
 	}{
 		{
-			first: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			first: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {},
 			}),
-			second: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			second: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {},
@@ -56,13 +56,13 @@ func TestEqualDependencyGraph(t *testing.T) {
 			expected: true,
 		},
 		{
-			first: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			first: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {},
 				"node4": {},
 			}),
-			second: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			second: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {},
@@ -70,12 +70,12 @@ func TestEqualDependencyGraph(t *testing.T) {
 			expected: false,
 		},
 		{
-			first: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			first: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node4": {},
 			}),
-			second: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			second: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {},
@@ -83,23 +83,23 @@ func TestEqualDependencyGraph(t *testing.T) {
 			expected: false,
 		},
 		{
-			first: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			first: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 			}),
-			second: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			second: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 			}),
 			expected: false,
 		},
 		{
-			first: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			first: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {},
 			}),
-			second: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			second: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node3": {}},
 				"node3": {},
@@ -107,12 +107,12 @@ func TestEqualDependencyGraph(t *testing.T) {
 			expected: false,
 		},
 		{
-			first: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			first: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node1": {}, "node2": {}},
 			}),
-			second: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			second: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node1": {}, "node2": {}},
@@ -138,7 +138,7 @@ func TestAddDependency(t *testing.T) {
 		{
 			nodes: []string{"node1", "node2", "node3"},
 			edges: []edge{},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {},
@@ -147,7 +147,7 @@ func TestAddDependency(t *testing.T) {
 		{
 			nodes: []string{"node1", "node2", "node3"},
 			edges: []edge{{"node2", "node1"}, {"node3", "node1"}, {"node3", "node2"}},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}, "node1": {}},
@@ -175,39 +175,39 @@ func TestRemoveDependency(t *testing.T) {
 		expected algorithms.DependencyGraph
 	}{
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}, "node1": {}},
 			}),
 			edges: []edge{},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}, "node1": {}},
 			}),
 		},
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}, "node1": {}},
 			}),
 			edges: []edge{{"node2", "node1"}},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {"node2": {}, "node1": {}},
 			}),
 		},
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}, "node1": {}},
 			}),
 			edges: []edge{{"node2", "node1"}, {"node3", "node2"}, {"node3", "node1"}},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {},
@@ -233,39 +233,39 @@ func TestInvertDependency(t *testing.T) {
 		expected algorithms.DependencyGraph
 	}{
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}, "node1": {}},
 			}),
 			edges: []edge{},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}, "node1": {}},
 			}),
 		},
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}, "node1": {}},
 			}),
 			edges: []edge{{"node2", "node1"}},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {"node2": {}},
 				"node2": {},
 				"node3": {"node2": {}, "node1": {}},
 			}),
 		},
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}, "node1": {}},
 			}),
 			edges: []edge{{"node2", "node1"}, {"node3", "node2"}, {"node3", "node1"}},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {"node2": {}, "node3": {}},
 				"node2": {"node3": {}},
 				"node3": {},
@@ -290,7 +290,7 @@ func TestGetDependencies(t *testing.T) {
 		expected map[string]map[string]struct{}
 	}{
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}},
@@ -303,7 +303,7 @@ func TestGetDependencies(t *testing.T) {
 		},
 
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {"node3": {}},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}},
@@ -339,62 +339,62 @@ func TestTransitiveReduction(t *testing.T) {
 		expected algorithms.DependencyGraph
 	}{
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}, "node1": {}},
 			}),
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}},
 			}),
 		},
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {},
 			}),
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {},
 			}),
 		},
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}},
 			}),
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}},
 			}),
 		},
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {"node1": {}, "node2": {}},
 			}),
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {"node1": {}, "node2": {}},
 			}),
 		},
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node1": {}},
 				"node4": {"node1": {}, "node3": {}},
 				"node5": {"node1": {}, "node2": {}},
 			}),
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node1": {}},
@@ -420,7 +420,7 @@ func TestGetSchedules(t *testing.T) {
 		expected [][]string
 	}{
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {},
 				"node3": {},
@@ -429,7 +429,7 @@ func TestGetSchedules(t *testing.T) {
 			expected: [][]string{{"node1"}, {"node2"}, {"node3"}},
 		},
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node2": {}},
@@ -438,7 +438,7 @@ func TestGetSchedules(t *testing.T) {
 			expected: [][]string{{"node1", "node2", "node3"}},
 		},
 		{
-			graph: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			graph: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"node1": {},
 				"node2": {"node1": {}},
 				"node3": {"node1": {}},
@@ -486,3 +486,136 @@ func TestGetSchedules(t *testing.T) {
 
 	}
 }
+
+func TestGetSchedulesPollution(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		graph    func() algorithms.DependencyGraph
+		tests    []string
+		expected [][]string
+	}{
+		{
+			graph: func() algorithms.DependencyGraph {
+				g := algorithms.NewDependencyGraph([]string{"node1", "node2", "node3", "node4"})
+				g.AddDependency("node4", "node1")
+				g.AddDependency("node4", "node3")
+				g.AddPollution("node3", "node1", "node2")
+
+				return g
+			},
+			tests:    []string{"node1", "node2", "node3", "node4"},
+			expected: [][]string{{"node1", "node2", "node3", "node4"}},
+		},
+		{
+			graph: func() algorithms.DependencyGraph {
+				g := algorithms.NewDependencyGraph([]string{"node1", "node2", "node3"})
+				g.AddDependency("node3", "node1")
+				g.AddDependency("node3", "node2")
+				g.AddPollution("node2", "node1")
+
+				return g
+			},
+			tests:    []string{"node1", "node2", "node3"},
+			expected: [][]string{{"node1", "node2", "node3"}, {"node2"}},
+		},
+		{
+			graph: func() algorithms.DependencyGraph {
+				g := algorithms.NewDependencyGraph([]string{"node1", "node2"})
+				g.AddPollution("node1", "node2")
+
+				return g
+			},
+			tests:    []string{"node1", "node2"},
+			expected: [][]string{{"node2"}, {"node1"}},
+		},
+		{
+			graph: func() algorithms.DependencyGraph {
+				g := algorithms.NewDependencyGraph([]string{"node1", "node2", "node3", "node4", "node5"})
+				g.AddDependency("node4", "node1")
+				g.AddDependency("node4", "node3")
+				g.AddDependency("node5", "node4")
+				g.AddPollution("node5", "node1", "node2")
+				g.AddPollution("node5", "node3")
+
+				return g
+			},
+			tests:    []string{"node1", "node2", "node3", "node4", "node5"},
+			expected: [][]string{{"node1", "node2", "node3", "node4", "node5"}},
+		},
+	}
+
+	for _, test := range tests {
+		assert.DeepEqual(t, test.graph().GetSchedules(test.tests), test.expected)
+	}
+}
+
+func TestPollutedRuns(t *testing.T) {
+	t.Parallel()
+
+	g := algorithms.NewDependencyGraph([]string{"node1", "node2", "node3", "node4", "node5"})
+	g.AddDependency("node3", "node1")
+	g.AddDependency("node3", "node2")
+	g.AddPollution("node2", "node1")
+	g.AddDependency("node4", "node1")
+	g.AddDependency("node5", "node4")
+	g.AddPollution("node5", "node1")
+
+	tests := []string{"node1", "node2", "node3", "node4", "node5"}
+	schedules := g.GetSchedules(tests)
+	assert.DeepEqual(t, schedules, [][]string{
+		{"node1", "node4", "node5"},
+		{"node1", "node2", "node3"},
+		{"node2"},
+	})
+
+	// The test node5 is polluted by the tests it depends on, so its only
+	// outcome is kept.
+	assert.DeepEqual(t, g.PollutedRuns(schedules), []map[string]struct{}{
+		{},
+		{"node2": {}},
+		{},
+	})
+}
+
+func TestSelectTests(t *testing.T) {
+	t.Parallel()
+
+	g := algorithms.NewDependencyGraph([]string{"node1", "node2", "node3", "node4", "node5", "node6"})
+	g.AddDependency("node3", "node1")
+	g.AddDependency("node4", "node3")
+	g.AddPollution("node5", "node2", "node1")
+	g.AddDependency("node6", "node2")
+
+	var (
+		nodes = []string{"node1", "node2", "node3", "node4", "node5", "node6"}
+		tests = []struct {
+			selected []string
+			expected []string
+		}{
+			{selected: []string{}, expected: []string{}},
+			{selected: []string{"node4"}, expected: []string{"node1", "node3", "node4"}},
+			{selected: []string{"node5", "node6"}, expected: []string{"node2", "node5", "node6"}},
+			{selected: []string{"node6", "node5"}, expected: []string{"node2", "node5", "node6"}},
+			{selected: []string{"node4", "node7"}, expected: []string{"node1", "node3", "node4"}},
+		}
+	)
+
+	for _, test := range tests {
+		assert.DeepEqual(t, g.SelectTests(nodes, test.selected), test.expected)
+	}
+}
+
+func TestSelectTestsUncleanedPolluter(t *testing.T) {
+	t.Parallel()
+
+	nodes := []string{"node1", "node2", "node3", "node4", "node5"}
+	g := algorithms.NewDependencyGraph(nodes)
+	g.AddDependency("node4", "node1")
+	g.AddDependency("node4", "node3")
+	g.AddDependency("node5", "node4")
+	g.AddPollution("node5", "node1", "node2")
+	g.AddPollution("node5", "node3")
+
+	assert.DeepEqual(t, g.SelectTests(nodes, []string{"node5"}), nodes)
+}
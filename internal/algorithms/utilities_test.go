@@ -1,10 +1,13 @@
 package algorithms_test
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/rand"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/pako-23/gtdd/internal/algorithms"
@@ -17,7 +20,7 @@ type mockRunner struct {
 	id            string
 }
 
-func newMockRunnerBuilder(id string, options ...runner.RunnerOption[*mockRunner]) (*mockRunner, error) {
+func newMockRunnerBuilder(ctx context.Context, id string, options ...runner.RunnerOption[*mockRunner]) (*mockRunner, error) {
 	runner := &mockRunner{dependencyMap: map[string][][]string{}, id: id}
 
 	for _, option := range options {
@@ -36,11 +39,11 @@ func withDependencyMap(dependecyMap map[string][][]string) func(*mockRunner) err
 	}
 }
 
-func (m *mockRunner) ResetApplication() error {
+func (m *mockRunner) ResetApplication(ctx context.Context) error {
 	return nil
 }
 
-func (m *mockRunner) Delete() error {
+func (m *mockRunner) Delete(ctx context.Context) error {
 	return nil
 }
 
@@ -48,16 +51,18 @@ func (m *mockRunner) Id() string {
 	return m.id
 }
 
-func (m *mockRunner) Run(tests []string) ([]bool, error) {
-	results := make([]bool, len(tests))
+func (m *mockRunner) Run(ctx context.Context, tests []string) ([]runner.TestOutcome, error) {
+	results := make([]runner.TestOutcome, len(tests))
 
 	for i := range tests {
 		deps, ok := m.dependencyMap[tests[i]]
 		if !ok || len(deps) == 0 {
-			results[i] = true
+			results[i].Status = runner.StatusPass
 			continue
 		}
 
+		results[i].Status = runner.StatusFail
+
 		for _, dep := range deps {
 			j, k := 0, 0
 			for j < len(dep) && k < i {
@@ -68,12 +73,12 @@ func (m *mockRunner) Run(tests []string) ([]bool, error) {
 			}
 
 			if j == len(dep) {
-				results[i] = true
+				results[i].Status = runner.StatusPass
 				break
 			}
 		}
 
-		if !results[i] {
+		if !results[i].Passed() {
 			break
 		}
 	}
@@ -110,8 +115,8 @@ func testNoDependencies(t *testing.T, algo algorithms.DependencyDetector) {
 	}
 
 	for _, test := range tests {
-		runner, _ := runner.NewRunnerSet[*mockRunner](5, newMockRunnerBuilder)
-		got, err := algo(test, runner)
+		runner, _ := runner.NewRunnerSet[*mockRunner](context.Background(), 5, newMockRunnerBuilder)
+		got, err := algo(context.Background(), test, runner)
 		expected := algorithms.NewDependencyGraph(test)
 
 		assert.NilError(t, err)
@@ -134,7 +139,7 @@ func testExistingDependencies(t *testing.T, algo algorithms.DependencyDetector)
 				"test2": {{"test1"}},
 				"test3": {{"test1", "test2"}},
 			},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"test1": {},
 				"test2": {"test1": {}},
 				"test3": {"test2": {}},
@@ -146,7 +151,7 @@ func testExistingDependencies(t *testing.T, algo algorithms.DependencyDetector)
 				"test3": {{"test1", "test2"}},
 				"test5": {{"test1", "test2", "test3"}},
 			},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"test1": {},
 				"test2": {},
 				"test3": {"test1": {}, "test2": {}},
@@ -159,7 +164,7 @@ func testExistingDependencies(t *testing.T, algo algorithms.DependencyDetector)
 			dependencies: map[string][][]string{
 				"test5": {{"test1", "test2", "test3", "test4"}},
 			},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"test1": {},
 				"test2": {},
 				"test3": {},
@@ -353,7 +358,7 @@ func testExistingDependencies(t *testing.T, algo algorithms.DependencyDetector)
 					},
 				},
 			},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"tests.AddressBookAddAddressBookTest": {},
 				"tests.AddressBookAddGroupTest":       {},
 				"tests.AddressBookAddMultipleAddressBookTest": {
@@ -565,7 +570,7 @@ func testExistingDependencies(t *testing.T, algo algorithms.DependencyDetector)
 					},
 				},
 			},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"PasswordManagerAddEntryTest": {},
 				"PasswordManagerAddMultipleEntriesTest": {
 					"PasswordManagerRemoveEntryTest": {},
@@ -731,7 +736,7 @@ func testExistingDependencies(t *testing.T, algo algorithms.DependencyDetector)
 					},
 				},
 			},
-			expected: algorithms.DependencyGraph(map[string]map[string]struct{}{
+			expected: algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 				"AddDiscountCodeAmountTest":  {},
 				"AddDiscountCodePercentTest": {},
 				"AddExistingUserFailsTest": {
@@ -806,10 +811,10 @@ func testExistingDependencies(t *testing.T, algo algorithms.DependencyDetector)
 	}
 
 	for _, test := range tests {
-		runner, _ := runner.NewRunnerSet[*mockRunner](12,
+		runner, _ := runner.NewRunnerSet[*mockRunner](context.Background(), 12,
 			newMockRunnerBuilder,
 			withDependencyMap(test.dependencies))
-		got, err := algo(test.testsuite, runner)
+		got, err := algo(context.Background(), test.testsuite, runner)
 
 		assert.NilError(t, err)
 		assert.Check(t, got.Equal(test.expected),
@@ -850,11 +855,11 @@ func testErdosRenyiGenerated(t *testing.T, algo algorithms.DependencyDetector) {
 			})
 		}
 
-		runner, _ := runner.NewRunnerSet[*mockRunner](12,
+		runner, _ := runner.NewRunnerSet[*mockRunner](context.Background(), 12,
 			newMockRunnerBuilder,
 			withDependencyMap(dependencies))
 
-		got, err := algo(nodes, runner)
+		got, err := algo(context.Background(), nodes, runner)
 
 		assert.NilError(t, err)
 		assert.Check(t, got.Equal(expected),
@@ -876,12 +881,12 @@ func testOrDependencies(t *testing.T, algo algorithms.DependencyDetector) {
 				"test3": {{"test1"}, {"test2"}},
 			},
 			expected: []algorithms.DependencyGraph{
-				algorithms.DependencyGraph(map[string]map[string]struct{}{
+				algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 					"test1": {},
 					"test2": {},
 					"test3": {"test1": {}},
 				}),
-				algorithms.DependencyGraph(map[string]map[string]struct{}{
+				algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 					"test1": {},
 					"test2": {},
 					"test3": {"test2": {}},
@@ -899,7 +904,7 @@ func testOrDependencies(t *testing.T, algo algorithms.DependencyDetector) {
 				},
 			},
 			expected: []algorithms.DependencyGraph{
-				algorithms.DependencyGraph(map[string]map[string]struct{}{
+				algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 					"test1": {},
 					"test2": {},
 					"test3": {"test2": {}},
@@ -917,14 +922,14 @@ func testOrDependencies(t *testing.T, algo algorithms.DependencyDetector) {
 				},
 			},
 			expected: []algorithms.DependencyGraph{
-				algorithms.DependencyGraph(map[string]map[string]struct{}{
+				algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 					"test1": {},
 					"test2": {},
 					"test3": {},
 					"test4": {},
 					"test5": {"test1": {}, "test3": {}},
 				}),
-				algorithms.DependencyGraph(map[string]map[string]struct{}{
+				algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 					"test1": {},
 					"test2": {},
 					"test3": {},
@@ -948,7 +953,7 @@ func testOrDependencies(t *testing.T, algo algorithms.DependencyDetector) {
 				},
 			},
 			expected: []algorithms.DependencyGraph{
-				algorithms.DependencyGraph(map[string]map[string]struct{}{
+				algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 					"test1": {},
 					"test2": {},
 					"test3": {"test2": {}},
@@ -961,10 +966,10 @@ func testOrDependencies(t *testing.T, algo algorithms.DependencyDetector) {
 	}
 
 	for _, test := range tests {
-		runner, _ := runner.NewRunnerSet[*mockRunner](5,
+		runner, _ := runner.NewRunnerSet[*mockRunner](context.Background(), 5,
 			newMockRunnerBuilder,
 			withDependencyMap(test.dependencies))
-		got, err := algo(test.testsuite, runner)
+		got, err := algo(context.Background(), test.testsuite, runner)
 
 		assert.NilError(t, err)
 		found := false
@@ -1000,7 +1005,7 @@ func testMinLenOrDependencies(t *testing.T, algo algorithms.DependencyDetector)
 				},
 			},
 			expected: []algorithms.DependencyGraph{
-				algorithms.DependencyGraph(map[string]map[string]struct{}{
+				algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 					"test1": {},
 					"test2": {"test1": {}},
 					"test3": {"test2": {}},
@@ -1024,7 +1029,7 @@ func testMinLenOrDependencies(t *testing.T, algo algorithms.DependencyDetector)
 				},
 			},
 			expected: []algorithms.DependencyGraph{
-				algorithms.DependencyGraph(map[string]map[string]struct{}{
+				algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
 					"test1": {},
 					"test2": {"test1": {}},
 					"test3": {"test2": {}},
@@ -1037,10 +1042,10 @@ func testMinLenOrDependencies(t *testing.T, algo algorithms.DependencyDetector)
 	}
 
 	for _, test := range tests {
-		runner, _ := runner.NewRunnerSet[*mockRunner](5,
+		runner, _ := runner.NewRunnerSet[*mockRunner](context.Background(), 5,
 			newMockRunnerBuilder,
 			withDependencyMap(test.dependencies))
-		got, err := algo(test.testsuite, runner)
+		got, err := algo(context.Background(), test.testsuite, runner)
 
 		assert.NilError(t, err)
 		found := false
@@ -1056,3 +1061,97 @@ func testMinLenOrDependencies(t *testing.T, algo algorithms.DependencyDetector)
 			fmt.Sprintf("expected one of the following graphs %v, but got %v", test.expected, got))
 	}
 }
+
+// unreliableOracle simulates an infrastructure failure on the first run of
+// each schedule: the first test ends with the provided status and the others
+// are not run. If persistent is set, all the runs fail.
+type unreliableOracle struct {
+	oracle     runner.ScheduleRunner
+	status     runner.Status
+	persistent bool
+	seen       map[string]struct{}
+	mu         sync.Mutex
+}
+
+func (u *unreliableOracle) RunSchedule(ctx context.Context, schedule []string) (runner.RunResults, error) {
+	key := strings.Join(schedule, ",")
+
+	u.mu.Lock()
+	_, seen := u.seen[key]
+	u.seen[key] = struct{}{}
+	u.mu.Unlock()
+
+	if seen && !u.persistent {
+		return u.oracle.RunSchedule(ctx, schedule)
+	}
+
+	results := make([]runner.TestOutcome, len(schedule))
+	results[0] = runner.TestOutcome{Status: u.status, Message: "connection refused"}
+
+	return runner.RunResults{Results: results}, nil
+}
+
+func (u *unreliableOracle) Size() int {
+	return u.oracle.Size()
+}
+
+func testInfrastructureErrors(t *testing.T, algo algorithms.DependencyDetector) {
+	t.Parallel()
+
+	var (
+		testsuite    = []string{"test1", "test2", "test3", "test4", "test5"}
+		dependencies = map[string][][]string{
+			"test3": {{"test1", "test2"}},
+			"test5": {{"test1", "test2", "test3"}},
+		}
+		expected = algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
+			"test1": {},
+			"test2": {},
+			"test3": {"test1": {}, "test2": {}},
+			"test4": {},
+			"test5": {"test3": {}},
+		})
+	)
+
+	set, err := runner.NewRunnerSet[*mockRunner](context.Background(), 5,
+		newMockRunnerBuilder,
+		withDependencyMap(dependencies))
+	assert.NilError(t, err)
+
+	oracle := &unreliableOracle{oracle: set, status: runner.StatusError, seen: map[string]struct{}{}}
+	got, err := algo(context.Background(), testsuite, oracle)
+	assert.NilError(t, err)
+	assert.Check(t, got.Equal(expected),
+		fmt.Sprintf("expected graph %v, but got %v", expected, got))
+
+	oracle = &unreliableOracle{oracle: set, status: runner.StatusNotRun, persistent: true, seen: map[string]struct{}{}}
+	_, err = algo(context.Background(), testsuite, oracle)
+	assert.ErrorIs(t, err, algorithms.ErrInconclusiveSchedule)
+
+	got, err = algo(context.Background(), testsuite, erroringOracle{oracle: set})
+	assert.NilError(t, err)
+	assert.Check(t, got.Equal(expected),
+		fmt.Sprintf("expected graph %v, but got %v", expected, got))
+}
+
+// erroringOracle reports the tests failing because of a dependency as
+// errored, as the tests which raise an unexpected exception when their
+// dependencies are not run.
+type erroringOracle struct {
+	oracle runner.ScheduleRunner
+}
+
+func (e erroringOracle) RunSchedule(ctx context.Context, schedule []string) (runner.RunResults, error) {
+	results, err := e.oracle.RunSchedule(ctx, schedule)
+	for i := range results.Results {
+		if results.Results[i].Status == runner.StatusFail {
+			results.Results[i].Status = runner.StatusError
+		}
+	}
+
+	return results, err
+}
+
+func (e erroringOracle) Size() int {
+	return e.oracle.Size()
+}
@@ -1,5 +1,22 @@
 package algorithms
 
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrInconclusiveSchedule is returned when a schedule keeps ending with
+// outcomes which say nothing about the dependencies between its tests.
+var ErrInconclusiveSchedule = errors.New("the schedule did not produce conclusive outcomes")
+
+// The maximum number of times a schedule is run to obtain conclusive
+// outcomes.
+const maxInconclusiveRuns = 3
+
 // remove removes the element at a given index from a list of strings and
 // resulting list.
 func remove(s []string, index int) []string {
@@ -8,3 +25,59 @@ func remove(s []string, index int) []string {
 
 	return append(ret, s[index+1:]...)
 }
+
+// send sends a value on a channel unless the context is done first. It
+// reports whether the value was sent.
+func send[T any](ctx context.Context, ch chan<- T, value T) bool {
+	select {
+	case ch <- value:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// receive receives a value from a channel unless the context is done first.
+// If the context is done, the context error is returned.
+func receive[T any](ctx context.Context, ch <-chan T) (T, error) {
+	select {
+	case value := <-ch:
+		return value, nil
+	case <-ctx.Done():
+		var zero T
+
+		return zero, ctx.Err()
+	}
+}
+
+// runSchedule runs a schedule on the provided oracle. If a test errors, times
+// out or is not run before any test fails on an assertion, the schedule is
+// run again, as the infrastructure may be the cause. After maxInconclusiveRuns
+// runs, a test which keeps erroring is used as a failure, while an error is
+// returned if the outcomes are still inconclusive.
+func runSchedule(ctx context.Context, oracle runner.ScheduleRunner, schedule []string) (runner.RunResults, error) {
+	for run := 1; ; run++ {
+		results, err := oracle.RunSchedule(ctx, schedule)
+		if err != nil {
+			return results, err
+		}
+
+		index := runner.FirstNotPassed(results.Results)
+		if index == -1 || results.Results[index].Failed() {
+			return results, nil
+		}
+
+		if run == maxInconclusiveRuns {
+			if runner.Conclusive(results.Results) {
+				log.Warnf("test %s keeps ending with status %v in schedule %v, using it as a failure",
+					schedule[index], results.Results[index].Status, schedule)
+				return results, nil
+			}
+
+			return results, fmt.Errorf("%w: test %s ended with status %v in schedule %v",
+				ErrInconclusiveSchedule, schedule[index], results.Results[index].Status, schedule)
+		}
+		log.Warnf("test %s ended with status %v in schedule %v, running it again: %s",
+			schedule[index], results.Results[index].Status, schedule, results.Results[index].Message)
+	}
+}
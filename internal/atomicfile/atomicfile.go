@@ -0,0 +1,53 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes some data to the file at the provided path. The data is
+// first written into a temporary file in the same directory, which is then
+// renamed over the file: if the write fails or the process crashes, the
+// previous content of the file is left untouched. The temporary file and the
+// directory are synced to the disk, so that the file survives a reboot of
+// the host. The temporary file has a ".tmp" extension. If there is any
+// error, it is returned.
+func WriteFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir syncs a directory to the disk, so that a file renamed into it
+// survives a reboot of the host. If there is any error, it is returned.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
@@ -0,0 +1,38 @@
+package atomicfile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pako-23/gtdd/internal/atomicfile"
+	"gotest.tools/v3/assert"
+)
+
+func TestWriteFile(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir  = t.TempDir()
+		path = filepath.Join(dir, "file.json")
+	)
+
+	assert.NilError(t, atomicfile.WriteFile(path, []byte("first")))
+	assert.NilError(t, atomicfile.WriteFile(path, []byte("second")))
+
+	data, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), "second")
+
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 1)
+}
+
+func TestWriteFileMissingDirectory(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "missing", "file.json")
+
+	assert.ErrorIs(t, atomicfile.WriteFile(path, []byte("data")), os.ErrNotExist)
+}
@@ -0,0 +1,8 @@
+// Copyright 2023 The GTDD Authors. All rights reserved.
+// Use of this source code is governed by a GPL-style
+// license that can be found in the LICENSE file.
+
+// Write files atomically, so that their readers never see them partially
+// written.
+
+package atomicfile
@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/atomicfile"
+	"github.com/pako-23/gtdd/internal/runner"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/exp/slices"
+)
+
+// The extension of the files storing the cache entries.
+const entryExtension = ".json"
+
+// The version of the format of the cache entries. It changes every time the
+// entries written by a previous version cannot be read anymore.
+const entryVersion = 1
+
+// errUnsupportedEntry is returned when reading a cache entry written into an
+// unsupported format.
+var errUnsupportedEntry = errors.New("unsupported cache entry format")
+
+// errInvalidKey is returned when reading a cache entry whose key is not the
+// digest naming its file.
+var errInvalidKey = errors.New("invalid cache entry key")
+
+// Environment identifies the setting in which the schedules are run. The
+// results of a schedule are only reused if they were obtained into the same
+// environment.
+type Environment struct {
+	// The digest of the Docker image of the test suite.
+	TestSuite string `json:"testsuite"`
+	// The digest of the definitions of the application and the driver.
+	Definitions string `json:"definitions"`
+	// The Docker Compose profiles enabled into the definitions.
+	Profiles []string `json:"profiles,omitempty"`
+	// The environment variables passed to the test suite.
+	Env []string `json:"env"`
+	// The backend running the schedules.
+	Backend string `json:"backend,omitempty"`
+	// The shell command running the tests with the local backend.
+	RunCommand string `json:"run_command,omitempty"`
+	// The shell command resetting the application with the local backend.
+	ResetScript string `json:"reset_script,omitempty"`
+}
+
+// Entry represents the results of a schedule stored into the cache.
+type Entry struct {
+	// The version of the format of the entry.
+	Version int `json:"version"`
+	// The content-address of the entry.
+	Key string `json:"key"`
+	// The digest of the environment in which the schedule was run.
+	Environment string `json:"environment"`
+	// The schedule that was run.
+	Schedule []string `json:"schedule"`
+	// The results of the tests into the schedule.
+	Results []runner.TestOutcome `json:"results"`
+	// The time needed to run the schedule.
+	RunningTime time.Duration `json:"running_time"`
+	// The time at which the schedule was run.
+	Created time.Time `json:"created"`
+}
+
+// Cache is a content-addressed on-disk store of the results of the schedules
+// run into a given environment. A Cache wraps an oracle: if the results of a
+// schedule are already stored, they are returned without running it; otherwise
+// the schedule is run on the wrapped oracle and its results are stored.
+//
+// A stored result is reused at most once for each Cache. If the same schedule
+// is requested again, it is treated as a retry and run on the wrapped oracle,
+// so that algorithms retrying schedules to detect flakiness keep working.
+type Cache struct {
+	// The directory containing the cache entries.
+	dir string
+	// The digest of the environment in which the schedules are run.
+	environment string
+	// The oracle used to run the schedules which are not into the cache.
+	oracle runner.ScheduleRunner
+	// The keys of the entries already reused.
+	served map[string]struct{}
+	mu     sync.Mutex
+	hits   atomic.Int32
+	misses atomic.Int32
+}
+
+// New creates a cache storing its entries into the provided directory for the
+// provided environment. If there is any error, it is returned.
+func New(dir string, env Environment, oracle runner.ScheduleRunner) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cache environment: %w", err)
+	}
+	digest := sha256.Sum256(data)
+
+	return &Cache{
+		dir:         dir,
+		environment: hex.EncodeToString(digest[:]),
+		oracle:      oracle,
+		served:      map[string]struct{}{},
+	}, nil
+}
+
+// DigestFiles returns a digest of the content of the provided files. If there
+// is any error in reading the files, it is returned.
+func DigestFiles(paths ...string) (string, error) {
+	hash := sha256.New()
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file to digest: %w", err)
+		}
+
+		fmt.Fprintf(hash, "%s\x00%d\x00", filepath.Base(path), len(data))
+		hash.Write(data)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// key returns the content-address of a schedule into the cache.
+func (c *Cache) key(schedule []string) string {
+	digest := sha256.Sum256([]byte(c.environment + "\x00" + strings.Join(schedule, "\x00")))
+
+	return hex.EncodeToString(digest[:])
+}
+
+// RunSchedule returns the results of a schedule from the cache if they are
+// available; otherwise, the schedule is run on the wrapped oracle and the
+// results are stored into the cache.
+func (c *Cache) RunSchedule(ctx context.Context, schedule []string) (runner.RunResults, error) {
+	key := c.key(schedule)
+
+	c.mu.Lock()
+	_, served := c.served[key]
+	c.served[key] = struct{}{}
+	c.mu.Unlock()
+
+	if !served {
+		if entry, err := readEntry(filepath.Join(c.dir, key+entryExtension)); err == nil &&
+			slices.Equal(entry.Schedule, schedule) {
+			c.hits.Add(1)
+			log.Debugf("cache hit for schedule %v", schedule)
+
+			return runner.RunResults{
+				Results:     entry.Results,
+				RunningTime: entry.RunningTime,
+			}, nil
+		}
+	}
+
+	c.misses.Add(1)
+	results, err := c.oracle.RunSchedule(ctx, schedule)
+	if err != nil {
+		return results, err
+	} else if !runner.Conclusive(results.Results) {
+		// A schedule whose tests errored, timed out or were not run says
+		// nothing certain about its tests, so its results are not worth
+		// reusing.
+		return results, nil
+	}
+
+	entry := &Entry{
+		Version:     entryVersion,
+		Key:         key,
+		Environment: c.environment,
+		Schedule:    schedule,
+		Results:     results.Results,
+		RunningTime: results.RunningTime,
+		Created:     time.Now(),
+	}
+	if err := c.writeEntry(entry); err != nil {
+		log.Warnf("failed to store schedule results into cache: %v", err)
+	}
+
+	return results, nil
+}
+
+// Size returns the number of schedules that can be run concurrently.
+func (c *Cache) Size() int {
+	return c.oracle.Size()
+}
+
+// Stats returns the number of schedules whose results were taken from the
+// cache and the number of schedules that were run.
+func (c *Cache) Stats() (int, int) {
+	return int(c.hits.Load()), int(c.misses.Load())
+}
+
+// writeEntry stores an entry into the cache. Concurrent readers never see a
+// partially written entry.
+func (c *Cache) writeEntry(entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	if err := atomicfile.WriteFile(filepath.Join(c.dir, entry.Key+entryExtension), data); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	return nil
+}
+
+// readEntry reads a cache entry from a file. The key of the entry must be the
+// digest naming the file. If there is any error, it is returned.
+func readEntry(path string) (*Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache entry: %w", err)
+	}
+
+	var version struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return nil, fmt.Errorf("failed to decode cache entry: %w", err)
+	} else if version.Version != entryVersion {
+		return nil, fmt.Errorf("%w: version %d", errUnsupportedEntry, version.Version)
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode cache entry: %w", err)
+	} else if len(entry.Key) != hex.EncodedLen(sha256.Size) ||
+		entry.Key+entryExtension != filepath.Base(path) {
+		return nil, fmt.Errorf("%w: %q", errInvalidKey, entry.Key)
+	}
+
+	return &entry, nil
+}
+
+// List returns all the entries stored into a cache directory sorted by
+// creation time, together with the names of the files which cannot be read as
+// entries, such as the ones written into an older format. The unreadable
+// files are left into the cache until it is cleared. If there is any error,
+// it is returned.
+func List(dir string) ([]*Entry, []string, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Entry{}, []string{}, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var (
+		entries    = make([]*Entry, 0, len(files))
+		unreadable = []string{}
+	)
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != entryExtension {
+			continue
+		}
+
+		entry, err := readEntry(filepath.Join(dir, file.Name()))
+		if err != nil {
+			log.Debugf("unreadable cache entry %s: %v", file.Name(), err)
+			unreadable = append(unreadable, file.Name())
+
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Created.Before(entries[j].Created)
+	})
+
+	return entries, unreadable, nil
+}
+
+// Clear removes all the entries stored into a cache directory and returns
+// the number of removed entries. If there is any error, it is returned.
+func Clear(dir string) (int, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	removed := 0
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != entryExtension {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, file.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
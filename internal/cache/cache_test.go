@@ -0,0 +1,282 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/cache"
+	"github.com/pako-23/gtdd/internal/runner"
+	"gotest.tools/v3/assert"
+)
+
+var errInjectedFailure = errors.New("injected failure")
+
+type mockOracle struct {
+	runs    atomic.Int32
+	fail    bool
+	timeout bool
+}
+
+func (m *mockOracle) RunSchedule(ctx context.Context, schedule []string) (runner.RunResults, error) {
+	m.runs.Add(1)
+	if m.fail {
+		return runner.RunResults{}, errInjectedFailure
+	}
+
+	results := make([]runner.TestOutcome, len(schedule))
+	for i := range schedule {
+		switch {
+		case m.timeout:
+			results[i].Status = runner.StatusTimeout
+		case schedule[i] == "PASS":
+			results[i].Status = runner.StatusPass
+		default:
+			results[i].Status = runner.StatusFail
+		}
+	}
+
+	return runner.RunResults{Results: results, RunningTime: time.Second}, nil
+}
+
+func (m *mockOracle) Size() int {
+	return 1
+}
+
+func TestCacheReuse(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir      = t.TempDir()
+		env      = cache.Environment{TestSuite: "sha256:suite", Env: []string{"A=1"}}
+		schedule = []string{"PASS", "FAIL", "PASS"}
+		oracle   = &mockOracle{}
+	)
+
+	first, err := cache.New(dir, env, oracle)
+	assert.NilError(t, err)
+
+	results, err := first.RunSchedule(context.Background(), schedule)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, results.Results, []runner.TestOutcome{
+		{Status: runner.StatusPass}, {Status: runner.StatusFail}, {Status: runner.StatusPass},
+	})
+	assert.Equal(t, oracle.runs.Load(), int32(1))
+
+	second, err := cache.New(dir, env, oracle)
+	assert.NilError(t, err)
+
+	results, err = second.RunSchedule(context.Background(), schedule)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, results.Results, []runner.TestOutcome{
+		{Status: runner.StatusPass}, {Status: runner.StatusFail}, {Status: runner.StatusPass},
+	})
+	assert.Equal(t, results.RunningTime, time.Second)
+	assert.Equal(t, oracle.runs.Load(), int32(1))
+
+	hits, misses := second.Stats()
+	assert.Equal(t, hits, 1)
+	assert.Equal(t, misses, 0)
+}
+
+func TestCacheRetryRunsSchedule(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir      = t.TempDir()
+		schedule = []string{"PASS", "FAIL"}
+		oracle   = &mockOracle{}
+	)
+
+	c, err := cache.New(dir, cache.Environment{}, oracle)
+	assert.NilError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := c.RunSchedule(context.Background(), schedule)
+		assert.NilError(t, err)
+	}
+
+	assert.Equal(t, oracle.runs.Load(), int32(3))
+}
+
+func TestCacheEnvironmentIsolation(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir      = t.TempDir()
+		schedule = []string{"PASS"}
+		oracle   = &mockOracle{}
+	)
+
+	for _, env := range []cache.Environment{
+		{TestSuite: "sha256:suite1"},
+		{TestSuite: "sha256:suite2"},
+		{TestSuite: "sha256:suite1", Definitions: "app"},
+		{TestSuite: "sha256:suite1", Env: []string{"A=1"}},
+		{TestSuite: "sha256:suite1", Backend: "podman"},
+		{TestSuite: "sha256:suite1", Backend: "local", RunCommand: "pytest"},
+		{TestSuite: "sha256:suite1", Backend: "local", RunCommand: "pytest", ResetScript: "./reset.sh"},
+	} {
+		c, err := cache.New(dir, env, oracle)
+		assert.NilError(t, err)
+
+		_, err = c.RunSchedule(context.Background(), schedule)
+		assert.NilError(t, err)
+	}
+
+	assert.Equal(t, oracle.runs.Load(), int32(7))
+}
+
+func TestCacheOracleError(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	c, err := cache.New(dir, cache.Environment{}, &mockOracle{fail: true})
+	assert.NilError(t, err)
+
+	_, err = c.RunSchedule(context.Background(), []string{"PASS"})
+	assert.ErrorIs(t, err, errInjectedFailure)
+
+	entries, _, err := cache.List(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0)
+}
+
+func TestCacheTimedOutNotStored(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	c, err := cache.New(dir, cache.Environment{}, &mockOracle{timeout: true})
+	assert.NilError(t, err)
+
+	results, err := c.RunSchedule(context.Background(), []string{"PASS"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, results.Results, []runner.TestOutcome{{Status: runner.StatusTimeout}})
+
+	entries, _, err := cache.List(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0)
+}
+
+func TestCacheListAndClear(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	c, err := cache.New(dir, cache.Environment{}, &mockOracle{})
+	assert.NilError(t, err)
+
+	schedules := [][]string{{"PASS"}, {"PASS", "FAIL"}, {"FAIL"}}
+	for _, schedule := range schedules {
+		_, err := c.RunSchedule(context.Background(), schedule)
+		assert.NilError(t, err)
+	}
+
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("not an entry"), 0o644))
+
+	entries, _, err := cache.List(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), len(schedules))
+
+	removed, err := cache.Clear(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, removed, len(schedules))
+
+	entries, _, err = cache.List(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0)
+}
+
+func TestCacheOldEntries(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir    = t.TempDir()
+		oracle = &mockOracle{}
+	)
+
+	c, err := cache.New(dir, cache.Environment{}, oracle)
+	assert.NilError(t, err)
+
+	_, err = c.RunSchedule(context.Background(), []string{"PASS"})
+	assert.NilError(t, err)
+
+	entries, _, err := cache.List(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 1)
+
+	key := entries[0].Key
+	old := `{"key":"` + key + `","schedule":["PASS"],"results":[true]}`
+	path := filepath.Join(dir, key+".json")
+	assert.NilError(t, os.WriteFile(path, []byte(old), 0o644))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644))
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "short.json"),
+		[]byte(`{"version":1,"key":"short","schedule":["PASS"],"results":[]}`), 0o644))
+
+	c, err = cache.New(dir, cache.Environment{}, oracle)
+	assert.NilError(t, err)
+
+	_, err = c.RunSchedule(context.Background(), []string{"PASS"})
+	assert.NilError(t, err)
+	assert.Equal(t, oracle.runs.Load(), int32(2))
+
+	assert.NilError(t, os.WriteFile(path, []byte(old), 0o644))
+
+	entries, unreadable, err := cache.List(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0)
+	expected := []string{key + ".json", "broken.json", "short.json"}
+	sort.Strings(expected)
+	assert.DeepEqual(t, unreadable, expected)
+
+	_, err = os.Stat(path)
+	assert.NilError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "broken.json"))
+	assert.NilError(t, err)
+
+	removed, err := cache.Clear(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, removed, 3)
+}
+
+func TestCacheListNotExistingDir(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "missing")
+
+	entries, _, err := cache.List(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0)
+
+	removed, err := cache.Clear(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, removed, 0)
+}
+
+func TestDigestFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.yml")
+	second := filepath.Join(dir, "second.yml")
+
+	assert.NilError(t, os.WriteFile(first, []byte("services: {}"), 0o644))
+	assert.NilError(t, os.WriteFile(second, []byte("services: {}"), 0o644))
+
+	digest1, err := cache.DigestFiles(first)
+	assert.NilError(t, err)
+	digest2, err := cache.DigestFiles(first)
+	assert.NilError(t, err)
+	assert.Equal(t, digest1, digest2)
+
+	digest3, err := cache.DigestFiles(first, second)
+	assert.NilError(t, err)
+	assert.Check(t, digest1 != digest3)
+
+	_, err = cache.DigestFiles(filepath.Join(dir, "missing.yml"))
+	assert.ErrorContains(t, err, "failed to read file to digest")
+}
@@ -0,0 +1,8 @@
+// Copyright 2023 The GTDD Authors. All rights reserved.
+// Use of this source code is governed by a GPL-style
+// license that can be found in the LICENSE file.
+
+// Store the results of the schedules run on a test suite, so that they can
+// be reused across different invocations.
+
+package cache
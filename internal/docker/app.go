@@ -2,9 +2,15 @@ package docker
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,7 +18,10 @@ import (
 	cgotypes "github.com/compose-spec/compose-go/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/go-connections/nat"
+	units "github.com/docker/go-units"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -23,10 +32,67 @@ type service struct {
 	Image       string
 	Healthcheck *container.HealthConfig
 	ShmSize     int64
+	// The command run inside the container to reset its state without
+	// recreating it.
+	ResetCommand strslice.StrSlice
+	// The services that should reach a condition before the container is
+	// started, along with the condition.
+	DependsOn map[string]string
+	// The volumes and the bind mounts of the container.
+	Volumes []volume
+	// The tmpfs mounts of the container along with their options.
+	Tmpfs      map[string]string
+	WorkingDir string
+	User       string
+	Labels     map[string]string
+	ExtraHosts []string
+	// The ports exposed by the container along with their bindings on the
+	// host.
+	Ports     nat.PortMap
+	Resources container.Resources
 }
 
+// volume represents a volume mounted into the container of a service.
+type volume struct {
+	mount.Mount
+	// Whether the volume is managed outside of the App. External volumes
+	// are shared by all the instances of the App.
+	External bool
+}
+
+// ResetExecLabel is the label of a service defining the shell command that
+// resets the state of its container, such as truncating the tables of a
+// database.
+const ResetExecLabel = "gtdd.reset.exec"
+
 type App map[string]*service
 
+// HasResetHooks returns whether any service of the App defines a command
+// resetting the state of its container.
+func (a App) HasResetHooks() bool {
+	for _, srv := range a {
+		if len(srv.ResetCommand) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The strategies to reset an App between the runs of the test schedules.
+const (
+	// ResetRecreate resets an App by recreating its containers from their
+	// images.
+	ResetRecreate = "recreate"
+	// ResetSnapshot resets an App by recreating its containers from a
+	// snapshot taken once the App was first started.
+	ResetSnapshot = "snapshot"
+)
+
+// The extension of a Docker Compose file selecting the reset strategy of the
+// App it defines.
+const resetStrategyExtension = "x-gtdd-reset"
+
 func newService(name string, config *cgotypes.ServiceConfig) *service {
 	result := service{
 		Command:     strslice.StrSlice(config.Command),
@@ -41,6 +107,29 @@ func newService(name string, config *cgotypes.ServiceConfig) *service {
 		result.Image = strings.Join([]string{filepath.Base(config.Build.Context), name}, "-")
 	}
 
+	if command, ok := config.Labels[ResetExecLabel]; ok && command != "" {
+		result.ResetCommand = strslice.StrSlice{"/bin/sh", "-c", command}
+	}
+
+	if len(config.DependsOn) != 0 {
+		result.DependsOn = make(map[string]string, len(config.DependsOn))
+		for name, dependency := range config.DependsOn {
+			result.DependsOn[name] = dependency.Condition
+			if dependency.Condition == "" {
+				result.DependsOn[name] = cgotypes.ServiceConditionStarted
+			}
+		}
+	}
+
+	result.Volumes = newVolumes(config.Volumes)
+	result.Tmpfs = newTmpfs(config.Tmpfs)
+	result.WorkingDir = config.WorkingDir
+	result.User = config.User
+	result.Labels = config.Labels
+	result.ExtraHosts = config.ExtraHosts.AsList()
+	result.Ports = newPorts(config.Ports, config.Expose)
+	result.Resources = newResources(config)
+
 	for k, v := range config.Environment {
 		if v == nil {
 			result.Environment = append(result.Environment, k)
@@ -80,6 +169,149 @@ func newService(name string, config *cgotypes.ServiceConfig) *service {
 	return &result
 }
 
+// newVolumes translates the volumes of a service into mounts. The volumes
+// without a source are anonymous volumes.
+func newVolumes(configs []cgotypes.ServiceVolumeConfig) []volume {
+	volumes := make([]volume, 0, len(configs))
+
+	for _, config := range configs {
+		result := volume{Mount: mount.Mount{
+			Type:     mount.Type(config.Type),
+			Source:   config.Source,
+			Target:   config.Target,
+			ReadOnly: config.ReadOnly,
+		}}
+
+		switch {
+		case config.Type == cgotypes.VolumeTypeBind && config.Bind != nil:
+			result.BindOptions = &mount.BindOptions{
+				Propagation:      mount.Propagation(config.Bind.Propagation),
+				CreateMountpoint: config.Bind.CreateHostPath,
+			}
+		case config.Type == cgotypes.VolumeTypeVolume && config.Volume != nil:
+			result.VolumeOptions = &mount.VolumeOptions{NoCopy: config.Volume.NoCopy}
+		case config.Type == cgotypes.VolumeTypeTmpfs && config.Tmpfs != nil:
+			result.TmpfsOptions = &mount.TmpfsOptions{
+				SizeBytes: int64(config.Tmpfs.Size),
+				Mode:      fs.FileMode(config.Tmpfs.Mode),
+			}
+		}
+
+		volumes = append(volumes, result)
+	}
+
+	return volumes
+}
+
+// newTmpfs translates the tmpfs mounts of a service, each formatted as a path
+// optionally followed by the mount options after a colon.
+func newTmpfs(paths []string) map[string]string {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	tmpfs := make(map[string]string, len(paths))
+	for _, path := range paths {
+		target, options, _ := strings.Cut(path, ":")
+		tmpfs[target] = options
+	}
+
+	return tmpfs
+}
+
+// newPorts translates the ports published and exposed by a service. The
+// ports are published on ephemeral host ports, as the published ports would
+// clash between the instances of the App running concurrently.
+func newPorts(published []cgotypes.ServicePortConfig, exposed []string) nat.PortMap {
+	if len(published) == 0 && len(exposed) == 0 {
+		return nil
+	}
+
+	ports := nat.PortMap{}
+	for _, config := range published {
+		protocol := config.Protocol
+		if protocol == "" {
+			protocol = "tcp"
+		}
+
+		port := nat.Port(fmt.Sprintf("%d/%s", config.Target, protocol))
+		ports[port] = append(ports[port], nat.PortBinding{HostIP: config.HostIP})
+	}
+
+	for _, expose := range exposed {
+		port, err := nat.NewPort(nat.SplitProtoPort(expose))
+		if err != nil {
+			continue
+		}
+
+		if _, ok := ports[port]; !ok {
+			ports[port] = nil
+		}
+	}
+
+	return ports
+}
+
+// newResources translates the ulimits and the resource limits of a service.
+// The limits into the deploy section take precedence over the ones of the
+// service.
+func newResources(config *cgotypes.ServiceConfig) container.Resources {
+	resources := container.Resources{
+		Memory:   int64(config.MemLimit),
+		NanoCPUs: int64(config.CPUS * 1e9),
+	}
+
+	if config.PidsLimit != 0 {
+		resources.PidsLimit = &config.PidsLimit
+	}
+
+	if config.Deploy != nil && config.Deploy.Resources.Limits != nil {
+		limits := config.Deploy.Resources.Limits
+
+		if limits.MemoryBytes != 0 {
+			resources.Memory = int64(limits.MemoryBytes)
+		}
+		if cpus, err := strconv.ParseFloat(limits.NanoCPUs, 64); err == nil {
+			resources.NanoCPUs = int64(cpus * 1e9)
+		}
+		if limits.Pids != 0 {
+			resources.PidsLimit = &limits.Pids
+		}
+	}
+
+	for name, limit := range config.Ulimits {
+		ulimit := &units.Ulimit{Name: name, Soft: int64(limit.Soft), Hard: int64(limit.Hard)}
+		if limit.Single != 0 {
+			ulimit.Soft, ulimit.Hard = int64(limit.Single), int64(limit.Single)
+		}
+		resources.Ulimits = append(resources.Ulimits, ulimit)
+	}
+
+	return resources
+}
+
+// newProjectService translates a service of a Docker Compose project. The
+// volumes of the service declared as external into the project keep their
+// external name.
+func newProjectService(project *cgotypes.Project, name string) *service {
+	config, _ := project.GetService(name)
+	result := newService(name, &config)
+
+	for i := range result.Volumes {
+		volume := &result.Volumes[i]
+		if volume.Type != mount.TypeVolume {
+			continue
+		}
+
+		if projectVolume, ok := project.Volumes[volume.Source]; ok && bool(projectVolume.External.External) {
+			volume.External = true
+			volume.Source = projectVolume.Name
+		}
+	}
+
+	return result
+}
+
 func (c *Client) pull(ctx context.Context, imageName string) error {
 	reader, err := c.client.ImagePull(ctx, imageName, image.PullOptions{})
 	if err != nil {
@@ -93,16 +325,179 @@ func (c *Client) pull(ctx context.Context, imageName string) error {
 	return nil
 }
 
-func (c *Client) NewApp(definition string) (App, error) {
-	project, err := cgo.ProjectFromOptions(&cgo.ProjectOptions{
-		ConfigPaths: []string{definition},
-	})
+// Definition represents the Docker Compose files defining an App, along with
+// the options to load them as docker compose does.
+type Definition struct {
+	// The Docker Compose files, each one overriding the previous ones.
+	Files []string
+	// The profiles enabling the services which are assigned to them.
+	Profiles []string
+	// The files containing the variables interpolated into the Docker
+	// Compose files. If there is none, the .env file into the directory of
+	// the first Docker Compose file is used, if it exists.
+	EnvFiles []string
+}
+
+// Paths returns the paths of all the files read to load the definition. If
+// there is any error, it is returned.
+func (d Definition) Paths() ([]string, error) {
+	paths := append(slices.Clone(d.Files), d.EnvFiles...)
+	if len(d.EnvFiles) != 0 || len(d.Files) == 0 {
+		return paths, nil
+	}
+
+	dotEnv := filepath.Join(filepath.Dir(d.Files[0]), ".env")
+	if _, err := os.Stat(dotEnv); err == nil {
+		paths = append(paths, dotEnv)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// Digest returns a digest of the project resolved from the definition, after
+// interpolating the variables from the environment and from the env files,
+// so that it changes whenever the definition would run a different App. If
+// there is any error, it is returned.
+func (d Definition) Digest() (string, error) {
+	project, err := loadProject(d)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := project.MarshalJSON()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode app definition: %w", err)
+	}
+	digest := sha256.Sum256(data)
+
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// String returns the files of the definition.
+func (d Definition) String() string {
+	return strings.Join(d.Files, ", ")
+}
+
+// loadProject reads the Docker Compose files of a definition. If there is
+// any error, it is returned.
+func loadProject(definition Definition) (*cgotypes.Project, error) {
+	options, err := cgo.NewProjectOptions(definition.Files,
+		cgo.WithOsEnv,
+		cgo.WithEnvFiles(definition.EnvFiles...),
+		cgo.WithDotEnv,
+		cgo.WithProfiles(definition.Profiles))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load app definition file: %w", err)
 	}
 
+	project, err := cgo.ProjectFromOptions(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load app definition file: %w", err)
+	}
+
+	if err := checkDependencies(project); err != nil {
+		return nil, fmt.Errorf("failed to load app definition file: %w", err)
+	}
+
+	return project, nil
+}
+
+// checkDependencies checks that the services of a Docker Compose project do
+// not depend on each other in a cycle, as they could never be started. If
+// there is a cycle, an error is returned.
+func checkDependencies(project *cgotypes.Project) error {
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	state := make(map[string]int, len(project.Services))
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle detected at service %s", name)
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		config, err := project.GetService(name)
+		if err != nil {
+			return nil
+		}
+
+		for dependency := range config.DependsOn {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, name := range project.ServiceNames() {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadApp reads the definition of an App from a Docker Compose file without
+// pulling or building its images. It is meant for the runners which do not
+// run the App through the Docker daemon. If there is any error, it is
+// returned.
+func LoadApp(definition Definition) (App, error) {
+	project, err := loadProject(definition)
+	if err != nil {
+		return nil, err
+	}
+
+	app := make(App, len(project.ServiceNames()))
+	for _, name := range project.ServiceNames() {
+		app[name] = newProjectService(project, name)
+	}
+
+	return app, nil
+}
+
+// LoadResetStrategy returns the strategy to reset the App defined into a
+// Docker Compose file. The strategy is selected by the x-gtdd-reset
+// extension, and it is ResetRecreate if the extension is not set. If there is
+// any error, it is returned.
+func LoadResetStrategy(definition Definition) (string, error) {
+	project, err := loadProject(definition)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := project.Extensions[resetStrategyExtension]
+	if !ok {
+		return ResetRecreate, nil
+	}
+
+	switch strategy, _ := value.(string); strategy {
+	case ResetRecreate, ResetSnapshot:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown app reset strategy: %v", value)
+	}
+}
+
+func (c *Client) NewApp(ctx context.Context, definition Definition) (App, error) {
+	project, err := loadProject(definition)
+	if err != nil {
+		return nil, err
+	}
+
 	resultsCh := make(chan instance)
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	app := make(App, len(project.ServiceNames()))
@@ -139,8 +534,7 @@ func (c *Client) NewApp(definition string) (App, error) {
 			continue
 		}
 
-		config, _ := project.GetService(result.service)
-		app[result.service] = newService(result.service, &config)
+		app[result.service] = newProjectService(project, result.service)
 	}
 
 	if err != nil {
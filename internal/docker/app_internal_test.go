@@ -13,7 +13,10 @@ import (
 
 	cgotypes "github.com/compose-spec/compose-go/types"
 	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/strslice"
+	"github.com/docker/go-connections/nat"
+	units "github.com/docker/go-units"
 	"gotest.tools/v3/assert"
 )
 
@@ -46,7 +49,7 @@ func TestNotExistingFile(t *testing.T) {
 	client := newMockClient()
 	defer client.Close()
 
-	app, err := client.NewApp("not-existing-file.yaml")
+	app, err := client.NewApp(context.TODO(), Definition{Files: []string{"not-existing-file.yaml"}})
 	assert.ErrorContains(t, err, "failed to load app definition file")
 	assert.Check(t, app == nil)
 }
@@ -251,7 +254,7 @@ func TestValidAppDefinition(t *testing.T) {
 			}
 
 			file.Close()
-			app, err := client.NewApp(file.Name())
+			app, err := client.NewApp(context.TODO(), Definition{Files: []string{file.Name()}})
 
 			assert.NilError(t, err)
 			assert.Check(t, app != nil)
@@ -322,10 +325,282 @@ func TestInvalidAppDefinition(t *testing.T) {
 			}
 
 			file.Close()
-			app, err := client.NewApp(file.Name())
+			app, err := client.NewApp(context.TODO(), Definition{Files: []string{file.Name()}})
 
 			assert.Check(t, app == nil)
 			assert.Check(t, err != nil)
 		}(i)
 	}
 }
+
+func TestLoadApp(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "docker-compose.yml")
+	err := os.WriteFile(path, []byte("services:\n  app:\n    image: not-existing-image\n  db:\n    build:\n      context: .\n"), 0o644)
+	assert.NilError(t, err)
+
+	app, err := LoadApp(Definition{Files: []string{path}})
+	assert.NilError(t, err)
+	assert.Equal(t, len(app), 2)
+	assert.Equal(t, app["app"].Image, "not-existing-image")
+	assert.Equal(t, app["db"].Image, filepath.Base(filepath.Dir(path))+"-db")
+
+	_, err = LoadApp(Definition{Files: []string{"not-existing-file.yaml"}})
+	assert.ErrorContains(t, err, "failed to load app definition file")
+}
+
+func TestLoadResetStrategy(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		definition string
+		strategy   string
+		error      string
+	}{
+		{definition: "services:\n  app:\n    image: app\n", strategy: ResetRecreate},
+		{definition: "x-gtdd-reset: recreate\nservices:\n  app:\n    image: app\n", strategy: ResetRecreate},
+		{definition: "x-gtdd-reset: snapshot\nservices:\n  app:\n    image: app\n", strategy: ResetSnapshot},
+		{definition: "x-gtdd-reset: checkpoint\nservices:\n  app:\n    image: app\n", error: "unknown app reset strategy"},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), "docker-compose.yml")
+		assert.NilError(t, os.WriteFile(path, []byte(test.definition), 0o644))
+
+		strategy, err := LoadResetStrategy(Definition{Files: []string{path}})
+		if test.error != "" {
+			assert.ErrorContains(t, err, test.error)
+			continue
+		}
+		assert.NilError(t, err)
+		assert.Equal(t, strategy, test.strategy)
+	}
+}
+
+func TestNewServiceResetCommand(t *testing.T) {
+	t.Parallel()
+
+	srv := newService("db", &cgotypes.ServiceConfig{
+		Image:  "postgres",
+		Labels: cgotypes.Labels{ResetExecLabel: "psql -c 'TRUNCATE users'"},
+	})
+	assert.DeepEqual(t, srv.ResetCommand, strslice.StrSlice{"/bin/sh", "-c", "psql -c 'TRUNCATE users'"})
+
+	srv = newService("db", &cgotypes.ServiceConfig{Image: "postgres"})
+	assert.Check(t, srv.ResetCommand == nil)
+}
+
+func TestLoadAppFields(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docker-compose.yml")
+	err := os.WriteFile(path, []byte(`services:
+  db:
+    image: postgres
+    working_dir: /srv
+    user: "1000:1000"
+    labels:
+      app.role: db
+    extra_hosts:
+      - "api.local:10.0.0.1"
+    volumes:
+      - data:/var/lib/postgresql/data
+      - shared:/shared:ro
+      - ./init:/docker-entrypoint-initdb.d
+      - /anonymous
+      - type: tmpfs
+        target: /cache
+        tmpfs:
+          size: 1024
+    tmpfs:
+      - /run:size=64k
+    ports:
+      - "5432:5432"
+      - "127.0.0.1::53/udp"
+    expose:
+      - "8080"
+    ulimits:
+      nproc: 65535
+      nofile:
+        soft: 1024
+        hard: 2048
+    mem_limit: 512m
+    pids_limit: 100
+    deploy:
+      resources:
+        limits:
+          cpus: "0.5"
+  web:
+    image: web
+    depends_on:
+      cache:
+        condition: service_started
+      db:
+        condition: service_healthy
+      migrations:
+        condition: service_completed_successfully
+  cache:
+    image: redis
+  migrations:
+    image: migrations
+    depends_on:
+      - db
+volumes:
+  data:
+  shared:
+    external: true
+    name: gtdd-shared
+`), 0o644)
+	assert.NilError(t, err)
+
+	app, err := LoadApp(Definition{Files: []string{path}})
+	assert.NilError(t, err)
+
+	db := app["db"]
+	assert.Equal(t, db.WorkingDir, "/srv")
+	assert.Equal(t, db.User, "1000:1000")
+	assert.DeepEqual(t, db.Labels, map[string]string{"app.role": "db"})
+	assert.DeepEqual(t, db.ExtraHosts, []string{"api.local:10.0.0.1"})
+	assert.DeepEqual(t, db.Volumes, []volume{
+		{Mount: mount.Mount{Type: mount.TypeVolume, Source: "data", Target: "/var/lib/postgresql/data", VolumeOptions: &mount.VolumeOptions{}}},
+		{Mount: mount.Mount{Type: mount.TypeVolume, Source: "gtdd-shared", Target: "/shared", ReadOnly: true, VolumeOptions: &mount.VolumeOptions{}}, External: true},
+		{Mount: mount.Mount{Type: mount.TypeBind, Source: filepath.Join(dir, "init"), Target: "/docker-entrypoint-initdb.d", BindOptions: &mount.BindOptions{CreateMountpoint: true}}},
+		{Mount: mount.Mount{Type: mount.TypeVolume, Target: "/anonymous", VolumeOptions: &mount.VolumeOptions{}}},
+		{Mount: mount.Mount{Type: mount.TypeTmpfs, Target: "/cache", TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 1024}}},
+	})
+	assert.DeepEqual(t, db.Tmpfs, map[string]string{"/run": "size=64k"})
+	assert.DeepEqual(t, db.Ports, nat.PortMap{
+		"5432/tcp": {{HostIP: ""}},
+		"53/udp":   {{HostIP: "127.0.0.1"}},
+		"8080/tcp": nil,
+	})
+	assert.Equal(t, db.Resources.Memory, int64(512*1024*1024))
+	assert.Equal(t, db.Resources.NanoCPUs, int64(5e8))
+	assert.Equal(t, *db.Resources.PidsLimit, int64(100))
+	assert.Equal(t, len(db.Resources.Ulimits), 2)
+	for _, ulimit := range db.Resources.Ulimits {
+		switch ulimit.Name {
+		case "nproc":
+			assert.DeepEqual(t, ulimit, &units.Ulimit{Name: "nproc", Soft: 65535, Hard: 65535})
+		case "nofile":
+			assert.DeepEqual(t, ulimit, &units.Ulimit{Name: "nofile", Soft: 1024, Hard: 2048})
+		default:
+			t.Errorf("unexpected ulimit %s", ulimit.Name)
+		}
+	}
+
+	assert.DeepEqual(t, app["web"].DependsOn, map[string]string{
+		"cache":      cgotypes.ServiceConditionStarted,
+		"db":         cgotypes.ServiceConditionHealthy,
+		"migrations": cgotypes.ServiceConditionCompletedSuccessfully,
+	})
+	assert.DeepEqual(t, app["migrations"].DependsOn, map[string]string{"db": cgotypes.ServiceConditionStarted})
+	assert.Check(t, app["cache"].DependsOn == nil)
+}
+
+func TestLoadAppDependencyCycle(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "docker-compose.yml")
+	err := os.WriteFile(path, []byte(`services:
+  a:
+    image: a
+    depends_on: [b]
+  b:
+    image: b
+    depends_on: [c]
+  c:
+    image: c
+    depends_on: [a]
+`), 0o644)
+	assert.NilError(t, err)
+
+	_, err = LoadApp(Definition{Files: []string{path}})
+	assert.ErrorContains(t, err, "dependency cycle detected")
+}
+
+func TestLoadAppDefinitionOptions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"docker-compose.yml": `services:
+  app:
+    image: app:${APP_TAG}
+    environment:
+      DB_HOST: ${DB_HOST:-db}
+  db:
+    image: postgres
+  debug:
+    image: debug
+    profiles: [debug]
+`,
+		"docker-compose.override.yml": `services:
+  app:
+    image: app:override-${APP_TAG}
+`,
+		".env":      "APP_TAG=dotenv\n",
+		"ci.env":    "APP_TAG=ci\nDB_HOST=ci-db\n",
+		"other.env": "DB_HOST=other-db\n",
+	}
+	for name, content := range files {
+		assert.NilError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	base := filepath.Join(dir, "docker-compose.yml")
+	override := filepath.Join(dir, "docker-compose.override.yml")
+
+	app, err := LoadApp(Definition{Files: []string{base}})
+	assert.NilError(t, err)
+	assert.Equal(t, len(app), 2)
+	assert.Equal(t, app["app"].Image, "app:dotenv")
+	assert.Check(t, slices.Contains(app["app"].Environment, "DB_HOST=db"))
+
+	app, err = LoadApp(Definition{
+		Files:    []string{base, override},
+		Profiles: []string{"debug"},
+		EnvFiles: []string{filepath.Join(dir, "ci.env"), filepath.Join(dir, "other.env")},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(app), 3)
+	assert.Equal(t, app["app"].Image, "app:override-ci")
+	assert.Check(t, slices.Contains(app["app"].Environment, "DB_HOST=other-db"))
+
+	paths, err := Definition{Files: []string{base, override}}.Paths()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, paths, []string{base, override, filepath.Join(dir, ".env")})
+
+	paths, err = Definition{Files: []string{base}, EnvFiles: []string{filepath.Join(dir, "ci.env")}}.Paths()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, paths, []string{base, filepath.Join(dir, "ci.env")})
+
+	paths, err = Definition{}.Paths()
+	assert.NilError(t, err)
+	assert.Equal(t, len(paths), 0)
+}
+
+func TestDefinitionDigest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docker-compose.yml")
+	err := os.WriteFile(path, []byte(`services:
+  app:
+    image: app:${TAG}
+`), 0o644)
+	assert.NilError(t, err)
+	definition := Definition{Files: []string{path}}
+
+	t.Setenv("TAG", "v1")
+	first, err := definition.Digest()
+	assert.NilError(t, err)
+	again, err := definition.Digest()
+	assert.NilError(t, err)
+	assert.Equal(t, first, again)
+
+	t.Setenv("TAG", "v2")
+	second, err := definition.Digest()
+	assert.NilError(t, err)
+	assert.Assert(t, first != second)
+
+	_, err = Definition{Files: []string{filepath.Join(dir, "missing.yml")}}.Digest()
+	assert.ErrorContains(t, err, "failed to load app definition file")
+}
@@ -50,6 +50,6 @@ func (c *Client) buildImage(ctx context.Context, imageName, srcPath, dockerfile
 	return nil
 }
 
-func (c *Client) BuildImage(imageName, srcPath, dockerfile string) error {
-	return c.buildImage(context.Background(), imageName, srcPath, dockerfile)
+func (c *Client) BuildImage(ctx context.Context, imageName, srcPath, dockerfile string) error {
+	return c.buildImage(ctx, imageName, srcPath, dockerfile)
 }
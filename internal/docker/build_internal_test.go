@@ -63,7 +63,7 @@ func TestSuccessfulBuild(t *testing.T) {
 	t.Parallel()
 	client := newMockClient()
 	defer client.Close()
-	assert.NilError(t, client.BuildImage("test", "/tmp", "correct"))
+	assert.NilError(t, client.BuildImage(context.TODO(), "test", "/tmp", "correct"))
 }
 
 func TestBuildFailures(t *testing.T) {
@@ -83,6 +83,6 @@ func TestBuildFailures(t *testing.T) {
 	defer client.Close()
 
 	for _, test := range tests {
-		assert.ErrorContains(t, client.BuildImage("test", "/tmp", test.file), test.err)
+		assert.ErrorContains(t, client.BuildImage(context.TODO(), "test", "/tmp", test.file), test.err)
 	}
 }
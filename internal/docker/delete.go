@@ -3,18 +3,43 @@ package docker
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
 )
 
-func (c *Client) Delete(instance AppInstance) error {
-	options := container.RemoveOptions{Force: true}
+// Delete removes the containers of an application instance along with the
+// volumes created for them. If there is an error, it is returned.
+func (c *Client) Delete(ctx context.Context, instance AppInstance) error {
+	options := container.RemoveOptions{Force: true, RemoveVolumes: true}
+	volumes := map[string]struct{}{}
 
 	for name, containerID := range instance {
-		if err := c.client.ContainerRemove(context.Background(), containerID, options); err != nil {
+		stats, err := c.client.ContainerInspect(ctx, containerID)
+		if err != nil {
+			return fmt.Errorf("failed in deleting application instance: %w", err)
+		}
+
+		if label := stats.Config.Labels[volumesLabel]; label != "" {
+			for _, volume := range strings.Split(label, ",") {
+				volumes[volume] = struct{}{}
+			}
+		}
+
+		if err := c.client.ContainerRemove(ctx, containerID, options); err != nil {
 			return fmt.Errorf("failed in deleting application instance: %w", err)
 		}
 		delete(instance, name)
 	}
+
+	// The volumes are removed once all the containers sharing them are
+	// removed.
+	for volume := range volumes {
+		if err := c.client.VolumeRemove(ctx, volume, true); err != nil && !errdefs.IsNotFound(err) {
+			return fmt.Errorf("failed in deleting application instance volume: %w", err)
+		}
+	}
+
 	return nil
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 
+	"sync"
 	"testing"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"gotest.tools/v3/assert"
 )
 
@@ -24,6 +26,23 @@ func (m *mockClient) ContainerRemove(ctx context.Context, containerID string, op
 	return nil
 }
 
+var (
+	removedVolumes   = map[string]struct{}{}
+	removedVolumesMu = sync.Mutex{}
+)
+
+func (m *mockClient) VolumeRemove(ctx context.Context, volumeID string, force bool) error {
+	if _, ok := m.failures["VolumeRemove"]; ok {
+		return errInjectedFailure
+	}
+
+	removedVolumesMu.Lock()
+	defer removedVolumesMu.Unlock()
+	removedVolumes[volumeID] = struct{}{}
+
+	return nil
+}
+
 func TestValidDelete(t *testing.T) {
 	t.Parallel()
 
@@ -45,7 +64,7 @@ func TestValidDelete(t *testing.T) {
 
 	}
 
-	err := client.Delete(instance)
+	err := client.Delete(context.TODO(), instance)
 	assert.NilError(t, err)
 	assert.Check(t, len(instance) == 0)
 }
@@ -72,7 +91,7 @@ func TestInvalidDelete(t *testing.T) {
 
 	instance["app4"] = "invaliddelete5"
 
-	err := client.Delete(instance)
+	err := client.Delete(context.TODO(), instance)
 	assert.ErrorContains(t, err, "failed in deleting application instance")
 	assert.Check(t, len(instance) != 0)
 
@@ -83,3 +102,56 @@ func TestInvalidDelete(t *testing.T) {
 		delete(createdContainers, name)
 	}
 }
+
+func TestDeleteVolumes(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient()
+	defer client.Close()
+
+	app := App{
+		"delete-volumes-db": {
+			Image: "running",
+			Volumes: []volume{
+				{Mount: mount.Mount{Type: mount.TypeVolume, Source: "delete-volumes-data", Target: "/data"}},
+				{Mount: mount.Mount{Type: mount.TypeVolume, Source: "delete-volumes-shared", Target: "/shared"}, External: true},
+			},
+		},
+		"delete-volumes-web": {
+			Image: "running",
+			Volumes: []volume{
+				{Mount: mount.Mount{Type: mount.TypeVolume, Source: "delete-volumes-data", Target: "/data"}},
+			},
+		},
+	}
+
+	instance, err := client.Run(context.TODO(), app, RunOptions{Prefix: "prefix"})
+	assert.NilError(t, err)
+	assert.NilError(t, client.Delete(context.TODO(), instance))
+
+	removedVolumesMu.Lock()
+	defer removedVolumesMu.Unlock()
+
+	_, ok := removedVolumes["prefix-delete-volumes-data"]
+	assert.Check(t, ok)
+	_, ok = removedVolumes["delete-volumes-shared"]
+	assert.Check(t, !ok)
+}
+
+func TestDeleteVolumesErr(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient("VolumeRemove")
+	defer client.Close()
+
+	res, _ := client.client.ContainerCreate(
+		context.TODO(),
+		&container.Config{Image: "running", Labels: map[string]string{volumesLabel: "delete-volumes-err"}},
+		nil,
+		nil,
+		nil,
+		"delete-volumes-err")
+
+	err := client.Delete(context.TODO(), AppInstance{"app": res.ID})
+	assert.ErrorContains(t, err, "failed in deleting application instance volume")
+}
@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	log "github.com/sirupsen/logrus"
+)
+
+// exec runs a command inside a running container and waits for it to
+// complete. If the command exits with a non-zero status, an error containing
+// its output is returned.
+func (c *Client) exec(ctx context.Context, containerID string, cmd []string) error {
+	res, err := c.client.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          cmd,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create Docker exec: %w", err)
+	}
+
+	stream, err := c.client.ContainerExecAttach(ctx, res.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to attach to Docker exec: %w", err)
+	}
+	defer stream.Close()
+
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, stream.Reader); err != nil {
+		return fmt.Errorf("failed to read Docker exec output: %w", err)
+	}
+
+	stats, err := c.client.ContainerExecInspect(ctx, res.ID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect Docker exec: %w", err)
+	} else if stats.ExitCode != 0 {
+		return fmt.Errorf("the command exited with status code %d: %s",
+			stats.ExitCode, strings.TrimSpace(output.String()))
+	}
+
+	return nil
+}
+
+// RunResetHooks runs the reset command of each service of an App inside its
+// container of an application instance. If any command fails, the error is
+// returned.
+func (c *Client) RunResetHooks(ctx context.Context, app App, instance AppInstance) error {
+	for name, srv := range app {
+		if len(srv.ResetCommand) == 0 {
+			continue
+		}
+
+		containerID, ok := instance[name]
+		if !ok {
+			return fmt.Errorf("no running container for service %s", name)
+		}
+
+		if err := c.exec(ctx, containerID, srv.ResetCommand); err != nil {
+			return fmt.Errorf("reset hook failed for service %s: %w", name, err)
+		}
+		log.Debugf("successfully ran reset hook of service %s", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"gotest.tools/v3/assert"
+)
+
+type execMock struct {
+	containerID string
+	cmd         []string
+}
+
+var (
+	createdExecs        = map[string]*execMock{}
+	lastExecId     uint = 0
+	createdExecsMu      = sync.Mutex{}
+)
+
+// execExitCode returns the exit code of a command run into a container. The
+// commands mentioning fail exit with an error.
+func execExitCode(cmd []string) int {
+	if strings.Contains(strings.Join(cmd, " "), "fail") {
+		return 1
+	}
+
+	return 0
+}
+
+func (m *mockClient) ContainerExecCreate(ctx context.Context, containerID string, options container.ExecOptions) (types.IDResponse, error) {
+	if _, ok := m.failures["ContainerExecCreate"]; ok {
+		return types.IDResponse{}, errInjectedFailure
+	}
+
+	createdContainersMu.Lock()
+	_, ok := createdContainers[containerID]
+	createdContainersMu.Unlock()
+	if !ok {
+		return types.IDResponse{}, errContainerNotFound
+	}
+
+	createdExecsMu.Lock()
+	defer createdExecsMu.Unlock()
+
+	id := fmt.Sprintf("exec-%d", lastExecId)
+	lastExecId += 1
+	createdExecs[id] = &execMock{containerID: containerID, cmd: options.Cmd}
+
+	return types.IDResponse{ID: id}, nil
+}
+
+func (m *mockClient) ContainerExecAttach(ctx context.Context, execID string, config container.ExecAttachOptions) (types.HijackedResponse, error) {
+	if _, ok := m.failures["ContainerExecAttach"]; ok {
+		return types.HijackedResponse{}, errInjectedFailure
+	}
+
+	createdExecsMu.Lock()
+	exec, ok := createdExecs[execID]
+	createdExecsMu.Unlock()
+	if !ok {
+		return types.HijackedResponse{}, fmt.Errorf("exec not found")
+	}
+
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		if execExitCode(exec.cmd) != 0 {
+			stdcopy.NewStdWriter(server, stdcopy.Stderr).Write([]byte("reset failed\n"))
+		} else {
+			stdcopy.NewStdWriter(server, stdcopy.Stdout).Write([]byte("reset done\n"))
+		}
+	}()
+
+	return types.HijackedResponse{Conn: client, Reader: bufio.NewReader(client)}, nil
+}
+
+func (m *mockClient) ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error) {
+	if _, ok := m.failures["ContainerExecInspect"]; ok {
+		return container.ExecInspect{}, errInjectedFailure
+	}
+
+	createdExecsMu.Lock()
+	defer createdExecsMu.Unlock()
+	exec, ok := createdExecs[execID]
+	if !ok {
+		return container.ExecInspect{}, fmt.Errorf("exec not found")
+	}
+
+	return container.ExecInspect{
+		ExecID:      execID,
+		ContainerID: exec.containerID,
+		ExitCode:    execExitCode(exec.cmd),
+	}, nil
+}
+
+func TestRunResetHooks(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient()
+	defer client.Close()
+
+	app := App{
+		"db":  {Image: "running", ResetCommand: []string{"/bin/sh", "-c", "psql -c 'TRUNCATE users'"}},
+		"web": {Image: "running"},
+	}
+	assert.Check(t, app.HasResetHooks())
+	assert.Check(t, !App{"web": {Image: "running"}}.HasResetHooks())
+
+	instance, err := client.Run(context.TODO(), app, RunOptions{Prefix: "hooks"})
+	assert.NilError(t, err)
+	defer client.Delete(context.TODO(), instance)
+
+	assert.NilError(t, client.RunResetHooks(context.TODO(), app, instance))
+
+	createdExecsMu.Lock()
+	defer createdExecsMu.Unlock()
+
+	execs := 0
+	for _, exec := range createdExecs {
+		if exec.containerID == instance["db"] {
+			assert.DeepEqual(t, exec.cmd, []string{"/bin/sh", "-c", "psql -c 'TRUNCATE users'"})
+			execs++
+		}
+		assert.Check(t, exec.containerID != instance["web"])
+	}
+	assert.Equal(t, execs, 1)
+}
+
+func TestRunResetHooksErrors(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		failures []string
+		command  string
+		error    string
+	}{
+		{command: "redis-cli fail", error: "exited with status code 1: reset failed"},
+		{failures: []string{"ContainerExecCreate"}, command: "true", error: "failed to create Docker exec"},
+		{failures: []string{"ContainerExecAttach"}, command: "true", error: "failed to attach to Docker exec"},
+		{failures: []string{"ContainerExecInspect"}, command: "true", error: "failed to inspect Docker exec"},
+	}
+
+	for _, test := range tests {
+		client := newMockClient(test.failures...)
+		defer client.Close()
+
+		app := App{"cache": {Image: "running", ResetCommand: []string{"/bin/sh", "-c", test.command}}}
+
+		instance, err := client.Run(context.TODO(), app, RunOptions{})
+		assert.NilError(t, err)
+
+		err = client.RunResetHooks(context.TODO(), app, instance)
+		assert.ErrorContains(t, err, "reset hook failed for service cache")
+		assert.ErrorContains(t, err, test.error)
+		assert.NilError(t, client.Delete(context.TODO(), instance))
+	}
+
+	client := newMockClient()
+	defer client.Close()
+
+	app := App{"cache": {Image: "running", ResetCommand: []string{"/bin/sh", "-c", "true"}}}
+	err := client.RunResetHooks(context.TODO(), app, AppInstance{})
+	assert.ErrorContains(t, err, "no running container for service cache")
+}
@@ -0,0 +1,17 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+)
+
+// ImageID returns the content-addressable ID of a Docker image. If there is
+// any error in inspecting the image, it is returned.
+func (c *Client) ImageID(ctx context.Context, imageName string) (string, error) {
+	image, _, err := c.client.ImageInspectWithRaw(ctx, imageName)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect Docker image: %w", err)
+	}
+
+	return image.ID, nil
+}
@@ -0,0 +1,53 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"gotest.tools/v3/assert"
+)
+
+func (m *mockClient) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	if _, ok := m.failures["ImageInspectWithRaw"]; ok {
+		return types.ImageInspect{}, nil, errInjectedFailure
+	}
+
+	if _, ok := images[imageID]; !ok {
+		return types.ImageInspect{}, nil, errImageNotFound
+	}
+
+	return types.ImageInspect{ID: "sha256:" + imageID}, nil, nil
+}
+
+func TestImageID(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient()
+	defer client.Close()
+
+	id, err := client.ImageID(context.TODO(), "test-image")
+	assert.NilError(t, err)
+	assert.Equal(t, id, "sha256:test-image")
+}
+
+func TestImageIDErr(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		image    string
+		failures []string
+	}{
+		{"not-existing", nil},
+		{"test-image", []string{"ImageInspectWithRaw"}},
+	}
+
+	for _, test := range tests {
+		client := newMockClient(test.failures...)
+
+		id, err := client.ImageID(context.TODO(), test.image)
+		assert.ErrorContains(t, err, "failed to inspect Docker image")
+		assert.Equal(t, id, "")
+		client.Close()
+	}
+}
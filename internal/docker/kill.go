@@ -0,0 +1,19 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+)
+
+// Kill sends a SIGKILL to all the containers of an application instance
+// without removing them, so that their logs can still be retrieved. If there
+// is an error, it is returned.
+func (c *Client) Kill(ctx context.Context, instance AppInstance) error {
+	for _, containerID := range instance {
+		if err := c.client.ContainerKill(ctx, containerID, "SIGKILL"); err != nil {
+			return fmt.Errorf("failed in killing application instance: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"gotest.tools/v3/assert"
+)
+
+func (m *mockClient) ContainerKill(ctx context.Context, containerID, signal string) error {
+	if _, ok := m.failures["ContainerKill"]; ok {
+		return errInjectedFailure
+	}
+
+	createdContainersMu.Lock()
+	defer createdContainersMu.Unlock()
+	if _, ok := createdContainers[containerID]; !ok {
+		return fmt.Errorf("container not found")
+	}
+
+	return nil
+}
+
+func TestKill(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient()
+	defer client.Close()
+
+	instance := AppInstance{}
+
+	for _, name := range []string{"kill1", "kill2"} {
+		res, _ := client.client.ContainerCreate(
+			context.TODO(),
+			&container.Config{Image: "running"},
+			nil,
+			nil,
+			nil,
+			name)
+		instance[name] = res.ID
+	}
+
+	assert.NilError(t, client.Kill(context.TODO(), instance))
+	assert.Equal(t, len(instance), 2)
+	assert.NilError(t, client.Delete(context.TODO(), instance))
+}
+
+func TestKillErr(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient("ContainerKill")
+	defer client.Close()
+
+	err := client.Kill(context.TODO(), AppInstance{"app": "kill3"})
+	assert.ErrorContains(t, err, "failed in killing application instance")
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/pkg/stdcopy"
@@ -22,8 +23,7 @@ func ErrContainerLogs(msg string) error {
 
 // getContainerLogs returns the logs from a given container. If there is any
 // error in retrieving the logs, it is returned.
-func (c *Client) GetContainerLogs(containerID string) (string, error) {
-	ctx := context.Background()
+func (c *Client) GetContainerLogs(ctx context.Context, containerID string) (string, error) {
 	statusCh, errCh := c.client.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
 	select {
 	case err := <-errCh:
@@ -51,3 +51,26 @@ func (c *Client) GetContainerLogs(containerID string) (string, error) {
 
 	return stdout.String(), nil
 }
+
+// FollowContainerLogs returns the logs from a given container once it exits,
+// while writing them to the provided writer as the container prints them. If
+// there is any error in retrieving the logs, it is returned.
+func (c *Client) FollowContainerLogs(ctx context.Context, containerID string, w io.Writer) (string, error) {
+	out, err := c.client.ContainerLogs(ctx, containerID, container.LogsOptions{ShowStdout: true, Follow: true})
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve container logs: %w", err)
+	}
+	defer out.Close()
+	log.Debugf("successfully connected to container %s to follow its logs", containerID)
+
+	var stdout, stderr bytes.Buffer
+
+	if _, err := stdcopy.StdCopy(io.MultiWriter(&stdout, w), &stderr, out); err != nil {
+		return "", fmt.Errorf("failed to copy logs from container: %w", err)
+	} else if stderr.Len() > 0 {
+		return "", ErrContainerLogs(stderr.String())
+	}
+	log.Debugf("successfully followed logs from container %s", containerID)
+
+	return stdout.String(), nil
+}
@@ -100,7 +100,7 @@ func TestCorrectLogs(t *testing.T) {
 	client := newMockClient()
 	defer client.Close()
 
-	logs, err := client.GetContainerLogs("correct")
+	logs, err := client.GetContainerLogs(context.TODO(), "correct")
 	assert.NilError(t, err)
 	assert.Check(t, logs == "no error")
 }
@@ -121,7 +121,7 @@ func TestLogErr(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		logs, err := client.GetContainerLogs(test.container)
+		logs, err := client.GetContainerLogs(context.TODO(), test.container)
 		assert.Check(t, logs == "")
 		assert.ErrorContains(t, err, test.err)
 	}
@@ -138,10 +138,27 @@ func TestLogClientErr(t *testing.T) {
 
 	for _, test := range tests {
 		client := newMockClient(test)
-		logs, err := client.GetContainerLogs("container")
+		logs, err := client.GetContainerLogs(context.TODO(), "container")
 
 		assert.Check(t, logs == "")
 		assert.ErrorContains(t, err, errInjectedFailure.Error())
 		client.Close()
 	}
 }
+
+func TestFollowLogs(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient()
+	defer client.Close()
+
+	var followed bytes.Buffer
+
+	logs, err := client.FollowContainerLogs(context.TODO(), "correct", &followed)
+	assert.NilError(t, err)
+	assert.Equal(t, logs, "no error")
+	assert.Equal(t, followed.String(), "no error")
+
+	_, err = client.FollowContainerLogs(context.TODO(), "stderr", io.Discard)
+	assert.ErrorContains(t, err, errContainerLogs.Error())
+}
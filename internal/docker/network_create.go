@@ -7,8 +7,8 @@ import (
 	"github.com/docker/docker/api/types"
 )
 
-func (c *Client) NetworkCreate(name string) (string, error) {
-	res, err := c.client.NetworkCreate(context.Background(), name, types.NetworkCreate{})
+func (c *Client) NetworkCreate(ctx context.Context, name string) (string, error) {
+	res, err := c.client.NetworkCreate(ctx, name, types.NetworkCreate{})
 	if err != nil {
 		return "", fmt.Errorf("failed to create network: %w", err)
 	}
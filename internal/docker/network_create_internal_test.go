@@ -39,7 +39,7 @@ func TestNetworkCreation(t *testing.T) {
 	defer client.Close()
 	for i := 0; i < 10; i++ {
 		name := fmt.Sprintf("network-create-%d", i)
-		networkID, err := client.NetworkCreate(name)
+		networkID, err := client.NetworkCreate(context.TODO(), name)
 
 		assert.NilError(t, err)
 		assert.DeepEqual(t, name, networkID)
@@ -61,7 +61,7 @@ func TestNetworkCreationErr(t *testing.T) {
 	defer client.Close()
 	for i := 0; i < 10; i++ {
 		name := fmt.Sprintf("network-create-%d", i)
-		networkID, err := client.NetworkCreate(name)
+		networkID, err := client.NetworkCreate(context.TODO(), name)
 
 		assert.NilError(t, err)
 		assert.DeepEqual(t, name, networkID)
@@ -69,7 +69,7 @@ func TestNetworkCreationErr(t *testing.T) {
 
 	for i := 0; i < 10; i++ {
 		name := fmt.Sprintf("network-create-%d", i)
-		networkID, err := client.NetworkCreate(name)
+		networkID, err := client.NetworkCreate(context.TODO(), name)
 
 		assert.ErrorContains(t, err, errNetworkAlreadyExists.Error())
 		assert.DeepEqual(t, "", networkID)
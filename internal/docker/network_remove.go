@@ -2,6 +2,6 @@ package docker
 
 import "context"
 
-func (c *Client) NetworkRemove(networkID string) error {
-	return c.client.NetworkRemove(context.Background(), networkID)
+func (c *Client) NetworkRemove(ctx context.Context, networkID string) error {
+	return c.client.NetworkRemove(ctx, networkID)
 }
@@ -33,14 +33,14 @@ func TestNetworkRemove(t *testing.T) {
 
 	for i := 0; i < 10; i++ {
 		name := fmt.Sprintf("network-remove-%d", i)
-		_, err := client.NetworkCreate(name)
+		_, err := client.NetworkCreate(context.TODO(), name)
 
 		assert.NilError(t, err)
 	}
 
 	for i := 0; i < 10; i++ {
 		name := fmt.Sprintf("network-remove-%d", i)
-		err := client.NetworkRemove(name)
+		err := client.NetworkRemove(context.TODO(), name)
 		assert.NilError(t, err)
 	}
 
@@ -58,9 +58,9 @@ func TestNetworkRemoveErr(t *testing.T) {
 	client := newMockClient()
 	defer client.Close()
 
-	_, err := client.NetworkCreate("network-remove-err")
+	_, err := client.NetworkCreate(context.TODO(), "network-remove-err")
 	assert.NilError(t, err)
-	err = client.NetworkRemove("network-remove-not-existing")
+	err = client.NetworkRemove(context.TODO(), "network-remove-not-existing")
 	assert.ErrorContains(t, err, errNetworkNotFound.Error())
 
 	networksMu.Lock()
@@ -6,14 +6,19 @@ import (
 	"strings"
 	"time"
 
+	cgotypes "github.com/compose-spec/compose-go/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
 	log "github.com/sirupsen/logrus"
 )
 
 type RunOptions struct {
 	Prefix   string
 	Networks []string
+	// beforeStart is called on each created container before it is started.
+	beforeStart func(ctx context.Context, service, containerID string) error
 }
 
 type instance struct {
@@ -24,20 +29,69 @@ type instance struct {
 
 type AppInstance map[string]string
 
-func (c *Client) create(ctx context.Context, name string, config *service) (string, error) {
+// startPollInterval is the time between two checks of a starting container
+// whose service does not define a healthcheck.
+const startPollInterval = 100 * time.Millisecond
+
+// volumesLabel is the label of a container listing the volumes created for
+// it, which are removed along with the container.
+const volumesLabel = "gtdd.volumes"
+
+// volumeName returns the name of a named volume of an App instance. The
+// volumes are prefixed as the containers, so that the instances of the App
+// do not share their state.
+func volumeName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return strings.Join([]string{prefix, name}, "-")
+}
+
+func (c *Client) create(ctx context.Context, name string, config *service, options RunOptions) (string, error) {
 	var (
 		containerConfig = &container.Config{
-			Cmd:         config.Command,
-			Entrypoint:  config.Entrypoint,
-			Env:         config.Environment,
-			Image:       config.Image,
-			Healthcheck: config.Healthcheck,
+			Cmd:          config.Command,
+			Entrypoint:   config.Entrypoint,
+			Env:          config.Environment,
+			Image:        config.Image,
+			Healthcheck:  config.Healthcheck,
+			WorkingDir:   config.WorkingDir,
+			User:         config.User,
+			Labels:       make(map[string]string, len(config.Labels)+1),
+			ExposedPorts: nat.PortSet{},
 		}
 		hostConfig = &container.HostConfig{
-			ShmSize: config.ShmSize,
+			ShmSize:      config.ShmSize,
+			Tmpfs:        config.Tmpfs,
+			ExtraHosts:   config.ExtraHosts,
+			PortBindings: config.Ports,
+			Resources:    config.Resources,
 		}
+		volumes []string
 	)
 
+	for key, value := range config.Labels {
+		containerConfig.Labels[key] = value
+	}
+
+	for port := range config.Ports {
+		containerConfig.ExposedPorts[port] = struct{}{}
+	}
+
+	for _, volume := range config.Volumes {
+		volumeMount := volume.Mount
+		if volumeMount.Type == mount.TypeVolume && volumeMount.Source != "" && !volume.External {
+			volumeMount.Source = volumeName(options.Prefix, volumeMount.Source)
+			volumes = append(volumes, volumeMount.Source)
+		}
+		hostConfig.Mounts = append(hostConfig.Mounts, volumeMount)
+	}
+
+	if len(volumes) != 0 {
+		containerConfig.Labels[volumesLabel] = strings.Join(volumes, ",")
+	}
+
 	res, err := c.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, name)
 	if err != nil {
 		return "", fmt.Errorf("failed to create Docker container: %w", err)
@@ -86,16 +140,94 @@ func (c *Client) isRunning(ctx context.Context, containerID string) (bool, error
 	return false, nil
 }
 
-func (c *Client) Run(app App, config RunOptions) (AppInstance, error) {
+// sleep pauses the current goroutine for the given duration. If the context
+// is done before the duration elapses, the context error is returned.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// serviceState tracks the start-up of the container of a service, so that
+// the services depending on it can wait for it.
+type serviceState struct {
+	containerID string
+	// Closed once the container is started.
+	started chan struct{}
+	// Closed once the container is running and healthy, or once it exited
+	// successfully.
+	ready chan struct{}
+}
+
+// waitDependencies waits for the services on which a service depends to
+// reach the conditions required by the service. The dependencies which are
+// not part of the App are ignored. If there is any error, it is returned.
+func (c *Client) waitDependencies(ctx context.Context, srv *service, states map[string]*serviceState) error {
+	for dependency, condition := range srv.DependsOn {
+		state, ok := states[dependency]
+		if !ok {
+			continue
+		}
+
+		event := state.ready
+		if condition == cgotypes.ServiceConditionStarted {
+			event = state.started
+		}
+
+		select {
+		case <-event:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if condition != cgotypes.ServiceConditionCompletedSuccessfully {
+			continue
+		}
+
+		statusCh, errCh := c.client.ContainerWait(ctx, state.containerID, container.WaitConditionNotRunning)
+		select {
+		case err := <-errCh:
+			return fmt.Errorf("failed to wait for dependency %s: %w", dependency, err)
+		case status := <-statusCh:
+			if status.StatusCode != 0 {
+				return fmt.Errorf("dependency %s exited with status code %d", dependency, status.StatusCode)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Run starts the containers of an App. The containers are started
+// concurrently, except that each container is started only when the services
+// on which it depends reach the required condition. The containers are
+// returned once they are all running and healthy. If there is any error, the
+// created containers are removed and the error is returned.
+func (c *Client) Run(ctx context.Context, app App, config RunOptions) (AppInstance, error) {
 	result := make(AppInstance, len(app))
 	ch := make(chan instance)
 
-	ctx, cancel := context.WithCancel(context.Background())
+	states := make(map[string]*serviceState, len(app))
+	for name := range app {
+		states[name] = &serviceState{
+			started: make(chan struct{}),
+			ready:   make(chan struct{}),
+		}
+	}
+
+	// The containers must be cleaned up even if the run is cancelled.
+	cleanupCtx := context.WithoutCancel(ctx)
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	cleanup := func(containerID string) {
-		err := c.client.ContainerRemove(context.Background(), containerID, container.RemoveOptions{Force: true})
-		if err != nil {
+		if err := c.Delete(cleanupCtx, AppInstance{"": containerID}); err != nil {
 			log.Errorf("failed to delete failed Docker container: %v", err)
 		}
 	}
@@ -110,7 +242,13 @@ func (c *Client) Run(app App, config RunOptions) (AppInstance, error) {
 				containerName = strings.Join([]string{config.Prefix, name}, "-")
 			}
 
-			containerID, err := c.create(ctx, containerName, srv)
+			if err := c.waitDependencies(ctx, srv, states); err != nil {
+				ch <- instance{err: err}
+
+				return
+			}
+
+			containerID, err := c.create(ctx, containerName, srv, config)
 			if err != nil {
 				ch <- instance{err: err}
 
@@ -127,15 +265,31 @@ func (c *Client) Run(app App, config RunOptions) (AppInstance, error) {
 
 			}
 
+			if config.beforeStart != nil {
+				if err := config.beforeStart(ctx, name, containerID); err != nil {
+					cleanup(containerID)
+					ch <- instance{err: err}
+
+					return
+				}
+			}
+
 			if err := c.client.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
 				cleanup(containerID)
 				ch <- instance{err: err}
 
 				return
 			}
+			states[name].containerID = containerID
+			close(states[name].started)
 
 			if srv.Healthcheck != nil {
-				time.Sleep(time.Duration(srv.Healthcheck.StartPeriod))
+				if err := sleep(ctx, srv.Healthcheck.StartPeriod); err != nil {
+					cleanup(containerID)
+					ch <- instance{err: err}
+
+					return
+				}
 			}
 
 			for {
@@ -148,10 +302,19 @@ func (c *Client) Run(app App, config RunOptions) (AppInstance, error) {
 					break
 				}
 
+				interval := startPollInterval
 				if srv.Healthcheck != nil {
-					time.Sleep(time.Duration(srv.Healthcheck.Interval))
+					interval = srv.Healthcheck.Interval
+				}
+
+				if err := sleep(ctx, interval); err != nil {
+					cleanup(containerID)
+					ch <- instance{err: err}
+
+					return
 				}
 			}
+			close(states[name].ready)
 
 			ch <- instance{err: nil, service: name, containerID: containerID}
 		}(name, srv)
@@ -161,16 +324,20 @@ func (c *Client) Run(app App, config RunOptions) (AppInstance, error) {
 
 	for i := 0; i < len(app); i++ {
 		stat := <-ch
-		if runErr == nil && stat.err != nil {
-			cancel()
-			runErr = stat.err
+		if stat.err != nil {
+			if runErr == nil {
+				cancel()
+				runErr = stat.err
+			}
 			continue
 		}
 		result[stat.service] = stat.containerID
 	}
 
 	if runErr != nil {
-		c.Delete(result)
+		if err := c.Delete(cleanupCtx, result); err != nil {
+			log.Errorf("failed to delete containers of failed run: %v", err)
+		}
 
 		return nil, runErr
 	}
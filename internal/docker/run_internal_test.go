@@ -5,14 +5,18 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	cgotypes "github.com/compose-spec/compose-go/types"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"gotest.tools/v3/assert"
 )
@@ -23,6 +27,9 @@ type containerMock struct {
 	hostConfig *container.HostConfig
 	state      *types.ContainerState
 	networks   []string
+	stopped    bool
+	// The content copied into the container for each destination path.
+	copied map[string]string
 }
 
 var (
@@ -39,6 +46,7 @@ var (
 				FailingStreak: 0,
 			},
 		},
+		"stateful": {Status: "running"},
 		"paused":   {Status: "paused"},
 		"removing": {Status: "removing"},
 		"exited": {
@@ -102,6 +110,7 @@ func (m *mockClient) ContainerCreate(ctx context.Context, config *container.Conf
 		config:     config,
 		hostConfig: hostConfig,
 		state:      &state,
+		copied:     map[string]string{},
 	}
 
 	return container.CreateResponse{ID: id}, nil
@@ -127,9 +136,15 @@ func (m *mockClient) ContainerInspect(ctx context.Context, containerID string) (
 	data, _ = json.Marshal(existingContainer.config)
 	json.Unmarshal(data, &config)
 
+	var mounts []types.MountPoint
+	for _, volume := range imageVolumes[config.Image] {
+		mounts = append(mounts, types.MountPoint{Type: mount.TypeVolume, Destination: volume})
+	}
+
 	return types.ContainerJSON{
 		ContainerJSONBase: &types.ContainerJSONBase{State: &state},
 		Config:            &config,
+		Mounts:            mounts,
 	}, nil
 }
 
@@ -222,7 +237,7 @@ func TestSuccessfulContainerCreate(t *testing.T) {
 
 	for name := range tests {
 		func(name string) {
-			id, err := client.create(context.TODO(), name, tests[name])
+			id, err := client.create(context.TODO(), name, tests[name], RunOptions{})
 			assert.NilError(t, err)
 
 			createdContainersMu.Lock()
@@ -245,7 +260,7 @@ func TestFailedContainerCreate(t *testing.T) {
 	t.Parallel()
 	client := newMockClient()
 	defer client.Close()
-	_, err := client.create(context.TODO(), "not-existing", &service{})
+	_, err := client.create(context.TODO(), "not-existing", &service{}, RunOptions{})
 	assert.ErrorContains(t, err, errImageNotFound.Error())
 }
 
@@ -468,7 +483,7 @@ func TestRun(t *testing.T) {
 	for i := range tests {
 
 		func(test App, options RunOptions) {
-			instance, err := client.Run(test, options)
+			instance, err := client.Run(context.TODO(), test, options)
 
 			assert.NilError(t, err)
 			assert.Check(t, len(instance) == len(test))
@@ -578,7 +593,7 @@ func TestRunErrors(t *testing.T) {
 
 	for _, test := range tests {
 		func(app App, options RunOptions, errMsg string) {
-			instance, err := client.Run(app, options)
+			instance, err := client.Run(context.TODO(), app, options)
 			assert.Check(t, len(instance) == 0)
 			assert.ErrorContains(t, err, errMsg)
 
@@ -609,7 +624,7 @@ func TestFailedStart(t *testing.T) {
 
 	for _, test := range tests {
 		client := newMockClient(test...)
-		instance, err := client.Run(app, RunOptions{})
+		instance, err := client.Run(context.TODO(), app, RunOptions{})
 
 		assert.Check(t, instance == nil)
 		assert.ErrorContains(t, err, errInjectedFailure.Error())
@@ -656,7 +671,7 @@ func TestRunningAfterFailure(t *testing.T) {
 
 	}()
 
-	instance, err := client.Run(app, RunOptions{})
+	instance, err := client.Run(context.TODO(), app, RunOptions{})
 
 	assert.NilError(t, err)
 	assert.Check(t, instance != nil)
@@ -676,3 +691,156 @@ func TestRunningAfterFailure(t *testing.T) {
 	assert.Check(t, runningContainer.state.Health.Status == "healthy")
 	delete(createdContainers, id)
 }
+
+func TestRunCancelled(t *testing.T) {
+	t.Parallel()
+
+	name := "run-cancelled-test"
+	app := App{
+		name: {
+			Image: "not-passed-yet-health",
+			Healthcheck: &container.HealthConfig{
+				Retries:  5,
+				Interval: time.Hour,
+			},
+		},
+	}
+
+	client := newMockClient()
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	instance, err := client.Run(ctx, app, RunOptions{})
+	assert.Check(t, instance == nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	createdContainersMu.Lock()
+	defer createdContainersMu.Unlock()
+
+	for _, mock := range createdContainers {
+		assert.Check(t, mock.name != name)
+	}
+}
+
+func TestContainerCreateFields(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient()
+	defer client.Close()
+
+	pids := int64(100)
+	srv := &service{
+		Image:      "running",
+		WorkingDir: "/srv",
+		User:       "1000",
+		Labels:     map[string]string{"app.role": "db"},
+		ExtraHosts: []string{"api.local:10.0.0.1"},
+		Tmpfs:      map[string]string{"/run": "size=64k"},
+		Ports:      nat.PortMap{"5432/tcp": {{}}, "8080/tcp": nil},
+		Resources:  container.Resources{Memory: 1024, PidsLimit: &pids},
+		Volumes: []volume{
+			{Mount: mount.Mount{Type: mount.TypeVolume, Source: "data", Target: "/data"}},
+			{Mount: mount.Mount{Type: mount.TypeVolume, Source: "shared", Target: "/shared"}, External: true},
+			{Mount: mount.Mount{Type: mount.TypeVolume, Target: "/anonymous"}},
+			{Mount: mount.Mount{Type: mount.TypeBind, Source: "/host", Target: "/bind"}},
+		},
+	}
+
+	id, err := client.create(context.TODO(), "prefix-db", srv, RunOptions{Prefix: "prefix"})
+	assert.NilError(t, err)
+
+	createdContainersMu.Lock()
+	defer createdContainersMu.Unlock()
+
+	created := createdContainers[id]
+	assert.Equal(t, created.config.WorkingDir, "/srv")
+	assert.Equal(t, created.config.User, "1000")
+	assert.DeepEqual(t, created.config.Labels, map[string]string{
+		"app.role":   "db",
+		volumesLabel: "prefix-data",
+	})
+	assert.DeepEqual(t, created.config.ExposedPorts, nat.PortSet{"5432/tcp": {}, "8080/tcp": {}})
+	assert.DeepEqual(t, created.hostConfig.PortBindings, srv.Ports)
+	assert.DeepEqual(t, created.hostConfig.ExtraHosts, srv.ExtraHosts)
+	assert.DeepEqual(t, created.hostConfig.Tmpfs, srv.Tmpfs)
+	assert.DeepEqual(t, created.hostConfig.Resources, srv.Resources)
+	assert.DeepEqual(t, created.hostConfig.Mounts, []mount.Mount{
+		{Type: mount.TypeVolume, Source: "prefix-data", Target: "/data"},
+		{Type: mount.TypeVolume, Source: "shared", Target: "/shared"},
+		{Type: mount.TypeVolume, Target: "/anonymous"},
+		{Type: mount.TypeBind, Source: "/host", Target: "/bind"},
+	})
+	assert.Equal(t, srv.Volumes[0].Source, "data")
+	delete(createdContainers, id)
+}
+
+// containerNumber returns the sequence number of a container created by the
+// mock client, which reflects the order of creation.
+func containerNumber(id string) int {
+	number, _ := strconv.Atoi(strings.TrimPrefix(id, "container-"))
+	return number
+}
+
+func TestRunDependencies(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient()
+	defer client.Close()
+
+	app := App{
+		"db": {
+			Image:       "healthy",
+			Healthcheck: &container.HealthConfig{Test: []string{"true"}},
+		},
+		"migrations": {
+			Image:     "exited",
+			DependsOn: map[string]string{"db": cgotypes.ServiceConditionHealthy},
+		},
+		"cache": {Image: "running"},
+		"web": {
+			Image: "running",
+			DependsOn: map[string]string{
+				"cache":      cgotypes.ServiceConditionStarted,
+				"db":         cgotypes.ServiceConditionHealthy,
+				"migrations": cgotypes.ServiceConditionCompletedSuccessfully,
+				"external":   cgotypes.ServiceConditionStarted,
+			},
+		},
+	}
+
+	instance, err := client.Run(context.TODO(), app, RunOptions{Prefix: "dependencies"})
+	assert.NilError(t, err)
+	defer client.Delete(context.TODO(), instance)
+
+	assert.Check(t, containerNumber(instance["db"]) < containerNumber(instance["migrations"]))
+	assert.Check(t, containerNumber(instance["migrations"]) < containerNumber(instance["web"]))
+	assert.Check(t, containerNumber(instance["cache"]) < containerNumber(instance["web"]))
+}
+
+func TestRunDependencyFailure(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient()
+	defer client.Close()
+
+	app := App{
+		"run-dependency-failure-db": {Image: "exited-1"},
+		"run-dependency-failure-web": {
+			Image:     "running",
+			DependsOn: map[string]string{"run-dependency-failure-db": cgotypes.ServiceConditionHealthy},
+		},
+	}
+
+	instance, err := client.Run(context.TODO(), app, RunOptions{})
+	assert.Check(t, instance == nil)
+	assert.ErrorContains(t, err, "the container exited with status code 1")
+
+	createdContainersMu.Lock()
+	defer createdContainersMu.Unlock()
+
+	for _, existingContainer := range createdContainers {
+		assert.Check(t, existingContainer.name != "run-dependency-failure-web")
+	}
+}
@@ -0,0 +1,165 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+)
+
+// Snapshot represents the state of the containers of an application instance
+// captured after its start-up. The filesystem of each container is committed
+// into an image, while the content of its volumes is archived into a
+// temporary directory, as it is not part of the committed images.
+type Snapshot struct {
+	// The App running the committed images.
+	app App
+	// The directory containing the archives of the volumes.
+	dir string
+	// The paths at which the volumes of each service are mounted.
+	volumes map[string][]string
+}
+
+// archivePath returns the path of the archive of a volume of a service.
+func (s *Snapshot) archivePath(service string, index int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s-%d.tar", service, index))
+}
+
+// Snapshot stops the containers of an application instance and captures their
+// state. The containers are not removed. If there is any error, it is
+// returned.
+func (c *Client) Snapshot(ctx context.Context, app App, instance AppInstance) (*Snapshot, error) {
+	dir, err := os.MkdirTemp("", "gtdd-snapshot-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	snapshot := &Snapshot{
+		app:     make(App, len(instance)),
+		dir:     dir,
+		volumes: make(map[string][]string, len(instance)),
+	}
+
+	for name, containerID := range instance {
+		if err := c.snapshotContainer(ctx, snapshot, name, app[name], containerID); err != nil {
+			_ = c.RemoveSnapshot(context.WithoutCancel(ctx), snapshot)
+			return nil, err
+		}
+	}
+
+	return snapshot, nil
+}
+
+// snapshotContainer stops a container of an application instance, archives
+// its volumes and commits its filesystem into the snapshot. If there is any
+// error, it is returned.
+func (c *Client) snapshotContainer(ctx context.Context, snapshot *Snapshot, name string, srv *service, containerID string) error {
+	stats, err := c.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect Docker container: %w", err)
+	}
+
+	if err := c.client.ContainerStop(ctx, containerID, container.StopOptions{}); err != nil {
+		return fmt.Errorf("failed to stop Docker container: %w", err)
+	}
+
+	for _, mountPoint := range stats.Mounts {
+		if mountPoint.Type != mount.TypeVolume {
+			continue
+		}
+
+		index := len(snapshot.volumes[name])
+		if err := c.archiveVolume(ctx, containerID, mountPoint.Destination, snapshot.archivePath(name, index)); err != nil {
+			return err
+		}
+		snapshot.volumes[name] = append(snapshot.volumes[name], mountPoint.Destination)
+	}
+
+	res, err := c.client.ContainerCommit(ctx, containerID, container.CommitOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to commit Docker container: %w", err)
+	}
+
+	committed := *srv
+	committed.Image = res.ID
+	snapshot.app[name] = &committed
+
+	return nil
+}
+
+// archiveVolume copies the content of a volume mounted into a container into
+// an archive file. If there is any error, it is returned.
+func (c *Client) archiveVolume(ctx context.Context, containerID, volume, archive string) error {
+	reader, _, err := c.client.CopyFromContainer(ctx, containerID, volume)
+	if err != nil {
+		return fmt.Errorf("failed to copy volume from Docker container: %w", err)
+	}
+	defer reader.Close()
+
+	file, err := os.Create(archive)
+	if err != nil {
+		return fmt.Errorf("failed to create volume archive: %w", err)
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write volume archive: %w", err)
+	}
+
+	return file.Close()
+}
+
+// restoreVolumes copies the archived volumes of a service into a created
+// container. If there is any error, it is returned.
+func (c *Client) restoreVolumes(ctx context.Context, snapshot *Snapshot, name, containerID string) error {
+	for index, volume := range snapshot.volumes[name] {
+		file, err := os.Open(snapshot.archivePath(name, index))
+		if err != nil {
+			return fmt.Errorf("failed to open volume archive: %w", err)
+		}
+
+		// The archive contains the volume directory itself, so it is
+		// extracted into its parent.
+		err = c.client.CopyToContainer(ctx, containerID, path.Dir(volume), file, container.CopyToContainerOptions{})
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to copy volume to Docker container: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Restore runs a new application instance from a snapshot. If there is any
+// error, it is returned.
+func (c *Client) Restore(ctx context.Context, snapshot *Snapshot, config RunOptions) (AppInstance, error) {
+	config.beforeStart = func(ctx context.Context, name, containerID string) error {
+		return c.restoreVolumes(ctx, snapshot, name, containerID)
+	}
+
+	return c.Run(ctx, snapshot.app, config)
+}
+
+// RemoveSnapshot removes the images and the archives of a snapshot. If there
+// is an error, it is returned.
+func (c *Client) RemoveSnapshot(ctx context.Context, snapshot *Snapshot) error {
+	for name, srv := range snapshot.app {
+		_, err := c.client.ImageRemove(ctx, srv.Image, image.RemoveOptions{Force: true, PruneChildren: true})
+		if err != nil {
+			return fmt.Errorf("failed to remove snapshot image: %w", err)
+		}
+		delete(snapshot.app, name)
+	}
+
+	if err := os.RemoveAll(snapshot.dir); err != nil {
+		return fmt.Errorf("failed to remove snapshot directory: %w", err)
+	}
+
+	return nil
+}
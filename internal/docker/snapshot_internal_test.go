@@ -0,0 +1,201 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"gotest.tools/v3/assert"
+)
+
+// The volumes declared by each image.
+var imageVolumes = map[string][]string{
+	"stateful": {"/var/lib/data", "/etc/config"},
+}
+
+func (m *mockClient) ContainerStop(ctx context.Context, containerID string, options container.StopOptions) error {
+	if _, ok := m.failures["ContainerStop"]; ok {
+		return errInjectedFailure
+	}
+
+	createdContainersMu.Lock()
+	defer createdContainersMu.Unlock()
+	container, ok := createdContainers[containerID]
+	if !ok {
+		return errContainerNotFound
+	}
+	container.stopped = true
+
+	return nil
+}
+
+func (m *mockClient) ContainerCommit(ctx context.Context, containerID string, options container.CommitOptions) (types.IDResponse, error) {
+	if _, ok := m.failures["ContainerCommit"]; ok {
+		return types.IDResponse{}, errInjectedFailure
+	}
+
+	createdContainersMu.Lock()
+	defer createdContainersMu.Unlock()
+	container, ok := createdContainers[containerID]
+	if !ok {
+		return types.IDResponse{}, errContainerNotFound
+	}
+
+	// The committed image behaves as the image of the container.
+	return types.IDResponse{ID: container.config.Image}, nil
+}
+
+func (m *mockClient) CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error) {
+	if _, ok := m.failures["CopyFromContainer"]; ok {
+		return nil, container.PathStat{}, errInjectedFailure
+	}
+
+	return io.NopCloser(strings.NewReader("archive of " + srcPath)), container.PathStat{}, nil
+}
+
+func (m *mockClient) CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, options container.CopyToContainerOptions) error {
+	if _, ok := m.failures["CopyToContainer"]; ok {
+		return errInjectedFailure
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	createdContainersMu.Lock()
+	defer createdContainersMu.Unlock()
+	container, ok := createdContainers[containerID]
+	if !ok {
+		return errContainerNotFound
+	}
+	container.copied[dstPath] += string(data)
+
+	return nil
+}
+
+func (m *mockClient) ImageRemove(ctx context.Context, imageID string, options image.RemoveOptions) ([]image.DeleteResponse, error) {
+	if _, ok := m.failures["ImageRemove"]; ok {
+		return nil, errInjectedFailure
+	}
+
+	return []image.DeleteResponse{{Deleted: imageID}}, nil
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient()
+	defer client.Close()
+
+	app := App{
+		"db":  {Image: "stateful", Environment: []string{"VAR=1"}},
+		"web": {Image: "running"},
+	}
+
+	instance, err := client.Run(context.TODO(), app, RunOptions{Prefix: "snapshot"})
+	assert.NilError(t, err)
+
+	snapshot, err := client.Snapshot(context.TODO(), app, instance)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, snapshot.volumes, map[string][]string{"db": {"/var/lib/data", "/etc/config"}})
+	assert.DeepEqual(t, snapshot.app["db"].Environment, []string{"VAR=1"})
+
+	createdContainersMu.Lock()
+	for _, containerID := range instance {
+		assert.Check(t, createdContainers[containerID].stopped)
+	}
+	createdContainersMu.Unlock()
+
+	assert.NilError(t, client.Delete(context.TODO(), instance))
+
+	restored, err := client.Restore(context.TODO(), snapshot, RunOptions{Prefix: "snapshot"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(restored), 2)
+
+	createdContainersMu.Lock()
+	assert.DeepEqual(t, createdContainers[restored["db"]].copied, map[string]string{
+		"/var/lib": "archive of /var/lib/data",
+		"/etc":     "archive of /etc/config",
+	})
+	assert.Equal(t, len(createdContainers[restored["web"]].copied), 0)
+	createdContainersMu.Unlock()
+
+	assert.NilError(t, client.Delete(context.TODO(), restored))
+
+	assert.NilError(t, client.RemoveSnapshot(context.TODO(), snapshot))
+	assert.Equal(t, len(snapshot.app), 0)
+	_, err = os.Stat(snapshot.dir)
+	assert.Check(t, os.IsNotExist(err))
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		failure string
+		error   string
+	}{
+		{failure: "ContainerInspect", error: "failed to inspect Docker container"},
+		{failure: "ContainerStop", error: "failed to stop Docker container"},
+		{failure: "CopyFromContainer", error: "failed to copy volume from Docker container"},
+		{failure: "ContainerCommit", error: "failed to commit Docker container"},
+	}
+
+	app := App{"db": {Image: "stateful"}}
+
+	for _, test := range tests {
+		client := newMockClient(test.failure)
+		defer client.Close()
+
+		res, _ := client.client.ContainerCreate(
+			context.TODO(),
+			&container.Config{Image: "stateful"},
+			nil,
+			nil,
+			nil,
+			"db")
+		instance := AppInstance{"db": res.ID}
+
+		_, err := client.Snapshot(context.TODO(), app, instance)
+		assert.ErrorContains(t, err, test.error)
+		assert.NilError(t, newMockClient().Delete(context.TODO(), instance))
+	}
+}
+
+func TestRestoreErr(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient("CopyToContainer")
+	defer client.Close()
+
+	app := App{"db": {Image: "stateful"}}
+
+	instance, err := client.Run(context.TODO(), app, RunOptions{})
+	assert.NilError(t, err)
+
+	snapshot, err := client.Snapshot(context.TODO(), app, instance)
+	assert.NilError(t, err)
+	defer client.RemoveSnapshot(context.TODO(), snapshot)
+	assert.NilError(t, client.Delete(context.TODO(), instance))
+
+	_, err = client.Restore(context.TODO(), snapshot, RunOptions{})
+	assert.ErrorContains(t, err, "failed to copy volume to Docker container")
+}
+
+func TestRemoveSnapshotErr(t *testing.T) {
+	t.Parallel()
+
+	client := newMockClient("ImageRemove")
+	defer client.Close()
+
+	snapshot := &Snapshot{app: App{"db": {Image: "stateful"}}, dir: t.TempDir()}
+
+	err := client.RemoveSnapshot(context.TODO(), snapshot)
+	assert.ErrorContains(t, err, "failed to remove snapshot image")
+}
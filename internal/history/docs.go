@@ -0,0 +1,8 @@
+// Copyright 2023 The GTDD Authors. All rights reserved.
+// Use of this source code is governed by a GPL-style
+// license that can be found in the LICENSE file.
+
+// Record the durations of the tests and schedules run on a test suite, so
+// that they can drive later scheduling decisions.
+
+package history
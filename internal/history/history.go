@@ -0,0 +1,253 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/atomicfile"
+	"github.com/pako-23/gtdd/internal/runner"
+)
+
+// Stats summarizes the durations measured for a test or a schedule.
+type Stats struct {
+	// The number of measured runs.
+	Runs int `json:"runs"`
+	// The sum of the measured durations.
+	Total time.Duration `json:"total"`
+	// The shortest measured duration.
+	Min time.Duration `json:"min"`
+	// The longest measured duration.
+	Max time.Duration `json:"max"`
+	// The last measured duration.
+	Last time.Duration `json:"last"`
+	// The time of the last measure.
+	Updated time.Time `json:"updated"`
+}
+
+// Mean returns the average of the measured durations.
+func (s *Stats) Mean() time.Duration {
+	if s.Runs == 0 {
+		return 0
+	}
+
+	return s.Total / time.Duration(s.Runs)
+}
+
+// add records a new measured duration.
+func (s *Stats) add(duration time.Duration) {
+	if s.Runs == 0 || duration < s.Min {
+		s.Min = duration
+	}
+	if duration > s.Max {
+		s.Max = duration
+	}
+
+	s.Runs++
+	s.Total += duration
+	s.Last = duration
+	s.Updated = time.Now()
+}
+
+// historyData is the content of a history file.
+type historyData struct {
+	Tests     map[string]*Stats `json:"tests"`
+	Schedules map[string]*Stats `json:"schedules"`
+}
+
+// History stores the durations of the tests and of the schedules run on a
+// test suite into a file. The durations of the tests are only recorded when
+// the test suite reports them.
+type History struct {
+	// The path of the history file.
+	path string
+	data historyData
+	mu   sync.Mutex
+}
+
+// Open loads the history of the provided test suite from a directory. If the
+// test suite has no history yet, an empty one is returned. If there is any
+// error, it is returned.
+func Open(dir, suite string) (*History, error) {
+	h := &History{
+		path: filepath.Join(dir, suite+".json"),
+		data: historyData{
+			Tests:     map[string]*Stats{},
+			Schedules: map[string]*Stats{},
+		},
+	}
+
+	data, err := os.ReadFile(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &h.data); err != nil {
+		return nil, fmt.Errorf("failed to decode history data: %w", err)
+	}
+
+	if h.data.Tests == nil {
+		h.data.Tests = map[string]*Stats{}
+	}
+	if h.data.Schedules == nil {
+		h.data.Schedules = map[string]*Stats{}
+	}
+
+	return h, nil
+}
+
+// key returns the key identifying a schedule into the history.
+func key(schedule []string) string {
+	return strings.Join(schedule, ",")
+}
+
+// Record adds the durations measured while running a schedule to the
+// history. The durations of the tests which did not pass are ignored, as
+// they may have stopped early; for the same reason, the running time of the
+// schedule is only recorded if all its tests passed.
+func (h *History) Record(schedule []string, results runner.RunResults) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if runner.FirstNotPassed(results.Results) == -1 {
+		stats, ok := h.data.Schedules[key(schedule)]
+		if !ok {
+			stats = &Stats{}
+			h.data.Schedules[key(schedule)] = stats
+		}
+		stats.add(results.RunningTime)
+	}
+
+	for i, outcome := range results.Results {
+		if i >= len(schedule) || outcome.Duration <= 0 || !outcome.Passed() {
+			continue
+		}
+
+		stats, ok := h.data.Tests[schedule[i]]
+		if !ok {
+			stats = &Stats{}
+			h.data.Tests[schedule[i]] = stats
+		}
+		stats.add(outcome.Duration)
+	}
+}
+
+// Tests returns the statistics of the tests into the history.
+func (h *History) Tests() map[string]Stats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return copyStats(h.data.Tests)
+}
+
+// Schedules returns the statistics of the schedules into the history. The
+// schedules are identified by their tests joined by commas.
+func (h *History) Schedules() map[string]Stats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return copyStats(h.data.Schedules)
+}
+
+func copyStats(stats map[string]*Stats) map[string]Stats {
+	copied := make(map[string]Stats, len(stats))
+	for name, s := range stats {
+		copied[name] = *s
+	}
+
+	return copied
+}
+
+// Durations returns the average duration of each test into the history.
+func (h *History) Durations() map[string]time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	durations := make(map[string]time.Duration, len(h.data.Tests))
+	for test, stats := range h.data.Tests {
+		durations[test] = stats.Mean()
+	}
+
+	return durations
+}
+
+// Expected returns the expected running time of a schedule. If the schedule
+// was already run, it is its average running time; otherwise, it is the sum
+// of the average durations of its tests. The tests never measured are
+// expected to last as the average of the measured ones. If nothing is known,
+// it returns zero.
+func (h *History) Expected(schedule []string) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if stats, ok := h.data.Schedules[key(schedule)]; ok {
+		return stats.Mean()
+	}
+
+	var (
+		expected, total time.Duration
+		missing         int
+	)
+
+	for _, stats := range h.data.Tests {
+		total += stats.Mean()
+	}
+
+	for _, test := range schedule {
+		if stats, ok := h.data.Tests[test]; ok {
+			expected += stats.Mean()
+		} else {
+			missing++
+		}
+	}
+
+	if missing > 0 && len(h.data.Tests) > 0 {
+		expected += total / time.Duration(len(h.data.Tests)) * time.Duration(missing)
+	}
+
+	return expected
+}
+
+// SortLongestFirst sorts the schedules from the one expected to run for the
+// longest time, so that the longest schedules start first. The schedules
+// whose running time cannot be estimated keep their relative order.
+func (h *History) SortLongestFirst(schedules [][]string) {
+	expected := make(map[string]time.Duration, len(schedules))
+	for _, schedule := range schedules {
+		expected[key(schedule)] = h.Expected(schedule)
+	}
+
+	sort.SliceStable(schedules, func(i, j int) bool {
+		return expected[key(schedules[i])] > expected[key(schedules[j])]
+	})
+}
+
+// Save writes the history to its file, leaving the previous history intact
+// if the write fails. If there is any error, it is returned.
+func (h *History) Save() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, err := json.Marshal(&h.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode history data: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	if err := atomicfile.WriteFile(h.path, data); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package history_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/history"
+	"github.com/pako-23/gtdd/internal/runner"
+	"gotest.tools/v3/assert"
+)
+
+func results(running time.Duration, durations ...time.Duration) runner.RunResults {
+	outcomes := make([]runner.TestOutcome, len(durations))
+	for i, duration := range durations {
+		outcomes[i] = runner.TestOutcome{Status: runner.StatusPass, Duration: duration}
+	}
+
+	return runner.RunResults{Results: outcomes, RunningTime: running}
+}
+
+func TestHistoryRecord(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	h, err := history.Open(dir, "suite")
+	assert.NilError(t, err)
+
+	h.Record([]string{"test1", "test2"}, results(4*time.Second, time.Second, 2*time.Second))
+	h.Record([]string{"test1"}, results(4*time.Second, 3*time.Second))
+	h.Record([]string{"test2"}, runner.RunResults{
+		Results:     []runner.TestOutcome{{Status: runner.StatusFail, Duration: time.Millisecond}},
+		RunningTime: time.Second,
+	})
+	assert.NilError(t, h.Save())
+
+	h, err = history.Open(dir, "suite")
+	assert.NilError(t, err)
+
+	tests := h.Tests()
+	assert.Equal(t, len(tests), 2)
+	assert.Equal(t, tests["test1"].Runs, 2)
+	assert.Equal(t, tests["test1"].Min, time.Second)
+	assert.Equal(t, tests["test1"].Max, 3*time.Second)
+	assert.Equal(t, tests["test1"].Last, 3*time.Second)
+	assert.Equal(t, tests["test2"].Runs, 1)
+
+	schedules := h.Schedules()
+	assert.Equal(t, len(schedules), 2)
+	assert.Equal(t, schedules["test1,test2"].Runs, 1)
+
+	assert.DeepEqual(t, h.Durations(), map[string]time.Duration{
+		"test1": 2 * time.Second,
+		"test2": 2 * time.Second,
+	})
+}
+
+func TestHistoryExpected(t *testing.T) {
+	t.Parallel()
+
+	h, err := history.Open(t.TempDir(), "suite")
+	assert.NilError(t, err)
+	assert.Equal(t, h.Expected([]string{"test1"}), time.Duration(0))
+
+	h.Record([]string{"test1", "test2"}, results(10*time.Second, time.Second, 3*time.Second))
+
+	assert.Equal(t, h.Expected([]string{"test1", "test2"}), 10*time.Second)
+	assert.Equal(t, h.Expected([]string{"test2", "test1"}), 4*time.Second)
+	assert.Equal(t, h.Expected([]string{"test1", "test3"}), 3*time.Second)
+}
+
+func TestHistorySortLongestFirst(t *testing.T) {
+	t.Parallel()
+
+	h, err := history.Open(t.TempDir(), "suite")
+	assert.NilError(t, err)
+	h.Record([]string{"test1", "test2", "test3"}, results(6*time.Second, time.Second, 2*time.Second, 3*time.Second))
+
+	schedules := [][]string{{"test1"}, {"test1", "test3"}, {"test2"}, {"test3"}}
+	h.SortLongestFirst(schedules)
+
+	assert.DeepEqual(t, schedules, [][]string{{"test1", "test3"}, {"test3"}, {"test2"}, {"test1"}})
+}
@@ -0,0 +1,163 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The prefix of the paths of the Podman REST API.
+const apiPrefix = "/v4.0.0/libpod"
+
+// ErrNotFound is returned when the resource targeted by a request does not
+// exist.
+var ErrNotFound = errors.New("resource not found")
+
+// Client talks with the Podman REST API exposed on a socket.
+type Client struct {
+	client  *http.Client
+	baseURL string
+}
+
+// Host returns the address of the Podman service pointed by the
+// CONTAINER_HOST environment variable or, if it is not set, of the default
+// socket of the service run by the current user. The service also exposes
+// the Docker-compatible REST API on the same address.
+func Host() string {
+	if host := os.Getenv("CONTAINER_HOST"); host != "" {
+		return host
+	}
+
+	return "unix://" + defaultSocket()
+}
+
+// NewClient creates a client for the Podman service at the address returned
+// by Host. If there is any error, it is returned.
+func NewClient() (*Client, error) {
+	address, err := url.Parse(Host())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Podman client: %w", err)
+	}
+
+	switch address.Scheme {
+	case "unix":
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", address.Path)
+			},
+		}
+
+		return newClient("http://podman", &http.Client{Transport: transport}), nil
+	case "tcp":
+		return newClient("http://"+address.Host, &http.Client{}), nil
+	default:
+		return nil, fmt.Errorf("failed to create Podman client: unsupported scheme %q", address.Scheme)
+	}
+}
+
+func newClient(baseURL string, client *http.Client) *Client {
+	return &Client{client: client, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// defaultSocket returns the path of the socket of the Podman service run by
+// the current user.
+func defaultSocket() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" && os.Geteuid() != 0 {
+		return filepath.Join(runtimeDir, "podman", "podman.sock")
+	}
+
+	return "/run/podman/podman.sock"
+}
+
+func (c *Client) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// apiError represents the body of the responses of the Podman REST API
+// reporting an error.
+type apiError struct {
+	Cause    string `json:"cause"`
+	Message  string `json:"message"`
+	Response int    `json:"response"`
+}
+
+// do sends a request to the Podman REST API and returns the response if it
+// succeeded. The body, if any, is encoded as JSON. The caller must close the
+// body of the returned response. If there is any error, it is returned.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode Podman request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	address := c.baseURL + apiPrefix + path
+	if len(query) > 0 {
+		address += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, address, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Podman request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send Podman request: %w", err)
+	}
+
+	if res.StatusCode < http.StatusBadRequest {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	var apiErr apiError
+	if err := json.NewDecoder(res.Body).Decode(&apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = res.Status
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, apiErr.Message)
+	}
+
+	return nil, fmt.Errorf("podman request %s %s failed: %s", method, path, apiErr.Message)
+}
+
+// call sends a request to the Podman REST API and decodes the JSON body of
+// the response into out, unless it is nil. If there is any error, it is
+// returned.
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	res, err := c.do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if out == nil {
+		_, err := io.Copy(io.Discard, res.Body)
+		return err
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Podman response: %w", err)
+	}
+
+	return nil
+}
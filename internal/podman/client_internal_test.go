@@ -0,0 +1,59 @@
+package podman
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pako-23/gtdd/internal/podman/podmantest"
+	"gotest.tools/v3/assert"
+)
+
+func newFakeClient(t *testing.T, fake *podmantest.Server) *Client {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return newClient(server.URL, server.Client())
+}
+
+func TestNewClient(t *testing.T) {
+	var tests = []struct {
+		host    string
+		baseURL string
+	}{
+		{host: "unix:///run/user/1000/podman/podman.sock", baseURL: "http://podman"},
+		{host: "tcp://localhost:8888", baseURL: "http://localhost:8888"},
+	}
+
+	for _, test := range tests {
+		t.Setenv("CONTAINER_HOST", test.host)
+
+		client, err := NewClient()
+		assert.NilError(t, err)
+		assert.Equal(t, client.baseURL, test.baseURL)
+		assert.NilError(t, client.Close())
+	}
+}
+
+func TestNewClientUnsupportedScheme(t *testing.T) {
+	t.Setenv("CONTAINER_HOST", "ssh://user@host/run/podman/podman.sock")
+
+	client, err := NewClient()
+	assert.ErrorContains(t, err, "unsupported scheme")
+	assert.Check(t, client == nil)
+}
+
+func TestClientErrors(t *testing.T) {
+	t.Parallel()
+
+	client := newFakeClient(t, podmantest.NewServer())
+
+	_, err := client.inspectContainer(context.TODO(), "not-existing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorContains(t, err, "no such container")
+
+	err = client.createContainer(context.TODO(), &containerSpec{Name: "container", Image: "image"})
+	assert.NilError(t, err)
+	err = client.createContainer(context.TODO(), &containerSpec{Name: "container", Image: "image"})
+	assert.ErrorContains(t, err, "name already in use")
+}
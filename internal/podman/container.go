@@ -0,0 +1,261 @@
+package podman
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// containerSpec represents the body of a request to create a container. The
+// size of the shared memory, the published ports and the extra hosts are
+// options of the pod, as its containers share its namespaces.
+type containerSpec struct {
+	Name       string                  `json:"name"`
+	Image      string                  `json:"image"`
+	Command    []string                `json:"command,omitempty"`
+	Entrypoint []string                `json:"entrypoint,omitempty"`
+	Env        map[string]string       `json:"env,omitempty"`
+	Pod        string                  `json:"pod,omitempty"`
+	Health     *container.HealthConfig `json:"healthconfig,omitempty"`
+	WorkDir    string                  `json:"work_dir,omitempty"`
+	User       string                  `json:"user,omitempty"`
+	Labels     map[string]string       `json:"labels,omitempty"`
+	Mounts     []mountSpec             `json:"mounts,omitempty"`
+	Volumes    []namedVolume           `json:"volumes,omitempty"`
+	Resources  *resourceLimits         `json:"resource_limits,omitempty"`
+	Rlimits    []rlimit                `json:"r_limits,omitempty"`
+}
+
+// mountSpec represents a bind or a tmpfs mount of a container.
+type mountSpec struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type"`
+	Source      string   `json:"source,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+// namedVolume represents a volume mounted into a container. A volume without
+// a name is an anonymous volume.
+type namedVolume struct {
+	Name    string   `json:"Name"`
+	Dest    string   `json:"Dest"`
+	Options []string `json:"Options,omitempty"`
+}
+
+// resourceLimits represents the resource limits of a container.
+type resourceLimits struct {
+	Memory *memoryLimits `json:"memory,omitempty"`
+	CPU    *cpuLimits    `json:"cpu,omitempty"`
+	Pids   *pidsLimits   `json:"pids,omitempty"`
+}
+
+type memoryLimits struct {
+	Limit int64 `json:"limit"`
+}
+
+type cpuLimits struct {
+	Quota  int64  `json:"quota"`
+	Period uint64 `json:"period"`
+}
+
+type pidsLimits struct {
+	Limit int64 `json:"limit"`
+}
+
+// rlimit represents a ulimit of a container.
+type rlimit struct {
+	Type string `json:"type"`
+	Hard uint64 `json:"hard"`
+	Soft uint64 `json:"soft"`
+}
+
+// containerState represents the state of a container as reported by the
+// inspection of the container.
+type containerState struct {
+	State struct {
+		Status   string `json:"Status"`
+		ExitCode int    `json:"ExitCode"`
+	} `json:"State"`
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// healthCheck represents the result of running the health check of a
+// container.
+type healthCheck struct {
+	Status        string `json:"Status"`
+	FailingStreak int    `json:"FailingStreak"`
+	Log           []struct {
+		Output string `json:"Output"`
+	} `json:"Log"`
+}
+
+// createContainer creates a container from a specification. If there is any
+// error, it is returned.
+func (c *Client) createContainer(ctx context.Context, spec *containerSpec) error {
+	if err := c.call(ctx, http.MethodPost, "/containers/create", nil, spec, nil); err != nil {
+		return fmt.Errorf("failed to create Podman container: %w", err)
+	}
+
+	return nil
+}
+
+// startContainer starts a created container. If there is any error, it is
+// returned.
+func (c *Client) startContainer(ctx context.Context, name string) error {
+	err := c.call(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/start", nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start Podman container: %w", err)
+	}
+
+	return nil
+}
+
+// inspectContainer returns the state of a container. If there is any error,
+// it is returned.
+func (c *Client) inspectContainer(ctx context.Context, name string) (*containerState, error) {
+	var state containerState
+
+	err := c.call(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/json", nil, nil, &state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect Podman container: %w", err)
+	}
+
+	return &state, nil
+}
+
+// runHealthCheck runs the health check of a container once and returns its
+// result. Podman does not always run the health checks on its own, for
+// example when systemd is not available. If there is any error, it is
+// returned.
+func (c *Client) runHealthCheck(ctx context.Context, name string) (*healthCheck, error) {
+	var result healthCheck
+
+	err := c.call(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/healthcheck", nil, nil, &result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run Podman container health check: %w", err)
+	}
+
+	return &result, nil
+}
+
+// removeContainer forcibly removes a container along with its anonymous
+// volumes. If the container does not exist, nothing is done. If there is any
+// error, it is returned.
+func (c *Client) removeContainer(ctx context.Context, name string) error {
+	err := c.call(ctx, http.MethodDelete, "/containers/"+url.PathEscape(name),
+		url.Values{"force": {"true"}, "v": {"true"}}, nil, nil)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to remove Podman container: %w", err)
+	}
+
+	return nil
+}
+
+// removeVolume forcibly removes a volume. If the volume does not exist,
+// nothing is done. If there is any error, it is returned.
+func (c *Client) removeVolume(ctx context.Context, name string) error {
+	err := c.call(ctx, http.MethodDelete, "/volumes/"+url.PathEscape(name),
+		url.Values{"force": {"true"}}, nil, nil)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to remove Podman volume: %w", err)
+	}
+
+	return nil
+}
+
+// pullImage pulls an image unless it is already available. If there is any
+// error, it is returned.
+func (c *Client) pullImage(ctx context.Context, reference string) error {
+	res, err := c.do(ctx, http.MethodPost, "/images/pull", url.Values{
+		"reference": {reference},
+		"policy":    {"missing"},
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to pull Podman image: %w", err)
+	}
+	defer res.Body.Close()
+
+	// The progress of the pull is streamed as JSON objects, and a failure is
+	// only reported into the stream.
+	decoder := json.NewDecoder(res.Body)
+	for {
+		var report struct {
+			Error string `json:"error"`
+		}
+
+		if err := decoder.Decode(&report); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read Podman image pull progress: %w", err)
+		} else if report.Error != "" {
+			return fmt.Errorf("failed to pull Podman image: %s", report.Error)
+		}
+	}
+}
+
+// GetContainerLogs waits for a container to exit and returns its standard
+// output. If there is any error in retrieving the logs, it is returned.
+func (c *Client) GetContainerLogs(ctx context.Context, name string) (string, error) {
+	err := c.call(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/wait",
+		url.Values{"condition": {"stopped", "exited"}}, nil, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to wait for Podman container: %w", err)
+	}
+
+	return c.containerLogs(ctx, name)
+}
+
+// FollowContainerLogs returns the standard output of a container once it
+// exits, while writing it to the provided writer as the container prints it.
+// If there is any error in retrieving the logs, it is returned.
+func (c *Client) FollowContainerLogs(ctx context.Context, name string, w io.Writer) (string, error) {
+	return c.copyLogs(ctx, name, url.Values{"stdout": {"true"}, "follow": {"true"}}, w)
+}
+
+// containerLogs returns the standard output printed by a container so far.
+// If there is any error, it is returned.
+func (c *Client) containerLogs(ctx context.Context, name string) (string, error) {
+	return c.copyLogs(ctx, name, url.Values{"stdout": {"true"}}, io.Discard)
+}
+
+// copyLogs returns the standard output of a container retrieved with the
+// provided query, while writing it to the provided writer. If there is any
+// error, it is returned.
+func (c *Client) copyLogs(ctx context.Context, name string, query url.Values, w io.Writer) (string, error) {
+	res, err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/logs", query, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve container logs: %w", err)
+	}
+	defer res.Body.Close()
+
+	var stdout bytes.Buffer
+	if _, err := stdcopy.StdCopy(io.MultiWriter(&stdout, w), io.Discard, res.Body); err != nil {
+		return "", fmt.Errorf("failed to copy logs from container: %w", err)
+	}
+
+	return stdout.String(), nil
+}
+
+// ImageID returns the content-addressable ID of an image. If there is any
+// error, it is returned.
+func (c *Client) ImageID(ctx context.Context, name string) (string, error) {
+	var image struct {
+		ID string `json:"Id"`
+	}
+
+	if err := c.call(ctx, http.MethodGet, "/images/"+url.PathEscape(name)+"/json", nil, nil, &image); err != nil {
+		return "", fmt.Errorf("failed to inspect Podman image: %w", err)
+	}
+
+	return image.ID, nil
+}
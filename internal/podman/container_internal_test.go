@@ -0,0 +1,44 @@
+package podman
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pako-23/gtdd/internal/docker"
+	"github.com/pako-23/gtdd/internal/podman/podmantest"
+	"gotest.tools/v3/assert"
+)
+
+func TestGetContainerLogs(t *testing.T) {
+	t.Parallel()
+
+	fake := podmantest.NewServer()
+	fake.ExitCodes["suite-image"] = 0
+	fake.Stdout["suite-image"] = "test1 1\ntest2 0\n"
+	client := newFakeClient(t, fake)
+
+	instance, err := client.Run(context.TODO(), docker.App{"suite": {Image: "suite-image"}}, RunOptions{})
+	assert.NilError(t, err)
+
+	logs, err := client.GetContainerLogs(context.TODO(), instance["suite"])
+	assert.NilError(t, err)
+	assert.Equal(t, logs, "test1 1\ntest2 0\n")
+
+	_, err = client.GetContainerLogs(context.TODO(), "not-existing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestImageID(t *testing.T) {
+	t.Parallel()
+
+	fake := podmantest.NewServer()
+	fake.Missing["missing-image"] = struct{}{}
+	client := newFakeClient(t, fake)
+
+	id, err := client.ImageID(context.TODO(), "suite-image")
+	assert.NilError(t, err)
+	assert.Equal(t, id, "sha256-suite-image")
+
+	_, err = client.ImageID(context.TODO(), "missing-image")
+	assert.ErrorContains(t, err, "no such image")
+}
@@ -0,0 +1,9 @@
+// Copyright 2023 The GTDD Authors. All rights reserved.
+// Use of this source code is governed by a GPL-style
+// license that can be found in the LICENSE file.
+
+// Run the services defined into a Docker Compose definition file as a set of
+// containers grouped into a pod through the Podman REST API, without needing
+// the Docker daemon.
+
+package podman
@@ -0,0 +1,90 @@
+package podman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/docker/go-connections/nat"
+)
+
+// PodOptions represents the configuration of a pod.
+type PodOptions struct {
+	// The hostnames resolved to the pod itself, so that its containers can
+	// reach each other through the names of their services.
+	Hosts []string
+	// The extra hosts of the pod, in the host:ip form.
+	ExtraHosts []string
+	// The size of the shared memory of the pod, in bytes. If it is 0, the
+	// default size is used.
+	ShmSize int64
+	// The ports of the containers of the pod along with their bindings on
+	// the host. The ports without bindings are not published.
+	Ports nat.PortMap
+}
+
+// podSpec represents the body of a request to create a pod.
+type podSpec struct {
+	Name         string        `json:"name"`
+	HostAdd      []string      `json:"hostadd,omitempty"`
+	ShmSize      *int64        `json:"shm_size,omitempty"`
+	PortMappings []portMapping `json:"portmappings,omitempty"`
+}
+
+// portMapping represents a port of a pod published on the host. If the host
+// port is 0, an ephemeral port is used.
+type portMapping struct {
+	HostIP        string `json:"host_ip,omitempty"`
+	ContainerPort uint16 `json:"container_port"`
+	HostPort      uint16 `json:"host_port,omitempty"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+// PodCreate creates a pod with a given name whose containers share the
+// network. If there is any error, it is returned.
+func (c *Client) PodCreate(ctx context.Context, name string, options PodOptions) error {
+	spec := podSpec{Name: name, HostAdd: make([]string, 0, len(options.Hosts))}
+
+	for _, host := range options.Hosts {
+		spec.HostAdd = append(spec.HostAdd, host+":127.0.0.1")
+	}
+	spec.HostAdd = append(spec.HostAdd, options.ExtraHosts...)
+
+	for port, bindings := range options.Ports {
+		for _, binding := range bindings {
+			// An invalid host port is left to Podman to choose.
+			hostPort, _ := strconv.ParseUint(binding.HostPort, 10, 16)
+			spec.PortMappings = append(spec.PortMappings, portMapping{
+				HostIP:        binding.HostIP,
+				ContainerPort: uint16(port.Int()),
+				HostPort:      uint16(hostPort),
+				Protocol:      port.Proto(),
+			})
+		}
+	}
+
+	if options.ShmSize > 0 {
+		spec.ShmSize = &options.ShmSize
+	}
+
+	if err := c.call(ctx, http.MethodPost, "/pods/create", nil, spec, nil); err != nil {
+		return fmt.Errorf("failed to create Podman pod: %w", err)
+	}
+
+	return nil
+}
+
+// PodRemove removes a pod together with all its containers. If the pod does
+// not exist, nothing is done. If there is any error, it is returned.
+func (c *Client) PodRemove(ctx context.Context, name string) error {
+	err := c.call(ctx, http.MethodDelete, "/pods/"+url.PathEscape(name),
+		url.Values{"force": {"true"}}, nil, nil)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("failed to remove Podman pod: %w", err)
+	}
+
+	return nil
+}
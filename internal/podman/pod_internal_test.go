@@ -0,0 +1,52 @@
+package podman
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/pako-23/gtdd/internal/podman/podmantest"
+	"gotest.tools/v3/assert"
+)
+
+func TestPodCreate(t *testing.T) {
+	t.Parallel()
+
+	fake := podmantest.NewServer()
+	client := newFakeClient(t, fake)
+
+	err := client.PodCreate(context.TODO(), "runner-0", PodOptions{
+		Hosts:      []string{"app", "db"},
+		ExtraHosts: []string{"api.local:10.0.0.1"},
+		ShmSize:    1 << 30,
+		Ports: nat.PortMap{
+			"53/udp":   {{HostIP: "127.0.0.1"}},
+			"8080/tcp": nil,
+		},
+	})
+	assert.NilError(t, err)
+
+	pod, ok := fake.Pods["runner-0"]
+	assert.Assert(t, ok)
+	assert.DeepEqual(t, pod.HostAdd, []string{"app:127.0.0.1", "db:127.0.0.1", "api.local:10.0.0.1"})
+	assert.Equal(t, *pod.ShmSize, int64(1<<30))
+	assert.DeepEqual(t, pod.PortMappings, []podmantest.PortMapping{
+		{HostIP: "127.0.0.1", ContainerPort: 53, Protocol: "udp"},
+	})
+
+	assert.NilError(t, client.PodCreate(context.TODO(), "runner-1", PodOptions{}))
+	assert.Check(t, fake.Pods["runner-1"].ShmSize == nil)
+}
+
+func TestPodRemove(t *testing.T) {
+	t.Parallel()
+
+	fake := podmantest.NewServer()
+	client := newFakeClient(t, fake)
+
+	assert.NilError(t, client.PodCreate(context.TODO(), "runner-0", PodOptions{}))
+	assert.NilError(t, client.PodRemove(context.TODO(), "runner-0"))
+	assert.Equal(t, len(fake.Pods), 0)
+
+	assert.NilError(t, client.PodRemove(context.TODO(), "not-existing"))
+}
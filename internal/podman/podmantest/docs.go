@@ -0,0 +1,8 @@
+// Copyright 2023 The GTDD Authors. All rights reserved.
+// Use of this source code is governed by a GPL-style
+// license that can be found in the LICENSE file.
+
+// Emulate the subset of the Podman REST API used by gtdd, so that the
+// packages talking with Podman can be tested without a Podman service.
+
+package podmantest
@@ -0,0 +1,256 @@
+package podmantest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// The prefix of the paths of the Podman REST API.
+const apiPrefix = "/v4.0.0/libpod"
+
+// PodSpec represents a pod as created through the API.
+type PodSpec struct {
+	Name         string        `json:"name"`
+	HostAdd      []string      `json:"hostadd"`
+	ShmSize      *int64        `json:"shm_size"`
+	PortMappings []PortMapping `json:"portmappings"`
+}
+
+// PortMapping represents a port published by a pod.
+type PortMapping struct {
+	HostIP        string `json:"host_ip"`
+	ContainerPort uint16 `json:"container_port"`
+	HostPort      uint16 `json:"host_port"`
+	Protocol      string `json:"protocol"`
+}
+
+// ContainerSpec represents a container as created through the API.
+type ContainerSpec struct {
+	Name       string                  `json:"name"`
+	Image      string                  `json:"image"`
+	Command    []string                `json:"command"`
+	Entrypoint []string                `json:"entrypoint"`
+	Env        map[string]string       `json:"env"`
+	Pod        string                  `json:"pod"`
+	Health     *container.HealthConfig `json:"healthconfig"`
+	WorkDir    string                  `json:"work_dir"`
+	User       string                  `json:"user"`
+	Labels     map[string]string       `json:"labels"`
+	Mounts     []Mount                 `json:"mounts"`
+	Volumes    []NamedVolume           `json:"volumes"`
+	Resources  *ResourceLimits         `json:"resource_limits"`
+	Rlimits    []Rlimit                `json:"r_limits"`
+}
+
+// Mount represents a bind or a tmpfs mount of a container.
+type Mount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type"`
+	Source      string   `json:"source"`
+	Options     []string `json:"options"`
+}
+
+// NamedVolume represents a volume mounted into a container.
+type NamedVolume struct {
+	Name    string   `json:"Name"`
+	Dest    string   `json:"Dest"`
+	Options []string `json:"Options"`
+}
+
+// ResourceLimits represents the resource limits of a container.
+type ResourceLimits struct {
+	Memory *struct {
+		Limit int64 `json:"limit"`
+	} `json:"memory"`
+	CPU *struct {
+		Quota  int64  `json:"quota"`
+		Period uint64 `json:"period"`
+	} `json:"cpu"`
+	Pids *struct {
+		Limit int64 `json:"limit"`
+	} `json:"pids"`
+}
+
+// Rlimit represents a ulimit of a container.
+type Rlimit struct {
+	Type string `json:"type"`
+	Hard uint64 `json:"hard"`
+	Soft uint64 `json:"soft"`
+}
+
+// Container represents a container created on the fake service.
+type Container struct {
+	Spec     ContainerSpec
+	Status   string
+	ExitCode int
+	Health   string
+	Stdout   string
+	Stderr   string
+}
+
+// Server emulates the subset of the Podman REST API used by gtdd. The
+// containers run as soon as they are started, and exit with the exit code
+// configured for their image. The fields can be read once the requests under
+// test are completed.
+type Server struct {
+	mu         sync.Mutex
+	Pods       map[string]PodSpec
+	Containers map[string]*Container
+	// The volumes created for the containers.
+	Volumes map[string]struct{}
+	// The exit code of the containers of each image. The containers of the
+	// images not listed keep running.
+	ExitCodes map[string]int
+	// The health of the containers of each image.
+	Health map[string]string
+	// The standard output of the containers of each image.
+	Stdout map[string]string
+	// The images which cannot be pulled.
+	Missing map[string]struct{}
+	// The containers which were killed.
+	Killed []string
+}
+
+// NewServer returns a fake Podman service without any resource.
+func NewServer() *Server {
+	return &Server{
+		Pods:       map[string]PodSpec{},
+		Containers: map[string]*Container{},
+		Volumes:    map[string]struct{}{},
+		ExitCodes:  map[string]int{},
+		Health:     map[string]string{},
+		Stdout:     map[string]string{},
+		Missing:    map[string]struct{}{},
+	}
+}
+
+type apiError struct {
+	Message  string `json:"message"`
+	Response int    `json:"response"`
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiError{Message: msg, Response: status})
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, ok := strings.CutPrefix(r.URL.Path, apiPrefix)
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown API version")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && path == "/pods/create":
+		var spec PodSpec
+		json.NewDecoder(r.Body).Decode(&spec)
+		s.Pods[spec.Name] = spec
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Id": %q}`, spec.Name)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/pods/"):
+		name := strings.TrimPrefix(path, "/pods/")
+		if _, ok := s.Pods[name]; !ok {
+			writeError(w, http.StatusNotFound, "no such pod")
+			return
+		}
+		delete(s.Pods, name)
+		for containerName, container := range s.Containers {
+			if container.Spec.Pod == name {
+				delete(s.Containers, containerName)
+			}
+		}
+		fmt.Fprint(w, `[]`)
+	case r.Method == http.MethodPost && path == "/images/pull":
+		if _, ok := s.Missing[r.URL.Query().Get("reference")]; ok {
+			fmt.Fprint(w, `{"stream": "Trying to pull"}`+"\n"+`{"error": "manifest unknown"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"images": [%q]}`, r.URL.Query().Get("reference"))
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/images/"):
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "/images/"), "/json")
+		if _, ok := s.Missing[name]; ok {
+			writeError(w, http.StatusNotFound, "no such image")
+			return
+		}
+		fmt.Fprintf(w, `{"Id": "sha256-%s"}`, name)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/volumes/"):
+		name := strings.TrimPrefix(path, "/volumes/")
+		if _, ok := s.Volumes[name]; !ok {
+			writeError(w, http.StatusNotFound, "no such volume")
+			return
+		}
+		delete(s.Volumes, name)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && path == "/containers/create":
+		var spec ContainerSpec
+		json.NewDecoder(r.Body).Decode(&spec)
+		if _, ok := s.Containers[spec.Name]; ok {
+			writeError(w, http.StatusConflict, "name already in use")
+			return
+		}
+		s.Containers[spec.Name] = &Container{Spec: spec, Status: "created"}
+		for _, volume := range spec.Volumes {
+			if volume.Name != "" {
+				s.Volumes[volume.Name] = struct{}{}
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Id": %q}`, spec.Name)
+	case strings.HasPrefix(path, "/containers/"):
+		s.serveContainer(w, r, strings.TrimPrefix(path, "/containers/"))
+	default:
+		writeError(w, http.StatusNotFound, "unknown endpoint")
+	}
+}
+
+func (s *Server) serveContainer(w http.ResponseWriter, r *http.Request, path string) {
+	name, action, _ := strings.Cut(path, "/")
+	container, ok := s.Containers[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "no such container")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && action == "start":
+		container.Status = "running"
+		if code, ok := s.ExitCodes[container.Spec.Image]; ok {
+			container.Status, container.ExitCode = "exited", code
+		}
+		container.Health = s.Health[container.Spec.Image]
+		container.Stdout = s.Stdout[container.Spec.Image]
+		container.Stderr = "some error output\n"
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && action == "json":
+		json.NewEncoder(w).Encode(map[string]any{
+			"State":  map[string]any{"Status": container.Status, "ExitCode": container.ExitCode},
+			"Config": map[string]any{"Labels": container.Spec.Labels},
+		})
+	case r.Method == http.MethodGet && action == "healthcheck":
+		fmt.Fprintf(w, `{"Status": %q, "FailingStreak": 5, "Log": [{"Output": "unhealthy"}]}`, container.Health)
+	case r.Method == http.MethodPost && action == "wait":
+		fmt.Fprint(w, container.ExitCode)
+	case r.Method == http.MethodPost && action == "kill":
+		s.Killed = append(s.Killed, name)
+		container.Status, container.ExitCode = "exited", 137
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && action == "logs":
+		stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte(container.Stdout))
+		stdcopy.NewStdWriter(w, stdcopy.Stderr).Write([]byte(container.Stderr))
+	case r.Method == http.MethodDelete && action == "":
+		delete(s.Containers, name)
+		fmt.Fprint(w, `[]`)
+	default:
+		writeError(w, http.StatusNotFound, "unknown endpoint")
+	}
+}
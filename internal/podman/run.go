@@ -0,0 +1,369 @@
+package podman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/pako-23/gtdd/internal/docker"
+	log "github.com/sirupsen/logrus"
+)
+
+// The time between two checks of the state of a starting container without a
+// health check.
+const pollInterval = 100 * time.Millisecond
+
+// RunOptions represents the configuration used to run an App.
+type RunOptions struct {
+	// The prefix of the names of the containers.
+	Prefix string
+	// The pod in which the containers are run.
+	Pod string
+}
+
+// volumesLabel is the label of a container listing the volumes created for
+// it, which are removed along with the container.
+const volumesLabel = "gtdd.volumes"
+
+// The period, in microseconds, over which the CPU quota of a container is
+// enforced.
+const cpuPeriod = 100000
+
+// AppInstance maps each service of a running App to the name of its
+// container.
+type AppInstance map[string]string
+
+// sleep pauses the current goroutine for the given duration. If the context
+// is done before the duration elapses, the context error is returned.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// environment translates environment variables in the KEY=VALUE form into
+// the map expected by Podman.
+func environment(variables []string) map[string]string {
+	env := make(map[string]string, len(variables))
+
+	for _, variable := range variables {
+		if variable == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(variable, "=")
+		env[name] = value
+	}
+
+	return env
+}
+
+// volumeName returns the name of a named volume of an App instance. The
+// volumes are prefixed as the containers, so that the instances of the App
+// do not share their state.
+func volumeName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return strings.Join([]string{prefix, name}, "-")
+}
+
+// newContainerSpec returns the specification of the container of a service
+// of an App. The named volumes which are not external are created for the
+// container and listed into its labels.
+func newContainerSpec(app docker.App, name, containerName string, config RunOptions) *containerSpec {
+	srv := app[name]
+	spec := &containerSpec{
+		Name:       containerName,
+		Image:      srv.Image,
+		Command:    srv.Command,
+		Entrypoint: srv.Entrypoint,
+		Env:        environment(srv.Environment),
+		Pod:        config.Pod,
+		Health:     srv.Healthcheck,
+		WorkDir:    srv.WorkingDir,
+		User:       srv.User,
+		Labels:     make(map[string]string, len(srv.Labels)+1),
+	}
+
+	for key, value := range srv.Labels {
+		spec.Labels[key] = value
+	}
+
+	var volumes []string
+	for _, volume := range srv.Volumes {
+		var options []string
+		if volume.ReadOnly {
+			options = append(options, "ro")
+		}
+
+		switch volume.Type {
+		case mount.TypeVolume:
+			source := volume.Source
+			if source != "" && !volume.External {
+				source = volumeName(config.Prefix, source)
+				volumes = append(volumes, source)
+			}
+			if volume.VolumeOptions != nil && volume.VolumeOptions.NoCopy {
+				options = append(options, "nocopy")
+			}
+
+			spec.Volumes = append(spec.Volumes, namedVolume{Name: source, Dest: volume.Target, Options: options})
+		case mount.TypeBind:
+			options = append(options, "rbind")
+			if volume.BindOptions != nil && volume.BindOptions.Propagation != "" {
+				options = append(options, string(volume.BindOptions.Propagation))
+			}
+
+			spec.Mounts = append(spec.Mounts, mountSpec{
+				Destination: volume.Target,
+				Type:        string(volume.Type),
+				Source:      volume.Source,
+				Options:     options,
+			})
+		case mount.TypeTmpfs:
+			if volume.TmpfsOptions != nil && volume.TmpfsOptions.SizeBytes > 0 {
+				options = append(options, fmt.Sprintf("size=%d", volume.TmpfsOptions.SizeBytes))
+			}
+			if volume.TmpfsOptions != nil && volume.TmpfsOptions.Mode != 0 {
+				options = append(options, fmt.Sprintf("mode=%o", volume.TmpfsOptions.Mode))
+			}
+
+			spec.Mounts = append(spec.Mounts, mountSpec{
+				Destination: volume.Target,
+				Type:        string(volume.Type),
+				Source:      string(volume.Type),
+				Options:     options,
+			})
+		default:
+			log.Warnf("ignoring %s mount %s of service %s, which is not supported by Podman", volume.Type, volume.Target, name)
+		}
+	}
+
+	if len(volumes) != 0 {
+		spec.Labels[volumesLabel] = strings.Join(volumes, ",")
+	}
+
+	for target, options := range srv.Tmpfs {
+		tmpfs := mountSpec{Destination: target, Type: "tmpfs", Source: "tmpfs"}
+		if options != "" {
+			tmpfs.Options = strings.Split(options, ",")
+		}
+		spec.Mounts = append(spec.Mounts, tmpfs)
+	}
+
+	spec.Resources, spec.Rlimits = newResourceLimits(srv.Resources)
+
+	return spec
+}
+
+// newResourceLimits translates the resource limits and the ulimits of a
+// container into the ones expected by Podman.
+func newResourceLimits(resources container.Resources) (*resourceLimits, []rlimit) {
+	var (
+		limits  resourceLimits
+		rlimits []rlimit
+	)
+
+	if resources.Memory > 0 {
+		limits.Memory = &memoryLimits{Limit: resources.Memory}
+	}
+
+	if resources.NanoCPUs > 0 {
+		limits.CPU = &cpuLimits{Quota: resources.NanoCPUs * cpuPeriod / 1e9, Period: cpuPeriod}
+	}
+
+	if resources.PidsLimit != nil {
+		limits.Pids = &pidsLimits{Limit: *resources.PidsLimit}
+	}
+
+	for _, ulimit := range resources.Ulimits {
+		rlimits = append(rlimits, rlimit{
+			Type: "RLIMIT_" + strings.ToUpper(ulimit.Name),
+			Hard: uint64(ulimit.Hard),
+			Soft: uint64(ulimit.Soft),
+		})
+	}
+
+	if limits == (resourceLimits{}) {
+		return nil, rlimits
+	}
+
+	return &limits, rlimits
+}
+
+// isRunning reports whether a started container is ready to be used. A
+// container with a health check is ready once it is healthy; any other
+// container is ready once it is running or exited successfully. If the
+// container cannot become ready, an error is returned.
+func (c *Client) isRunning(ctx context.Context, name string, healthcheck bool, retries int) (bool, error) {
+	if healthcheck {
+		result, err := c.runHealthCheck(ctx, name)
+		if err != nil {
+			return false, err
+		}
+
+		if result.Status == "healthy" {
+			return true, nil
+		}
+
+		if retries > 0 && result.FailingStreak >= retries {
+			logs := make([]string, len(result.Log))
+			for i, log := range result.Log {
+				logs[i] = log.Output
+			}
+
+			return false, fmt.Errorf("container healthcheck failing: %s", strings.Join(logs, " "))
+		}
+
+		return false, nil
+	}
+
+	stats, err := c.inspectContainer(ctx, name)
+	if err != nil {
+		return false, err
+	}
+
+	switch stats.State.Status {
+	case "exited", "stopped":
+		if stats.State.ExitCode == 0 {
+			return true, nil
+		}
+
+		return false, fmt.Errorf("the container exited with status code %d", stats.State.ExitCode)
+	case "paused", "stopping", "removing":
+		return false, fmt.Errorf("the container is into state: %s", stats.State.Status)
+	default:
+		return stats.State.Status == "running", nil
+	}
+}
+
+// Run starts a container for each service of an App into a pod and waits for
+// all of them to be ready. The images which are not available are pulled. If
+// there is any error, the started containers are removed and the error is
+// returned.
+func (c *Client) Run(ctx context.Context, app docker.App, config RunOptions) (AppInstance, error) {
+	result := make(AppInstance, len(app))
+
+	// The containers must be cleaned up even if the run is cancelled.
+	cleanupCtx := context.WithoutCancel(ctx)
+	fail := func(err error) (AppInstance, error) {
+		if deleteErr := c.Delete(cleanupCtx, result); deleteErr != nil {
+			log.Errorf("failed to delete containers of failed run: %v", deleteErr)
+		}
+
+		return nil, err
+	}
+
+	for name, srv := range app {
+		containerName := name
+		if config.Prefix != "" {
+			containerName = strings.Join([]string{config.Prefix, name}, "-")
+		}
+
+		if err := c.pullImage(ctx, srv.Image); err != nil {
+			return fail(err)
+		}
+
+		if err := c.createContainer(ctx, newContainerSpec(app, name, containerName, config)); err != nil {
+			return fail(err)
+		}
+		result[name] = containerName
+
+		if err := c.startContainer(ctx, containerName); err != nil {
+			return fail(err)
+		}
+		log.Debugf("started Podman container %s", containerName)
+	}
+
+	for name, srv := range app {
+		var (
+			healthcheck = srv.Healthcheck != nil && len(srv.Healthcheck.Test) > 0 &&
+				srv.Healthcheck.Test[0] != "NONE"
+			interval = pollInterval
+			retries  int
+		)
+
+		if healthcheck {
+			if err := sleep(ctx, srv.Healthcheck.StartPeriod); err != nil {
+				return fail(err)
+			}
+
+			interval, retries = max(srv.Healthcheck.Interval, time.Second), srv.Healthcheck.Retries
+		}
+
+		for {
+			if running, err := c.isRunning(ctx, result[name], healthcheck, retries); err != nil {
+				return fail(err)
+			} else if running {
+				break
+			}
+
+			if err := sleep(ctx, interval); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Delete removes all the containers of an application instance along with
+// the volumes created for them. If there is an error, it is returned.
+func (c *Client) Delete(ctx context.Context, instance AppInstance) error {
+	volumes := map[string]struct{}{}
+
+	for name, containerName := range instance {
+		state, err := c.inspectContainer(ctx, containerName)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("failed in deleting application instance: %w", err)
+		} else if err == nil && state.Config.Labels[volumesLabel] != "" {
+			for _, volume := range strings.Split(state.Config.Labels[volumesLabel], ",") {
+				volumes[volume] = struct{}{}
+			}
+		}
+
+		if err := c.removeContainer(ctx, containerName); err != nil {
+			return fmt.Errorf("failed in deleting application instance: %w", err)
+		}
+		delete(instance, name)
+	}
+
+	// The volumes are removed once all the containers sharing them are
+	// removed.
+	for volume := range volumes {
+		if err := c.removeVolume(ctx, volume); err != nil {
+			return fmt.Errorf("failed in deleting application instance volume: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Kill sends a SIGKILL to all the containers of an application instance
+// without removing them, so that their logs can still be retrieved. If there
+// is an error, it is returned.
+func (c *Client) Kill(ctx context.Context, instance AppInstance) error {
+	for _, containerName := range instance {
+		err := c.call(ctx, http.MethodPost, "/containers/"+url.PathEscape(containerName)+"/kill",
+			url.Values{"signal": {"SIGKILL"}}, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed in killing application instance: %w", err)
+		}
+	}
+
+	return nil
+}
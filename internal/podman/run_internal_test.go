@@ -0,0 +1,202 @@
+package podman
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/pako-23/gtdd/internal/docker"
+	"github.com/pako-23/gtdd/internal/podman/podmantest"
+	"gotest.tools/v3/assert"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	fake := podmantest.NewServer()
+	fake.Health["db-image"] = "healthy"
+	fake.ExitCodes["init-image"] = 0
+	client := newFakeClient(t, fake)
+
+	app := docker.App{
+		"app": {Image: "app-image", Environment: []string{"", "DB_HOST=db", "DEBUG"}},
+		"db": {
+			Image:       "db-image",
+			Command:     []string{"--port", "5432"},
+			Healthcheck: &container.HealthConfig{Test: []string{"CMD", "pg_isready"}, Retries: 3},
+		},
+		"init": {Image: "init-image"},
+	}
+
+	instance, err := client.Run(context.TODO(), app, RunOptions{Prefix: "runner-0", Pod: "runner-0"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, instance, AppInstance{
+		"app":  "runner-0-app",
+		"db":   "runner-0-db",
+		"init": "runner-0-init",
+	})
+
+	spec := fake.Containers["runner-0-app"].Spec
+	assert.Equal(t, spec.Pod, "runner-0")
+	assert.DeepEqual(t, spec.Env, map[string]string{"DB_HOST": "db", "DEBUG": ""})
+
+	spec = fake.Containers["runner-0-db"].Spec
+	assert.DeepEqual(t, spec.Command, []string{"--port", "5432"})
+	assert.DeepEqual(t, spec.Health.Test, []string{"CMD", "pg_isready"})
+}
+
+func TestRunServiceSpec(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docker-compose.yml")
+	err := os.WriteFile(path, []byte(`services:
+  db:
+    image: postgres
+    working_dir: /srv
+    user: "1000:1000"
+    labels:
+      app.role: db
+    volumes:
+      - data:/var/lib/postgresql/data
+      - shared:/shared:ro
+      - ./init:/docker-entrypoint-initdb.d
+      - /anonymous
+      - type: tmpfs
+        target: /cache
+        tmpfs:
+          size: 1024
+    tmpfs:
+      - /run:size=64k
+    ulimits:
+      nofile:
+        soft: 1024
+        hard: 2048
+    mem_limit: 512m
+    pids_limit: 100
+    deploy:
+      resources:
+        limits:
+          cpus: "0.5"
+volumes:
+  data:
+  shared:
+    external: true
+    name: gtdd-shared
+`), 0o644)
+	assert.NilError(t, err)
+
+	app, err := docker.LoadApp(docker.Definition{Files: []string{path}})
+	assert.NilError(t, err)
+
+	fake := podmantest.NewServer()
+	client := newFakeClient(t, fake)
+
+	instance, err := client.Run(context.TODO(), app, RunOptions{Prefix: "runner-0", Pod: "runner-0"})
+	assert.NilError(t, err)
+
+	spec := fake.Containers["runner-0-db"].Spec
+	assert.Equal(t, spec.WorkDir, "/srv")
+	assert.Equal(t, spec.User, "1000:1000")
+	assert.DeepEqual(t, spec.Labels, map[string]string{"app.role": "db", volumesLabel: "runner-0-data"})
+	assert.DeepEqual(t, spec.Volumes, []podmantest.NamedVolume{
+		{Name: "runner-0-data", Dest: "/var/lib/postgresql/data"},
+		{Name: "gtdd-shared", Dest: "/shared", Options: []string{"ro"}},
+		{Dest: "/anonymous"},
+	})
+	assert.DeepEqual(t, spec.Mounts, []podmantest.Mount{
+		{Destination: "/docker-entrypoint-initdb.d", Type: "bind", Source: filepath.Join(dir, "init"), Options: []string{"rbind"}},
+		{Destination: "/cache", Type: "tmpfs", Source: "tmpfs", Options: []string{"size=1024"}},
+		{Destination: "/run", Type: "tmpfs", Source: "tmpfs", Options: []string{"size=64k"}},
+	})
+	assert.Equal(t, spec.Resources.Memory.Limit, int64(512*1024*1024))
+	assert.Equal(t, spec.Resources.CPU.Quota, int64(50000))
+	assert.Equal(t, spec.Resources.CPU.Period, uint64(100000))
+	assert.Equal(t, spec.Resources.Pids.Limit, int64(100))
+	assert.DeepEqual(t, spec.Rlimits, []podmantest.Rlimit{{Type: "RLIMIT_NOFILE", Hard: 2048, Soft: 1024}})
+
+	assert.NilError(t, client.Delete(context.TODO(), instance))
+	assert.Equal(t, len(fake.Containers), 0)
+	assert.DeepEqual(t, fake.Volumes, map[string]struct{}{"gtdd-shared": {}})
+}
+
+func TestRunErrors(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		app docker.App
+		err string
+	}{
+		{
+			app: docker.App{"app": {Image: "missing-image"}},
+			err: "manifest unknown",
+		},
+		{
+			app: docker.App{"app": {Image: "failing-image"}},
+			err: "the container exited with status code 1",
+		},
+		{
+			app: docker.App{"app": {
+				Image:       "unhealthy-image",
+				Healthcheck: &container.HealthConfig{Test: []string{"CMD", "false"}, Retries: 3},
+			}},
+			err: "container healthcheck failing: unhealthy",
+		},
+	}
+
+	for _, test := range tests {
+		fake := podmantest.NewServer()
+		fake.Missing["missing-image"] = struct{}{}
+		fake.ExitCodes["failing-image"] = 1
+		fake.Health["unhealthy-image"] = "unhealthy"
+		client := newFakeClient(t, fake)
+
+		instance, err := client.Run(context.TODO(), test.app, RunOptions{Prefix: "runner-0"})
+		assert.ErrorContains(t, err, test.err)
+		assert.Check(t, instance == nil)
+		assert.Equal(t, len(fake.Containers), 0)
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	t.Parallel()
+
+	fake := podmantest.NewServer()
+	fake.Health["starting-image"] = "starting"
+	client := newFakeClient(t, fake)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	app := docker.App{"app": {
+		Image:       "starting-image",
+		Healthcheck: &container.HealthConfig{Test: []string{"CMD", "true"}, Interval: time.Hour},
+	}}
+
+	_, err := client.Run(ctx, app, RunOptions{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, len(fake.Containers), 0)
+}
+
+func TestDeleteAndKill(t *testing.T) {
+	t.Parallel()
+
+	fake := podmantest.NewServer()
+	client := newFakeClient(t, fake)
+
+	instance, err := client.Run(context.TODO(), docker.App{"app": {Image: "app-image"}}, RunOptions{})
+	assert.NilError(t, err)
+
+	assert.NilError(t, client.Kill(context.TODO(), instance))
+	assert.DeepEqual(t, fake.Killed, []string{"app"})
+
+	assert.NilError(t, client.Delete(context.TODO(), instance))
+	assert.Equal(t, len(instance), 0)
+	assert.Equal(t, len(fake.Containers), 0)
+
+	err = client.Kill(context.TODO(), AppInstance{"app": "not-existing"})
+	assert.ErrorContains(t, err, "failed in killing application instance")
+}
@@ -0,0 +1,277 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrCoordinatorClosed is returned when running a schedule on a coordinator
+// which was closed.
+var ErrCoordinatorClosed = errors.New("the coordinator was closed")
+
+// ErrDigestMismatch is returned to a worker running another test suite than
+// the one of the coordinator.
+var ErrDigestMismatch = errors.New("the worker runs another test suite")
+
+// job is a schedule waiting to be run by a worker.
+type job struct {
+	id       uint64
+	ctx      context.Context
+	schedule []string
+	result   chan result
+}
+
+// Coordinator hands the schedules to run to the remote workers connected to
+// it. It is a runner.ScheduleRunner, so that the dependency detection can
+// run on the workers. If a worker disconnects, the schedules it was running
+// are handed to another worker.
+type Coordinator struct {
+	// The digest of the test suite the workers must run, if any.
+	digest    string
+	queue     chan *job
+	done      chan struct{}
+	closeOnce sync.Once
+	nextID    atomic.Uint64
+
+	mu       sync.Mutex
+	workers  int
+	capacity int
+	// The number of schedules reported as run concurrently, even if fewer
+	// workers are connected.
+	maxCapacity int
+	// Closed and replaced every time a worker connects or disconnects.
+	changed chan struct{}
+}
+
+// NewCoordinator creates a coordinator without any worker. The workers
+// running a test suite with another digest are rejected, unless the digest
+// is empty.
+func NewCoordinator(digest string) *Coordinator {
+	return &Coordinator{
+		digest:  digest,
+		queue:   make(chan *job),
+		done:    make(chan struct{}),
+		changed: make(chan struct{}),
+	}
+}
+
+// NewServer creates a gRPC server accepting the connections of the workers
+// of the coordinator.
+func NewServer(c *Coordinator, options ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(append(options, grpc.ForceServerCodec(jsonCodec{}))...)
+	server.RegisterService(&serviceDesc, c)
+
+	return server
+}
+
+// RunSchedule runs a schedule on one of the workers and returns its results.
+// If no worker is available, it waits for one. If there is any error, it is
+// returned.
+func (c *Coordinator) RunSchedule(ctx context.Context, schedule []string) (runner.RunResults, error) {
+	j := &job{
+		id:       c.nextID.Add(1),
+		ctx:      ctx,
+		schedule: schedule,
+		result:   make(chan result, 1),
+	}
+
+	select {
+	case c.queue <- j:
+	case <-ctx.Done():
+		return runner.RunResults{}, ctx.Err()
+	case <-c.done:
+		return runner.RunResults{}, ErrCoordinatorClosed
+	}
+
+	select {
+	case res := <-j.result:
+		if res.Error != "" {
+			return runner.RunResults{}, fmt.Errorf("failed to run schedule on runner %s: %s", res.Runner, res.Error)
+		}
+
+		return runner.RunResults{
+			Results:     res.Results,
+			RunningTime: res.RunningTime,
+			Runner:      res.Runner,
+		}, nil
+	case <-ctx.Done():
+		return runner.RunResults{}, ctx.Err()
+	case <-c.done:
+		return runner.RunResults{}, ErrCoordinatorClosed
+	}
+}
+
+// Size returns the number of schedules the connected workers can run
+// concurrently, which is at least one and at least the maximum capacity.
+// The detection algorithms read it once, so the maximum capacity lets the
+// workers connecting later run schedules concurrently too.
+func (c *Coordinator) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return max(c.capacity, c.maxCapacity, 1)
+}
+
+// SetMaxCapacity sets the number of schedules the coordinator reports it can
+// run concurrently even before enough workers are connected. The schedules
+// exceeding the capacity of the connected workers wait for a worker.
+func (c *Coordinator) SetMaxCapacity(capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxCapacity = capacity
+}
+
+// WaitForWorkers waits until at least the provided number of workers are
+// connected. If the context is cancelled, its error is returned.
+func (c *Coordinator) WaitForWorkers(ctx context.Context, workers int) error {
+	for {
+		c.mu.Lock()
+		connected, changed := c.workers, c.changed
+		c.mu.Unlock()
+
+		if connected >= workers {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close notifies the connected workers that there are no more schedules to
+// run, and makes the pending schedules fail.
+func (c *Coordinator) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+}
+
+// join records the workers connecting with the provided capacity, or
+// disconnecting if the numbers are negative.
+func (c *Coordinator) join(workers, capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.workers += workers
+	c.capacity += capacity
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// requeue hands a schedule run by a disconnected worker to another worker.
+func (c *Coordinator) requeue(j *job) {
+	go func() {
+		select {
+		case c.queue <- j:
+		case <-j.ctx.Done():
+		case <-c.done:
+		}
+	}()
+}
+
+// Work serves the stream of a worker: the schedules are sent to the worker
+// as long as it has free runners, and their results are handed back to the
+// callers of RunSchedule. When the stream ends, the schedules the worker was
+// running are requeued.
+func (c *Coordinator) Work(stream grpc.ServerStream) error {
+	var msg workerMessage
+	if err := stream.RecvMsg(&msg); err != nil {
+		return err
+	} else if msg.Hello == nil || msg.Hello.Capacity < 1 {
+		return errors.New("the worker did not introduce itself")
+	} else if c.digest != "" && msg.Hello.Digest != c.digest {
+		log.Warnf("rejected worker %s running test suite %s", msg.Hello.Worker, msg.Hello.Digest)
+		return status.Errorf(codes.FailedPrecondition, "%v: expected test suite %s, got %s",
+			ErrDigestMismatch, c.digest, msg.Hello.Digest)
+	}
+
+	var (
+		worker   = msg.Hello.Worker
+		capacity = msg.Hello.Capacity
+		slots    = make(chan struct{}, capacity)
+		recvErr  = make(chan error, 1)
+		mu       sync.Mutex
+		inFlight = map[uint64]*job{}
+	)
+
+	c.join(1, capacity)
+	log.Infof("worker %s connected with %d runners", worker, capacity)
+
+	defer func() {
+		c.join(-1, -capacity)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, j := range inFlight {
+			log.Warnf("worker %s disconnected, requeuing schedule %v", worker, j.schedule)
+			c.requeue(j)
+		}
+		inFlight = map[uint64]*job{}
+	}()
+
+	go func() {
+		for {
+			var msg workerMessage
+			if err := stream.RecvMsg(&msg); err != nil {
+				recvErr <- err
+				return
+			} else if msg.Result == nil {
+				continue
+			}
+
+			mu.Lock()
+			j, ok := inFlight[msg.Result.ID]
+			delete(inFlight, msg.Result.ID)
+			mu.Unlock()
+
+			if ok {
+				j.result <- *msg.Result
+				<-slots
+			}
+		}
+	}()
+
+	for {
+		select {
+		case slots <- struct{}{}:
+		case err := <-recvErr:
+			return err
+		case <-c.done:
+			return stream.SendMsg(&jobMessage{Done: true})
+		}
+
+		select {
+		case j := <-c.queue:
+			if j.ctx.Err() != nil {
+				<-slots
+				continue
+			}
+
+			mu.Lock()
+			inFlight[j.id] = j
+			mu.Unlock()
+
+			if err := stream.SendMsg(&jobMessage{ID: j.id, Schedule: j.schedule}); err != nil {
+				return err
+			}
+		case err := <-recvErr:
+			return err
+		case <-c.done:
+			return stream.SendMsg(&jobMessage{Done: true})
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// Copyright 2023 The GTDD Authors. All rights reserved.
+// Use of this source code is governed by a GPL-style
+// license that can be found in the LICENSE file.
+
+// Distribute the schedules run while detecting the dependencies between tests
+// to remote workers, each running them on its own set of runners.
+
+package remote
@@ -0,0 +1,90 @@
+package remote
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	"google.golang.org/grpc"
+)
+
+// The messages are encoded as JSON, so that the protocol does not need any
+// generated code.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+// workerMessage is a message sent by a worker to the coordinator. The first
+// message of a worker introduces it, while the next ones carry the results of
+// the schedules it ran.
+type workerMessage struct {
+	Hello  *hello  `json:"hello,omitempty"`
+	Result *result `json:"result,omitempty"`
+}
+
+// hello introduces a worker to the coordinator.
+type hello struct {
+	// The identifier of the worker.
+	Worker string `json:"worker"`
+	// The number of schedules the worker can run concurrently.
+	Capacity int `json:"capacity"`
+	// The digest of the test suite run by the worker.
+	Digest string `json:"digest"`
+}
+
+// result is the outcome of a schedule run by a worker.
+type result struct {
+	ID          uint64               `json:"id"`
+	Results     []runner.TestOutcome `json:"results,omitempty"`
+	RunningTime time.Duration        `json:"running_time,omitempty"`
+	Runner      string               `json:"runner,omitempty"`
+	// The error preventing the worker from running the schedule, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// jobMessage is a message sent by the coordinator to a worker: either a
+// schedule to run, or the notification that there is nothing left to run.
+type jobMessage struct {
+	ID       uint64   `json:"id,omitempty"`
+	Schedule []string `json:"schedule,omitempty"`
+	Done     bool     `json:"done,omitempty"`
+}
+
+// workServer is the server side of the protocol between the coordinator and
+// the workers.
+type workServer interface {
+	Work(stream grpc.ServerStream) error
+}
+
+// serviceDesc describes the coordinator service. Each worker opens a single
+// bidirectional stream on which it receives the schedules to run and sends
+// back their results.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "gtdd.Coordinator",
+	HandlerType: (*workServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Work",
+			Handler:       workHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
+
+// workMethod is the full name of the method of the coordinator service.
+const workMethod = "/gtdd.Coordinator/Work"
+
+func workHandler(srv any, stream grpc.ServerStream) error {
+	return srv.(workServer).Work(stream)
+}
@@ -0,0 +1,282 @@
+package remote_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/algorithms"
+	"github.com/pako-23/gtdd/internal/remote"
+	"github.com/pako-23/gtdd/internal/runner"
+	local_runner "github.com/pako-23/gtdd/internal/runner/local-runner"
+	"gotest.tools/v3/assert"
+)
+
+// dependencyRunner makes a test fail if any of its dependencies does not run
+// before it into the schedule.
+type dependencyRunner struct {
+	dependencies map[string][]string
+	size         int
+	runs         atomic.Int64
+}
+
+func (d *dependencyRunner) RunSchedule(ctx context.Context, schedule []string) (runner.RunResults, error) {
+	d.runs.Add(1)
+
+	results := make([]runner.TestOutcome, len(schedule))
+	for i, test := range schedule {
+		results[i].Status = runner.StatusPass
+
+		for _, dependency := range d.dependencies[test] {
+			found := false
+			for _, previous := range schedule[:i] {
+				found = found || previous == dependency
+			}
+
+			if !found {
+				results[i].Status = runner.StatusFail
+			}
+		}
+	}
+
+	return runner.RunResults{Results: results, Runner: "runner-0"}, nil
+}
+
+func (d *dependencyRunner) Size() int {
+	return d.size
+}
+
+// blockingRunner never completes a schedule until its context is cancelled.
+type blockingRunner struct {
+	started chan struct{}
+}
+
+func (b *blockingRunner) RunSchedule(ctx context.Context, schedule []string) (runner.RunResults, error) {
+	b.started <- struct{}{}
+	<-ctx.Done()
+
+	return runner.RunResults{}, ctx.Err()
+}
+
+func (b *blockingRunner) Size() int {
+	return 1
+}
+
+func startCoordinator(t *testing.T, digest string) (*remote.Coordinator, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	var (
+		coordinator = remote.NewCoordinator(digest)
+		server      = remote.NewServer(coordinator)
+	)
+
+	go server.Serve(listener)
+	t.Cleanup(func() {
+		coordinator.Close()
+		server.Stop()
+	})
+
+	return coordinator, listener.Addr().String()
+}
+
+func TestDistributedDetection(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx, cancel  = context.WithTimeout(context.Background(), 30*time.Second)
+		tests        = []string{"test1", "test2", "test3", "test4", "test5"}
+		dependencies = map[string][]string{
+			"test3": {"test1", "test2"},
+			"test5": {"test3"},
+		}
+		expected = algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
+			"test1": {},
+			"test2": {},
+			"test3": {"test1": {}, "test2": {}},
+			"test4": {},
+			"test5": {"test3": {}},
+		})
+		coordinator, address = startCoordinator(t, "digest")
+		runners              = []*dependencyRunner{}
+		wg                   sync.WaitGroup
+	)
+	defer cancel()
+
+	for i := 0; i < 3; i++ {
+		runners = append(runners, &dependencyRunner{dependencies: dependencies, size: 2})
+
+		wg.Add(1)
+		go func(r *dependencyRunner) {
+			defer wg.Done()
+			assert.Check(t, remote.NewWorker("worker", "digest", r).Run(ctx, address, 10*time.Millisecond))
+		}(runners[i])
+	}
+
+	assert.NilError(t, coordinator.WaitForWorkers(ctx, 3))
+	assert.Equal(t, coordinator.Size(), 6)
+
+	g, err := algorithms.PFAST(ctx, tests, coordinator)
+	assert.NilError(t, err)
+	assert.Check(t, g.Equal(expected))
+
+	coordinator.Close()
+	wg.Wait()
+
+	var runs int64
+	for _, r := range runners {
+		runs += r.runs.Load()
+	}
+	assert.Assert(t, runs > 0)
+}
+
+// The script emulating a test suite run by the local backend. The test test3
+// passes only if test1 ran before it since the last reset.
+const localRunScript = `#!/bin/sh
+for test in "$@"; do
+	case "$test" in
+	test1) touch state/test1; echo "test1 1 1" ;;
+	test3) if [ -f state/test1 ]; then echo "test3 1 1"; else echo "test3 0 1 no state"; fi ;;
+	*) echo "$test 1 1" ;;
+	esac
+done
+`
+
+func TestDistributedDetectionLocalBackend(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+		suite       = t.TempDir()
+		resetScript = "rm -rf state && mkdir state"
+		tests       = []string{"test1", "test2", "test3"}
+		expected    = algorithms.DependencyGraph(map[string]map[string]algorithms.Edge{
+			"test1": {},
+			"test2": {},
+			"test3": {"test1": {}},
+		})
+	)
+	defer cancel()
+
+	assert.NilError(t, os.WriteFile(filepath.Join(suite, "run.sh"), []byte(localRunScript), 0o755))
+	assert.NilError(t, os.Mkdir(filepath.Join(suite, "state"), 0o755))
+
+	// The coordinator and the worker digest the test suite on their own,
+	// as gtdd serve and gtdd worker do.
+	digest, err := local_runner.Digest(suite, "./run.sh", resetScript)
+	assert.NilError(t, err)
+	coordinator, address := startCoordinator(t, digest)
+
+	runners, err := runner.NewRunnerSet[*local_runner.LocalRunner](ctx, 2,
+		local_runner.LocalRunnerBuilder,
+		local_runner.WithSuitePath(suite),
+		local_runner.WithRunCommand("./run.sh"),
+		local_runner.WithResetScript(resetScript))
+	assert.NilError(t, err)
+	defer runners.Delete(context.Background())
+
+	workerDigest, err := local_runner.Digest(suite, "./run.sh", resetScript)
+	assert.NilError(t, err)
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- remote.NewWorker("local", workerDigest, runners).Run(ctx, address, 10*time.Millisecond)
+	}()
+	assert.NilError(t, coordinator.WaitForWorkers(ctx, 1))
+
+	g, err := algorithms.PFAST(ctx, tests, coordinator)
+	assert.NilError(t, err)
+	assert.Check(t, g.Equal(expected))
+
+	coordinator.Close()
+	assert.NilError(t, <-stopped)
+}
+
+func TestWorkerDisconnect(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx, cancel          = context.WithTimeout(context.Background(), 30*time.Second)
+		coordinator, address = startCoordinator(t, "digest")
+		blocking             = &blockingRunner{started: make(chan struct{}, 1)}
+		workerCtx, stop      = context.WithCancel(ctx)
+		stopped              = make(chan error, 1)
+	)
+	defer cancel()
+
+	go func() {
+		stopped <- remote.NewWorker("blocking", "digest", blocking).Run(workerCtx, address, time.Second)
+	}()
+	assert.NilError(t, coordinator.WaitForWorkers(ctx, 1))
+
+	results := make(chan error, 1)
+	go func() {
+		out, err := coordinator.RunSchedule(ctx, []string{"test1", "test2"})
+		if err == nil && len(out.Results) != 2 {
+			err = context.DeadlineExceeded
+		}
+		results <- err
+	}()
+
+	<-blocking.started
+	stop()
+	assert.ErrorIs(t, <-stopped, context.Canceled)
+
+	go remote.NewWorker("healthy", "digest", &dependencyRunner{size: 1}).Run(ctx, address, 10*time.Millisecond)
+	assert.NilError(t, <-results)
+}
+
+func TestWorkerDigestMismatch(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx, cancel          = context.WithTimeout(context.Background(), 30*time.Second)
+		coordinator, address = startCoordinator(t, "digest")
+		r                    = &dependencyRunner{size: 1}
+	)
+	defer cancel()
+
+	err := remote.NewWorker("stale", "other-digest", r).Run(ctx, address, 10*time.Millisecond)
+	assert.ErrorContains(t, err, "rejected the worker")
+	assert.ErrorContains(t, err, remote.ErrDigestMismatch.Error())
+	assert.Equal(t, coordinator.Size(), 1)
+	assert.Equal(t, r.runs.Load(), int64(0))
+}
+
+func TestCoordinatorMaxCapacity(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ctx, cancel          = context.WithTimeout(context.Background(), 30*time.Second)
+		coordinator, address = startCoordinator(t, "digest")
+		first, second        = &blockingRunner{started: make(chan struct{}, 1)}, &blockingRunner{started: make(chan struct{}, 1)}
+		stopped              = make(chan error, 2)
+	)
+	defer cancel()
+
+	coordinator.SetMaxCapacity(2)
+	assert.Equal(t, coordinator.Size(), 2)
+
+	go func() { stopped <- remote.NewWorker("first", "digest", first).Run(ctx, address, time.Second) }()
+	assert.NilError(t, coordinator.WaitForWorkers(ctx, 1))
+
+	// The schedules beyond the capacity of the connected workers wait for
+	// the workers connecting later.
+	for i := 0; i < coordinator.Size(); i++ {
+		go coordinator.RunSchedule(ctx, []string{"test1"})
+	}
+	<-first.started
+
+	go func() { stopped <- remote.NewWorker("second", "digest", second).Run(ctx, address, time.Second) }()
+	<-second.started
+	assert.Equal(t, coordinator.Size(), 2)
+
+	cancel()
+	<-stopped
+	<-stopped
+}
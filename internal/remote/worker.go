@@ -0,0 +1,126 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// Worker runs the schedules handed by a coordinator on a local set of
+// runners. The connection to the coordinator is neither encrypted nor
+// authenticated, so it should only go through trusted networks.
+type Worker struct {
+	id      string
+	digest  string
+	runners runner.ScheduleRunner
+}
+
+// NewWorker creates a worker with the provided identifier running the
+// schedules of the test suite with the provided digest on the provided
+// runners.
+func NewWorker(id, digest string, runners runner.ScheduleRunner) *Worker {
+	return &Worker{id: id, digest: digest, runners: runners}
+}
+
+// Run connects to the coordinator at the provided address and runs the
+// schedules it hands until the coordinator has no more schedules to run. If
+// the connection is lost, the worker connects again after the provided retry
+// interval, unless the coordinator rejected the worker. If the context is
+// cancelled, its error is returned.
+func (w *Worker) Run(ctx context.Context, address string, retryInterval time.Duration) error {
+	conn, err := grpc.DialContext(ctx, address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonCodec{})))
+	if err != nil {
+		return fmt.Errorf("failed to connect to coordinator %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	for {
+		err := w.serve(ctx, conn)
+		if err == nil {
+			log.Infof("coordinator %s has no more schedules to run", address)
+			return nil
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		} else if status.Code(err) == codes.FailedPrecondition {
+			return fmt.Errorf("coordinator %s rejected the worker: %s", address, status.Convert(err).Message())
+		}
+		log.Warnf("lost connection to coordinator %s, retrying in %v: %v", address, retryInterval, err)
+
+		select {
+		case <-time.After(retryInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// serve opens a stream to the coordinator and runs the schedules received on
+// it, until the coordinator has no more schedules to run. If there is any
+// error, it is returned.
+func (w *Worker) serve(ctx context.Context, conn *grpc.ClientConn) error {
+	var (
+		sendMu sync.Mutex
+		wg     sync.WaitGroup
+	)
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], workMethod, grpc.WaitForReady(true))
+	if err != nil {
+		return err
+	}
+
+	send := func(msg *workerMessage) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+
+		return stream.SendMsg(msg)
+	}
+
+	if err := send(&workerMessage{Hello: &hello{Worker: w.id, Capacity: w.runners.Size(), Digest: w.digest}}); err != nil {
+		return err
+	}
+
+	for {
+		var msg jobMessage
+		if err := stream.RecvMsg(&msg); err != nil {
+			return err
+		} else if msg.Done {
+			return nil
+		} else if len(msg.Schedule) == 0 {
+			return errors.New("the coordinator sent an empty schedule")
+		}
+
+		wg.Add(1)
+		go func(msg jobMessage) {
+			defer wg.Done()
+
+			res := result{ID: msg.ID, Runner: w.id}
+			out, err := w.runners.RunSchedule(ctx, msg.Schedule)
+			if err != nil {
+				res.Error = err.Error()
+			} else {
+				res.Results = out.Results
+				res.RunningTime = out.RunningTime
+				res.Runner = w.id + "/" + out.Runner
+			}
+
+			if err := send(&workerMessage{Result: &res}); err != nil {
+				log.Warnf("failed to send results of schedule %v: %v", msg.Schedule, err)
+			}
+		}(msg)
+	}
+}
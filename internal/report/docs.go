@@ -0,0 +1,8 @@
+// Copyright 2023 The GTDD Authors. All rights reserved.
+// Use of this source code is governed by a GPL-style
+// license that can be found in the LICENSE file.
+
+// Produce reports on the schedules run on a test suite in formats which can
+// be consumed by other tools.
+
+package report
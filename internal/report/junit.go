@@ -0,0 +1,222 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/runner"
+)
+
+// junitTestSuites is the root element of a JUnit XML report.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite represents a schedule into a JUnit XML report.
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitTestCase represents a test into a JUnit XML report.
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Output  string `xml:",chardata"`
+}
+
+// scheduleRun is a schedule run on a runner together with its results.
+type scheduleRun struct {
+	schedule []string
+	results  runner.RunResults
+}
+
+// JUnit collects the results of the schedules run on a test suite to produce
+// a JUnit XML report. Each schedule is reported as a test suite whose
+// properties are the schedule and the runner it ran on. As a test can be part
+// of many schedules, each test is reported once: in the first schedule where
+// it did not pass if any, otherwise in the first schedule where it ran.
+type JUnit struct {
+	// The name of the report.
+	name string
+	runs []scheduleRun
+}
+
+// NewJUnit creates an empty JUnit XML report with the provided name.
+func NewJUnit(name string) *JUnit {
+	return &JUnit{name: name, runs: []scheduleRun{}}
+}
+
+// Add records the results of a schedule into the report.
+func (j *JUnit) Add(schedule []string, results runner.RunResults) {
+	j.runs = append(j.runs, scheduleRun{schedule: schedule, results: results})
+}
+
+// Write writes the JUnit XML report. If there is any error, it is returned.
+func (j *JUnit) Write(w io.Writer) error {
+	report := j.build()
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+
+	return nil
+}
+
+// build returns the content of the report from the recorded schedules.
+func (j *JUnit) build() *junitTestSuites {
+	selected := selectOutcomes(j.runs)
+
+	report := &junitTestSuites{Name: j.name, Suites: []junitTestSuite{}}
+	var total time.Duration
+
+	for i, run := range j.runs {
+		suite := junitTestSuite{
+			Name: fmt.Sprintf("schedule-%d", i),
+			Time: seconds(run.results.RunningTime),
+			Properties: []junitProperty{
+				{Name: "runner", Value: run.results.Runner},
+				{Name: "schedule", Value: strings.Join(run.schedule, ",")},
+			},
+		}
+
+		for k, test := range run.schedule {
+			if position, ok := selected[test]; !ok || position.run != i || position.index != k {
+				continue
+			}
+
+			testCase := newTestCase(test, suite.Name, run.results.Results[k])
+			switch {
+			case testCase.Failure != nil:
+				suite.Failures++
+			case testCase.Error != nil:
+				suite.Errors++
+			case testCase.Skipped != nil:
+				suite.Skipped++
+			}
+
+			suite.Cases = append(suite.Cases, testCase)
+		}
+
+		if len(suite.Cases) == 0 {
+			continue
+		}
+
+		suite.Tests = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		total += run.results.RunningTime
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = seconds(total)
+
+	return report
+}
+
+// position is the position of a test into a recorded schedule.
+type position struct {
+	run   int
+	index int
+}
+
+// selectOutcomes returns the position of the outcome reported for each test
+// of the recorded schedules: the first one where the test did not pass if
+// any, otherwise the first one where it ran.
+func selectOutcomes(runs []scheduleRun) map[string]position {
+	selected := map[string]position{}
+
+	for i, run := range runs {
+		for k, test := range run.schedule {
+			if k >= len(run.results.Results) {
+				break
+			}
+
+			previous, ok := selected[test]
+			if !ok || (runs[previous.run].results.Results[previous.index].Passed() &&
+				!run.results.Results[k].Passed()) {
+				selected[test] = position{run: i, index: k}
+			}
+		}
+	}
+
+	return selected
+}
+
+// newTestCase returns the representation of the outcome of a test into a
+// JUnit XML report. The tests named as class#method are split into their
+// class and method; the other tests are attributed to their schedule.
+func newTestCase(test, suite string, outcome runner.TestOutcome) junitTestCase {
+	testCase := junitTestCase{
+		Name:      test,
+		ClassName: suite,
+		Time:      seconds(outcome.Duration),
+	}
+
+	if class, method, ok := strings.Cut(test, "#"); ok {
+		testCase.ClassName, testCase.Name = class, method
+	}
+
+	message := &junitMessage{
+		Message: outcome.Message,
+		Type:    outcome.Status.String(),
+		Output:  outcome.Output,
+	}
+
+	switch outcome.Status {
+	case runner.StatusFail:
+		testCase.Failure = message
+	case runner.StatusError, runner.StatusTimeout, runner.StatusNotRun:
+		testCase.Error = message
+	case runner.StatusSkip:
+		testCase.Skipped = message
+	}
+
+	return testCase
+}
+
+// seconds formats a duration as seconds as expected by JUnit XML reports.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
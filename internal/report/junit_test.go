@@ -0,0 +1,85 @@
+package report_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/report"
+	"github.com/pako-23/gtdd/internal/runner"
+	"gotest.tools/v3/assert"
+)
+
+func TestJUnitReport(t *testing.T) {
+	t.Parallel()
+
+	junit := report.NewJUnit("gtdd")
+	junit.Add([]string{"test1", "test2"}, runner.RunResults{
+		Results: []runner.TestOutcome{
+			{Status: runner.StatusPass, Duration: 1500 * time.Millisecond},
+			{Status: runner.StatusSkip},
+		},
+		RunningTime: 2 * time.Second,
+		Runner:      "runner-0",
+	})
+	junit.Add([]string{"test1", "Suite#test3", "test4"}, runner.RunResults{
+		Results: []runner.TestOutcome{
+			{Status: runner.StatusPass},
+			{Status: runner.StatusFail, Message: "expected <1>", Output: "at Suite.test3"},
+			{Status: runner.StatusNotRun},
+		},
+		RunningTime: 3 * time.Second,
+		Runner:      "runner-1",
+	})
+
+	var out bytes.Buffer
+	assert.NilError(t, junit.Write(&out))
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="gtdd" tests="4" failures="1" errors="1" skipped="1" time="5.000">
+  <testsuite name="schedule-0" tests="2" failures="0" errors="0" skipped="1" time="2.000">
+    <properties>
+      <property name="runner" value="runner-0"></property>
+      <property name="schedule" value="test1,test2"></property>
+    </properties>
+    <testcase name="test1" classname="schedule-0" time="1.500"></testcase>
+    <testcase name="test2" classname="schedule-0" time="0.000">
+      <skipped type="skip"></skipped>
+    </testcase>
+  </testsuite>
+  <testsuite name="schedule-1" tests="2" failures="1" errors="1" skipped="0" time="3.000">
+    <properties>
+      <property name="runner" value="runner-1"></property>
+      <property name="schedule" value="test1,Suite#test3,test4"></property>
+    </properties>
+    <testcase name="test3" classname="Suite" time="0.000">
+      <failure message="expected &lt;1&gt;" type="fail">at Suite.test3</failure>
+    </testcase>
+    <testcase name="test4" classname="schedule-1" time="0.000">
+      <error type="not-run"></error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	assert.Equal(t, out.String(), expected)
+}
+
+func TestJUnitReportFailureWins(t *testing.T) {
+	t.Parallel()
+
+	junit := report.NewJUnit("gtdd")
+	junit.Add([]string{"test1"}, runner.RunResults{
+		Results: []runner.TestOutcome{{Status: runner.StatusPass}},
+		Runner:  "runner-0",
+	})
+	junit.Add([]string{"test1"}, runner.RunResults{
+		Results: []runner.TestOutcome{{Status: runner.StatusTimeout, Message: "timed out"}},
+		Runner:  "runner-1",
+	})
+
+	var out bytes.Buffer
+	assert.NilError(t, junit.Write(&out))
+	assert.Check(t, !strings.Contains(out.String(), `value="runner-0"`))
+	assert.Check(t, strings.Contains(out.String(), `<error message="timed out" type="timeout"></error>`))
+}
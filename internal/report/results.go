@@ -0,0 +1,146 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/runner"
+)
+
+// resultsRun is the JSON representation of a schedule run on a runner.
+type resultsRun struct {
+	Schedule    []string             `json:"schedule"`
+	Runner      string               `json:"runner"`
+	RunningTime time.Duration        `json:"running_time"`
+	Results     []runner.TestOutcome `json:"results"`
+}
+
+// Results collects the results of the schedules run on a test suite, so that
+// the results of the shards of a test suite run on different machines can be
+// stored and merged into a single report.
+type Results struct {
+	runs []scheduleRun
+}
+
+// NewResults creates an empty collection of schedule results.
+func NewResults() *Results {
+	return &Results{runs: []scheduleRun{}}
+}
+
+// ReadResults reads the schedule results written by Write. If there is any
+// error, it is returned.
+func ReadResults(r io.Reader) (*Results, error) {
+	runs := []resultsRun{}
+	if err := json.NewDecoder(r).Decode(&runs); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+
+	results := NewResults()
+	for _, run := range runs {
+		results.Add(run.Schedule, runner.RunResults{
+			Results:     run.Results,
+			RunningTime: run.RunningTime,
+			Runner:      run.Runner,
+		})
+	}
+
+	return results, nil
+}
+
+// Add records the results of a schedule.
+func (r *Results) Add(schedule []string, results runner.RunResults) {
+	r.runs = append(r.runs, scheduleRun{schedule: schedule, results: results})
+}
+
+// Merge records all the schedule results of another collection.
+func (r *Results) Merge(other *Results) {
+	r.runs = append(r.runs, other.runs...)
+}
+
+// Write writes a JSON representation of the schedule results. If there is any
+// error, it is returned.
+func (r *Results) Write(w io.Writer) error {
+	runs := make([]resultsRun, 0, len(r.runs))
+	for _, run := range r.runs {
+		runs = append(runs, resultsRun{
+			Schedule:    run.schedule,
+			Runner:      run.results.Runner,
+			RunningTime: run.results.RunningTime,
+			Results:     run.results.Results,
+		})
+	}
+
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to create json from data: %w", err)
+	}
+
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+
+	return nil
+}
+
+// JUnit returns a JUnit XML report with the provided name of the recorded
+// schedule results.
+func (r *Results) JUnit(name string) *JUnit {
+	junit := NewJUnit(name)
+	for _, run := range r.runs {
+		junit.Add(run.schedule, run.results)
+	}
+
+	return junit
+}
+
+// Summary counts the outcomes of the tests of a test suite. As a test can be
+// part of many schedules, the outcome of each test is selected as into a
+// JUnit XML report.
+type Summary struct {
+	// The number of schedules which were run.
+	Schedules int `json:"schedules"`
+	// The number of distinct tests which were run.
+	Tests   int `json:"tests"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Errored int `json:"errored"`
+	Skipped int `json:"skipped"`
+	// The running time of the longest schedule.
+	Longest time.Duration `json:"longest"`
+	// The sum of the running times of all the schedules.
+	Total time.Duration `json:"total"`
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d tests in %d schedules: %d passed, %d failed, %d errored, %d skipped; longest schedule %v, total %v",
+		s.Tests, s.Schedules, s.Passed, s.Failed, s.Errored, s.Skipped, s.Longest, s.Total)
+}
+
+// Summary returns a summary of the recorded schedule results.
+func (r *Results) Summary() Summary {
+	summary := Summary{Schedules: len(r.runs)}
+
+	for _, run := range r.runs {
+		summary.Longest = max(summary.Longest, run.results.RunningTime)
+		summary.Total += run.results.RunningTime
+	}
+
+	for _, position := range selectOutcomes(r.runs) {
+		summary.Tests++
+
+		switch r.runs[position.run].results.Results[position.index].Status {
+		case runner.StatusPass:
+			summary.Passed++
+		case runner.StatusFail:
+			summary.Failed++
+		case runner.StatusSkip:
+			summary.Skipped++
+		default:
+			summary.Errored++
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,96 @@
+package report_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/report"
+	"github.com/pako-23/gtdd/internal/runner"
+	"gotest.tools/v3/assert"
+)
+
+func TestResultsMerge(t *testing.T) {
+	t.Parallel()
+
+	first := report.NewResults()
+	first.Add([]string{"test1", "test2"}, runner.RunResults{
+		Results: []runner.TestOutcome{
+			{Status: runner.StatusPass, Duration: time.Second},
+			{Status: runner.StatusPass},
+		},
+		RunningTime: 2 * time.Second,
+		Runner:      "runner-0",
+	})
+
+	second := report.NewResults()
+	second.Add([]string{"test1", "test3", "test4"}, runner.RunResults{
+		Results: []runner.TestOutcome{
+			{Status: runner.StatusPass},
+			{Status: runner.StatusFail, Message: "expected <1>"},
+			{Status: runner.StatusNotRun},
+		},
+		RunningTime: 3 * time.Second,
+		Runner:      "runner-0",
+	})
+	second.Add([]string{"test5"}, runner.RunResults{
+		Results:     []runner.TestOutcome{{Status: runner.StatusSkip}},
+		RunningTime: time.Second,
+		Runner:      "runner-1",
+	})
+
+	var buf bytes.Buffer
+	assert.NilError(t, second.Write(&buf))
+
+	read, err := report.ReadResults(&buf)
+	assert.NilError(t, err)
+
+	first.Merge(read)
+	assert.DeepEqual(t, first.Summary(), report.Summary{
+		Schedules: 3,
+		Tests:     5,
+		Passed:    2,
+		Failed:    1,
+		Errored:   1,
+		Skipped:   1,
+		Longest:   3 * time.Second,
+		Total:     6 * time.Second,
+	})
+
+	var merged, expected bytes.Buffer
+	assert.NilError(t, first.JUnit("gtdd").Write(&merged))
+
+	junit := report.NewJUnit("gtdd")
+	junit.Add([]string{"test1", "test2"}, runner.RunResults{
+		Results: []runner.TestOutcome{
+			{Status: runner.StatusPass, Duration: time.Second},
+			{Status: runner.StatusPass},
+		},
+		RunningTime: 2 * time.Second,
+		Runner:      "runner-0",
+	})
+	junit.Add([]string{"test1", "test3", "test4"}, runner.RunResults{
+		Results: []runner.TestOutcome{
+			{Status: runner.StatusPass},
+			{Status: runner.StatusFail, Message: "expected <1>"},
+			{Status: runner.StatusNotRun},
+		},
+		RunningTime: 3 * time.Second,
+		Runner:      "runner-0",
+	})
+	junit.Add([]string{"test5"}, runner.RunResults{
+		Results:     []runner.TestOutcome{{Status: runner.StatusSkip}},
+		RunningTime: time.Second,
+		Runner:      "runner-1",
+	})
+	assert.NilError(t, junit.Write(&expected))
+
+	assert.Equal(t, merged.String(), expected.String())
+}
+
+func TestReadResultsInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := report.ReadResults(bytes.NewBufferString("{"))
+	assert.ErrorContains(t, err, "failed to decode results")
+}
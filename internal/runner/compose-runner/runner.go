@@ -1,6 +1,7 @@
 package compose_runner
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
@@ -18,9 +19,22 @@ type ComposeRunner struct {
 	app docker.AppInstance
 	// The definition of the App against which the test suite is being run.
 	appDefinition docker.App
+	// The Docker Compose files defining the App against which the test suite
+	// is being run.
+	appDefinitionFiles docker.Definition
+	// The strategy used to reset the App before running a test schedule.
+	resetStrategy string
+	// The snapshot from which the App is restored when it is reset with the
+	// snapshot strategy. It is taken on the first reset of the App.
+	snapshot *docker.Snapshot
 	// The running containers for the drivers needed to run the test suite.
 	// An example could be the WebDriver to run a Selenium test suite.
 	driver docker.AppInstance
+	// The definition of the drivers needed to run the test suite.
+	driverDefinition docker.App
+	// The Docker Compose files defining the drivers needed to run the test
+	// suite.
+	driverDefinitionFiles docker.Definition
 	// A name associated with the runner.
 	id string
 	// The ID of the Docker network in which all the Docker containers needed
@@ -39,13 +53,13 @@ type ComposeRunner struct {
 
 // NewRunner creates a new runner based on the runner configuration.
 // If there is an error, it is returned.
-func ComposeRunnerBuilder(id string, options ...runner.RunnerOption[*ComposeRunner]) (*ComposeRunner, error) {
+func ComposeRunnerBuilder(ctx context.Context, id string, options ...runner.RunnerOption[*ComposeRunner]) (*ComposeRunner, error) {
 	client, err := docker.NewClient()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client to create runner %s: %w", id, err)
 	}
 
-	net, err := client.NetworkCreate(id)
+	net, err := client.NetworkCreate(ctx, id)
 	if err != nil {
 		client.Close()
 		return nil, fmt.Errorf("failed to create network: %w", err)
@@ -56,51 +70,107 @@ func ComposeRunnerBuilder(id string, options ...runner.RunnerOption[*ComposeRunn
 		client:  client,
 		network: net,
 		app:     docker.AppInstance{},
+		driver:  docker.AppInstance{},
 		id:      id,
 	}
 
-	for _, option := range options {
-		if err := option(runner); err != nil {
-			// TODO: handle errors
-			return nil, err
+	if err := runner.setup(ctx, options...); err != nil {
+		if deleteErr := runner.Delete(context.WithoutCancel(ctx)); deleteErr != nil {
+			log.Errorf("failed to delete runner %s: %v", id, deleteErr)
 		}
 
+		return nil, err
 	}
 
-	runner.translatedEnv = runner.translateEnv(runner.env)
-
 	return runner, nil
 }
 
-func WithAppDefinition(path string) func(*ComposeRunner) error {
-	return func(runner *ComposeRunner) error {
-		app, err := runner.client.NewApp(path)
-		if err != nil {
+// setup applies the options to the runner and starts the drivers needed to
+// run the test suite. If there is an error, it is returned.
+func (c *ComposeRunner) setup(ctx context.Context, options ...runner.RunnerOption[*ComposeRunner]) error {
+	for _, option := range options {
+		if err := option(c); err != nil {
 			return err
 		}
-
-		runner.appDefinition = app
-		return nil
 	}
 
-}
+	if len(c.appDefinitionFiles.Files) != 0 {
+		app, err := c.client.NewApp(ctx, c.appDefinitionFiles)
+		if err != nil {
+			return err
+		}
 
-func WithDriverDefinition(path string) func(*ComposeRunner) error {
-	return func(runner *ComposeRunner) error {
-		definition, err := runner.client.NewApp(path)
+		c.appDefinition = app
+
+		strategy, err := docker.LoadResetStrategy(c.appDefinitionFiles)
 		if err != nil {
 			return err
 		}
+		c.resetStrategy = strategy
+	}
 
-		driver, err := runner.client.Run(definition, docker.RunOptions{
-			Prefix:   runner.Id(),
-			Networks: []string{runner.network}})
+	if len(c.driverDefinitionFiles.Files) != 0 {
+		definition, err := c.client.NewApp(ctx, c.driverDefinitionFiles)
 		if err != nil {
 			return err
 		}
+		c.driverDefinition = definition
+
+		if err := c.startDriver(ctx); err != nil {
+			return err
+		}
+	}
+
+	c.translatedEnv = c.translateEnv(c.env)
+
+	return nil
+}
+
+// startDriver starts the drivers needed to run the test suite. If there is
+// an error, it is returned.
+func (c *ComposeRunner) startDriver(ctx context.Context) error {
+	driver, err := c.client.Run(ctx, c.driverDefinition, docker.RunOptions{
+		Prefix:   c.Id(),
+		Networks: []string{c.network}})
+	if err != nil {
+		return err
+	}
+	c.driver = driver
+
+	return nil
+}
+
+// restartDriver replaces the running drivers with new ones. It is used when a
+// schedule timed out, as the drivers may be stuck into the hung test. If there
+// is an error, it is returned.
+func (c *ComposeRunner) restartDriver(ctx context.Context) error {
+	if c.driverDefinition == nil {
+		return nil
+	}
+
+	if err := c.client.Delete(ctx, c.driver); err != nil {
+		return fmt.Errorf("driver deletion failed in driver restart: %w", err)
+	}
+
+	if err := c.startDriver(ctx); err != nil {
+		return fmt.Errorf("driver start-up failed in driver restart: %w", err)
+	}
+	log.Debugf("[runner=%s] successfully restarted driver", c.Id())
 
-		runner.driver = driver
+	return nil
+}
+
+func WithAppDefinition(definition docker.Definition) func(*ComposeRunner) error {
+	return func(runner *ComposeRunner) error {
+		runner.appDefinitionFiles = definition
+		return nil
+	}
+
+}
 
+func WithDriverDefinition(definition docker.Definition) func(*ComposeRunner) error {
+	return func(runner *ComposeRunner) error {
+		runner.driverDefinitionFiles = definition
 		return nil
 	}
 }
@@ -148,16 +218,39 @@ func (c *ComposeRunner) translateEnv(variables []string) []string {
 
 // ResetApplication deletes the containers related to the currently running
 // application and sets up the containers to run a provided application.
-// If there is an error in the process, it is returned.
-func (c *ComposeRunner) ResetApplication() error {
-	if err := c.client.Delete(c.app); err != nil {
+// With the snapshot reset strategy, the containers are restored from a
+// snapshot of the application instead. If the services of the application
+// define reset hooks, the hooks are run into the running containers, and the
+// containers are only recreated when a hook fails. If there is an error in
+// the process, it is returned.
+func (c *ComposeRunner) ResetApplication(ctx context.Context) error {
+	if len(c.app) != 0 && c.appDefinition.HasResetHooks() {
+		err := c.client.RunResetHooks(ctx, c.appDefinition, c.app)
+		if err == nil {
+			log.Debugf("[runner=%s] successfully reset app through reset hooks", c.Id())
+			return nil
+		}
+		log.Warnf("[runner=%s] recreating app after reset hooks failure: %v", c.Id(), err)
+	}
+
+	if err := c.client.Delete(ctx, c.app); err != nil {
 		return fmt.Errorf("app deletion failed in app reset:  %w", err)
 	}
 
-	instance, err := c.client.Run(c.appDefinition, docker.RunOptions{
-		Prefix:   c.Id(),
-		Networks: []string{c.network},
-	})
+	var (
+		instance docker.AppInstance
+		err      error
+		options  = docker.RunOptions{
+			Prefix:   c.Id(),
+			Networks: []string{c.network},
+		}
+	)
+
+	if c.resetStrategy == docker.ResetSnapshot {
+		instance, err = c.restoreApp(ctx, options)
+	} else {
+		instance, err = c.client.Run(ctx, c.appDefinition, options)
+	}
 	if err != nil {
 		return fmt.Errorf("app start-up failed in app reset: %w", err)
 	}
@@ -167,21 +260,57 @@ func (c *ComposeRunner) ResetApplication() error {
 	return nil
 }
 
+// restoreApp starts the application from its snapshot. The first time, the
+// application is started from its definition and its snapshot is taken. If
+// there is an error, it is returned.
+func (c *ComposeRunner) restoreApp(ctx context.Context, options docker.RunOptions) (docker.AppInstance, error) {
+	if c.snapshot == nil {
+		instance, err := c.client.Run(ctx, c.appDefinition, options)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot, err := c.client.Snapshot(ctx, c.appDefinition, instance)
+		if err != nil {
+			if deleteErr := c.client.Delete(context.WithoutCancel(ctx), instance); deleteErr != nil {
+				log.Errorf("[runner=%s] %v", c.Id(), deleteErr)
+			}
+
+			return nil, fmt.Errorf("app snapshot failed: %w", err)
+		}
+		c.snapshot = snapshot
+		log.Debugf("[runner=%s] successfully took app snapshot", c.Id())
+
+		if err := c.client.Delete(ctx, instance); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.client.Restore(ctx, c.snapshot, options)
+}
+
 // Delete releases all the resources allocated for the runner. If there is an
 // error in the process, it is returned.
-func (c *ComposeRunner) Delete() error {
+func (c *ComposeRunner) Delete(ctx context.Context) error {
 
-	if err := c.client.Delete(c.driver); err != nil {
+	if err := c.client.Delete(ctx, c.driver); err != nil {
 		return fmt.Errorf("driver deletion failed when deleting runner %s: %w", c.Id(), err)
 	}
 	log.Debugf("[runner=%s] successfully deleted driver", c.Id())
 
-	if err := c.client.Delete(c.app); err != nil {
+	if err := c.client.Delete(ctx, c.app); err != nil {
 		return fmt.Errorf("app deletion failed when deleting runner %s: %w", c.Id(), err)
 	}
 	log.Debugf("[runner=%s] successfully deleted app", c.Id())
 
-	if err := c.client.NetworkRemove(c.network); err != nil {
+	if c.snapshot != nil {
+		if err := c.client.RemoveSnapshot(ctx, c.snapshot); err != nil {
+			return fmt.Errorf("app snapshot deletion failed when deleting runner %s: %w", c.Id(), err)
+		}
+		log.Debugf("[runner=%s] successfully deleted app snapshot", c.Id())
+	}
+
+	if err := c.client.NetworkRemove(ctx, c.network); err != nil {
 		return fmt.Errorf("network deletion failed when deleting runner %s: %w", c.Id(), err)
 	}
 	log.Debugf("[runner=%s] successfully deleted network", c.Id())
@@ -190,17 +319,25 @@ func (c *ComposeRunner) Delete() error {
 	return nil
 }
 
-// Run runs a test schedule on this runner. The test results are represented
-// as booleans. If the test is passed, the value is true; otherwise it is
-// false. If there is any error, it is returned.
-func (c *ComposeRunner) Run(tests []string) ([]bool, error) {
-	results, err := c.testSuite.Run(&testsuite.RunConfig{
+// Run runs a test schedule on this runner and returns the outcomes of its
+// tests. If there is any error, it is returned. If the schedule exceeds the
+// deadline of the context, the test suite is killed, the drivers are
+// restarted and the outcomes of the tests which completed are returned with
+// the error.
+func (c *ComposeRunner) Run(ctx context.Context, tests []string) ([]runner.TestOutcome, error) {
+	results, err := c.testSuite.Run(ctx, &testsuite.RunConfig{
 		Name:        fmt.Sprintf("%s-testsuite", c.Id()),
 		Env:         c.translatedEnv,
 		Tests:       tests,
 		StartConfig: &docker.RunOptions{Networks: []string{c.network}},
 	})
-	if err != nil {
+	if err != nil && runner.TimedOut(ctx) {
+		if restartErr := c.restartDriver(context.WithoutCancel(ctx)); restartErr != nil {
+			log.Errorf("[runner=%s] %v", c.Id(), restartErr)
+		}
+
+		return results, fmt.Errorf("schedule timed out on runner %s: %w", c.Id(), err)
+	} else if err != nil {
 		return nil, fmt.Errorf("failed to run test suite on runner %s: %w", c.Id(), err)
 	}
 	return results, nil
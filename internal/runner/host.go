@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The time between the two readings of the host resources measuring the
+// CPUs in use, short enough to follow the runners added every second.
+const cpuSampleInterval = 250 * time.Millisecond
+
+// HostResources describes the resources of the host running the runners.
+type HostResources struct {
+	// The number of CPUs of the host.
+	CPUs int
+	// The time spent by the CPUs of the host running processes since boot, in
+	// clock ticks.
+	BusyTime uint64
+	// The time elapsed on the CPUs of the host since boot, in clock ticks.
+	TotalTime uint64
+	// The memory in bytes available to start new processes.
+	AvailableMemory uint64
+}
+
+// ReadHostResources reads the resources of the host from the proc file
+// system. If there is any error, it is returned.
+func ReadHostResources() (HostResources, error) {
+	resources := HostResources{CPUs: runtime.NumCPU()}
+
+	stat, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return HostResources{}, fmt.Errorf("failed to read host load: %w", err)
+	}
+
+	// The first line sums the time spent by all the CPUs into the user,
+	// nice, system, idle, iowait, irq, softirq and steal states.
+	line, _, _ := strings.Cut(string(stat), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 9 || fields[0] != "cpu" {
+		return HostResources{}, fmt.Errorf("failed to parse host load: %q", line)
+	}
+
+	for i, field := range fields[1:9] {
+		ticks, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return HostResources{}, fmt.Errorf("failed to parse host load: %w", err)
+		}
+
+		resources.TotalTime += ticks
+		if i != 3 && i != 4 {
+			resources.BusyTime += ticks
+		}
+	}
+
+	meminfo, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return HostResources{}, fmt.Errorf("failed to read host memory: %w", err)
+	}
+	defer meminfo.Close()
+
+	scanner := bufio.NewScanner(meminfo)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+
+		kilobytes, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return HostResources{}, fmt.Errorf("failed to parse host memory: %w", err)
+		}
+		resources.AvailableMemory = kilobytes * 1024
+
+		return resources, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return HostResources{}, fmt.Errorf("failed to read host memory: %w", err)
+	}
+
+	return HostResources{}, errors.New("failed to find the available memory of the host")
+}
+
+// BusyCPUs returns the number of CPUs of the host running processes between
+// an earlier reading of the resources and this one.
+func (h HostResources) BusyCPUs(earlier HostResources) float64 {
+	if h.TotalTime <= earlier.TotalTime {
+		return 0
+	}
+
+	return float64(h.CPUs) * float64(h.BusyTime-earlier.BusyTime) / float64(h.TotalTime-earlier.TotalTime)
+}
+
+// Fits reports whether one more runner needing the provided CPUs and memory
+// in bytes fits into the resources, given the CPUs used since an earlier
+// reading of the resources.
+func (h HostResources) Fits(earlier HostResources, cpus float64, memory uint64) bool {
+	return h.BusyCPUs(earlier)+cpus <= float64(h.CPUs) && memory <= h.AvailableMemory
+}
+
+// HostHeadroom returns a function reporting whether the host has the
+// resources to run one more runner needing the provided CPUs and memory in
+// bytes, to bound the growth of an elastic set of runners. The CPUs in use
+// are measured over a fraction of a second rather than taken from the load
+// average, which would still miss the runners added in the last minute. If
+// the resources of the host cannot be read, the growth is not bounded.
+func HostHeadroom(cpus float64, memory uint64) func() bool {
+	return func() bool {
+		earlier, err := ReadHostResources()
+		if err != nil {
+			log.Debugf("not bounding runners by host resources: %v", err)
+			return true
+		}
+
+		time.Sleep(cpuSampleInterval)
+
+		resources, err := ReadHostResources()
+		if err != nil {
+			log.Debugf("not bounding runners by host resources: %v", err)
+			return true
+		}
+
+		if !resources.Fits(earlier, cpus, memory) {
+			log.Debugf("no headroom for another runner: %.2f of %d CPUs busy, %d bytes of memory available",
+				resources.BusyCPUs(earlier), resources.CPUs, resources.AvailableMemory)
+			return false
+		}
+
+		return true
+	}
+}
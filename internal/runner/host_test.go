@@ -0,0 +1,59 @@
+package runner_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	"gotest.tools/v3/assert"
+)
+
+func TestHostResourcesFits(t *testing.T) {
+	t.Parallel()
+
+	earlier := runner.HostResources{CPUs: 4, BusyTime: 1000, TotalTime: 4000}
+
+	var tests = []struct {
+		resources runner.HostResources
+		cpus      float64
+		memory    uint64
+		expected  bool
+	}{
+		{runner.HostResources{CPUs: 4, BusyTime: 1100, TotalTime: 4400, AvailableMemory: 2048}, 1, 1024, true},
+		{runner.HostResources{CPUs: 4, BusyTime: 1300, TotalTime: 4400, AvailableMemory: 2048}, 1, 2048, true},
+		{runner.HostResources{CPUs: 4, BusyTime: 1350, TotalTime: 4400, AvailableMemory: 2048}, 1, 1024, false},
+		{runner.HostResources{CPUs: 4, BusyTime: 1100, TotalTime: 4400, AvailableMemory: 512}, 1, 1024, false},
+		{runner.HostResources{CPUs: 4, BusyTime: 1000, TotalTime: 4000, AvailableMemory: 0}, 0, 0, true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.resources.Fits(earlier, test.cpus, test.memory), test.expected)
+	}
+}
+
+func TestHostResourcesBusyCPUs(t *testing.T) {
+	t.Parallel()
+
+	var (
+		earlier   = runner.HostResources{CPUs: 8, BusyTime: 500, TotalTime: 1000}
+		resources = runner.HostResources{CPUs: 8, BusyTime: 700, TotalTime: 1800}
+	)
+
+	assert.Equal(t, resources.BusyCPUs(earlier), 2.0)
+	assert.Equal(t, earlier.BusyCPUs(earlier), 0.0)
+}
+
+func TestReadHostResources(t *testing.T) {
+	t.Parallel()
+
+	if _, err := os.Stat("/proc/stat"); err != nil {
+		t.Skip("the host has no proc file system")
+	}
+
+	resources, err := runner.ReadHostResources()
+	assert.NilError(t, err)
+	assert.Check(t, resources.CPUs > 0)
+	assert.Check(t, resources.TotalTime > 0)
+	assert.Check(t, resources.BusyTime <= resources.TotalTime)
+	assert.Check(t, resources.AvailableMemory > 0)
+}
@@ -0,0 +1,7 @@
+// Copyright 2023 The GTDD Authors. All rights reserved.
+// Use of this source code is governed by a GPL-style
+// license that can be found in the LICENSE file.
+
+// Create runners to run a specific test suite on a Kubernetes cluster.
+
+package k8s_runner
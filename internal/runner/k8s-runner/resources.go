@@ -0,0 +1,353 @@
+package k8s_runner
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	cgotypes "github.com/compose-spec/compose-go/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	"github.com/pako-23/gtdd/internal/docker"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// The label identifying the runner owning a resource.
+	labelRunner = "gtdd.io/runner"
+	// The label identifying the role of a resource into the runner.
+	labelRole = "gtdd.io/role"
+	// The label identifying the service of an App run by a pod.
+	labelService = "gtdd.io/service"
+	// The label identifying the job running the test suite into a pod.
+	labelJob = "gtdd.io/job"
+)
+
+const (
+	// The role of the resources running the application.
+	roleApp = "app"
+	// The role of the resources running the drivers.
+	roleDriver = "driver"
+	// The role of the resources running the test suite.
+	roleTestSuite = "testsuite"
+)
+
+// The name of the volume used to size the shared memory of a container.
+const shmVolume = "dshm"
+
+// ErrUnsupportedService is returned when a service of an App uses a feature
+// of Docker Compose which cannot be translated into Kubernetes resources.
+var ErrUnsupportedService = errors.New("the service cannot be run by the k8s backend")
+
+// checkApp checks that the services of an App can be translated into
+// Kubernetes resources. Each service runs into its own pod, so the bind
+// mounts, the external volumes and the named volumes shared by several
+// services are rejected rather than dropped. If there is any service which
+// cannot be translated, an error is returned.
+func checkApp(app docker.App) error {
+	users := map[string][]string{}
+
+	names := make([]string, 0, len(app))
+	for name := range app {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, volume := range app[name].Volumes {
+			switch {
+			case volume.Type == mount.TypeBind:
+				return fmt.Errorf("%w: service %s mounts the host path %s", ErrUnsupportedService, name, volume.Source)
+			case volume.Type == mount.TypeVolume && volume.External:
+				return fmt.Errorf("%w: service %s mounts the external volume %s", ErrUnsupportedService, name, volume.Source)
+			case volume.Type == mount.TypeVolume && volume.Source != "":
+				users[volume.Source] = append(users[volume.Source], name)
+			case volume.Type != mount.TypeVolume && volume.Type != mount.TypeTmpfs:
+				return fmt.Errorf("%w: service %s mounts a volume of type %s", ErrUnsupportedService, name, volume.Type)
+			}
+		}
+	}
+
+	volumes := make([]string, 0, len(users))
+	for volume := range users {
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+
+	for _, volume := range volumes {
+		if services := slices.Compact(users[volume]); len(services) > 1 {
+			return fmt.Errorf("%w: the volume %s is shared by the services %s, which run into different pods",
+				ErrUnsupportedService, volume, strings.Join(services, ", "))
+		}
+	}
+
+	return nil
+}
+
+// newServices translates the services of an App into headless Kubernetes
+// services, so that each service can be reached through its name from
+// inside the namespace of the runner as into a Docker network. The services
+// expose the ports exposed or published by the containers.
+func newServices(app docker.App, namespace, id, role string) []*corev1.Service {
+	services := make([]*corev1.Service, 0, len(app))
+
+	for name, service := range app {
+		var ports []corev1.ServicePort
+		for _, port := range containerPorts(service.Ports) {
+			ports = append(ports, corev1.ServicePort{
+				Name:     port.Name,
+				Port:     port.ContainerPort,
+				Protocol: port.Protocol,
+			})
+		}
+
+		services = append(services, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{labelRunner: id, labelRole: role},
+			},
+			Spec: corev1.ServiceSpec{
+				ClusterIP: corev1.ClusterIPNone,
+				Selector:  map[string]string{labelRole: role, labelService: name},
+				Ports:     ports,
+			},
+		})
+	}
+
+	return services
+}
+
+// containerPorts translates the ports exposed or published by a container
+// into Kubernetes container ports. The bindings on the host are dropped, as
+// the services are reached from inside the namespace of the runner.
+func containerPorts(ports nat.PortMap) []corev1.ContainerPort {
+	keys := make([]nat.Port, 0, len(ports))
+	for port := range ports {
+		keys = append(keys, port)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	result := make([]corev1.ContainerPort, 0, len(keys))
+	for _, port := range keys {
+		result = append(result, corev1.ContainerPort{
+			Name:          fmt.Sprintf("%s-%d", port.Proto(), port.Int()),
+			ContainerPort: int32(port.Int()),
+			Protocol:      corev1.Protocol(strings.ToUpper(port.Proto())),
+		})
+	}
+
+	return result
+}
+
+// newPods translates the services of an App into Kubernetes pods. Each
+// service is run into its own pod named as the service.
+func newPods(app docker.App, namespace, id, role string) []*corev1.Pod {
+	pods := make([]*corev1.Pod, 0, len(app))
+
+	for name, service := range app {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					labelRunner:  id,
+					labelRole:    role,
+					labelService: name,
+				},
+			},
+			Spec: corev1.PodSpec{
+				Hostname:      name,
+				RestartPolicy: corev1.RestartPolicyNever,
+				Containers: []corev1.Container{{
+					Name:            name,
+					Image:           service.Image,
+					Command:         service.Entrypoint,
+					Args:            service.Command,
+					Env:             envVars(service.Environment),
+					Ports:           containerPorts(service.Ports),
+					ImagePullPolicy: corev1.PullIfNotPresent,
+					ReadinessProbe:  readinessProbe(service.Healthcheck),
+				}},
+			},
+		}
+
+		if service.ShmSize > 0 {
+			addEmptyDir(pod, shmVolume, "/dev/shm", corev1.StorageMediumMemory, service.ShmSize, false)
+		}
+
+		for i, volume := range service.Volumes {
+			medium, size := corev1.StorageMediumDefault, int64(0)
+			if volume.Type == mount.TypeTmpfs {
+				medium = corev1.StorageMediumMemory
+				if volume.TmpfsOptions != nil {
+					size = volume.TmpfsOptions.SizeBytes
+				}
+			}
+
+			addEmptyDir(pod, fmt.Sprintf("volume-%d", i), volume.Target, medium, size, volume.ReadOnly)
+		}
+
+		targets := make([]string, 0, len(service.Tmpfs))
+		for target := range service.Tmpfs {
+			targets = append(targets, target)
+		}
+		sort.Strings(targets)
+
+		for i, target := range targets {
+			addEmptyDir(pod, fmt.Sprintf("tmpfs-%d", i), target, corev1.StorageMediumMemory, 0, false)
+		}
+
+		pods = append(pods, pod)
+	}
+
+	return pods
+}
+
+// addEmptyDir mounts a new empty directory volume into the container of a
+// pod. The volumes of the services live as long as their pod, which is
+// recreated each time the application is reset, as the volumes of the
+// containers of an App instance. A zero size does not limit the volume.
+func addEmptyDir(pod *corev1.Pod, name, target string, medium corev1.StorageMedium, size int64, readOnly bool) {
+	emptyDir := &corev1.EmptyDirVolumeSource{Medium: medium}
+	if size > 0 {
+		emptyDir.SizeLimit = resource.NewQuantity(size, resource.BinarySI)
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name:         name,
+		VolumeSource: corev1.VolumeSource{EmptyDir: emptyDir},
+	})
+	pod.Spec.Containers[0].VolumeMounts = append(pod.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      name,
+		MountPath: target,
+		ReadOnly:  readOnly,
+	})
+}
+
+// dependenciesMet reports whether the services on which a service depends
+// reached the condition required by the service, given the pods running the
+// services by name. The dependencies which are not part of the App are
+// ignored, as when running the App with Docker.
+func dependenciesMet(app docker.App, name string, pods map[string]*corev1.Pod) bool {
+	for dependency, condition := range app[name].DependsOn {
+		if _, ok := app[dependency]; !ok {
+			continue
+		}
+
+		pod, ok := pods[dependency]
+		if !ok {
+			return false
+		}
+
+		succeeded := pod.Status.Phase == corev1.PodSucceeded
+		switch condition {
+		case cgotypes.ServiceConditionStarted:
+			if pod.Status.Phase != corev1.PodRunning && !succeeded {
+				return false
+			}
+		case cgotypes.ServiceConditionCompletedSuccessfully:
+			if !succeeded {
+				return false
+			}
+		default:
+			if !podReady(pod) && !succeeded {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// newJob returns the job running the provided tests of a test suite.
+func newJob(name, namespace, id, image string, tests, env []string) *batchv1.Job {
+	backoffLimit := int32(0)
+	labels := map[string]string{
+		labelRunner: id,
+		labelRole:   roleTestSuite,
+		labelJob:    name,
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:            roleTestSuite,
+						Image:           image,
+						Args:            tests,
+						Env:             envVars(env),
+						ImagePullPolicy: corev1.PullIfNotPresent,
+					}},
+				},
+			},
+		},
+	}
+}
+
+// envVars translates environment variables in the KEY=VALUE form into
+// Kubernetes environment variables.
+func envVars(variables []string) []corev1.EnvVar {
+	env := make([]corev1.EnvVar, 0, len(variables))
+
+	for _, variable := range variables {
+		if variable == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(variable, "=")
+		env = append(env, corev1.EnvVar{Name: name, Value: value})
+	}
+
+	return env
+}
+
+// readinessProbe translates a Docker health check into a Kubernetes
+// readiness probe. If there is no health check, it returns nil.
+func readinessProbe(healthcheck *container.HealthConfig) *corev1.Probe {
+	if healthcheck == nil || len(healthcheck.Test) == 0 {
+		return nil
+	}
+
+	var command []string
+	switch healthcheck.Test[0] {
+	case "CMD":
+		command = healthcheck.Test[1:]
+	case "CMD-SHELL":
+		command = []string{"/bin/sh", "-c", strings.Join(healthcheck.Test[1:], " ")}
+	default:
+		return nil
+	}
+
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{Command: command},
+		},
+		InitialDelaySeconds: seconds(healthcheck.StartPeriod),
+		PeriodSeconds:       seconds(healthcheck.Interval),
+		TimeoutSeconds:      seconds(healthcheck.Timeout),
+		FailureThreshold:    int32(healthcheck.Retries),
+	}
+}
+
+// seconds returns a duration rounded up to whole seconds, as used by the
+// Kubernetes probes.
+func seconds(duration time.Duration) int32 {
+	return int32((duration + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,627 @@
+package k8s_runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/docker"
+	"github.com/pako-23/gtdd/internal/runner"
+	"github.com/pako-23/gtdd/internal/testsuite"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// The time between two checks of the state of the resources of a runner.
+const pollInterval = time.Second
+
+// The default time allowed to a pod to start running its containers, after
+// which it is considered stuck, such as while waiting for a node or for its
+// image.
+const defaultStartTimeout = 5 * time.Minute
+
+// The reasons for which a container waits that prevent it from ever starting
+// without an intervention on the cluster.
+var stalledReasons = map[string]struct{}{
+	"ErrImagePull":               {},
+	"ImagePullBackOff":           {},
+	"InvalidImageName":           {},
+	"CreateContainerConfigError": {},
+}
+
+// K8sRunner represents an environment on a Kubernetes cluster where a test
+// suite can be run. Each runner owns a namespace, so that the services of
+// the application and of the drivers can be reached through their names as
+// into a Docker network. The test suite is run from the image built by gtdd
+// unless another image is provided through WithImage; as the cluster pulls
+// the image only if it is not present on its nodes, the image must either be
+// loaded into the nodes or be reachable from a registry. Each service runs
+// into its own pod, started once the services on which it depends reach the
+// required condition; its volumes live as long as its pod, while the volumes
+// which cannot be translated, such as bind mounts, are rejected.
+type K8sRunner struct {
+	// The definition of the App against which the test suite is being run.
+	appDefinition docker.App
+	// The Docker Compose files defining the App against which the test suite
+	// is being run.
+	appDefinitionFiles docker.Definition
+	// The definition of the drivers needed to run the test suite.
+	driverDefinition docker.App
+	// The Docker Compose files defining the drivers needed to run the test
+	// suite.
+	driverDefinitionFiles docker.Definition
+	// A name associated with the runner.
+	id string
+	// The namespace containing all the resources of the runner.
+	namespace string
+	// The number of jobs created to run the test suite, used to name them.
+	jobs int
+	// The test suite that should be run inside this runner.
+	testSuite *testsuite.TestSuite
+	// The environment variables that should be passed to the test suite.
+	env []string
+	// The image running the test suite, if it is not the one built by gtdd.
+	image string
+	// The time allowed to a pod to start running its containers.
+	startTimeout time.Duration
+
+	client kubernetes.Interface
+}
+
+// K8sRunnerBuilder creates a new runner on the Kubernetes cluster configured
+// into the kubeconfig, unless a client is provided through the options. If
+// there is an error, it is returned.
+func K8sRunnerBuilder(ctx context.Context, id string, options ...runner.RunnerOption[*K8sRunner]) (*K8sRunner, error) {
+	runner := &K8sRunner{id: id, startTimeout: defaultStartTimeout}
+
+	for _, option := range options {
+		if err := option(runner); err != nil {
+			return nil, err
+		}
+	}
+
+	if runner.client == nil {
+		client, err := newClient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client to create runner %s: %w", id, err)
+		}
+		runner.client = client
+	}
+
+	namespace, err := runner.client.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("gtdd-%s-%s", id, utilrand.String(5)),
+			Labels: map[string]string{labelRunner: id},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create namespace: %w", err)
+	}
+	runner.namespace = namespace.Name
+	log.Debugf("[runner=%s] successfully created namespace %s", id, runner.namespace)
+
+	if err := runner.setup(ctx); err != nil {
+		if deleteErr := runner.Delete(context.WithoutCancel(ctx)); deleteErr != nil {
+			log.Errorf("failed to delete runner %s: %v", id, deleteErr)
+		}
+
+		return nil, err
+	}
+
+	return runner, nil
+}
+
+// newClient creates a client for the cluster configured into the kubeconfig
+// or, if there is none, for the cluster in which gtdd is running. If there is
+// an error, it is returned.
+func newClient() (kubernetes.Interface, error) {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cluster configuration: %w", err)
+	}
+
+	return kubernetes.NewForConfig(config)
+}
+
+// setup reads the definitions of the application and of the drivers, creates
+// the services to reach them and starts the drivers. If there is an error, it
+// is returned.
+func (k *K8sRunner) setup(ctx context.Context) error {
+	if len(k.appDefinitionFiles.Files) != 0 {
+		app, err := docker.LoadApp(k.appDefinitionFiles)
+		if err != nil {
+			return err
+		} else if err := checkApp(app); err != nil {
+			return err
+		}
+		k.appDefinition = app
+
+		if err := k.createServices(ctx, k.appDefinition, roleApp); err != nil {
+			return err
+		}
+	}
+
+	if len(k.driverDefinitionFiles.Files) != 0 {
+		definition, err := docker.LoadApp(k.driverDefinitionFiles)
+		if err != nil {
+			return err
+		} else if err := checkApp(definition); err != nil {
+			return err
+		}
+		k.driverDefinition = definition
+
+		if err := k.createServices(ctx, k.driverDefinition, roleDriver); err != nil {
+			return err
+		}
+
+		if err := k.startPods(ctx, k.driverDefinition, roleDriver); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func WithAppDefinition(definition docker.Definition) func(*K8sRunner) error {
+	return func(runner *K8sRunner) error {
+		runner.appDefinitionFiles = definition
+		return nil
+	}
+}
+
+func WithDriverDefinition(definition docker.Definition) func(*K8sRunner) error {
+	return func(runner *K8sRunner) error {
+		runner.driverDefinitionFiles = definition
+		return nil
+	}
+}
+
+func WithEnv(env []string) func(*K8sRunner) error {
+	return func(runner *K8sRunner) error {
+		runner.env = env
+		return nil
+	}
+}
+
+func WithTestSuite(suite *testsuite.TestSuite) func(*K8sRunner) error {
+	return func(runner *K8sRunner) error {
+		runner.testSuite = suite
+		return nil
+	}
+}
+
+// WithImage sets the image running the test suite, such as the image built
+// by gtdd pushed into a registry reachable from the cluster.
+func WithImage(image string) func(*K8sRunner) error {
+	return func(runner *K8sRunner) error {
+		runner.image = image
+		return nil
+	}
+}
+
+// WithStartTimeout sets the time allowed to a pod to start running its
+// containers, after which the pod is considered stuck.
+func WithStartTimeout(timeout time.Duration) func(*K8sRunner) error {
+	return func(runner *K8sRunner) error {
+		runner.startTimeout = timeout
+		return nil
+	}
+}
+
+// WithClient sets the client used to talk with the cluster instead of the
+// one configured into the kubeconfig.
+func WithClient(client kubernetes.Interface) func(*K8sRunner) error {
+	return func(runner *K8sRunner) error {
+		runner.client = client
+		return nil
+	}
+}
+
+// createServices creates the services to reach each service of an App. If
+// there is an error, it is returned.
+func (k *K8sRunner) createServices(ctx context.Context, app docker.App, role string) error {
+	for _, service := range newServices(app, k.namespace, k.Id(), role) {
+		_, err := k.client.CoreV1().Services(k.namespace).Create(ctx, service, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create service %s: %w", service.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// startPods creates a pod for each service of an App and waits for all of
+// them to be ready. The pod of a service is created only once the services
+// on which it depends reached the required condition. If there is an error,
+// it is returned.
+func (k *K8sRunner) startPods(ctx context.Context, app docker.App, role string) error {
+	pending := map[string]*corev1.Pod{}
+	for _, pod := range newPods(app, k.namespace, k.Id(), role) {
+		pending[pod.Name] = pod
+	}
+
+	created := time.Now()
+	return wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		for {
+			started, err := k.startedPods(ctx, role, created)
+			if err != nil {
+				return false, err
+			}
+
+			progress := false
+			for name, pod := range pending {
+				if !dependenciesMet(app, name, started) {
+					continue
+				}
+
+				if _, err := k.client.CoreV1().Pods(k.namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+					return false, fmt.Errorf("failed to create pod %s: %w", pod.Name, err)
+				}
+				delete(pending, name)
+				progress = true
+			}
+
+			if progress {
+				continue
+			} else if len(pending) != 0 || len(started) != len(app) {
+				return false, nil
+			}
+
+			for _, pod := range started {
+				if !podReady(pod) && pod.Status.Phase != corev1.PodSucceeded {
+					return false, nil
+				}
+			}
+
+			return true, nil
+		}
+	})
+}
+
+// startedPods returns the pods with a given role by name. If any of them
+// failed or is stuck before starting since the provided time, an error is
+// returned.
+func (k *K8sRunner) startedPods(ctx context.Context, role string, created time.Time) (map[string]*corev1.Pod, error) {
+	pods, err := k.listPods(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	started := make(map[string]*corev1.Pod, len(pods))
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodFailed {
+			return nil, fmt.Errorf("pod %s exited before being ready", pod.Name)
+		} else if err := k.checkStarting(pod, created); err != nil {
+			return nil, err
+		}
+		started[pod.Name] = pod
+	}
+
+	return started, nil
+}
+
+// checkStarting returns an error if a pod created at the provided time is
+// stuck before running its containers: it cannot be scheduled, the image of
+// one of its containers cannot be pulled, or it did not start within the
+// time allowed.
+func (k *K8sRunner) checkStarting(pod *corev1.Pod, created time.Time) error {
+	if pod.Status.Phase != corev1.PodPending && pod.Status.Phase != "" {
+		return nil
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse &&
+			condition.Reason == corev1.PodReasonUnschedulable {
+			return fmt.Errorf("pod %s cannot be scheduled: %s", pod.Name, condition.Message)
+		}
+	}
+
+	statuses := append(slices.Clone(pod.Status.InitContainerStatuses), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if waiting := status.State.Waiting; waiting != nil {
+			if _, ok := stalledReasons[waiting.Reason]; ok {
+				return fmt.Errorf("container %s of pod %s cannot start: %s: %s",
+					status.Name, pod.Name, waiting.Reason, waiting.Message)
+			}
+		}
+	}
+
+	if k.startTimeout > 0 && time.Since(created) > k.startTimeout {
+		return fmt.Errorf("pod %s did not start within %v", pod.Name, k.startTimeout)
+	}
+
+	return nil
+}
+
+// deletePods deletes the pods with a given role and waits for them to be
+// gone, so that new pods with the same names can be created. If there is an
+// error, it is returned.
+func (k *K8sRunner) deletePods(ctx context.Context, role string) error {
+	gracePeriod := int64(0)
+
+	pods, err := k.listPods(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	for _, pod := range pods {
+		err := k.client.CoreV1().Pods(k.namespace).Delete(ctx, pod.Name,
+			metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pod %s: %w", pod.Name, err)
+		}
+	}
+
+	return wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		pods, err := k.listPods(ctx, role)
+
+		return len(pods) == 0, err
+	})
+}
+
+// listPods returns the pods with a given role. If there is an error, it is
+// returned.
+func (k *K8sRunner) listPods(ctx context.Context, role string) ([]corev1.Pod, error) {
+	pods, err := k.client.CoreV1().Pods(k.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: selector(role),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	return pods.Items, nil
+}
+
+// selector returns the label selector matching the resources with a given
+// role.
+func selector(role string) string {
+	return labels.Set{labelRole: role}.String()
+}
+
+// podReady reports whether all the containers of a pod are ready.
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// restartDriver replaces the running drivers with new ones. It is used when a
+// schedule timed out, as the drivers may be stuck into the hung test. If there
+// is an error, it is returned.
+func (k *K8sRunner) restartDriver(ctx context.Context) error {
+	if k.driverDefinition == nil {
+		return nil
+	}
+
+	if err := k.deletePods(ctx, roleDriver); err != nil {
+		return fmt.Errorf("driver deletion failed in driver restart: %w", err)
+	}
+
+	if err := k.startPods(ctx, k.driverDefinition, roleDriver); err != nil {
+		return fmt.Errorf("driver start-up failed in driver restart: %w", err)
+	}
+	log.Debugf("[runner=%s] successfully restarted driver", k.Id())
+
+	return nil
+}
+
+// ResetApplication deletes the pods of the currently running application and
+// creates new ones. If there is an error in the process, it is returned.
+func (k *K8sRunner) ResetApplication(ctx context.Context) error {
+	if err := k.deletePods(ctx, roleApp); err != nil {
+		return fmt.Errorf("app deletion failed in app reset: %w", err)
+	}
+
+	if err := k.startPods(ctx, k.appDefinition, roleApp); err != nil {
+		return fmt.Errorf("app start-up failed in app reset: %w", err)
+	}
+	log.Debugf("[runner=%s] successfully reset app", k.Id())
+
+	return nil
+}
+
+// Delete releases all the resources allocated for the runner by deleting its
+// namespace. If there is an error in the process, it is returned.
+func (k *K8sRunner) Delete(ctx context.Context) error {
+	if k.namespace == "" {
+		return nil
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	err := k.client.CoreV1().Namespaces().Delete(ctx, k.namespace, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil {
+		return fmt.Errorf("namespace deletion failed when deleting runner %s: %w", k.Id(), err)
+	}
+	log.Debugf("[runner=%s] successfully deleted namespace %s", k.Id(), k.namespace)
+
+	return nil
+}
+
+// Run runs a test schedule as a job on this runner and returns the outcomes
+// of its tests. If there is any error, such as a pod of the job which is stuck
+// before starting, it is returned. If the schedule exceeds the deadline of
+// the context, the job is deleted, the drivers are restarted and the outcomes
+// of the tests which completed are returned with the error.
+func (k *K8sRunner) Run(ctx context.Context, tests []string) ([]runner.TestOutcome, error) {
+	job, err := k.startJob(ctx, tests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create test suite job on runner %s: %w", k.Id(), err)
+	}
+	defer k.deleteJob(ctx, job)
+
+	completed := 0
+	_, err = k.waitJob(ctx, job, func(ctx context.Context) {
+		if runner.TracksProgress(ctx) {
+			completed = k.reportProgress(ctx, job, tests, completed)
+		}
+	})
+	if err != nil && runner.TimedOut(ctx) {
+		ctx := context.WithoutCancel(ctx)
+
+		var results []runner.TestOutcome
+		if logs, logsErr := k.jobLogs(ctx, job); logsErr != nil {
+			log.Warnf("[runner=%s] failed to obtain logs from interrupted test suite: %v", k.Id(), logsErr)
+		} else {
+			results = testsuite.ParsePartialLogs(logs, tests)
+		}
+
+		if restartErr := k.restartDriver(ctx); restartErr != nil {
+			log.Errorf("[runner=%s] %v", k.Id(), restartErr)
+		}
+
+		return results, fmt.Errorf("schedule timed out on runner %s: %w", k.Id(), err)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to run test suite on runner %s: %w", k.Id(), err)
+	}
+
+	logs, err := k.jobLogs(ctx, job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run test suite on runner %s: %w", k.Id(), err)
+	}
+	log.Debugf("[runner=%s] test suite logs: %s", k.Id(), logs)
+
+	return testsuite.ParseLogs(logs, tests), nil
+}
+
+// startJob creates a job running the image of the test suite with the
+// provided arguments, and returns its name. If there is an error, it is
+// returned.
+func (k *K8sRunner) startJob(ctx context.Context, args []string) (string, error) {
+	image := k.image
+	if image == "" {
+		image = k.testSuite.Image()
+	}
+
+	k.jobs++
+	job := newJob(fmt.Sprintf("testsuite-%d", k.jobs), k.namespace, k.Id(), image, args, k.env)
+
+	if _, err := k.client.BatchV1().Jobs(k.namespace).Create(ctx, job, metav1.CreateOptions{}); err != nil {
+		return "", err
+	}
+
+	return job.Name, nil
+}
+
+// deleteJob deletes a job along with its pods, even if the context is done.
+func (k *K8sRunner) deleteJob(ctx context.Context, name string) {
+	propagation := metav1.DeletePropagationBackground
+	err := k.client.BatchV1().Jobs(k.namespace).Delete(context.WithoutCancel(ctx), name,
+		metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil {
+		log.Warnf("[runner=%s] failed to delete test suite job: %v", k.Id(), err)
+	}
+}
+
+// waitJob waits for a job to complete and reports whether it succeeded. The
+// provided function is called at each check of the job. If the pod of the job
+// is stuck before starting or there is another error, it is returned.
+func (k *K8sRunner) waitJob(ctx context.Context, name string, check func(ctx context.Context)) (bool, error) {
+	succeeded, created := false, time.Now()
+
+	err := wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		current, err := k.client.BatchV1().Jobs(k.namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		pods, err := k.jobPods(ctx, name)
+		if err != nil {
+			return false, err
+		}
+
+		for _, pod := range pods {
+			if err := k.checkStarting(&pod, created); err != nil {
+				return false, err
+			}
+		}
+
+		if check != nil {
+			check(ctx)
+		}
+
+		succeeded = current.Status.Succeeded > 0
+
+		return succeeded || current.Status.Failed > 0, nil
+	})
+
+	return succeeded, err
+}
+
+// reportProgress reports through the context the tests whose outcome was
+// printed by a job since the provided number of tests completed, and returns
+// the number of tests completed so far. The logs cannot be read before the pod
+// of the job starts, so the failures to read them are ignored.
+func (k *K8sRunner) reportProgress(ctx context.Context, name string, tests []string, completed int) int {
+	logs, err := k.jobLogs(ctx, name)
+	if err != nil {
+		return completed
+	}
+
+	outcomes := len(testsuite.ParsePartialLogs(logs, tests))
+	for ; completed < outcomes; completed++ {
+		runner.ReportProgress(ctx)
+	}
+
+	return completed
+}
+
+// jobPods returns the pods run by a job. If there is an error, it is
+// returned.
+func (k *K8sRunner) jobPods(ctx context.Context, name string) ([]corev1.Pod, error) {
+	pods, err := k.client.CoreV1().Pods(k.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set{labelJob: name}.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list test suite pods: %w", err)
+	}
+
+	return pods.Items, nil
+}
+
+// jobLogs returns the logs of the pod run by a job. If there is any error, it
+// is returned.
+func (k *K8sRunner) jobLogs(ctx context.Context, name string) (string, error) {
+	pods, err := k.jobPods(ctx, name)
+	if err != nil {
+		return "", err
+	} else if len(pods) == 0 {
+		return "", fmt.Errorf("no pod found for job %s", name)
+	}
+
+	stream, err := k.client.CoreV1().Pods(k.namespace).
+		GetLogs(pods[0].Name, &corev1.PodLogOptions{}).Stream(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain test suite logs: %w", err)
+	}
+	defer stream.Close()
+
+	logs, err := io.ReadAll(stream)
+	if err != nil {
+		return "", fmt.Errorf("failed to read test suite logs: %w", err)
+	}
+
+	return string(logs), nil
+}
+
+func (k *K8sRunner) Id() string {
+	return k.id
+}
@@ -0,0 +1,477 @@
+package k8s_runner_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/docker"
+	"github.com/pako-23/gtdd/internal/runner"
+	k8s_runner "github.com/pako-23/gtdd/internal/runner/k8s-runner"
+	"github.com/pako-23/gtdd/internal/testsuite"
+	"gotest.tools/v3/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const appDefinition = `services:
+  app:
+    image: app-image
+    environment:
+      DB_HOST: db
+  db:
+    image: db-image
+    command: ["--port", "5432"]
+    healthcheck:
+      test: ["CMD-SHELL", "pg_isready"]
+      interval: 2s
+      retries: 5
+`
+
+const driverDefinition = `services:
+  chrome:
+    image: chrome-image
+    shm_size: 2gb
+`
+
+// newClient returns a fake clientset emulating a cluster in which the pods
+// become ready as soon as they are created, unless another reactor already
+// set their phase. The jobs complete as soon as they are created if complete
+// is true; otherwise, they never complete.
+func newClient(complete bool) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		if pod.Status.Phase == "" {
+			pod.Status.Phase = corev1.PodRunning
+			pod.Status.Conditions = []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+			}
+		}
+
+		return false, nil, nil
+	})
+
+	client.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		if complete {
+			job.Status.Succeeded = 1
+		}
+
+		// The fake clientset has no job controller creating the pod.
+		err := client.Tracker().Add(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      job.Name + "-pod",
+				Namespace: job.Namespace,
+				Labels:    job.Spec.Template.Labels,
+			},
+			Spec: job.Spec.Template.Spec,
+		})
+
+		return false, nil, err
+	})
+
+	return client
+}
+
+func writeDefinition(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NilError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	return path
+}
+
+func newRunner(t *testing.T, client *fake.Clientset) *k8s_runner.K8sRunner {
+	suite, err := testsuite.NewTestSuite(filepath.Join(t.TempDir(), "my-suite"))
+	assert.NilError(t, err)
+
+	r, err := k8s_runner.K8sRunnerBuilder(context.TODO(), "runner-0",
+		k8s_runner.WithClient(client),
+		k8s_runner.WithAppDefinition(docker.Definition{Files: []string{writeDefinition(t, "docker-compose.yml", appDefinition)}}),
+		k8s_runner.WithDriverDefinition(docker.Definition{Files: []string{writeDefinition(t, "driver.yml", driverDefinition)}}),
+		k8s_runner.WithEnv([]string{"APP_URL=http://app:8080"}),
+		k8s_runner.WithTestSuite(suite))
+	assert.NilError(t, err)
+
+	return r
+}
+
+func namespace(t *testing.T, client *fake.Clientset) string {
+	namespaces, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(namespaces.Items), 1)
+
+	return namespaces.Items[0].Name
+}
+
+func pods(t *testing.T, client *fake.Clientset, ns string) map[string]corev1.Pod {
+	list, err := client.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{})
+	assert.NilError(t, err)
+
+	result := map[string]corev1.Pod{}
+	for _, pod := range list.Items {
+		result[pod.Name] = pod
+	}
+
+	return result
+}
+
+func TestK8sRunnerBuilder(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(true)
+	newRunner(t, client)
+
+	ns := namespace(t, client)
+	assert.Assert(t, len(ns) > len("gtdd-runner-0-"))
+
+	services, err := client.CoreV1().Services(ns).List(context.TODO(), metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(services.Items), 3)
+	for _, service := range services.Items {
+		assert.Equal(t, service.Spec.ClusterIP, corev1.ClusterIPNone)
+		assert.Equal(t, service.Spec.Selector["gtdd.io/service"], service.Name)
+	}
+
+	running := pods(t, client, ns)
+	assert.Equal(t, len(running), 1)
+
+	chrome, ok := running["chrome"]
+	assert.Assert(t, ok)
+	assert.Equal(t, chrome.Spec.Containers[0].Image, "chrome-image")
+	assert.Equal(t, len(chrome.Spec.Volumes), 1)
+	assert.Equal(t, chrome.Spec.Volumes[0].EmptyDir.SizeLimit.Value(), int64(2<<30))
+	assert.Equal(t, chrome.Spec.Containers[0].VolumeMounts[0].MountPath, "/dev/shm")
+}
+
+func TestK8sRunnerBuilderFailure(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(true)
+	_, err := k8s_runner.K8sRunnerBuilder(context.TODO(), "runner-0",
+		k8s_runner.WithClient(client),
+		k8s_runner.WithAppDefinition(docker.Definition{Files: []string{"not-existing-file.yml"}}))
+	assert.ErrorContains(t, err, "failed to load app definition file")
+
+	namespaces, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(namespaces.Items), 0)
+}
+
+func TestK8sRunnerResetApplication(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(true)
+	r := newRunner(t, client)
+	ns := namespace(t, client)
+
+	for i := 0; i < 2; i++ {
+		assert.NilError(t, r.ResetApplication(context.TODO()))
+
+		running := pods(t, client, ns)
+		assert.Equal(t, len(running), 3)
+
+		app := running["app"].Spec.Containers[0]
+		assert.Equal(t, app.Image, "app-image")
+		assert.DeepEqual(t, app.Env, []corev1.EnvVar{{Name: "DB_HOST", Value: "db"}})
+
+		db := running["db"].Spec.Containers[0]
+		assert.DeepEqual(t, db.Args, []string{"--port", "5432"})
+		assert.DeepEqual(t, db.ReadinessProbe.Exec.Command, []string{"/bin/sh", "-c", "pg_isready"})
+		assert.Equal(t, db.ReadinessProbe.PeriodSeconds, int32(2))
+		assert.Equal(t, db.ReadinessProbe.FailureThreshold, int32(5))
+	}
+
+	deletions := 0
+	for _, action := range client.Actions() {
+		if action.Matches("delete", "pods") {
+			deletions++
+		}
+	}
+	assert.Equal(t, deletions, 2)
+}
+
+func TestK8sRunnerResetApplicationNotReady(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(true)
+	r := newRunner(t, client)
+
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		pod.Status.Phase = corev1.PodFailed
+
+		return false, nil, nil
+	})
+
+	assert.ErrorContains(t, r.ResetApplication(context.TODO()), "exited before being ready")
+}
+
+const composeFieldsDefinition = `services:
+  app:
+    image: app-image
+    ports:
+      - "8080:80"
+    expose:
+      - "9090"
+    depends_on:
+      db:
+        condition: service_healthy
+      migrations:
+        condition: service_completed_successfully
+  migrations:
+    image: migrations-image
+    depends_on:
+      - db
+  db:
+    image: db-image
+    volumes:
+      - data:/var/lib/db
+    tmpfs:
+      - /run
+volumes:
+  data:
+`
+
+func TestK8sRunnerComposeFields(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(true)
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		if pod.Name == "migrations" {
+			pod.Status.Phase = corev1.PodSucceeded
+		}
+
+		return false, nil, nil
+	})
+
+	r, err := k8s_runner.K8sRunnerBuilder(context.TODO(), "runner-0",
+		k8s_runner.WithClient(client),
+		k8s_runner.WithAppDefinition(docker.Definition{Files: []string{writeDefinition(t, "docker-compose.yml", composeFieldsDefinition)}}))
+	assert.NilError(t, err)
+	assert.NilError(t, r.ResetApplication(context.TODO()))
+
+	created := []string{}
+	for _, action := range client.Actions() {
+		if action.Matches("create", "pods") {
+			created = append(created, action.(k8stesting.CreateAction).GetObject().(*corev1.Pod).Name)
+		}
+	}
+	assert.DeepEqual(t, created, []string{"db", "migrations", "app"})
+
+	ns := namespace(t, client)
+	service, err := client.CoreV1().Services(ns).Get(context.TODO(), "app", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, service.Spec.Ports, []corev1.ServicePort{
+		{Name: "tcp-80", Port: 80, Protocol: corev1.ProtocolTCP},
+		{Name: "tcp-9090", Port: 9090, Protocol: corev1.ProtocolTCP},
+	})
+
+	db := pods(t, client, ns)["db"]
+	assert.Equal(t, len(db.Spec.Volumes), 2)
+	assert.Equal(t, db.Spec.Volumes[1].EmptyDir.Medium, corev1.StorageMediumMemory)
+	assert.DeepEqual(t, db.Spec.Containers[0].VolumeMounts, []corev1.VolumeMount{
+		{Name: "volume-0", MountPath: "/var/lib/db"},
+		{Name: "tmpfs-0", MountPath: "/run"},
+	})
+}
+
+func TestK8sRunnerUnsupportedVolumes(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		definition string
+		err        string
+	}{
+		{
+			definition: "services:\n  app:\n    image: app-image\n    volumes:\n      - ./data:/data\n",
+			err:        "mounts the host path",
+		},
+		{
+			definition: "services:\n  app:\n    image: app-image\n    volumes:\n      - data:/data\n" +
+				"  db:\n    image: db-image\n    volumes:\n      - data:/var/lib/db\nvolumes:\n  data:\n",
+			err: "the volume data is shared by the services app, db",
+		},
+		{
+			definition: "services:\n  app:\n    image: app-image\n    volumes:\n      - data:/data\n" +
+				"volumes:\n  data:\n    external: true\n",
+			err: "mounts the external volume data",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := k8s_runner.K8sRunnerBuilder(context.TODO(), "runner-0",
+			k8s_runner.WithClient(newClient(true)),
+			k8s_runner.WithAppDefinition(docker.Definition{Files: []string{writeDefinition(t, "docker-compose.yml", test.definition)}}))
+		assert.ErrorIs(t, err, k8s_runner.ErrUnsupportedService)
+		assert.ErrorContains(t, err, test.err)
+	}
+}
+
+func TestK8sRunnerRun(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(true)
+	r := newRunner(t, client)
+	ns := namespace(t, client)
+	tests := []string{"test1", "test2"}
+
+	results, err := r.Run(context.TODO(), tests)
+	assert.NilError(t, err)
+	// The fake clientset does not return the real logs of the pods.
+	assert.DeepEqual(t, results, []runner.TestOutcome{
+		{
+			Status:  runner.StatusNotRun,
+			Message: "the test suite exited before running the test",
+			Output:  "fake logs",
+		},
+		{
+			Status:  runner.StatusNotRun,
+			Message: "the test suite exited before running the test",
+		},
+	})
+
+	var job *batchv1.Job
+	for _, action := range client.Actions() {
+		if action.Matches("create", "jobs") {
+			job = action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		}
+	}
+	assert.Assert(t, job != nil)
+	assert.Equal(t, job.Namespace, ns)
+
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, container.Image, "my-suite")
+	assert.DeepEqual(t, container.Args, tests)
+	assert.DeepEqual(t, container.Env, []corev1.EnvVar{{Name: "APP_URL", Value: "http://app:8080"}})
+
+	jobs, err := client.BatchV1().Jobs(ns).List(context.TODO(), metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(jobs.Items), 0)
+}
+
+func TestK8sRunnerRunTimeout(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(false)
+	r := newRunner(t, client)
+	ns := namespace(t, client)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	results, err := r.Run(ctx, []string{"test1", "test2"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, len(results), 0)
+
+	creations := 0
+	for _, action := range client.Actions() {
+		if action.Matches("create", "pods") {
+			creations++
+		}
+	}
+	assert.Equal(t, creations, 2)
+	assert.Equal(t, len(pods(t, client, ns)), 2)
+}
+
+func TestK8sRunnerRunImagePullBackOff(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(false)
+	r := newRunner(t, client)
+
+	client.PrependReactor("get", "jobs", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		resource := corev1.SchemeGroupVersion.WithResource("pods")
+		name := action.(k8stesting.GetAction).GetName() + "-pod"
+
+		object, err := client.Tracker().Get(resource, action.GetNamespace(), name)
+		if err != nil {
+			return false, nil, nil
+		}
+
+		pod := object.(*corev1.Pod)
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name: "testsuite",
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "image not found"},
+			},
+		}}
+
+		return false, nil, client.Tracker().Update(resource, pod, action.GetNamespace())
+	})
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.Run(ctx, []string{"test1"})
+	assert.ErrorContains(t, err, "ImagePullBackOff")
+	assert.Assert(t, ctx.Err() == nil)
+}
+
+func TestK8sRunnerRunStartTimeout(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(false)
+	suite, err := testsuite.NewTestSuite(filepath.Join(t.TempDir(), "my-suite"))
+	assert.NilError(t, err)
+
+	r, err := k8s_runner.K8sRunnerBuilder(context.TODO(), "runner-0",
+		k8s_runner.WithClient(client),
+		k8s_runner.WithTestSuite(suite),
+		k8s_runner.WithStartTimeout(100*time.Millisecond))
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancel()
+
+	_, err = r.Run(ctx, []string{"test1"})
+	assert.ErrorContains(t, err, "did not start within")
+	assert.Assert(t, ctx.Err() == nil)
+}
+
+func TestK8sRunnerDelete(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(true)
+	r := newRunner(t, client)
+
+	assert.NilError(t, r.Delete(context.TODO()))
+
+	namespaces, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(namespaces.Items), 0)
+}
+
+func TestK8sRunnerRunImage(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(true)
+	suite, err := testsuite.NewTestSuite(filepath.Join(t.TempDir(), "my-suite"))
+	assert.NilError(t, err)
+
+	r, err := k8s_runner.K8sRunnerBuilder(context.TODO(), "runner-0",
+		k8s_runner.WithClient(client),
+		k8s_runner.WithTestSuite(suite),
+		k8s_runner.WithImage("registry.example.com/my-suite:v1"))
+	assert.NilError(t, err)
+
+	_, err = r.Run(context.TODO(), []string{"test1"})
+	assert.NilError(t, err)
+
+	var job *batchv1.Job
+	for _, action := range client.Actions() {
+		if action.Matches("create", "jobs") {
+			job = action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		}
+	}
+	assert.Assert(t, job != nil)
+	assert.Equal(t, job.Spec.Template.Spec.Containers[0].Image, "registry.example.com/my-suite:v1")
+}
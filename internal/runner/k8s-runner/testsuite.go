@@ -0,0 +1,81 @@
+package k8s_runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	log "github.com/sirupsen/logrus"
+)
+
+// ListTests returns the tests of a test suite by running its image on the
+// cluster, so that they are listed from the image which runs the schedules.
+// The runner is configured by the provided options, such as the image and
+// the client. If there is any error, it is returned.
+func ListTests(ctx context.Context, options ...runner.RunnerOption[*K8sRunner]) ([]string, error) {
+	logs, _, err := inspect(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Split(strings.Trim(logs, "\n"), "\n"), nil
+}
+
+// Digest returns the ID of the image of a test suite as resolved by the
+// cluster when running it, so that it identifies the image which runs the
+// schedules. The runner is configured by the provided options, such as the
+// image and the client. If there is any error, it is returned.
+func Digest(ctx context.Context, options ...runner.RunnerOption[*K8sRunner]) (string, error) {
+	_, imageID, err := inspect(ctx, options...)
+
+	return imageID, err
+}
+
+// inspect lists the tests of a test suite by running its image as a job into
+// a runner created for the purpose, and returns the logs of the job together
+// with the ID of the image it ran. If there is any error, it is returned.
+func inspect(ctx context.Context, options ...runner.RunnerOption[*K8sRunner]) (string, string, error) {
+	k, err := K8sRunnerBuilder(ctx, "inspect", options...)
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		if err := k.Delete(context.WithoutCancel(ctx)); err != nil {
+			log.Errorf("failed to delete runner %s: %v", k.Id(), err)
+		}
+	}()
+
+	job, err := k.startJob(ctx, []string{"--list-tests"})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create test suite job: %w", err)
+	}
+	defer k.deleteJob(ctx, job)
+
+	succeeded, err := k.waitJob(ctx, job, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list tests: %w", err)
+	}
+
+	logs, err := k.jobLogs(ctx, job)
+	if err != nil {
+		return "", "", err
+	} else if !succeeded {
+		return "", "", fmt.Errorf("failed to list tests: the test suite job failed: %s", logs)
+	}
+
+	pods, err := k.jobPods(ctx, job)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.ImageID != "" {
+				return logs, status.ImageID, nil
+			}
+		}
+	}
+
+	return "", "", fmt.Errorf("the cluster did not report the image of job %s", job)
+}
@@ -0,0 +1,69 @@
+package k8s_runner_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	k8s_runner "github.com/pako-23/gtdd/internal/runner/k8s-runner"
+	"github.com/pako-23/gtdd/internal/testsuite"
+	"gotest.tools/v3/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestListTestsAndDigest(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(true)
+	client.PrependReactor("get", "jobs", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		resource := corev1.SchemeGroupVersion.WithResource("pods")
+		name := action.(k8stesting.GetAction).GetName() + "-pod"
+
+		object, err := client.Tracker().Get(resource, action.GetNamespace(), name)
+		if err != nil {
+			return false, nil, nil
+		}
+
+		pod := object.(*corev1.Pod)
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:    "testsuite",
+			ImageID: "registry.example.com/my-suite@sha256:1234",
+		}}
+
+		return false, nil, client.Tracker().Update(resource, pod, action.GetNamespace())
+	})
+
+	suite, err := testsuite.NewTestSuite(filepath.Join(t.TempDir(), "my-suite"))
+	assert.NilError(t, err)
+	options := []runner.RunnerOption[*k8s_runner.K8sRunner]{
+		k8s_runner.WithClient(client),
+		k8s_runner.WithTestSuite(suite),
+		k8s_runner.WithImage("registry.example.com/my-suite:v1"),
+	}
+
+	// The fake clientset does not return the real logs of the pods.
+	tests, err := k8s_runner.ListTests(context.TODO(), options...)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, tests, []string{"fake logs"})
+
+	digest, err := k8s_runner.Digest(context.TODO(), options...)
+	assert.NilError(t, err)
+	assert.Equal(t, digest, "registry.example.com/my-suite@sha256:1234")
+
+	for _, action := range client.Actions() {
+		if action.Matches("create", "jobs") {
+			job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+			assert.Equal(t, job.Spec.Template.Spec.Containers[0].Image, "registry.example.com/my-suite:v1")
+			assert.DeepEqual(t, job.Spec.Template.Spec.Containers[0].Args, []string{"--list-tests"})
+		}
+	}
+
+	namespaces, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, len(namespaces.Items), 0)
+}
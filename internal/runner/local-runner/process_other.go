@@ -0,0 +1,9 @@
+//go:build !unix
+
+package local_runner
+
+import "os/exec"
+
+// setProcessGroup does nothing on the systems without process groups, where
+// only the command itself is killed on cancellation.
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package local_runner
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup runs a command into its own process group and makes its
+// cancellation kill the whole group, so that no process spawned by the test
+// suite survives a timeout.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,344 @@
+package local_runner
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	"github.com/pako-23/gtdd/internal/testsuite"
+	log "github.com/sirupsen/logrus"
+)
+
+// The time allowed to a cancelled command to release its output before its
+// pipes are closed.
+const waitDelay = time.Second
+
+var (
+	// ErrMissingRunCommand is returned when a runner is created without the
+	// command running the test suite.
+	ErrMissingRunCommand = errors.New("the command to run the test suite is missing")
+	// ErrMissingListCommand is returned when the tests are listed without the
+	// command listing them.
+	ErrMissingListCommand = errors.New("the command to list the tests is missing")
+)
+
+// LocalRunner represents an environment where a test suite is run as local
+// processes. Each runner works into its own temporary copy of the test suite,
+// which is restored before each schedule, so that neither the runners nor
+// the schedules share the files written by the tests.
+type LocalRunner struct {
+	// A name associated with the runner.
+	id string
+	// The directory containing the test suite.
+	suitePath string
+	// The temporary directory in which the commands are run.
+	workDir string
+	// The shell command running the tests passed as arguments.
+	runCommand string
+	// The shell command resetting the state of the application.
+	resetScript string
+	// The environment variables that should be passed to the commands.
+	env []string
+}
+
+// LocalRunnerBuilder creates a new runner based on the runner configuration.
+// If there is an error, it is returned.
+func LocalRunnerBuilder(ctx context.Context, id string, options ...runner.RunnerOption[*LocalRunner]) (*LocalRunner, error) {
+	runner := &LocalRunner{id: id}
+
+	for _, option := range options {
+		if err := option(runner); err != nil {
+			return nil, err
+		}
+	}
+
+	if runner.runCommand == "" {
+		return nil, ErrMissingRunCommand
+	}
+
+	workDir, err := os.MkdirTemp("", fmt.Sprintf("gtdd-%s-", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create working directory of runner %s: %w", id, err)
+	}
+	runner.workDir = workDir
+
+	if runner.suitePath != "" {
+		if err := copyDir(runner.suitePath, workDir); err != nil {
+			if deleteErr := runner.Delete(context.WithoutCancel(ctx)); deleteErr != nil {
+				log.Errorf("failed to delete runner %s: %v", id, deleteErr)
+			}
+
+			return nil, fmt.Errorf("failed to copy test suite for runner %s: %w", id, err)
+		}
+	}
+	log.Debugf("[runner=%s] successfully created working directory %s", id, workDir)
+
+	return runner, nil
+}
+
+// WithSuitePath sets the directory of the test suite, which is copied into
+// the working directory of the runner.
+func WithSuitePath(path string) func(*LocalRunner) error {
+	return func(runner *LocalRunner) error {
+		runner.suitePath = path
+		return nil
+	}
+}
+
+// WithRunCommand sets the shell command running the test suite. The tests to
+// run are passed to the command as arguments.
+func WithRunCommand(command string) func(*LocalRunner) error {
+	return func(runner *LocalRunner) error {
+		runner.runCommand = command
+		return nil
+	}
+}
+
+// WithResetScript sets the shell command resetting the state of the
+// application before each schedule.
+func WithResetScript(script string) func(*LocalRunner) error {
+	return func(runner *LocalRunner) error {
+		runner.resetScript = script
+		return nil
+	}
+}
+
+func WithEnv(env []string) func(*LocalRunner) error {
+	return func(runner *LocalRunner) error {
+		runner.env = env
+		return nil
+	}
+}
+
+// command returns a shell command run into the working directory of the
+// runner with the provided arguments. The name of the runner is passed into
+// the GTDD_RUNNER environment variable, so that the commands can isolate the
+// resources of concurrent runners.
+func (l *LocalRunner) command(ctx context.Context, script string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", script + ` "$@"`, "sh"}, args...)...)
+	cmd.Dir = l.workDir
+	cmd.Env = append(append(os.Environ(), l.env...), "GTDD_RUNNER="+l.Id())
+	cmd.WaitDelay = waitDelay
+	setProcessGroup(cmd)
+
+	return cmd
+}
+
+// ResetApplication restores the working directory of the runner from the
+// test suite, discarding the files written by the previous schedule, and
+// runs the reset script of the runner, if any. If the script fails, an error
+// containing its output is returned.
+func (l *LocalRunner) ResetApplication(ctx context.Context) error {
+	if err := l.restoreWorkDir(); err != nil {
+		return fmt.Errorf("working directory restore failed in app reset on runner %s: %w", l.Id(), err)
+	}
+
+	if l.resetScript == "" {
+		return nil
+	}
+
+	if output, err := l.command(ctx, l.resetScript).CombinedOutput(); err != nil {
+		return fmt.Errorf("app reset failed on runner %s: %w: %s", l.Id(), err, bytes.TrimSpace(output))
+	}
+	log.Debugf("[runner=%s] successfully reset app", l.Id())
+
+	return nil
+}
+
+// restoreWorkDir replaces the content of the working directory of the runner
+// with a fresh copy of the test suite. The directory keeps its path, so that
+// the commands always run into the same directory. If there is any error, it
+// is returned.
+func (l *LocalRunner) restoreWorkDir() error {
+	entries, err := os.ReadDir(l.workDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(l.workDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	if l.suitePath == "" {
+		return nil
+	}
+
+	return copyDir(l.suitePath, l.workDir)
+}
+
+// Delete removes the working directory of the runner. If there is an error
+// in the process, it is returned.
+func (l *LocalRunner) Delete(ctx context.Context) error {
+	if err := os.RemoveAll(l.workDir); err != nil {
+		return fmt.Errorf("working directory deletion failed when deleting runner %s: %w", l.Id(), err)
+	}
+	log.Debugf("[runner=%s] successfully deleted working directory", l.Id())
+
+	return nil
+}
+
+// Run runs a test schedule on this runner and returns the outcomes of its
+// tests. The test suite may exit with an error when some tests do not pass,
+// so only a failure in starting it is returned as an error. If the schedule
+// exceeds the deadline of the context, the processes of the test suite are
+// killed and the outcomes of the tests which completed are returned with the
+// error.
+func (l *LocalRunner) Run(ctx context.Context, tests []string) ([]runner.TestOutcome, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := l.command(ctx, l.runCommand, tests...)
+	cmd.Stdout = io.MultiWriter(&stdout, testsuite.NewProgressWriter(ctx, tests))
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil && runner.TimedOut(ctx) {
+		return testsuite.ParsePartialLogs(stdout.String(), tests),
+			fmt.Errorf("schedule timed out on runner %s: %w", l.Id(), context.Cause(ctx))
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("failed to run test suite on runner %s: %w", l.Id(), err)
+	} else if err != nil {
+		log.Debugf("[runner=%s] test suite exited with status %d: %s", l.Id(), exitErr.ExitCode(), stderr.String())
+	}
+	log.Debugf("[runner=%s] test suite logs: %s", l.Id(), stdout.String())
+
+	return testsuite.ParseLogs(stdout.String(), tests), nil
+}
+
+func (l *LocalRunner) Id() string {
+	return l.id
+}
+
+// ListTests returns the tests printed one per line by a shell command run
+// into the directory of the test suite. If the command prints nothing, no
+// test is returned. If there is any error, it is returned.
+func ListTests(ctx context.Context, path, command string, env []string) ([]string, error) {
+	if command == "" {
+		return nil, ErrMissingListCommand
+	}
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Dir = path
+	cmd.Env = append(os.Environ(), env...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tests: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	trimmed := strings.Trim(string(output), "\n")
+	if trimmed == "" {
+		return []string{}, nil
+	}
+
+	return strings.Split(trimmed, "\n"), nil
+}
+
+// Digest returns a digest of the commands used to run a test suite and of the
+// names and the content of all the files into its directory. If there is any
+// error, it is returned.
+func Digest(path string, commands ...string) (string, error) {
+	hash := sha256.New()
+
+	for _, command := range commands {
+		fmt.Fprintf(hash, "%s\x00", command)
+	}
+
+	err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(hash, "%s\x00%d\x00", filepath.ToSlash(rel), len(data))
+		hash.Write(data)
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to digest test suite: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// copyDir copies the content of a directory into another existing directory,
+// preserving the permissions of the files. Symbolic links are copied as
+// links. If there is any error, it is returned.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(link, target)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+
+		return out.Close()
+	})
+}
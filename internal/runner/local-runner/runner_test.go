@@ -0,0 +1,223 @@
+package local_runner_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	local_runner "github.com/pako-23/gtdd/internal/runner/local-runner"
+	"gotest.tools/v3/assert"
+)
+
+// The script emulating a test suite. Each test passes if the file named as
+// the test exists, otherwise it fails. The test named hang never completes.
+const runScript = `#!/bin/sh
+for test in "$@"; do
+	if [ "$test" = hang ]; then
+		sleep 60
+	elif [ -f "state/$test" ]; then
+		echo "$test 1 5"
+	else
+		echo "missing $test"
+		echo "$test 0 3 no state for $test"
+	fi
+done
+exit 1
+`
+
+func newSuite(t *testing.T) string {
+	path := t.TempDir()
+
+	assert.NilError(t, os.WriteFile(filepath.Join(path, "run.sh"), []byte(runScript), 0o755))
+	assert.NilError(t, os.Mkdir(filepath.Join(path, "state"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(path, "state", "test1"), nil, 0o644))
+
+	return path
+}
+
+func newRunner(t *testing.T, options ...runner.RunnerOption[*local_runner.LocalRunner]) *local_runner.LocalRunner {
+	options = append([]runner.RunnerOption[*local_runner.LocalRunner]{
+		local_runner.WithSuitePath(newSuite(t)),
+		local_runner.WithRunCommand("./run.sh"),
+	}, options...)
+
+	r, err := local_runner.LocalRunnerBuilder(context.TODO(), "runner-0", options...)
+	assert.NilError(t, err)
+	t.Cleanup(func() { r.Delete(context.TODO()) })
+
+	return r
+}
+
+func TestLocalRunnerBuilderMissingCommand(t *testing.T) {
+	t.Parallel()
+
+	_, err := local_runner.LocalRunnerBuilder(context.TODO(), "runner-0",
+		local_runner.WithSuitePath(newSuite(t)))
+	assert.ErrorIs(t, err, local_runner.ErrMissingRunCommand)
+}
+
+func TestLocalRunnerRun(t *testing.T) {
+	t.Parallel()
+
+	r := newRunner(t)
+
+	results, err := r.Run(context.TODO(), []string{"test1", "test2", "test3"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, results, []runner.TestOutcome{
+		{Status: runner.StatusPass, Duration: 5 * time.Millisecond},
+		{
+			Status:   runner.StatusFail,
+			Duration: 3 * time.Millisecond,
+			Message:  "no state for test2",
+			Output:   "missing test2",
+		},
+		{
+			Status:   runner.StatusFail,
+			Duration: 3 * time.Millisecond,
+			Message:  "no state for test3",
+			Output:   "missing test3",
+		},
+	})
+}
+
+func TestLocalRunnerIsolation(t *testing.T) {
+	t.Parallel()
+
+	suite := newSuite(t)
+	runners := make([]*local_runner.LocalRunner, 2)
+	for i := range runners {
+		r, err := local_runner.LocalRunnerBuilder(context.TODO(), "runner-0",
+			local_runner.WithSuitePath(suite),
+			local_runner.WithRunCommand("./run.sh"),
+			local_runner.WithResetScript(`touch "state/$RESET_TEST"`),
+			local_runner.WithEnv([]string{"RESET_TEST=test2"}))
+		assert.NilError(t, err)
+		defer r.Delete(context.TODO())
+
+		runners[i] = r
+	}
+
+	assert.NilError(t, runners[0].ResetApplication(context.TODO()))
+
+	results, err := runners[0].Run(context.TODO(), []string{"test2"})
+	assert.NilError(t, err)
+	assert.Equal(t, results[0].Status, runner.StatusPass)
+
+	results, err = runners[1].Run(context.TODO(), []string{"test2"})
+	assert.NilError(t, err)
+	assert.Equal(t, results[0].Status, runner.StatusFail)
+
+	_, err = os.Stat(filepath.Join(suite, "state", "test2"))
+	assert.Check(t, os.IsNotExist(err))
+}
+
+func TestLocalRunnerResetApplicationWorkDir(t *testing.T) {
+	t.Parallel()
+
+	r := newRunner(t, local_runner.WithRunCommand(`./run.sh "$@"; touch state/test2; true`))
+
+	results, err := r.Run(context.TODO(), []string{"test2"})
+	assert.NilError(t, err)
+	assert.Equal(t, results[0].Status, runner.StatusFail)
+
+	results, err = r.Run(context.TODO(), []string{"test2"})
+	assert.NilError(t, err)
+	assert.Equal(t, results[0].Status, runner.StatusPass)
+
+	assert.NilError(t, r.ResetApplication(context.TODO()))
+
+	results, err = r.Run(context.TODO(), []string{"test2"})
+	assert.NilError(t, err)
+	assert.Equal(t, results[0].Status, runner.StatusFail)
+}
+
+func TestLocalRunnerResetApplicationFailure(t *testing.T) {
+	t.Parallel()
+
+	r := newRunner(t, local_runner.WithResetScript("echo database unavailable; exit 3"))
+
+	err := r.ResetApplication(context.TODO())
+	assert.ErrorContains(t, err, "database unavailable")
+}
+
+func TestLocalRunnerRunTimeout(t *testing.T) {
+	t.Parallel()
+
+	r := newRunner(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	results, err := r.Run(ctx, []string{"test1", "hang", "test2"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Assert(t, time.Since(start) < 10*time.Second)
+	assert.DeepEqual(t, results, []runner.TestOutcome{
+		{Status: runner.StatusPass, Duration: 5 * time.Millisecond},
+	})
+}
+
+func TestLocalRunnerDelete(t *testing.T) {
+	t.Parallel()
+
+	output := filepath.Join(t.TempDir(), "pwd")
+	r := newRunner(t, local_runner.WithRunCommand(`pwd > "$OUTPUT"; true`),
+		local_runner.WithEnv([]string{"OUTPUT=" + output}))
+
+	_, err := r.Run(context.TODO(), nil)
+	assert.NilError(t, err)
+
+	workDir, err := os.ReadFile(output)
+	assert.NilError(t, err)
+	_, err = os.Stat(strings.TrimSpace(string(workDir)))
+	assert.NilError(t, err)
+
+	assert.NilError(t, r.Delete(context.TODO()))
+	_, err = os.Stat(strings.TrimSpace(string(workDir)))
+	assert.Check(t, os.IsNotExist(err))
+}
+
+func TestListTests(t *testing.T) {
+	t.Parallel()
+
+	tests, err := local_runner.ListTests(context.TODO(), newSuite(t), `ls state; echo "$EXTRA"`,
+		[]string{"EXTRA=test2"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, tests, []string{"test1", "test2"})
+
+	tests, err = local_runner.ListTests(context.TODO(), newSuite(t), "true", nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, tests, []string{})
+
+	_, err = local_runner.ListTests(context.TODO(), newSuite(t), "exit 1", nil)
+	assert.ErrorContains(t, err, "failed to list tests")
+
+	_, err = local_runner.ListTests(context.TODO(), newSuite(t), "", nil)
+	assert.ErrorIs(t, err, local_runner.ErrMissingListCommand)
+}
+
+func TestDigest(t *testing.T) {
+	t.Parallel()
+
+	suite := newSuite(t)
+
+	digest, err := local_runner.Digest(suite, "./run.sh")
+	assert.NilError(t, err)
+
+	same, err := local_runner.Digest(suite, "./run.sh")
+	assert.NilError(t, err)
+	assert.Equal(t, digest, same)
+
+	other, err := local_runner.Digest(suite, "./run.sh --verbose")
+	assert.NilError(t, err)
+	assert.Assert(t, digest != other)
+
+	assert.NilError(t, os.WriteFile(filepath.Join(suite, "state", "test2"), nil, 0o644))
+	changed, err := local_runner.Digest(suite, "./run.sh")
+	assert.NilError(t, err)
+	assert.Assert(t, digest != changed)
+}
@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"fmt"
+	"time"
+)
+
+// Status represents how the run of a test ended.
+type Status int
+
+const (
+	// The test was not run, for example because the test suite crashed
+	// before reaching it.
+	StatusNotRun Status = iota
+	// The test passed.
+	StatusPass
+	// The test failed on an assertion.
+	StatusFail
+	// The test was aborted by an unexpected error.
+	StatusError
+	// The test was skipped by the test suite.
+	StatusSkip
+	// The test was interrupted because it exceeded its timeout.
+	StatusTimeout
+)
+
+var statusNames = [...]string{
+	StatusNotRun:  "not-run",
+	StatusPass:    "pass",
+	StatusFail:    "fail",
+	StatusError:   "error",
+	StatusSkip:    "skip",
+	StatusTimeout: "timeout",
+}
+
+func (s Status) String() string {
+	if s < 0 || int(s) >= len(statusNames) {
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+
+	return statusNames[s]
+}
+
+// MarshalText encodes the status as its name.
+func (s Status) MarshalText() ([]byte, error) {
+	if s < 0 || int(s) >= len(statusNames) {
+		return nil, fmt.Errorf("unknown test status %d", int(s))
+	}
+
+	return []byte(statusNames[s]), nil
+}
+
+// UnmarshalText decodes a status from its name.
+func (s *Status) UnmarshalText(text []byte) error {
+	for status, name := range statusNames {
+		if name == string(text) {
+			*s = Status(status)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown test status %q", text)
+}
+
+// TestOutcome represents the result of running a test into a schedule.
+type TestOutcome struct {
+	Status Status `json:"status"`
+	// A message explaining the status, such as the failed assertion.
+	Message string `json:"message,omitempty"`
+	// The time needed to run the test, if reported by the test suite.
+	Duration time.Duration `json:"duration,omitempty"`
+	// The output printed by the test suite while running the test. It is
+	// only kept for the tests which did not pass.
+	Output string `json:"output,omitempty"`
+}
+
+// Passed reports whether the test did not prevent its schedule from
+// passing. A skipped test is considered as passed.
+func (o TestOutcome) Passed() bool {
+	return o.Status == StatusPass || o.Status == StatusSkip
+}
+
+// Failed reports whether the test failed on an assertion. It is the only
+// outcome that tells something about the behaviour of the test.
+func (o TestOutcome) Failed() bool {
+	return o.Status == StatusFail
+}
+
+// FirstNotPassed returns the index of the first test which did not pass. If
+// all the tests passed, it returns -1.
+func FirstNotPassed(outcomes []TestOutcome) int {
+	for i, outcome := range outcomes {
+		if !outcome.Passed() {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Conclusive reports whether the outcomes of a schedule can be trusted: the
+// schedule is conclusive unless the first test which did not pass timed out
+// or was not run, as that is most likely caused by the infrastructure. An
+// error is conclusive, as an order dependency often shows up as an unexpected
+// exception rather than as a failed assertion.
+func Conclusive(outcomes []TestOutcome) bool {
+	first := FirstNotPassed(outcomes)
+
+	return first == -1 || (outcomes[first].Status != StatusNotRun && outcomes[first].Status != StatusTimeout)
+}
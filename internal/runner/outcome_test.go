@@ -0,0 +1,56 @@
+package runner_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	"gotest.tools/v3/assert"
+)
+
+func outcomes(statuses ...runner.Status) []runner.TestOutcome {
+	outcomes := make([]runner.TestOutcome, len(statuses))
+	for i, status := range statuses {
+		outcomes[i].Status = status
+	}
+
+	return outcomes
+}
+
+func TestConclusive(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		outcomes   []runner.TestOutcome
+		first      int
+		conclusive bool
+	}{
+		{outcomes(), -1, true},
+		{outcomes(runner.StatusPass, runner.StatusSkip), -1, true},
+		{outcomes(runner.StatusPass, runner.StatusFail, runner.StatusNotRun), 1, true},
+		{outcomes(runner.StatusPass, runner.StatusError, runner.StatusFail), 1, true},
+		{outcomes(runner.StatusTimeout, runner.StatusNotRun), 0, false},
+		{outcomes(runner.StatusPass, runner.StatusNotRun), 1, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, runner.FirstNotPassed(test.outcomes), test.first)
+		assert.Equal(t, runner.Conclusive(test.outcomes), test.conclusive)
+	}
+}
+
+func TestStatusJSON(t *testing.T) {
+	t.Parallel()
+
+	expected := runner.TestOutcome{Status: runner.StatusTimeout, Message: "timed out"}
+
+	data, err := json.Marshal(expected)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), `{"status":"timeout","message":"timed out"}`)
+
+	var got runner.TestOutcome
+	assert.NilError(t, json.Unmarshal(data, &got))
+	assert.DeepEqual(t, got, expected)
+
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"status":"unknown"}`), &got), "unknown test status")
+}
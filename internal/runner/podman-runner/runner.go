@@ -0,0 +1,294 @@
+package podman_runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/pako-23/gtdd/internal/docker"
+	"github.com/pako-23/gtdd/internal/podman"
+	"github.com/pako-23/gtdd/internal/runner"
+	"github.com/pako-23/gtdd/internal/testsuite"
+	log "github.com/sirupsen/logrus"
+)
+
+// PodmanRunner represents an environment where a test suite can be run on
+// Podman. All the containers of the runner are grouped into a pod, where the
+// names of the services resolve to the pod itself.
+type PodmanRunner struct {
+	// The running containers for the application against which the test
+	// suite is being run.
+	app podman.AppInstance
+	// The definition of the App against which the test suite is being run.
+	appDefinition docker.App
+	// The Docker Compose files defining the App against which the test suite
+	// is being run.
+	appDefinitionFiles docker.Definition
+	// The running containers for the drivers needed to run the test suite.
+	driver podman.AppInstance
+	// The definition of the drivers needed to run the test suite.
+	driverDefinition docker.App
+	// The Docker Compose files defining the drivers needed to run the test
+	// suite.
+	driverDefinitionFiles docker.Definition
+	// A name associated with the runner.
+	id string
+	// The name of the pod containing all the containers of the runner.
+	pod string
+	// The test suite that should be run inside this runner.
+	testSuite *testsuite.TestSuite
+	// The environment variables that should be passed to the container running
+	// the test suite.
+	env []string
+
+	client *podman.Client
+}
+
+// PodmanRunnerBuilder creates a new runner based on the runner configuration.
+// If there is an error, it is returned.
+func PodmanRunnerBuilder(ctx context.Context, id string, options ...runner.RunnerOption[*PodmanRunner]) (*PodmanRunner, error) {
+	client, err := podman.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client to create runner %s: %w", id, err)
+	}
+
+	runner := &PodmanRunner{
+		client: client,
+		app:    podman.AppInstance{},
+		driver: podman.AppInstance{},
+		id:     id,
+	}
+
+	if err := runner.setup(ctx, options...); err != nil {
+		if deleteErr := runner.Delete(context.WithoutCancel(ctx)); deleteErr != nil {
+			log.Errorf("failed to delete runner %s: %v", id, deleteErr)
+		}
+
+		return nil, err
+	}
+
+	return runner, nil
+}
+
+// setup applies the options to the runner, creates its pod and starts the
+// drivers needed to run the test suite. If there is an error, it is returned.
+func (p *PodmanRunner) setup(ctx context.Context, options ...runner.RunnerOption[*PodmanRunner]) error {
+	for _, option := range options {
+		if err := option(p); err != nil {
+			return err
+		}
+	}
+
+	if len(p.appDefinitionFiles.Files) != 0 {
+		app, err := docker.LoadApp(p.appDefinitionFiles)
+		if err != nil {
+			return err
+		}
+		p.appDefinition = app
+	}
+
+	if len(p.driverDefinitionFiles.Files) != 0 {
+		definition, err := docker.LoadApp(p.driverDefinitionFiles)
+		if err != nil {
+			return err
+		}
+		p.driverDefinition = definition
+	}
+
+	podOptions := podman.PodOptions{Ports: nat.PortMap{}}
+	for _, definition := range []docker.App{p.appDefinition, p.driverDefinition} {
+		for name, srv := range definition {
+			podOptions.Hosts = append(podOptions.Hosts, name)
+			podOptions.ExtraHosts = append(podOptions.ExtraHosts, srv.ExtraHosts...)
+			podOptions.ShmSize = max(podOptions.ShmSize, srv.ShmSize)
+			for port, bindings := range srv.Ports {
+				podOptions.Ports[port] = append(podOptions.Ports[port], bindings...)
+			}
+		}
+	}
+
+	if err := p.client.PodCreate(ctx, p.Id(), podOptions); err != nil {
+		return err
+	}
+	p.pod = p.Id()
+	log.Debugf("[runner=%s] successfully created pod %s", p.Id(), p.pod)
+
+	if p.driverDefinition != nil {
+		if err := p.startDriver(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startDriver starts the drivers needed to run the test suite. If there is
+// an error, it is returned.
+func (p *PodmanRunner) startDriver(ctx context.Context) error {
+	driver, err := p.client.Run(ctx, p.driverDefinition, podman.RunOptions{
+		Prefix: p.Id(),
+		Pod:    p.pod,
+	})
+	if err != nil {
+		return err
+	}
+	p.driver = driver
+
+	return nil
+}
+
+// restartDriver replaces the running drivers with new ones. It is used when a
+// schedule timed out, as the drivers may be stuck into the hung test. If there
+// is an error, it is returned.
+func (p *PodmanRunner) restartDriver(ctx context.Context) error {
+	if p.driverDefinition == nil {
+		return nil
+	}
+
+	if err := p.client.Delete(ctx, p.driver); err != nil {
+		return fmt.Errorf("driver deletion failed in driver restart: %w", err)
+	}
+
+	if err := p.startDriver(ctx); err != nil {
+		return fmt.Errorf("driver start-up failed in driver restart: %w", err)
+	}
+	log.Debugf("[runner=%s] successfully restarted driver", p.Id())
+
+	return nil
+}
+
+func WithAppDefinition(definition docker.Definition) func(*PodmanRunner) error {
+	return func(runner *PodmanRunner) error {
+		runner.appDefinitionFiles = definition
+		return nil
+	}
+}
+
+func WithDriverDefinition(definition docker.Definition) func(*PodmanRunner) error {
+	return func(runner *PodmanRunner) error {
+		runner.driverDefinitionFiles = definition
+		return nil
+	}
+}
+
+func WithEnv(env []string) func(*PodmanRunner) error {
+	return func(runner *PodmanRunner) error {
+		runner.env = env
+		return nil
+	}
+}
+
+func WithTestSuite(suite *testsuite.TestSuite) func(*PodmanRunner) error {
+	return func(runner *PodmanRunner) error {
+		runner.testSuite = suite
+		return nil
+	}
+}
+
+// ResetApplication deletes the containers related to the currently running
+// application and starts new ones into the pod of the runner. If there is an
+// error in the process, it is returned.
+func (p *PodmanRunner) ResetApplication(ctx context.Context) error {
+	if err := p.client.Delete(ctx, p.app); err != nil {
+		return fmt.Errorf("app deletion failed in app reset: %w", err)
+	}
+
+	instance, err := p.client.Run(ctx, p.appDefinition, podman.RunOptions{
+		Prefix: p.Id(),
+		Pod:    p.pod,
+	})
+	if err != nil {
+		return fmt.Errorf("app start-up failed in app reset: %w", err)
+	}
+	p.app = instance
+	log.Debugf("[runner=%s] successfully reset app", p.Id())
+
+	return nil
+}
+
+// Delete releases all the resources allocated for the runner. The containers
+// are removed before the pod, so that the volumes created for them are
+// removed too. If there is an error in the process, it is returned.
+func (p *PodmanRunner) Delete(ctx context.Context) error {
+	for _, instance := range []podman.AppInstance{p.app, p.driver} {
+		if err := p.client.Delete(ctx, instance); err != nil {
+			return fmt.Errorf("containers deletion failed when deleting runner %s: %w", p.Id(), err)
+		}
+	}
+
+	if p.pod != "" {
+		if err := p.client.PodRemove(ctx, p.pod); err != nil {
+			return fmt.Errorf("pod deletion failed when deleting runner %s: %w", p.Id(), err)
+		}
+		log.Debugf("[runner=%s] successfully deleted pod", p.Id())
+	}
+	_ = p.client.Close()
+
+	return nil
+}
+
+// Run runs a test schedule on this runner and returns the outcomes of its
+// tests. If there is any error, it is returned. If the schedule exceeds the
+// deadline of the context, the test suite is killed, the drivers are
+// restarted and the outcomes of the tests which completed are returned with
+// the error.
+func (p *PodmanRunner) Run(ctx context.Context, tests []string) (results []runner.TestOutcome, err error) {
+	name := fmt.Sprintf("%s-testsuite", p.Id())
+	suite := docker.App{
+		name: {
+			Command:     tests,
+			Image:       p.testSuite.Image(),
+			Environment: p.env,
+		},
+	}
+
+	instance, err := p.client.Run(ctx, suite, podman.RunOptions{Pod: p.pod})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run test suite on runner %s: %w", p.Id(), err)
+	}
+	defer func() {
+		deleteErr := p.client.Delete(context.WithoutCancel(ctx), instance)
+		if err == nil {
+			err = deleteErr
+		}
+	}()
+
+	logs, err := p.client.FollowContainerLogs(ctx, instance[name], testsuite.NewProgressWriter(ctx, tests))
+	if err != nil && runner.TimedOut(ctx) {
+		ctx := context.WithoutCancel(ctx)
+
+		results = p.interruptedResults(ctx, instance, name, tests)
+		if restartErr := p.restartDriver(ctx); restartErr != nil {
+			log.Errorf("[runner=%s] %v", p.Id(), restartErr)
+		}
+
+		return results, fmt.Errorf("schedule timed out on runner %s: %w", p.Id(), err)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to run test suite on runner %s: %w", p.Id(), err)
+	}
+	log.Debugf("[runner=%s] test suite logs: %s", p.Id(), logs)
+
+	return testsuite.ParseLogs(logs, tests), nil
+}
+
+// interruptedResults kills the container of a test suite whose run was
+// interrupted before completing, and returns the outcomes of the tests which
+// completed before the interruption.
+func (p *PodmanRunner) interruptedResults(ctx context.Context, instance podman.AppInstance, name string, tests []string) []runner.TestOutcome {
+	if err := p.client.Kill(ctx, instance); err != nil {
+		log.Warnf("[runner=%s] failed to kill interrupted test suite container: %v", p.Id(), err)
+		return nil
+	}
+
+	logs, err := p.client.GetContainerLogs(ctx, instance[name])
+	if err != nil {
+		log.Warnf("[runner=%s] failed to obtain logs from interrupted test suite container: %v", p.Id(), err)
+		return nil
+	}
+
+	return testsuite.ParsePartialLogs(logs, tests)
+}
+
+func (p *PodmanRunner) Id() string {
+	return p.id
+}
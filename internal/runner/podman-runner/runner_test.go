@@ -0,0 +1,173 @@
+package podman_runner_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pako-23/gtdd/internal/docker"
+	"github.com/pako-23/gtdd/internal/podman/podmantest"
+	"github.com/pako-23/gtdd/internal/runner"
+	podman_runner "github.com/pako-23/gtdd/internal/runner/podman-runner"
+	"github.com/pako-23/gtdd/internal/testsuite"
+	"gotest.tools/v3/assert"
+)
+
+const appDefinition = `services:
+  app:
+    image: app-image
+    environment:
+      DB_HOST: db
+    ports:
+      - "8080:8080"
+    extra_hosts:
+      - "api.local:10.0.0.1"
+    volumes:
+      - data:/data
+  db:
+    image: db-image
+volumes:
+  data:
+`
+
+const driverDefinition = `services:
+  chrome:
+    image: chrome-image
+    shm_size: 2gb
+`
+
+// newServer starts a fake Podman service and points the Podman clients to
+// it.
+func newServer(t *testing.T) *podmantest.Server {
+	fake := podmantest.NewServer()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	t.Setenv("CONTAINER_HOST", "tcp://"+strings.TrimPrefix(server.URL, "http://"))
+
+	return fake
+}
+
+func writeDefinition(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NilError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	return path
+}
+
+func newSuite(t *testing.T) *testsuite.TestSuite {
+	suite, err := testsuite.NewTestSuite(filepath.Join(t.TempDir(), "my-suite"))
+	assert.NilError(t, err)
+
+	return suite
+}
+
+func newRunner(t *testing.T) *podman_runner.PodmanRunner {
+	r, err := podman_runner.PodmanRunnerBuilder(context.TODO(), "runner-0",
+		podman_runner.WithAppDefinition(docker.Definition{Files: []string{writeDefinition(t, "docker-compose.yml", appDefinition)}}),
+		podman_runner.WithDriverDefinition(docker.Definition{Files: []string{writeDefinition(t, "driver.yml", driverDefinition)}}),
+		podman_runner.WithEnv([]string{"APP_URL=http://app:8080"}),
+		podman_runner.WithTestSuite(newSuite(t)))
+	assert.NilError(t, err)
+
+	return r
+}
+
+func TestPodmanRunnerBuilder(t *testing.T) {
+	fake := newServer(t)
+	r := newRunner(t)
+	assert.Equal(t, r.Id(), "runner-0")
+
+	pod, ok := fake.Pods["runner-0"]
+	assert.Assert(t, ok)
+	assert.Equal(t, *pod.ShmSize, int64(2<<30))
+	assert.Assert(t, len(pod.HostAdd) == 4)
+	for _, host := range []string{"app:127.0.0.1", "db:127.0.0.1", "chrome:127.0.0.1", "api.local:10.0.0.1"} {
+		assert.Assert(t, strings.Contains(strings.Join(pod.HostAdd, ","), host))
+	}
+	assert.DeepEqual(t, pod.PortMappings, []podmantest.PortMapping{{ContainerPort: 8080, Protocol: "tcp"}})
+
+	chrome, ok := fake.Containers["runner-0-chrome"]
+	assert.Assert(t, ok)
+	assert.Equal(t, chrome.Spec.Pod, "runner-0")
+	assert.Equal(t, chrome.Status, "running")
+}
+
+func TestPodmanRunnerBuilderFailure(t *testing.T) {
+	fake := newServer(t)
+	fake.Missing["chrome-image"] = struct{}{}
+
+	_, err := podman_runner.PodmanRunnerBuilder(context.TODO(), "runner-0",
+		podman_runner.WithDriverDefinition(docker.Definition{Files: []string{writeDefinition(t, "driver.yml", driverDefinition)}}),
+		podman_runner.WithTestSuite(newSuite(t)))
+	assert.ErrorContains(t, err, "manifest unknown")
+	assert.Equal(t, len(fake.Pods), 0)
+	assert.Equal(t, len(fake.Containers), 0)
+}
+
+func TestPodmanRunnerResetApplication(t *testing.T) {
+	fake := newServer(t)
+	r := newRunner(t)
+
+	for i := 0; i < 2; i++ {
+		assert.NilError(t, r.ResetApplication(context.TODO()))
+
+		app, ok := fake.Containers["runner-0-app"]
+		assert.Assert(t, ok)
+		assert.DeepEqual(t, app.Spec.Env, map[string]string{"DB_HOST": "db"})
+		assert.DeepEqual(t, app.Spec.Volumes, []podmantest.NamedVolume{{Name: "runner-0-data", Dest: "/data"}})
+		_, ok = fake.Containers["runner-0-db"]
+		assert.Assert(t, ok)
+	}
+}
+
+func TestPodmanRunnerRun(t *testing.T) {
+	fake := newServer(t)
+	fake.ExitCodes["my-suite"] = 0
+	fake.Stdout["my-suite"] = "test1 1\ntest2 0 12 assertion failed\n"
+	r := newRunner(t)
+	tests := []string{"test1", "test2", "test3"}
+
+	results, err := r.Run(context.TODO(), tests)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, results, []runner.TestOutcome{
+		{Status: runner.StatusPass},
+		{Status: runner.StatusFail, Duration: 12e6, Message: "assertion failed"},
+		{Status: runner.StatusNotRun, Message: "the test suite exited before running the test"},
+	})
+
+	_, ok := fake.Containers["runner-0-testsuite"]
+	assert.Assert(t, !ok)
+}
+
+func TestPodmanRunnerDelete(t *testing.T) {
+	fake := newServer(t)
+	r := newRunner(t)
+	assert.NilError(t, r.ResetApplication(context.TODO()))
+
+	_, ok := fake.Volumes["runner-0-data"]
+	assert.Assert(t, ok)
+
+	assert.NilError(t, r.Delete(context.TODO()))
+	assert.Equal(t, len(fake.Pods), 0)
+	assert.Equal(t, len(fake.Containers), 0)
+	assert.Equal(t, len(fake.Volumes), 0)
+}
+
+func TestListTestsAndDigest(t *testing.T) {
+	fake := newServer(t)
+	fake.ExitCodes["my-suite"] = 0
+	fake.Stdout["my-suite"] = "test1\ntest2\n"
+	suite := newSuite(t)
+
+	tests, err := podman_runner.ListTests(context.TODO(), suite)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, tests, []string{"test1", "test2"})
+	assert.Equal(t, len(fake.Containers), 0)
+
+	digest, err := podman_runner.Digest(context.TODO(), suite)
+	assert.NilError(t, err)
+	assert.Equal(t, digest, "sha256-my-suite")
+}
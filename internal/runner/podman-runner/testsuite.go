@@ -0,0 +1,56 @@
+package podman_runner
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pako-23/gtdd/internal/docker"
+	"github.com/pako-23/gtdd/internal/podman"
+	"github.com/pako-23/gtdd/internal/testsuite"
+)
+
+// ListTests returns the tests of a test suite by running its image on
+// Podman. If there is any error, it is returned.
+func ListTests(ctx context.Context, suite *testsuite.TestSuite) (tests []string, err error) {
+	client, err := podman.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	app := docker.App{
+		"testsuite": {
+			Command: []string{"--list-tests"},
+			Image:   suite.Image(),
+		},
+	}
+	instance, err := client.Run(ctx, app, podman.RunOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		deleteErr := client.Delete(context.WithoutCancel(ctx), instance)
+		if err == nil {
+			err = deleteErr
+		}
+	}()
+
+	logs, err := client.GetContainerLogs(ctx, instance["testsuite"])
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Split(strings.Trim(logs, "\n"), "\n"), nil
+}
+
+// Digest returns the content-addressable ID of the image of a test suite
+// stored by Podman. If there is any error, it is returned.
+func Digest(ctx context.Context, suite *testsuite.TestSuite) (string, error) {
+	client, err := podman.NewClient()
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	return client.ImageID(ctx, suite.Image())
+}
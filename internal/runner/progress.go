@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"context"
+	"errors"
+)
+
+type progressKey struct{}
+
+// WithProgress returns a copy of the context through which a runner reports
+// each test of a schedule which completed, by calling the provided function.
+func WithProgress(ctx context.Context, progress func()) context.Context {
+	return context.WithValue(ctx, progressKey{}, progress)
+}
+
+// ReportProgress reports through the context that a test of the schedule
+// being run completed. It does nothing if the context does not track the
+// progress of the schedule.
+func ReportProgress(ctx context.Context) {
+	if progress, ok := ctx.Value(progressKey{}).(func()); ok {
+		progress()
+	}
+}
+
+// TracksProgress reports whether the context tracks the progress of the
+// schedule being run, so that a runner which has to poll the test suite to
+// observe its progress can avoid it otherwise.
+func TracksProgress(ctx context.Context) bool {
+	_, ok := ctx.Value(progressKey{}).(func())
+
+	return ok
+}
+
+// TimedOut reports whether the context was done because the schedule or one
+// of its tests exceeded its timeout.
+func TimedOut(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), context.DeadlineExceeded)
+}
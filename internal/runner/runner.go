@@ -1,11 +1,24 @@
 package runner
 
+import "context"
+
 type Runner interface {
-	ResetApplication() error
-	Delete() error
-	Run(tests []string) ([]bool, error)
+	ResetApplication(ctx context.Context) error
+	Delete(ctx context.Context) error
+	// Run runs the tests in order and returns their outcomes. If the context
+	// is done before all the tests complete, the outcomes of the tests which
+	// completed are returned together with the error.
+	Run(ctx context.Context, tests []string) ([]TestOutcome, error)
 	Id() string
 }
 
 type RunnerOption[T Runner] func(runner T) error
-type RunnerBuilder[T Runner] func(id string, options ...RunnerOption[T]) (T, error)
+type RunnerBuilder[T Runner] func(ctx context.Context, id string, options ...RunnerOption[T]) (T, error)
+
+// ScheduleRunner is implemented by anything able to run test schedules and
+// report their results. A RunnerSet is the main implementation; other
+// implementations wrap it to add behaviour on top of it.
+type ScheduleRunner interface {
+	RunSchedule(ctx context.Context, schedule []string) (RunResults, error)
+	Size() int
+}
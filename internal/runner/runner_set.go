@@ -17,40 +17,249 @@ const DefaultSetSize = 1
 
 var (
 	ErrNoRunner           = errors.New("no runner to reserve")
+	ErrRunnerSetDeleted   = errors.New("the set of runners was deleted")
 	ErrWrongRunnerSetSize = errors.New("a runner set must have at least size 1")
+	ErrWrongScaling       = errors.New("an elastic runner set must have a minimum size of at least 1, not above its maximum size")
 )
 
 type RunResults struct {
-	Results     []bool
+	// The outcome of each test into the schedule.
+	Results     []TestOutcome
 	RunningTime time.Duration
+	// The identifier of the runner which ran the schedule.
+	Runner string
+}
+
+// ErrTestTimeout is the cause of the interruption of a schedule whose running
+// test exceeded the time allowed to each test.
+var ErrTestTimeout = fmt.Errorf("test timed out: %w", context.DeadlineExceeded)
+
+// Timeouts bounds the time allowed to run a schedule. A zero value disables
+// the corresponding timeout.
+type Timeouts struct {
+	// The maximum time allowed to each test into a schedule. It is measured
+	// from the start of the schedule or from the outcome of the previous
+	// test, as reported by the runner.
+	Test time.Duration
+	// The maximum time allowed to a whole schedule.
+	Schedule time.Duration
+}
+
+// withLimit returns a context which is done when the time allowed to run a
+// schedule or the test being run is over. The time allowed to a test starts
+// again each time the runner reports through the context that a test
+// completed.
+func (t Timeouts) withLimit(ctx context.Context) (context.Context, context.CancelFunc) {
+	cancelSchedule := context.CancelFunc(func() {})
+	if t.Schedule > 0 {
+		ctx, cancelSchedule = context.WithTimeout(ctx, t.Schedule)
+	}
+
+	if t.Test <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+
+		return ctx, func() {
+			cancel()
+			cancelSchedule()
+		}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(t.Test, func() { cancel(ErrTestTimeout) })
+
+	return WithProgress(ctx, func() { timer.Reset(t.Test) }), func() {
+		timer.Stop()
+		cancel(context.Canceled)
+		cancelSchedule()
+	}
+}
+
+// DefaultRecovery is the policy used by a set of runners to recover from
+// infrastructure failures, unless another one is set.
+var DefaultRecovery = Recovery{
+	Rebuilds:   3,
+	Retries:    2,
+	Backoff:    time.Second,
+	MaxBackoff: 30 * time.Second,
+}
+
+// Recovery controls how a set of runners recovers from infrastructure
+// failures, that is an application which cannot be reset or a runner which
+// fails to run a schedule.
+type Recovery struct {
+	// The maximum number of consecutive attempts to rebuild a failed runner
+	// before removing it from the set.
+	Rebuilds int
+	// The maximum number of times a schedule is run again on another runner
+	// after an infrastructure failure.
+	Retries int
+	// The time to wait before the first attempt to rebuild a runner, which
+	// doubles after each failed attempt.
+	Backoff time.Duration
+	// The maximum time to wait before an attempt to rebuild a runner. A zero
+	// value disables it.
+	MaxBackoff time.Duration
+}
+
+// backoff returns the time to wait before the provided attempt to rebuild a
+// runner, starting from zero.
+func (r Recovery) backoff(attempt int) time.Duration {
+	backoff := r.Backoff
+	for i := 0; i < attempt && (r.MaxBackoff == 0 || backoff < r.MaxBackoff); i++ {
+		backoff *= 2
+	}
+
+	if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+		return r.MaxBackoff
+	}
+
+	return backoff
+}
+
+// Health counts the infrastructure failures a set of runners went through.
+type Health struct {
+	// The number of runners into the set, including the quarantined ones.
+	Runners int
+	// The number of runners currently quarantined while being rebuilt.
+	Quarantined int
+	// The number of runners which were rebuilt successfully.
+	Rebuilds int
+	// The number of attempts to rebuild a runner which failed.
+	FailedRebuilds int
+	// The number of runners removed from the set after all the attempts to
+	// rebuild them failed.
+	Removed int
+	// The number of schedules run again after an infrastructure failure.
+	Retries int
+}
+
+func (h Health) String() string {
+	return fmt.Sprintf("%d runners, %d quarantined, %d rebuilt, %d failed rebuilds, %d removed, %d retried schedules",
+		h.Runners, h.Quarantined, h.Rebuilds, h.FailedRebuilds, h.Removed, h.Retries)
+}
+
+// Scaling controls how an elastic set of runners grows when the schedules
+// wait for a runner and shrinks when its runners are idle.
+type Scaling struct {
+	// The minimum number of runners into the set, created with the set.
+	Min int
+	// The maximum number of runners into the set.
+	Max int
+	// The time the runners of the set stay idle before a runner is removed.
+	// A zero value disables shrinking the set.
+	IdleTimeout time.Duration
+	// The time between two checks of the load of the set. A zero value
+	// checks it every second.
+	Interval time.Duration
+	// Reports whether the host has the resources to run one more runner. A
+	// nil function does not bound the growth of the set.
+	Headroom func() bool
 }
 
 // RunnerSet represents a group of runners used to run a test suites.
 type RunnerSet struct {
 	runners chan Runner
 	reset   chan Runner
-	size    atomic.Int32
-	ctx     context.Context
-	cancel  context.CancelFunc
+	// Receives the runners which failed to run a schedule, to be rebuilt.
+	broken chan Runner
+	// Notified each time a runner is removed from the set because it failed.
+	removed chan struct{}
+	// Closed when all the runners were removed from the set.
+	empty     chan struct{}
+	emptyOnce sync.Once
+	size      atomic.Int32
+	// The number of schedules waiting for a runner.
+	waiting atomic.Int32
+	// The identifier of the next runner added to the set.
+	nextID int
+	// The scaling of an elastic set, whose maximum is zero for a set with
+	// a fixed number of runners.
+	scaling Scaling
+	scaler  sync.WaitGroup
+	// Builds a runner with the configuration of the set.
+	build func(ctx context.Context, id string) (Runner, error)
+	// The timeouts applied to each schedule run on the set.
+	timeouts Timeouts
+	// The policy used to recover from infrastructure failures.
+	recovery Recovery
+	health   struct {
+		quarantined    atomic.Int32
+		rebuilds       atomic.Int32
+		failedRebuilds atomic.Int32
+		removed        atomic.Int32
+		retries        atomic.Int32
+	}
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NewRunnerSet creates a new set of runner with the provided configuration.
-// If there is an error in creating the set of runners, it is returned.
-func NewRunnerSet[T Runner](size int, builder RunnerBuilder[T], options ...RunnerOption[T]) (*RunnerSet, error) {
-	var n sync.WaitGroup
-
+// The runners of the set are reset until the provided context is done or the
+// set is deleted. A runner whose application cannot be reset when the set is
+// created is removed from the set, while the runners failing afterwards are
+// rebuilt with the same configuration. If there is an error in creating the
+// set of runners, it is returned.
+func NewRunnerSet[T Runner](ctx context.Context, size int, builder RunnerBuilder[T], options ...RunnerOption[T]) (*RunnerSet, error) {
 	if size < 1 {
 		return nil, ErrWrongRunnerSetSize
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	return newRunnerSet(ctx, size, size, builder, options...)
+}
+
+// NewElasticRunnerSet creates a new set of runners with the provided
+// configuration, which starts with the minimum number of runners of the
+// scaling. The set grows up to its maximum number of runners while the
+// schedules wait for a runner and the host has enough resources, and shrinks
+// back to its minimum number of runners when they are idle. If there is an
+// error in creating the set of runners, it is returned.
+func NewElasticRunnerSet[T Runner](ctx context.Context, scaling Scaling, builder RunnerBuilder[T], options ...RunnerOption[T]) (*RunnerSet, error) {
+	if scaling.Min < 1 || scaling.Max < scaling.Min {
+		return nil, ErrWrongScaling
+	}
+
+	if scaling.Interval <= 0 {
+		scaling.Interval = time.Second
+	}
+
+	set, err := newRunnerSet(ctx, scaling.Min, scaling.Max, builder, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	set.scaling = scaling
+	set.scaler.Add(1)
+	go set.scale()
+
+	return set, nil
+}
+
+// newRunnerSet creates a new set with the provided number of runners, which
+// can hold up to the provided capacity of runners.
+func newRunnerSet[T Runner](ctx context.Context, size, capacity int, builder RunnerBuilder[T], options ...RunnerOption[T]) (*RunnerSet, error) {
+	var n sync.WaitGroup
+
+	ctx, cancel := context.WithCancel(ctx)
 
 	set := &RunnerSet{
-		runners: make(chan Runner, size),
+		runners: make(chan Runner, capacity),
 		reset:   make(chan Runner),
+		broken:  make(chan Runner),
+		removed: make(chan struct{}, 1),
+		empty:   make(chan struct{}),
 		size:    atomic.Int32{},
-		ctx:     ctx,
-		cancel:  cancel,
+		nextID:  size,
+		build: func(ctx context.Context, id string) (Runner, error) {
+			runner, err := builder(ctx, id, options...)
+			if err != nil {
+				return nil, err
+			}
+
+			return runner, nil
+		},
+		recovery: DefaultRecovery,
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 
 	set.size.Store(int32(size))
@@ -58,20 +267,20 @@ func NewRunnerSet[T Runner](size int, builder RunnerBuilder[T], options ...Runne
 	for i := 0; i < size; i++ {
 		var runnerName = fmt.Sprintf("runner-%d", i)
 
-		runner, err := builder(runnerName, options...)
+		runner, err := builder(ctx, runnerName, options...)
 		if err != nil {
-			if deleteErr := set.Delete(); deleteErr != nil {
+			set.size.Add(-int32(size - i))
+			if deleteErr := set.Delete(context.WithoutCancel(ctx)); deleteErr != nil {
 				log.Errorf("failed to delete runner %s: %v", runnerName, deleteErr)
 			}
 
-			set.cancel()
 			return nil, err
 		}
 
 		n.Add(1)
 		go func() {
 			defer n.Done()
-			set.release()
+			set.release(false)
 		}()
 
 		set.reset <- runner
@@ -79,63 +288,259 @@ func NewRunnerSet[T Runner](size int, builder RunnerBuilder[T], options ...Runne
 
 	n.Wait()
 
-	for i := 0; i < set.Size(); i++ {
+	for i := 0; i < capacity; i++ {
 		go func() {
-			for {
-				if !set.release() {
-					return
-				}
+			for set.ctx.Err() == nil {
+				set.release(true)
 			}
 		}()
 	}
 
-	log.Infof("successfully initialized %d runners", set.Size())
+	log.Infof("successfully initialized %d runners", set.live())
 
 	return set, nil
 }
 
-func (r *RunnerSet) release() bool {
+// release resets the application of a runner given back to the set, so that
+// it can run another schedule. If the application cannot be reset or the
+// runner failed to run a schedule, the runner is quarantined and rebuilt if
+// requested, or removed from the set otherwise.
+func (r *RunnerSet) release(quarantine bool) {
+	var runner Runner
+
 	select {
-	case runner := <-r.reset:
-		if err := runner.ResetApplication(); err != nil {
-			log.Errorf("failed to reset application on runner %s: %v", runner.Id(), err)
+	case runner = <-r.reset:
+		err := runner.ResetApplication(r.ctx)
+		if err == nil {
+			r.runners <- runner
+			return
+		}
+		log.Errorf("failed to reset application on runner %s: %v", runner.Id(), err)
+	case runner = <-r.broken:
+	case <-r.ctx.Done():
+		return
+	}
 
-			if err = runner.Delete(); err != nil {
-				log.Errorf("failed to delete runner %s: %v", runner.Id(), err)
+	if err := runner.Delete(context.WithoutCancel(r.ctx)); err != nil {
+		log.Errorf("failed to delete runner %s: %v", runner.Id(), err)
+	}
+
+	if !quarantine || r.ctx.Err() != nil {
+		r.remove()
+		return
+	}
+
+	r.rebuild(runner.Id())
+}
+
+// rebuild quarantines the runner with the provided identifier until it is
+// built again, and removes it from the set if it cannot be built.
+func (r *RunnerSet) rebuild(id string) {
+	r.health.quarantined.Add(1)
+	runner := r.rebuildRunner(id)
+	r.health.quarantined.Add(-1)
+
+	if runner == nil {
+		r.remove()
+		return
+	}
+
+	r.runners <- runner
+}
+
+// rebuildRunner builds again the runner with the provided identifier,
+// waiting longer after each failed attempt. If all the attempts fail or the
+// set is deleted, it returns nil.
+func (r *RunnerSet) rebuildRunner(id string) Runner {
+	for attempt := 0; attempt < r.recovery.Rebuilds; attempt++ {
+		select {
+		case <-time.After(r.recovery.backoff(attempt)):
+		case <-r.ctx.Done():
+			return nil
+		}
+
+		runner, err := r.build(r.ctx, id)
+		if err == nil {
+			if err = runner.ResetApplication(r.ctx); err == nil {
+				log.Infof("rebuilt runner %s", id)
+				r.health.rebuilds.Add(1)
+				return runner
+			}
+
+			if deleteErr := runner.Delete(context.WithoutCancel(r.ctx)); deleteErr != nil {
+				log.Errorf("failed to delete runner %s: %v", id, deleteErr)
 			}
+		}
 
-			r.size.Add(-1)
-			return false
+		if r.ctx.Err() != nil {
+			return nil
 		}
-		r.runners <- runner
-		return true
-	case <-r.ctx.Done():
-		return false
+
+		log.Errorf("failed to rebuild runner %s (attempt %d of %d): %v", id, attempt+1, r.recovery.Rebuilds, err)
+		r.health.failedRebuilds.Add(1)
+	}
+
+	log.Errorf("removing runner %s after %d failed rebuilds", id, r.recovery.Rebuilds)
+	r.health.removed.Add(1)
+
+	return nil
+}
+
+// remove removes a runner from the set.
+func (r *RunnerSet) remove() {
+	if r.size.Add(-1) == 0 {
+		r.emptyOnce.Do(func() { close(r.empty) })
 	}
 
+	select {
+	case r.removed <- struct{}{}:
+	default:
+	}
 }
 
+// scale grows the set while the schedules wait for a runner and shrinks it
+// while its runners are idle, until the set is deleted.
+func (r *RunnerSet) scale() {
+	defer r.scaler.Done()
+
+	ticker := time.NewTicker(r.scaling.Interval)
+	defer ticker.Stop()
+
+	busy := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.ctx.Done():
+			return
+		}
+
+		if r.waiting.Load() > 0 || len(r.runners) == 0 {
+			busy = time.Now()
+		}
+
+		switch {
+		case r.waiting.Load() > 0 && r.live() > 0 && r.live() < r.scaling.Max:
+			if r.scaling.Headroom == nil || r.scaling.Headroom() {
+				r.grow()
+			}
+		case r.scaling.IdleTimeout > 0 && time.Since(busy) >= r.scaling.IdleTimeout && r.live() > r.scaling.Min:
+			r.shrink()
+			busy = time.Now()
+		}
+	}
+}
+
+// grow adds a new runner to the set.
+func (r *RunnerSet) grow() {
+	id := fmt.Sprintf("runner-%d", r.nextID)
+	r.nextID++
+
+	runner, err := r.build(r.ctx, id)
+	if err != nil {
+		log.Errorf("failed to add runner %s: %v", id, err)
+		return
+	}
+
+	if err := runner.ResetApplication(r.ctx); err != nil {
+		log.Errorf("failed to reset application on runner %s: %v", id, err)
+
+		if err := runner.Delete(context.WithoutCancel(r.ctx)); err != nil {
+			log.Errorf("failed to delete runner %s: %v", id, err)
+		}
+
+		return
+	}
+
+	r.size.Add(1)
+	r.runners <- runner
+	log.Infof("added runner %s, the set has %d runners", id, r.live())
+}
+
+// shrink removes an idle runner from the set, if there is any.
+func (r *RunnerSet) shrink() {
+	select {
+	case runner := <-r.runners:
+		r.size.Add(-1)
+
+		if err := runner.Delete(context.WithoutCancel(r.ctx)); err != nil {
+			log.Errorf("failed to delete runner %s: %v", runner.Id(), err)
+		}
+		log.Infof("removed idle runner %s, the set has %d runners", runner.Id(), r.live())
+	default:
+	}
+}
+
+// SetTimeouts sets the timeouts applied to the schedules run on the set. It
+// should be called before running any schedule.
+func (r *RunnerSet) SetTimeouts(timeouts Timeouts) {
+	r.timeouts = timeouts
+}
+
+// SetRecovery sets the policy used to recover from infrastructure failures.
+// It should be called before running any schedule.
+func (r *RunnerSet) SetRecovery(recovery Recovery) {
+	r.recovery = recovery
+}
+
+// Size returns the number of schedules the set can run concurrently, that is
+// the number of runners into the set, including the ones which are
+// quarantined while being rebuilt, or the maximum number of runners of an
+// elastic set.
 func (r *RunnerSet) Size() int {
+	return max(r.live(), r.scaling.Max)
+}
+
+// live returns the number of runners into the set, including the ones which
+// are quarantined while being rebuilt.
+func (r *RunnerSet) live() int {
 	return int(r.size.Load())
 }
 
-// Delete releases all the resources needed by the set of runners.
+// Health returns the counters of the infrastructure failures the set went
+// through.
+func (r *RunnerSet) Health() Health {
+	return Health{
+		Runners:        r.live(),
+		Quarantined:    int(r.health.quarantined.Load()),
+		Rebuilds:       int(r.health.rebuilds.Load()),
+		FailedRebuilds: int(r.health.failedRebuilds.Load()),
+		Removed:        int(r.health.removed.Load()),
+		Retries:        int(r.health.retries.Load()),
+	}
+}
+
+// Delete releases all the resources needed by the set of runners. It waits
+// for the schedules being run to complete before deleting their runners.
 // If there is an error in the process, it is returned.
-func (r *RunnerSet) Delete() error {
+func (r *RunnerSet) Delete(ctx context.Context) error {
 	var waitgroup errgroup.Group
 
 	r.cancel()
+	r.scaler.Wait()
 
-	for i := 0; i < r.Size(); i++ {
-		runner := <-r.runners
+	for collected := 0; collected < r.live(); {
+		var runner Runner
+
+		select {
+		case runner = <-r.runners:
+		case runner = <-r.reset:
+		case runner = <-r.broken:
+		case <-r.removed:
+			continue
+		}
+		collected++
 
 		waitgroup.Go(func(runner Runner) func() error {
 			return func() error {
-				return runner.Delete()
+				return runner.Delete(ctx)
 			}
 		}(runner))
 	}
 
+	if health := r.Health(); health.FailedRebuilds > 0 || health.Rebuilds > 0 || health.Retries > 0 {
+		log.Warnf("recovered from infrastructure failures: %v", health)
+	}
+
 	if err := waitgroup.Wait(); err != nil {
 		return fmt.Errorf("failed to delete set of runners: %w", err)
 	}
@@ -143,20 +548,118 @@ func (r *RunnerSet) Delete() error {
 	return nil
 }
 
-func (r *RunnerSet) RunSchedule(schedule []string) (RunResults, error) {
-	if r.Size() == 0 {
-		return RunResults{}, ErrNoRunner
+// RunSchedule runs a schedule on the first available runner of the set. If
+// the runner fails to run the schedule, it is quarantined and the schedule is
+// run again on another runner, as allowed by the recovery policy. If the
+// context is done before a runner is available, the context error is
+// returned.
+func (r *RunnerSet) RunSchedule(ctx context.Context, schedule []string) (RunResults, error) {
+	for retry := 0; ; retry++ {
+		results, broken, err := r.runSchedule(ctx, schedule)
+		if !broken || retry >= r.recovery.Retries {
+			return results, err
+		}
+
+		log.Warnf("running schedule %v again after infrastructure failure: %v", schedule, err)
+		r.health.retries.Add(1)
 	}
+}
+
+// runSchedule runs a schedule once on the first available runner of the set.
+// It reports whether the runner failed to run the schedule, in which case the
+// runner is given back to the set to be rebuilt.
+func (r *RunnerSet) runSchedule(ctx context.Context, schedule []string) (RunResults, bool, error) {
+	runner, err := r.reserve(ctx)
+	if err != nil {
+		return RunResults{}, false, err
+	}
+
+	runCtx, cancel := r.timeouts.withLimit(ctx)
+	defer cancel()
 
-	runner := <-r.runners
 	start := time.Now()
-	result, err := runner.Run(schedule)
+	result, err := runner.Run(runCtx, schedule)
 	duration := time.Since(start)
 
-	r.reset <- runner
+	broken := false
+
+	switch {
+	case err == nil || ctx.Err() != nil:
+		r.reset <- runner
+	case TimedOut(runCtx):
+		r.reset <- runner
+
+		message := fmt.Sprintf("the schedule timed out after %v", duration.Round(time.Millisecond))
+		if errors.Is(context.Cause(runCtx), ErrTestTimeout) {
+			message = fmt.Sprintf("the test timed out after %v without an outcome", r.timeouts.Test)
+		}
+		log.Warnf("schedule %v timed out after %v on runner %s: %s", schedule, duration, runner.Id(), message)
+
+		results := timedOutResults(schedule, result, duration, message)
+		results.Runner = runner.Id()
+
+		return results, false, nil
+	default:
+		broken = true
+		r.broken <- runner
+	}
 
 	return RunResults{
 		Results:     result,
 		RunningTime: duration,
-	}, err
+		Runner:      runner.Id(),
+	}, broken, err
+}
+
+// reserve returns the first available runner of the set. While no runner is
+// available, the schedule is counted as waiting, so that an elastic set can
+// grow. If the context is done before a runner is available, the context
+// error is returned.
+func (r *RunnerSet) reserve(ctx context.Context) (Runner, error) {
+	if r.live() == 0 {
+		return nil, ErrNoRunner
+	}
+
+	select {
+	case runner := <-r.runners:
+		return runner, nil
+	default:
+	}
+
+	r.waiting.Add(1)
+	defer r.waiting.Add(-1)
+
+	select {
+	case runner := <-r.runners:
+		return runner, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-r.ctx.Done():
+		return nil, ErrRunnerSetDeleted
+	case <-r.empty:
+		return nil, ErrNoRunner
+	}
+}
+
+// timedOutResults returns the results of a schedule which exceeded its
+// timeout or whose running test exceeded its own. The tests are run in order,
+// so the partial results collected before the timeout belong to the first
+// tests of the schedule. The test running when the timeout expired is
+// reported as timed out with the provided message, while the following ones
+// are reported as not run.
+func timedOutResults(schedule []string, partial []TestOutcome, duration time.Duration, message string) RunResults {
+	results := RunResults{
+		Results:     make([]TestOutcome, len(schedule)),
+		RunningTime: duration,
+	}
+
+	copy(results.Results, partial)
+	if len(partial) < len(schedule) {
+		results.Results[len(partial)] = TestOutcome{
+			Status:  StatusTimeout,
+			Message: message,
+		}
+	}
+
+	return results
 }
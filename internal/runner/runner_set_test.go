@@ -1,10 +1,14 @@
 package runner_test
 
 import (
+	"context"
 	"errors"
 	"math/rand"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/pako-23/gtdd/internal/runner"
 	"gotest.tools/v3/assert"
@@ -16,13 +20,19 @@ type mockRunner struct {
 	name       string
 	failDelete bool
 	failReset  bool
+	blockRun   bool
+	// The number of the next resets and runs which fail, shared by all the
+	// runners built with the same options.
+	resetFailures *atomic.Int32
+	runFailures   *atomic.Int32
 }
 
-func newMockRunnerBuilder(id string, options ...runner.RunnerOption[*mockRunner]) (*mockRunner, error) {
+func newMockRunnerBuilder(ctx context.Context, id string, options ...runner.RunnerOption[*mockRunner]) (*mockRunner, error) {
 	r := &mockRunner{
 		name:       id,
 		failDelete: false,
 		failReset:  false,
+		blockRun:   false,
 	}
 
 	for _, option := range options {
@@ -48,21 +58,42 @@ func withFailReset() func(*mockRunner) error {
 	}
 }
 
+func withBlockingRun() func(*mockRunner) error {
+	return func(r *mockRunner) error {
+		r.blockRun = true
+		return nil
+	}
+}
+
+func withFlakyReset(failures *atomic.Int32) func(*mockRunner) error {
+	return func(r *mockRunner) error {
+		r.resetFailures = failures
+		return nil
+	}
+}
+
+func withFlakyRun(failures *atomic.Int32) func(*mockRunner) error {
+	return func(r *mockRunner) error {
+		r.runFailures = failures
+		return nil
+	}
+}
+
 func withFailOption() func(*mockRunner) error {
 	return func(r *mockRunner) error {
 		return errInjectedFailure
 	}
 }
 
-func (m *mockRunner) ResetApplication() error {
-	if m.failReset {
+func (m *mockRunner) ResetApplication(ctx context.Context) error {
+	if m.failReset || (m.resetFailures != nil && m.resetFailures.Add(-1) >= 0) {
 		return errInjectedFailure
 	}
 
 	return nil
 }
 
-func (m *mockRunner) Delete() error {
+func (m *mockRunner) Delete(ctx context.Context) error {
 	if m.failDelete {
 		return errInjectedFailure
 	}
@@ -70,11 +101,36 @@ func (m *mockRunner) Delete() error {
 	return nil
 }
 
-func (m *mockRunner) Run(tests []string) ([]bool, error) {
-	results := make([]bool, len(tests))
+func (m *mockRunner) Run(ctx context.Context, tests []string) ([]runner.TestOutcome, error) {
+	if m.blockRun {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	if m.runFailures != nil && m.runFailures.Add(-1) >= 0 {
+		return nil, errInjectedFailure
+	}
+
+	results := make([]runner.TestOutcome, 0, len(tests))
 
 	for i := range tests {
-		results[i] = tests[i] == "PASS"
+		if tests[i] == "HANG" {
+			<-ctx.Done()
+
+			return results, ctx.Err()
+		}
+
+		if tests[i] == "SLOW" {
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		if tests[i] == "PASS" || tests[i] == "SLOW" {
+			results = append(results, runner.TestOutcome{Status: runner.StatusPass})
+		} else {
+			results = append(results, runner.TestOutcome{Status: runner.StatusFail})
+		}
+		runner.ReportProgress(ctx)
 	}
 
 	return results, nil
@@ -89,7 +145,7 @@ func TestNewRunnerSet(t *testing.T) {
 
 	for i := 0; i < 100; i++ {
 		size := rand.Intn(10) + 1
-		set, err := runner.NewRunnerSet(size, newMockRunnerBuilder)
+		set, err := runner.NewRunnerSet(context.Background(), size, newMockRunnerBuilder)
 
 		assert.NilError(t, err)
 		assert.Equal(t, set.Size(), size)
@@ -100,7 +156,7 @@ func TestNewRunnerSetInvalidSize(t *testing.T) {
 	t.Parallel()
 
 	for i := 0; i < 100; i++ {
-		_, err := runner.NewRunnerSet(-rand.Intn(10), newMockRunnerBuilder)
+		_, err := runner.NewRunnerSet(context.Background(), -rand.Intn(10), newMockRunnerBuilder)
 		assert.ErrorIs(t, err, runner.ErrWrongRunnerSetSize)
 	}
 }
@@ -109,10 +165,10 @@ func TestRunnerSetDelete(t *testing.T) {
 	t.Parallel()
 
 	for i := 0; i < 100; i++ {
-		set, err := runner.NewRunnerSet(rand.Intn(10)+1, newMockRunnerBuilder)
+		set, err := runner.NewRunnerSet(context.Background(), rand.Intn(10)+1, newMockRunnerBuilder)
 
 		assert.NilError(t, err)
-		assert.NilError(t, set.Delete())
+		assert.NilError(t, set.Delete(context.Background()))
 	}
 }
 
@@ -122,7 +178,7 @@ func TestRunSchedule(t *testing.T) {
 	for i := 0; i < 100; i++ {
 		var n sync.WaitGroup
 
-		set, err := runner.NewRunnerSet(rand.Intn(10)+1, newMockRunnerBuilder)
+		set, err := runner.NewRunnerSet(context.Background(), rand.Intn(10)+1, newMockRunnerBuilder)
 		assert.NilError(t, err)
 
 		size := rand.Intn(15) + 1
@@ -141,13 +197,13 @@ func TestRunSchedule(t *testing.T) {
 					}
 				}
 
-				results, err := set.RunSchedule(schedule)
+				results, err := set.RunSchedule(context.Background(), schedule)
 
 				assert.NilError(t, err)
 				assert.Equal(t, len(schedule), len(results.Results))
 
 				for k := range schedule {
-					assert.Equal(t, schedule[k] == "PASS", results.Results[k])
+					assert.Equal(t, schedule[k] == "PASS", results.Results[k].Passed())
 				}
 
 				assert.Check(t, results.RunningTime > 0)
@@ -157,3 +213,279 @@ func TestRunSchedule(t *testing.T) {
 		n.Wait()
 	}
 }
+
+func TestNewRunnerSetBuilderFailure(t *testing.T) {
+	t.Parallel()
+
+	set, err := runner.NewRunnerSet(context.Background(), 3, newMockRunnerBuilder, withFailOption())
+	assert.ErrorIs(t, err, errInjectedFailure)
+	assert.Check(t, set == nil)
+}
+
+func TestRunScheduleCancelled(t *testing.T) {
+	t.Parallel()
+
+	set, err := runner.NewRunnerSet(context.Background(), 1, newMockRunnerBuilder, withBlockingRun())
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = set.RunSchedule(ctx, []string{"PASS"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = set.RunSchedule(ctx, []string{"PASS"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.NilError(t, set.Delete(context.Background()))
+}
+
+func TestRunnerSetDeleteDuringRun(t *testing.T) {
+	t.Parallel()
+
+	var n sync.WaitGroup
+
+	set, err := runner.NewRunnerSet(context.Background(), 2, newMockRunnerBuilder, withBlockingRun())
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	for i := 0; i < 4; i++ {
+		n.Add(1)
+		go func() {
+			defer n.Done()
+			_, err := set.RunSchedule(ctx, []string{"PASS"})
+			assert.Check(t, err != nil)
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	assert.NilError(t, set.Delete(context.Background()))
+	n.Wait()
+
+	_, err = set.RunSchedule(context.Background(), []string{"PASS"})
+	assert.ErrorIs(t, err, runner.ErrRunnerSetDeleted)
+}
+
+func TestRunnerSetResetFailure(t *testing.T) {
+	t.Parallel()
+
+	set, err := runner.NewRunnerSet(context.Background(), 2, newMockRunnerBuilder, withFailReset())
+	assert.NilError(t, err)
+	assert.Equal(t, set.Size(), 0)
+
+	_, err = set.RunSchedule(context.Background(), []string{"PASS"})
+	assert.ErrorIs(t, err, runner.ErrNoRunner)
+	assert.NilError(t, set.Delete(context.Background()))
+}
+
+func TestRunnerSetRebuildAfterResetFailure(t *testing.T) {
+	t.Parallel()
+
+	var failures atomic.Int32
+
+	set, err := runner.NewRunnerSet(context.Background(), 1, newMockRunnerBuilder, withFlakyReset(&failures))
+	assert.NilError(t, err)
+	set.SetRecovery(runner.Recovery{Rebuilds: 3, Backoff: time.Millisecond})
+
+	failures.Store(2)
+	for i := 0; i < 3; i++ {
+		results, err := set.RunSchedule(context.Background(), []string{"PASS"})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, statuses(results), []runner.Status{runner.StatusPass})
+	}
+
+	assert.DeepEqual(t, set.Health(), runner.Health{Runners: 1, Rebuilds: 1, FailedRebuilds: 1})
+	assert.NilError(t, set.Delete(context.Background()))
+}
+
+func TestRunScheduleRetryAfterRunFailure(t *testing.T) {
+	t.Parallel()
+
+	var failures atomic.Int32
+
+	set, err := runner.NewRunnerSet(context.Background(), 2, newMockRunnerBuilder, withFlakyRun(&failures))
+	assert.NilError(t, err)
+	set.SetRecovery(runner.Recovery{Rebuilds: 1, Retries: 2, Backoff: time.Millisecond})
+
+	failures.Store(2)
+	results, err := set.RunSchedule(context.Background(), []string{"PASS", "FAIL"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, statuses(results), []runner.Status{runner.StatusPass, runner.StatusFail})
+
+	failures.Store(3)
+	_, err = set.RunSchedule(context.Background(), []string{"PASS"})
+	assert.ErrorIs(t, err, errInjectedFailure)
+
+	health := set.Health()
+	assert.Equal(t, health.Retries, 4)
+	assert.Equal(t, health.Runners, 2)
+	assert.NilError(t, set.Delete(context.Background()))
+}
+
+func TestRunnerSetRemoveAfterFailedRebuilds(t *testing.T) {
+	t.Parallel()
+
+	var failures atomic.Int32
+
+	set, err := runner.NewRunnerSet(context.Background(), 1, newMockRunnerBuilder, withFlakyReset(&failures))
+	assert.NilError(t, err)
+	set.SetRecovery(runner.Recovery{Rebuilds: 2, Backoff: time.Millisecond})
+
+	failures.Store(1000)
+	_, err = set.RunSchedule(context.Background(), []string{"PASS"})
+	assert.NilError(t, err)
+
+	_, err = set.RunSchedule(context.Background(), []string{"PASS"})
+	assert.ErrorIs(t, err, runner.ErrNoRunner)
+
+	health := set.Health()
+	assert.Equal(t, health.Runners, 0)
+	assert.Equal(t, health.FailedRebuilds, 2)
+	assert.Equal(t, health.Removed, 1)
+	assert.NilError(t, set.Delete(context.Background()))
+}
+
+func TestNewElasticRunnerSetInvalidScaling(t *testing.T) {
+	t.Parallel()
+
+	var tests = []runner.Scaling{
+		{Min: 0, Max: 3},
+		{Min: 3, Max: 2},
+		{Min: -1, Max: 0},
+	}
+
+	for _, scaling := range tests {
+		_, err := runner.NewElasticRunnerSet(context.Background(), scaling, newMockRunnerBuilder)
+		assert.ErrorIs(t, err, runner.ErrWrongScaling)
+	}
+}
+
+func TestElasticRunnerSetScaling(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		headroom bool
+		expected func(int) bool
+	}{
+		{true, func(runners int) bool { return runners > 1 && runners <= 3 }},
+		{false, func(runners int) bool { return runners == 1 }},
+	}
+
+	for _, test := range tests {
+		var n sync.WaitGroup
+
+		set, err := runner.NewElasticRunnerSet(context.Background(), runner.Scaling{
+			Min:      1,
+			Max:      3,
+			Interval: 5 * time.Millisecond,
+			Headroom: func() bool { return test.headroom },
+		}, newMockRunnerBuilder)
+		assert.NilError(t, err)
+		assert.Equal(t, set.Size(), 3)
+
+		for i := 0; i < set.Size(); i++ {
+			n.Add(1)
+			go func() {
+				defer n.Done()
+
+				for j := 0; j < 10; j++ {
+					results, err := set.RunSchedule(context.Background(), []string{"SLOW"})
+					assert.NilError(t, err)
+					assert.DeepEqual(t, statuses(results), []runner.Status{runner.StatusPass})
+				}
+			}()
+		}
+
+		n.Wait()
+
+		assert.Check(t, test.expected(set.Health().Runners), "runners: %d", set.Health().Runners)
+		assert.NilError(t, set.Delete(context.Background()))
+	}
+}
+
+func TestElasticRunnerSetShrinksWhenIdle(t *testing.T) {
+	t.Parallel()
+
+	var n sync.WaitGroup
+
+	set, err := runner.NewElasticRunnerSet(context.Background(), runner.Scaling{
+		Min:         1,
+		Max:         3,
+		IdleTimeout: 20 * time.Millisecond,
+		Interval:    5 * time.Millisecond,
+	}, newMockRunnerBuilder)
+	assert.NilError(t, err)
+
+	for i := 0; i < set.Size(); i++ {
+		n.Add(1)
+		go func() {
+			defer n.Done()
+
+			for j := 0; j < 10; j++ {
+				_, err := set.RunSchedule(context.Background(), []string{"SLOW"})
+				assert.NilError(t, err)
+			}
+		}()
+	}
+
+	n.Wait()
+	assert.Check(t, set.Health().Runners > 1)
+
+	for deadline := time.Now().Add(5 * time.Second); set.Health().Runners > 1 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Equal(t, set.Health().Runners, 1)
+	assert.Equal(t, set.Size(), 3)
+	assert.NilError(t, set.Delete(context.Background()))
+}
+
+func TestRunScheduleTimeout(t *testing.T) {
+	t.Parallel()
+
+	set, err := runner.NewRunnerSet(context.Background(), 1, newMockRunnerBuilder)
+	assert.NilError(t, err)
+	set.SetTimeouts(runner.Timeouts{Schedule: 50 * time.Millisecond})
+
+	results, err := set.RunSchedule(context.Background(), []string{"PASS", "FAIL", "HANG", "PASS"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, statuses(results), []runner.Status{
+		runner.StatusPass, runner.StatusFail, runner.StatusTimeout, runner.StatusNotRun,
+	})
+	assert.Equal(t, results.Runner, "runner-0")
+
+	results, err = set.RunSchedule(context.Background(), []string{"PASS", "FAIL"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, statuses(results), []runner.Status{runner.StatusPass, runner.StatusFail})
+	assert.NilError(t, set.Delete(context.Background()))
+}
+
+func TestRunScheduleTestTimeout(t *testing.T) {
+	t.Parallel()
+
+	set, err := runner.NewRunnerSet(context.Background(), 1, newMockRunnerBuilder)
+	assert.NilError(t, err)
+	set.SetTimeouts(runner.Timeouts{Test: 50 * time.Millisecond})
+
+	schedule := []string{"SLOW", "SLOW", "SLOW", "SLOW", "SLOW", "HANG", "PASS"}
+	results, err := set.RunSchedule(context.Background(), schedule)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, statuses(results), []runner.Status{
+		runner.StatusPass, runner.StatusPass, runner.StatusPass, runner.StatusPass,
+		runner.StatusPass, runner.StatusTimeout, runner.StatusNotRun,
+	})
+	assert.Check(t, strings.Contains(results.Results[5].Message, "test timed out"))
+	assert.NilError(t, set.Delete(context.Background()))
+}
+
+func statuses(results runner.RunResults) []runner.Status {
+	statuses := make([]runner.Status, len(results.Results))
+	for i, outcome := range results.Results {
+		statuses[i] = outcome.Status
+	}
+
+	return statuses
+}
@@ -0,0 +1,203 @@
+package testsuite
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// The directories which are not sources of a test suite, such as the ones of
+// the version control systems and of the build outputs.
+var skippedDirs = map[string]struct{}{
+	".git":         {},
+	".hg":          {},
+	".svn":         {},
+	".gradle":      {},
+	"__pycache__":  {},
+	"build":        {},
+	"node_modules": {},
+	"target":       {},
+}
+
+// sourceFile is a file into the directory of a test suite.
+type sourceFile struct {
+	path   string
+	digest []byte
+}
+
+// sourceIndex indexes the files of a test suite by their name without the
+// extension and by the identifiers they contain, so that the sources of a
+// test are found without scanning the content of all the files again.
+type sourceIndex struct {
+	root     string
+	files    []sourceFile
+	named    map[string][]int
+	mentions map[string][]int
+}
+
+// isIdentifier reports whether a rune can be part of an identifier.
+func isIdentifier(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// identifiers returns the distinct identifiers contained into some data.
+func identifiers(data []byte) map[string]struct{} {
+	result := map[string]struct{}{}
+	for _, field := range bytes.FieldsFunc(data, func(r rune) bool { return !isIdentifier(r) }) {
+		result[string(field)] = struct{}{}
+	}
+
+	return result
+}
+
+// add reads a file, hashes it and indexes it. If there is any error, it is
+// returned.
+func (s *sourceIndex) add(path string) error {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(data)
+	index := len(s.files)
+	s.files = append(s.files, sourceFile{path: filepath.ToSlash(rel), digest: digest[:]})
+
+	base := filepath.Base(path)
+	stem := strings.TrimSuffix(base, filepath.Ext(base))
+	s.named[stem] = append(s.named[stem], index)
+
+	for identifier := range identifiers(data) {
+		s.mentions[identifier] = append(s.mentions[identifier], index)
+	}
+
+	return nil
+}
+
+// Fingerprints returns a fingerprint of the sources of each test of the test
+// suite. The sources of a test are the files named after the last component
+// of the name of the test or, if there is none, the files mentioning it. The
+// directories of the version control systems and of the build outputs are
+// skipped. The tests without any source have no fingerprint. If there is any
+// error, it is returned.
+func (t *TestSuite) Fingerprints(tests []string) (map[string]string, error) {
+	index := &sourceIndex{root: t.path, named: map[string][]int{}, mentions: map[string][]int{}}
+
+	err := filepath.WalkDir(t.path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if _, ok := skippedDirs[entry.Name()]; ok && file != t.path {
+				return filepath.SkipDir
+			}
+
+			return nil
+		} else if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		return index.add(file)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fingerprint tests: %w", err)
+	}
+
+	fingerprints := make(map[string]string, len(tests))
+	for _, test := range tests {
+		sources, err := index.sources(test)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fingerprint tests: %w", err)
+		} else if len(sources) == 0 {
+			continue
+		}
+
+		hash := sha256.New()
+		for _, source := range sources {
+			fmt.Fprintf(hash, "%s\x00", index.files[source].path)
+			hash.Write(index.files[source].digest)
+		}
+
+		fingerprints[test] = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	return fingerprints, nil
+}
+
+// sources returns the indexes of the files named after the last component
+// of the name of a test or, if there is none, of the files mentioning it. A
+// name made of a single identifier is only mentioned as a whole word. If
+// there is any error, it is returned.
+func (s *sourceIndex) sources(test string) ([]int, error) {
+	components := strings.FieldsFunc(test, func(r rune) bool {
+		return strings.ContainsRune(".#:/\\", r)
+	})
+	if len(components) == 0 {
+		return nil, nil
+	}
+
+	name := components[len(components)-1]
+	if named := s.named[name]; len(named) > 0 {
+		return named, nil
+	}
+
+	words := strings.FieldsFunc(name, func(r rune) bool { return !isIdentifier(r) })
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	candidates := s.mentions[words[0]]
+	for _, word := range words[1:] {
+		candidates = intersect(candidates, s.mentions[word])
+	}
+
+	if len(words) == 1 && words[0] == name {
+		return candidates, nil
+	}
+
+	// The candidates contain all the words of the name, but only the ones
+	// containing the whole name mention the test.
+	mentioned := []int{}
+	for _, candidate := range candidates {
+		data, err := os.ReadFile(filepath.Join(s.root, filepath.FromSlash(s.files[candidate].path)))
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.Contains(data, []byte(name)) {
+			mentioned = append(mentioned, candidate)
+		}
+	}
+
+	return mentioned, nil
+}
+
+// intersect returns the elements of two sorted lists found into both.
+func intersect(a, b []int) []int {
+	result := []int{}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i, j = i+1, j+1
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,60 @@
+package testsuite_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pako-23/gtdd/internal/testsuite"
+	"gotest.tools/v3/assert"
+)
+
+func TestFingerprints(t *testing.T) {
+	t.Parallel()
+
+	var (
+		path  = t.TempDir()
+		tests = []string{"tests.LoginTest", "tests.LogoutTest", "test_cart.py::test_checkout", "tests.MissingTest", "cart.spec.js#adds an item"}
+		files = map[string]string{
+			"tests/LoginTest.java":            "class LoginTest {}",
+			"tests/LogoutTest.java":           "class LogoutTest {}",
+			"test_cart.py":                    "def test_checkout(): pass",
+			"suite.xml":                       "LoginTest LogoutTest",
+			"cart.spec.js":                    "it('adds an item', () => {})",
+			"other.spec.js":                   "it('adds an item twice', () => {}); it('an item adds', () => {})",
+			".git/objects/MissingTest":        "MissingTest",
+			"node_modules/lib/MissingTest.js": "MissingTest",
+			"target/MissingTest.class":        "MissingTest",
+		}
+	)
+
+	write := func(name, content string) {
+		assert.NilError(t, os.MkdirAll(filepath.Dir(filepath.Join(path, name)), 0o755))
+		assert.NilError(t, os.WriteFile(filepath.Join(path, name), []byte(content), 0o644))
+	}
+
+	for name, content := range files {
+		write(name, content)
+	}
+
+	suite, err := testsuite.NewTestSuite(path)
+	assert.NilError(t, err)
+
+	before, err := suite.Fingerprints(tests)
+	assert.NilError(t, err)
+	assert.Equal(t, len(before), 4)
+	_, ok := before["tests.MissingTest"]
+	assert.Assert(t, !ok)
+	assert.Assert(t, before["tests.LoginTest"] != before["tests.LogoutTest"])
+
+	write("tests/LoginTest.java", "class LoginTest { void login() {} }")
+	write("suite.xml", "LoginTest LogoutTest CheckoutTest")
+	write("other.spec.js", "it('an item adds', () => {})")
+
+	after, err := suite.Fingerprints(tests)
+	assert.NilError(t, err)
+	assert.Assert(t, before["tests.LoginTest"] != after["tests.LoginTest"])
+	assert.Equal(t, before["tests.LogoutTest"], after["tests.LogoutTest"])
+	assert.Equal(t, before["test_cart.py::test_checkout"], after["test_cart.py::test_checkout"])
+	assert.Assert(t, before["cart.spec.js#adds an item"] != after["cart.spec.js#adds an item"])
+}
@@ -1,11 +1,12 @@
 package testsuite
 
 import (
+	"context"
 	"fmt"
-	"regexp"
 	"strings"
 
 	"github.com/pako-23/gtdd/internal/docker"
+	"github.com/pako-23/gtdd/internal/runner"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -17,19 +18,19 @@ type JavaSeleniumTestSuite struct {
 
 // Build produces the artifacts needed to run the Java test suite. It will
 // create a Docker image on the host. If there is any error it is returned.
-func (j *JavaSeleniumTestSuite) Build(path string) error {
+func (j *JavaSeleniumTestSuite) Build(ctx context.Context, path string) error {
 	client, err := docker.NewClient()
 	if err != err {
 		return err
 	}
 	defer client.Close()
 
-	return client.BuildImage(j.Image, path, "Dockerfile")
+	return client.BuildImage(ctx, j.Image, path, "Dockerfile")
 }
 
 // ListTests returns the list of all tests declared into a Java test suite in
 // the order in which they are run. If there is any error, it is returned.
-func (j *JavaSeleniumTestSuite) ListTests() (tests []string, err error) {
+func (j *JavaSeleniumTestSuite) ListTests(ctx context.Context) (tests []string, err error) {
 	client, err := docker.NewClient()
 	if err != err {
 		return nil, err
@@ -39,19 +40,19 @@ func (j *JavaSeleniumTestSuite) ListTests() (tests []string, err error) {
 	app := docker.App{
 		"testsuite": {Command: []string{"--list-tests"}, Image: j.Image},
 	}
-	instance, err := client.Run(app, docker.RunOptions{})
+	instance, err := client.Run(ctx, app, docker.RunOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to start Java test suite container: %w", err)
 	}
 	defer func() {
-		deleteErr := client.Delete(instance)
+		deleteErr := client.Delete(context.WithoutCancel(ctx), instance)
 		if err == nil {
 			err = deleteErr
 
 		}
 	}()
 
-	logs, err := client.GetContainerLogs(instance["testsuite"])
+	logs, err := client.GetContainerLogs(ctx, instance["testsuite"])
 	if err != nil {
 		return nil, err
 	}
@@ -59,11 +60,9 @@ func (j *JavaSeleniumTestSuite) ListTests() (tests []string, err error) {
 	return strings.Split(strings.Trim(logs, "\n"), "\n"), nil
 }
 
-// Run invokes the Java test suite with a given configuration and returns its
-// results. The test results are represented as booleans. If the test
-// is passed, the value is true; otherwise it is false. If there is
-// any error, it is returned.
-func (j *JavaSeleniumTestSuite) Run(config *RunConfig) (results []bool, err error) {
+// Run invokes the Java test suite with a given configuration and returns the
+// outcomes of its tests. If there is any error, it is returned.
+func (j *JavaSeleniumTestSuite) Run(ctx context.Context, config *RunConfig) (results []runner.TestOutcome, err error) {
 	client, err := docker.NewClient()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client to run Java test suite: %w", err)
@@ -77,12 +76,12 @@ func (j *JavaSeleniumTestSuite) Run(config *RunConfig) (results []bool, err erro
 		},
 	}
 
-	instance, err := client.Run(suite, *config.StartConfig)
+	instance, err := client.Run(ctx, suite, *config.StartConfig)
 	if err != nil {
 		return nil, fmt.Errorf("error in starting java test suite container: %w", err)
 	}
 	defer func() {
-		deleteErr := client.Delete(instance)
+		deleteErr := client.Delete(context.WithoutCancel(ctx), instance)
 		if err == nil {
 			err = deleteErr
 
@@ -90,21 +89,14 @@ func (j *JavaSeleniumTestSuite) Run(config *RunConfig) (results []bool, err erro
 	}()
 	log.Debugf("successfully started java test suite container %s", instance[config.Name])
 
-	logs, err := client.GetContainerLogs(instance[config.Name])
-	if err != nil {
+	logs, err := client.FollowContainerLogs(ctx, instance[config.Name], NewProgressWriter(ctx, config.Tests))
+	if err != nil && ctx.Err() != nil {
+		return interruptedResults(ctx, client, instance, config), err
+	} else if err != nil {
 		return nil, err
 	}
 	log.Debugf("successfully obtained logs from java test suite container %s", instance["testsuite"])
 	log.Debugf("container logs: %s", logs)
 
-	result := make([]bool, 0, len(config.Tests))
-	r, _ := regexp.Compile("[a-zA-Z._0-9]+ (0|1)")
-	lines := strings.Split(strings.Trim(logs, "\n"), "\n")
-	for _, line := range lines[len(lines)-len(config.Tests):] {
-		if r.MatchString(line) {
-			result = append(result, line[len(line)-1] == '1')
-		}
-	}
-
-	return result, nil
+	return ParseLogs(logs, config.Tests), nil
 }
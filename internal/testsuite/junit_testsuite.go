@@ -1,8 +1,10 @@
 package testsuite
 
 import (
+	"context"
 	"fmt"
 	"github.com/pako-23/gtdd/internal/docker"
+	"github.com/pako-23/gtdd/internal/runner"
 	log "github.com/sirupsen/logrus"
 	"os"
 	"strings"
@@ -23,14 +25,11 @@ find target/ -name TEST*.xml -exec grep testcase {} \\; | awk -F'\"' '{
 const junitRunner = `import org.junit.runner.JUnitCore;
 import org.junit.runner.Request;
 import org.junit.runner.Result;
-import java.io.PrintWriter;
-import java.io.FileWriter;
-import java.io.IOException;
+import org.junit.runner.notification.Failure;
 
 public class CustomRunner {
     public static void main(final String[] args) throws ClassNotFoundException {
         JUnitCore core = new JUnitCore();
-        final boolean[] results = new boolean[args.length];
 
 	for (int i = 0; i < args.length; ++i) {
 	    String[] classAndMethod = args[i].split("#");
@@ -38,21 +37,28 @@ public class CustomRunner {
                classAndMethod[1]);
 
 	    Result result = core.run(request);
-	    results[i] = result.wasSuccessful();
-        }
-
-	try {
-	    PrintWriter out = new PrintWriter(new FileWriter("summary.txt"));
+	    String outcome = String.format("` + OutcomeMarker + `%s %s %d", args[i], status(result), result.getRunTime());
+	    if (!result.wasSuccessful()) {
+		Failure failure = result.getFailures().get(0);
+		String message = String.valueOf(failure.getMessage());
 
-	    for (int i = 0; i < results.length; ++i)
-		out.println(String.format("%s %d", args[i], results[i] ? 1 : 0));
+		System.out.print(failure.getTrace());
+		outcome += " " + String.join(" ", message.split(System.lineSeparator()));
+	    }
 
-	    out.close();
-        } catch (IOException e) {
-            System.exit(1);
+	    System.out.println(outcome);
+	    System.out.flush();
         }
         System.exit(0);
     }
+
+    private static String status(Result result) {
+        if (result.wasSuccessful())
+            return result.getRunCount() == 0 && result.getIgnoreCount() > 0 ? "S" : "1";
+
+        Failure failure = result.getFailures().get(0);
+        return failure.getException() instanceof AssertionError ? "0" : "E";
+    }
 }`
 
 const junitDockerFile = `FROM maven:3.6.1-jdk-8
@@ -67,7 +73,7 @@ RUN echo "#\!/bin/sh\n%s"  > ./list_tests.sh
 RUN chmod +x list_tests.sh
 RUN echo '%s' > CustomRunner.java
 RUN javac -cp "/app/junit-4.12.jar:$(cat cp.txt):" CustomRunner.java
-RUN echo "#\!/bin/sh\n\njava -cp \"/app/junit-4.12.jar:$(cat cp.txt):/app/target/test-classes/:/app/target/classes/:\" CustomRunner \"\$@\" >/dev/null\ncat summary.txt" > run_tests.sh
+RUN echo "#\!/bin/sh\n\njava -cp \"/app/junit-4.12.jar:$(cat cp.txt):/app/target/test-classes/:/app/target/classes/:\" CustomRunner \"\$@\"" > run_tests.sh
 RUN chmod +x run_tests.sh
 `
 
@@ -75,7 +81,7 @@ type JunitTestSuite struct {
 	Image string
 }
 
-func (j *JunitTestSuite) Build(path string) error {
+func (j *JunitTestSuite) Build(ctx context.Context, path string) error {
 	file, err := os.Create("Dockerfile")
 	if err != nil {
 		return err
@@ -94,10 +100,10 @@ func (j *JunitTestSuite) Build(path string) error {
 	}
 	defer client.Close()
 
-	return client.BuildImage(j.Image, ".", "Dockerfile")
+	return client.BuildImage(ctx, j.Image, ".", "Dockerfile")
 }
 
-func (j *JunitTestSuite) ListTests() (tests []string, err error) {
+func (j *JunitTestSuite) ListTests(ctx context.Context) (tests []string, err error) {
 	client, err := docker.NewClient()
 	if err != err {
 		return nil, err
@@ -107,19 +113,19 @@ func (j *JunitTestSuite) ListTests() (tests []string, err error) {
 	app := docker.App{
 		"testsuite": {Command: []string{"./list_tests.sh"}, Image: j.Image},
 	}
-	instance, err := client.Run(app, docker.RunOptions{})
+	instance, err := client.Run(ctx, app, docker.RunOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to start Java test suite container: %w", err)
 	}
 	defer func() {
-		deleteErr := client.Delete(instance)
+		deleteErr := client.Delete(context.WithoutCancel(ctx), instance)
 		if err == nil {
 			err = deleteErr
 
 		}
 	}()
 
-	logs, err := client.GetContainerLogs(instance["testsuite"])
+	logs, err := client.GetContainerLogs(ctx, instance["testsuite"])
 	if err != nil {
 		return nil, err
 	}
@@ -127,7 +133,7 @@ func (j *JunitTestSuite) ListTests() (tests []string, err error) {
 	return strings.Split(strings.Trim(logs, "\n"), "\n"), nil
 }
 
-func (j *JunitTestSuite) Run(config *RunConfig) (results []bool, err error) {
+func (j *JunitTestSuite) Run(ctx context.Context, config *RunConfig) (results []runner.TestOutcome, err error) {
 	client, err := docker.NewClient()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cleint for JUnit testsuite: %w", err)
@@ -141,12 +147,12 @@ func (j *JunitTestSuite) Run(config *RunConfig) (results []bool, err error) {
 		},
 	}
 
-	instance, err := client.Run(suite, *config.StartConfig)
+	instance, err := client.Run(ctx, suite, *config.StartConfig)
 	if err != nil {
 		return nil, fmt.Errorf("error in starting java test suite container: %w", err)
 	}
 	defer func() {
-		deleteErr := client.Delete(instance)
+		deleteErr := client.Delete(context.WithoutCancel(ctx), instance)
 		if err == nil {
 			err = deleteErr
 
@@ -154,19 +160,15 @@ func (j *JunitTestSuite) Run(config *RunConfig) (results []bool, err error) {
 	}()
 	log.Debugf("successfully started java test suite container %s", instance[config.Name])
 
-	logs, err := client.GetContainerLogs(instance[config.Name])
-	if err != nil {
+	logs, err := client.FollowContainerLogs(ctx, instance[config.Name], NewProgressWriter(ctx, config.Tests))
+	if err != nil && ctx.Err() != nil {
+		return interruptedResults(ctx, client, instance, config), err
+	} else if err != nil {
 		return nil, err
 	}
 
 	log.Debugf("successfully obtained logs from java test suite container %s", instance["testsuite"])
 	log.Debugf("container logs: %s", logs)
 
-	result := make([]bool, len(config.Tests))
-	lines := strings.Split(strings.Trim(logs, "\n"), "\n")
-	for i, line := range lines {
-		result[i] = line[len(line)-1] == '1'
-	}
-
-	return result, nil
+	return ParseLogs(logs, config.Tests), nil
 }
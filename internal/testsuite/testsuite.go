@@ -9,11 +9,17 @@ package testsuite
 import (
 	log "github.com/sirupsen/logrus"
 
+	"bytes"
+	"context"
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/docker/docker/client"
 	"github.com/pako-23/gtdd/internal/docker"
+	"github.com/pako-23/gtdd/internal/runner"
 )
 
 type RunConfig struct {
@@ -40,17 +46,42 @@ func NewTestSuite(path string) (*TestSuite, error) {
 	}, nil
 }
 
-func (t *TestSuite) Build() error {
-	client, err := docker.NewClient()
-	if err != err {
+// Image returns the name of the Docker image of the test suite.
+func (t *TestSuite) Image() string {
+	return t.image
+}
+
+// Path returns the directory of the test suite.
+func (t *TestSuite) Path() string {
+	return t.path
+}
+
+// Build builds the image of the test suite through the Docker client created
+// with the provided options, such as the host of a Docker-compatible engine.
+// If there is any error, it is returned.
+func (t *TestSuite) Build(ctx context.Context, options ...client.Opt) error {
+	client, err := docker.NewClient(options...)
+	if err != nil {
 		return err
 	}
 	defer client.Close()
 
-	return client.BuildImage(t.image, t.path, "Dockerfile")
+	return client.BuildImage(ctx, t.image, t.path, "Dockerfile")
 }
 
-func (t *TestSuite) ListTests() ([]string, error) {
+// Digest returns the content-addressable ID of the Docker image of the test
+// suite. If there is any error, it is returned.
+func (t *TestSuite) Digest(ctx context.Context) (string, error) {
+	client, err := docker.NewClient()
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	return client.ImageID(ctx, t.image)
+}
+
+func (t *TestSuite) ListTests(ctx context.Context) ([]string, error) {
 	client, err := docker.NewClient()
 	if err != err {
 		return nil, err
@@ -63,19 +94,19 @@ func (t *TestSuite) ListTests() ([]string, error) {
 			Image:   t.image,
 		},
 	}
-	instance, err := client.Run(app, docker.RunOptions{})
+	instance, err := client.Run(ctx, app, docker.RunOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to start test suite container: %w", err)
 	}
 	defer func() {
-		deleteErr := client.Delete(instance)
+		deleteErr := client.Delete(context.WithoutCancel(ctx), instance)
 		if err == nil {
 			err = deleteErr
 
 		}
 	}()
 
-	logs, err := client.GetContainerLogs(instance["testsuite"])
+	logs, err := client.GetContainerLogs(ctx, instance["testsuite"])
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +114,9 @@ func (t *TestSuite) ListTests() ([]string, error) {
 	return strings.Split(strings.Trim(logs, "\n"), "\n"), nil
 }
 
-func (t *TestSuite) Run(config *RunConfig) ([]bool, error) {
+// Run runs the tests of the test suite with a given configuration and returns
+// their outcomes. If there is any error, it is returned.
+func (t *TestSuite) Run(ctx context.Context, config *RunConfig) (results []runner.TestOutcome, err error) {
 	client, err := docker.NewClient()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client to run test suite: %w", err)
@@ -97,12 +130,12 @@ func (t *TestSuite) Run(config *RunConfig) ([]bool, error) {
 		},
 	}
 
-	instance, err := client.Run(suite, *config.StartConfig)
+	instance, err := client.Run(ctx, suite, *config.StartConfig)
 	if err != nil {
 		return nil, fmt.Errorf("error in starting test suite container: %w", err)
 	}
 	defer func() {
-		deleteErr := client.Delete(instance)
+		deleteErr := client.Delete(context.WithoutCancel(ctx), instance)
 		if err == nil {
 			err = deleteErr
 
@@ -110,18 +143,210 @@ func (t *TestSuite) Run(config *RunConfig) ([]bool, error) {
 	}()
 	log.Debugf("successfully started testsuite container %s", instance[config.Name])
 
-	logs, err := client.GetContainerLogs(instance[config.Name])
-	if err != nil {
+	logs, err := client.FollowContainerLogs(ctx, instance[config.Name], NewProgressWriter(ctx, config.Tests))
+	if err != nil && ctx.Err() != nil {
+		return interruptedResults(ctx, client, instance, config), err
+	} else if err != nil {
 		return nil, err
 	}
 	log.Debugf("successfully obtained logs from test suite container %s", instance["testsuite"])
 	log.Debugf("container logs: %s", logs)
 
-	result := make([]bool, len(config.Tests))
-	lines := strings.Split(strings.Trim(logs, "\n"), "\n")
-	for i, line := range lines[len(lines)-len(config.Tests):] {
-		result[i] = line[len(line)-1] == '1'
+	return ParseLogs(logs, config.Tests), nil
+}
+
+// ParseLogs returns the outcomes of the tests from the logs of a test suite
+// which exited. The tests without an outcome are reported as not run.
+func ParseLogs(logs string, tests []string) []runner.TestOutcome {
+	outcomes, output := parseOutcomes(logs, tests)
+
+	return completeOutcomes(outcomes, output, tests)
+}
+
+// ParsePartialLogs returns the outcomes of the tests which completed from the
+// logs of a test suite which was interrupted.
+func ParsePartialLogs(logs string, tests []string) []runner.TestOutcome {
+	outcomes, _ := parseOutcomes(logs, tests)
+
+	return outcomes
+}
+
+// interruptedResults kills the containers of a test suite whose run was
+// interrupted before completing, and returns the outcomes of the tests which
+// completed before the interruption.
+func interruptedResults(ctx context.Context, client *docker.Client, instance docker.AppInstance, config *RunConfig) []runner.TestOutcome {
+	ctx = context.WithoutCancel(ctx)
+
+	if err := client.Kill(ctx, instance); err != nil {
+		log.Warnf("failed to kill interrupted test suite container: %v", err)
+		return nil
+	}
+
+	logs, err := client.GetContainerLogs(ctx, instance[config.Name])
+	if err != nil {
+		log.Warnf("failed to obtain logs from interrupted test suite container: %v", err)
+		return nil
+	}
+
+	return ParsePartialLogs(logs, config.Tests)
+}
+
+// OutcomeMarker prefixes the outcome lines printed by the test suites, so that
+// they can be told apart from the output of the tests, even when a test does
+// not end its output with a newline.
+const OutcomeMarker = "@@gtdd-outcome@@ "
+
+// statusCodes maps the codes used by the test suites to report the outcome of
+// a test to the corresponding status.
+var statusCodes = map[string]runner.Status{
+	"1": runner.StatusPass,
+	"0": runner.StatusFail,
+	"E": runner.StatusError,
+	"S": runner.StatusSkip,
+}
+
+// parseOutcomes parses the outcomes of the tests which completed from the
+// logs of a test suite. Each outcome is a line containing the name of the
+// test followed by 1 if it passed, 0 if it failed, E if it errored or S if it
+// was skipped. The status can be followed by the running time of the test in
+// milliseconds and by a message. If the logs contain the OutcomeMarker, only
+// the text following a marker is an outcome, and the text printed before the
+// marker on the same line is kept as output. The lines printed before an
+// outcome are kept as the output of the test if it did not pass. The tests
+// are run in order, so the parsing stops at the first test without an
+// outcome. The lines following the last outcome are returned as the
+// remaining output.
+func parseOutcomes(logs string, tests []string) ([]runner.TestOutcome, string) {
+	var (
+		outcomes = make([]runner.TestOutcome, 0, len(tests))
+		output   = []string{}
+		marked   = strings.Contains(logs, OutcomeMarker)
+	)
+
+	for _, line := range strings.Split(strings.TrimRight(logs, "\n"), "\n") {
+		if len(outcomes) == len(tests) {
+			output = append(output, line)
+			continue
+		}
+
+		prefix, outcome, ok := parseOutcomeLine(line, tests[len(outcomes)], marked)
+		if !ok {
+			output = append(output, line)
+			continue
+		}
+
+		if prefix != "" {
+			output = append(output, prefix)
+		}
+
+		if !outcome.Passed() {
+			outcome.Output = strings.Join(output, "\n")
+		}
+
+		outcomes = append(outcomes, outcome)
+		output = output[:0]
+	}
+
+	return outcomes, strings.Join(output, "\n")
+}
+
+// parseOutcomeLine parses the outcome of the provided test from a line of the
+// logs of a test suite. If the logs are marked, only the text following the
+// OutcomeMarker is an outcome, and the text before the marker is returned as
+// output. It reports whether the line is the outcome of the test.
+func parseOutcomeLine(line, test string, marked bool) (string, runner.TestOutcome, bool) {
+	var prefix, candidate string
+	if marked {
+		index := strings.Index(line, OutcomeMarker)
+		if index == -1 {
+			return "", runner.TestOutcome{}, false
+		}
+		prefix, candidate = line[:index], line[index+len(OutcomeMarker):]
+	} else {
+		candidate = strings.TrimSpace(line)
+	}
+
+	if !strings.HasPrefix(candidate, test+" ") {
+		return "", runner.TestOutcome{}, false
+	}
+
+	fields := strings.SplitN(candidate[len(test)+1:], " ", 3)
+	status, ok := statusCodes[fields[0]]
+	if !ok {
+		return "", runner.TestOutcome{}, false
+	}
+
+	outcome := runner.TestOutcome{Status: status}
+	if len(fields) > 1 {
+		if millis, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			outcome.Duration = time.Duration(millis) * time.Millisecond
+		}
+	}
+	if len(fields) > 2 {
+		outcome.Message = fields[2]
+	}
+
+	return prefix, outcome, true
+}
+
+// ProgressWriter watches the logs of a test suite while they are written and
+// reports through a context each test which completed, so that the time
+// allowed to each test can start again.
+type ProgressWriter struct {
+	ctx   context.Context
+	tests []string
+	// The number of tests whose outcome was written so far.
+	completed int
+	// The last line written so far, if it does not end with a newline yet.
+	line []byte
+}
+
+// NewProgressWriter returns a writer reporting through the provided context
+// the tests of a schedule which completed, as their outcomes are written.
+func NewProgressWriter(ctx context.Context, tests []string) *ProgressWriter {
+	return &ProgressWriter{ctx: ctx, tests: tests}
+}
+
+// Write reports the outcomes found into the complete lines written so far.
+// It never fails.
+func (p *ProgressWriter) Write(data []byte) (int, error) {
+	p.line = append(p.line, data...)
+
+	for {
+		index := bytes.IndexByte(p.line, '\n')
+		if index == -1 {
+			break
+		}
+
+		line := string(p.line[:index])
+		p.line = p.line[index+1:]
+
+		if p.completed == len(p.tests) {
+			continue
+		}
+
+		marked := strings.Contains(line, OutcomeMarker)
+		if _, _, ok := parseOutcomeLine(line, p.tests[p.completed], marked); ok {
+			p.completed++
+			runner.ReportProgress(p.ctx)
+		}
+	}
+
+	return len(data), nil
+}
+
+// completeOutcomes reports the tests without an outcome as not run. It is
+// used when the test suite exited before running all the tests, so the
+// remaining output of the test suite is kept with the first test not run.
+func completeOutcomes(outcomes []runner.TestOutcome, output string, tests []string) []runner.TestOutcome {
+	for len(outcomes) < len(tests) {
+		outcomes = append(outcomes, runner.TestOutcome{
+			Status:  runner.StatusNotRun,
+			Message: "the test suite exited before running the test",
+			Output:  output,
+		})
+		output = ""
 	}
 
-	return result, nil
+	return outcomes
 }
@@ -0,0 +1,102 @@
+package testsuite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pako-23/gtdd/internal/runner"
+	"gotest.tools/v3/assert"
+)
+
+func TestParseOutcomes(t *testing.T) {
+	t.Parallel()
+
+	var tests = []struct {
+		logs     string
+		tests    []string
+		expected []runner.TestOutcome
+	}{
+		{
+			logs:  "test1 1\ntest2 0\n",
+			tests: []string{"test1", "test2"},
+			expected: []runner.TestOutcome{
+				{Status: runner.StatusPass},
+				{Status: runner.StatusFail},
+			},
+		},
+		{
+			logs:  "starting\ntest1 S 0\ntest2 E 1500 connection refused\nat Test3.java:12\ntest3 0 20 expected 1 but was 2\n",
+			tests: []string{"test1", "test2", "test3"},
+			expected: []runner.TestOutcome{
+				{Status: runner.StatusSkip},
+				{Status: runner.StatusError, Duration: 1500 * time.Millisecond, Message: "connection refused"},
+				{
+					Status:   runner.StatusFail,
+					Duration: 20 * time.Millisecond,
+					Message:  "expected 1 but was 2",
+					Output:   "at Test3.java:12",
+				},
+			},
+		},
+		{
+			logs:     "test2 1\ntest1 1\n",
+			tests:    []string{"test1", "test2"},
+			expected: []runner.TestOutcome{{Status: runner.StatusPass}},
+		},
+		{
+			logs:     "test10 1\ntest1 X\n",
+			tests:    []string{"test1"},
+			expected: []runner.TestOutcome{},
+		},
+		{
+			logs:  "test1 1\n" + OutcomeMarker + "test1 1 5\nprinting" + OutcomeMarker + "test2 E 7 boom\n",
+			tests: []string{"test1", "test2"},
+			expected: []runner.TestOutcome{
+				{Status: runner.StatusPass, Duration: 5 * time.Millisecond},
+				{Status: runner.StatusError, Duration: 7 * time.Millisecond, Message: "boom", Output: "printing"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		got, _ := parseOutcomes(test.logs, test.tests)
+		assert.DeepEqual(t, got, test.expected)
+	}
+}
+
+func TestCompleteOutcomes(t *testing.T) {
+	t.Parallel()
+
+	outcomes, output := parseOutcomes("test1 1\nException in thread main\n", []string{"test1", "test2", "test3"})
+	assert.Equal(t, output, "Exception in thread main")
+
+	outcomes = completeOutcomes(outcomes, output, []string{"test1", "test2", "test3"})
+	assert.Equal(t, len(outcomes), 3)
+	assert.Equal(t, outcomes[0].Status, runner.StatusPass)
+	assert.Equal(t, outcomes[1].Status, runner.StatusNotRun)
+	assert.Equal(t, outcomes[1].Output, "Exception in thread main")
+	assert.Equal(t, outcomes[2].Status, runner.StatusNotRun)
+	assert.Equal(t, outcomes[2].Output, "")
+}
+
+func TestProgressWriter(t *testing.T) {
+	t.Parallel()
+
+	completed := 0
+	ctx := runner.WithProgress(context.Background(), func() { completed++ })
+	w := NewProgressWriter(ctx, []string{"test1", "test2", "test3"})
+
+	for _, chunk := range []string{
+		"some output test1 1\n",
+		"@@gtdd-outcome@@ test1 1 5\nprinted without newline",
+		"@@gtdd-outcome@@ test2 0 ",
+		"3 failed\n@@gtdd-outcome@@ test4 1\n",
+	} {
+		n, err := w.Write([]byte(chunk))
+		assert.NilError(t, err)
+		assert.Equal(t, n, len(chunk))
+	}
+
+	assert.Equal(t, completed, 2)
+}